@@ -1,7 +1,150 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/address"
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/events"
+	"simplied-bitcoin-network-go/pkg/mining"
+	"simplied-bitcoin-network-go/pkg/storage"
+	"simplied-bitcoin-network-go/pkg/utils"
+	"simplied-bitcoin-network-go/pkg/utxo"
+)
+
+// Version 程序版本，构建时通过-ldflags注入
+var Version = "dev"
+
+// statsInterval 输出挖矿统计的间隔
+const statsInterval = 10 * time.Second
 
 func main() {
-	fmt.Sprintln("Miner main func ...")
+	configPath := flag.String("config", "config/config.yaml", "配置文件路径")
+	threads := flag.Int("threads", -1, "挖矿线程数，覆盖配置文件（0表示自动检测）")
+	address := flag.String("address", "", "接收区块奖励的地址，覆盖配置文件")
+	network := flag.String("network", "", "网络（mainnet、testnet、regtest、simnet），覆盖配置文件")
+	flag.Parse()
+
+	if err := run(*configPath, *threads, *address, *network); err != nil {
+		log.Fatalf("矿工退出: %v", err)
+	}
+}
+
+// run 加载配置、打开区块链并挖矿直到收到退出信号
+func run(configPath string, threads int, address, network string) error {
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if network != "" {
+		cfg.Blockchain.Network = network
+	}
+	if threads >= 0 {
+		cfg.Mining.Threads = threads
+	}
+	if address != "" {
+		cfg.Mining.MinerAddress = address
+	}
+
+	params, err := blockchain.ParamsForNetwork(cfg.Blockchain.Network)
+	if err != nil {
+		return err
+	}
+
+	payout, err := payoutScript(cfg.Mining.MinerAddress, params)
+	if err != nil {
+		return err
+	}
+
+	// 非主网的数据库放在以网络名命名的子目录中，使多个网络可以共用同一份配置
+	if params != blockchain.MainNetParams {
+		cfg.Database.Path = filepath.Join(filepath.Dir(cfg.Database.Path), params.Name, filepath.Base(cfg.Database.Path))
+	}
+
+	store, err := storage.Open(cfg.Database, params)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	utxoSet, err := utxo.NewSet(store.DB())
+	if err != nil {
+		return err
+	}
+
+	bus := events.NewBus()
+	c, err := chain.New(&chain.Config{
+		Store:   store,
+		UTXOSet: utxoSet,
+		Events:  bus,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	miner, err := mining.New(&mining.Config{
+		Chain:        c,
+		Events:       bus,
+		PayoutScript: payout,
+		Threads:      cfg.Mining.Threads,
+	})
+	if err != nil {
+		return err
+	}
+
+	bus.Subscribe(events.BlockConnected, func(e events.Event) {
+		data := e.Data.(*events.BlockEvent)
+		log.Printf("新区块 高度=%d 哈希=%x", data.Height, data.Block.Hash())
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		ticker := time.NewTicker(statsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				log.Printf("哈希率=%.2f H/s 已挖出区块=%d", miner.HashRate(), miner.BlocksFound())
+			}
+		}
+	}()
+
+	best := c.BestSnapshot()
+	log.Printf("矿工%s启动 网络=%s 线程=%d 主链高度=%d", Version, params.Name, miner.Threads(), best.Height)
+
+	if err := miner.Run(ctx); err != nil {
+		return err
+	}
+
+	log.Printf("矿工已停止 共挖出%d个区块", miner.BlocksFound())
+	return nil
+}
+
+// payoutScript 根据矿工地址构造锁定脚本
+//
+// 地址必须属于当前网络，可以是P2PKH、P2SH或隔离见证地址。
+func payoutScript(addr string, params *blockchain.ChainParams) ([]byte, error) {
+	if addr == "" {
+		return nil, fmt.Errorf("未配置矿工地址(mining.miner_address)")
+	}
+
+	decoded, err := address.Decode(addr, params)
+	if err != nil {
+		return nil, fmt.Errorf("解析矿工地址失败: %v", err)
+	}
+	return decoded.PkScript(), nil
 }
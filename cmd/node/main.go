@@ -1,7 +1,302 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/address"
+	"simplied-bitcoin-network-go/pkg/addrmgr"
+	"simplied-bitcoin-network-go/pkg/banman"
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/events"
+	"simplied-bitcoin-network-go/pkg/mempool"
+	"simplied-bitcoin-network-go/pkg/mining"
+	"simplied-bitcoin-network-go/pkg/netsync"
+	"simplied-bitcoin-network-go/pkg/network"
+	"simplied-bitcoin-network-go/pkg/rpc"
+	"simplied-bitcoin-network-go/pkg/storage"
+	"simplied-bitcoin-network-go/pkg/utils"
+	"simplied-bitcoin-network-go/pkg/utxo"
+)
+
+// Version 程序版本，构建时通过-ldflags注入
+var Version = "dev"
+
+// statsInterval 输出连接统计的间隔
+const statsInterval = time.Minute
 
 func main() {
-	fmt.Sprintln("Node main func ...")
+	configPath := flag.String("config", "config/config.yaml", "配置文件路径")
+	networkName := flag.String("network", "", "网络（mainnet、testnet、regtest、simnet），覆盖配置文件")
+	port := flag.Int("port", -1, "P2P监听端口，覆盖配置文件（0表示使用网络默认端口）")
+	rpcPort := flag.Int("rpcport", 0, "RPC监听端口，覆盖配置文件")
+	dbPath := flag.String("db", "", "数据库文件路径，覆盖配置文件")
+	mine := flag.Bool("mine", false, "启用挖矿，覆盖配置文件")
+	flag.Parse()
+
+	if err := run(*configPath, *networkName, *port, *rpcPort, *dbPath, *mine); err != nil {
+		log.Fatalf("节点退出: %v", err)
+	}
+}
+
+// run 加载配置、打开区块链并运行P2P网络直到收到退出信号
+func run(configPath, networkName string, port, rpcPort int, dbPath string, mine bool) error {
+	cfg, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if networkName != "" {
+		cfg.Blockchain.Network = networkName
+	}
+	if port >= 0 {
+		cfg.Network.Port = port
+	}
+	if rpcPort > 0 {
+		cfg.RPC.Port = rpcPort
+	}
+	if mine {
+		cfg.Mining.Enabled = true
+	}
+
+	params, err := blockchain.ParamsForNetwork(cfg.Blockchain.Network)
+	if err != nil {
+		return err
+	}
+
+	// 非主网的数据库放在以网络名命名的子目录中，使多个网络可以共用同一份配置
+	if dbPath != "" {
+		cfg.Database.Path = dbPath
+	} else if params != blockchain.MainNetParams {
+		cfg.Database.Path = filepath.Join(filepath.Dir(cfg.Database.Path), params.Name, filepath.Base(cfg.Database.Path))
+	}
+
+	store, err := storage.Open(cfg.Database, params)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	utxoSet, err := utxo.NewSet(store.DB())
+	if err != nil {
+		return err
+	}
+
+	bus := events.NewBus()
+	c, err := chain.New(&chain.Config{
+		Store:   store,
+		UTXOSet: utxoSet,
+		Events:  bus,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	// 重放主链审计货币发行量，超过配置的最大供应量或奖励计划时拒绝启动
+	audit, err := c.AuditSupply(cfg.GetMaxSupply())
+	if err != nil {
+		return fmt.Errorf("货币供应量审计失败: %v", err)
+	}
+	log.Printf("货币供应量审计通过 高度=%d 发行量=%d 奖励计划=%d", audit.Height, audit.Issued, audit.Scheduled)
+
+	txPool, err := mempool.New(&mempool.Config{Chain: c, UTXOSet: utxoSet, Events: bus})
+	if err != nil {
+		return err
+	}
+
+	// 内存池文件与数据库一样按网络区分目录
+	mempoolPath := filepath.Join(cfg.Blockchain.DataDir, mempool.DumpFileName)
+	if params != blockchain.MainNetParams {
+		mempoolPath = filepath.Join(cfg.Blockchain.DataDir, params.Name, mempool.DumpFileName)
+	}
+	if _, err := txPool.Load(mempoolPath); err != nil {
+		log.Printf("加载内存池失败: %v", err)
+	}
+	defer func() {
+		if err := txPool.Dump(mempoolPath); err != nil {
+			log.Printf("保存内存池失败: %v", err)
+		}
+	}()
+
+	syncManager, err := netsync.New(&netsync.Config{Chain: c, Events: bus, TxPool: txPool})
+	if err != nil {
+		return err
+	}
+	syncManager.Start()
+	defer syncManager.Stop()
+
+	addrManager, err := addrmgr.New(store.DB())
+	if err != nil {
+		return err
+	}
+	addrManager.Start()
+	defer func() {
+		if err := addrManager.Stop(); err != nil {
+			log.Printf("保存地址簿失败: %v", err)
+		}
+	}()
+
+	banList, err := banman.New(store.DB())
+	if err != nil {
+		return err
+	}
+
+	managerCfg := managerConfig(cfg.Network, params)
+	managerCfg.AddrBook = addrManager
+	managerCfg.BanList = banList
+	managerCfg.UserAgent = fmt.Sprintf("/simplied-bitcoin-network:%s/", Version)
+	managerCfg.BestHeight = func() int32 { return c.BestSnapshot().Height }
+	managerCfg.OnPeerConnected = func(p *network.Peer) {
+		log.Printf("节点已连接 %s 协议版本=%d 高度=%d 代理=%s", p, p.ProtocolVersion(), p.StartingHeight(), p.UserAgent())
+		syncManager.NewPeer(p)
+	}
+	managerCfg.OnPeerDisconnected = func(p *network.Peer) {
+		if reason := p.DisconnectReason(); reason != nil {
+			log.Printf("节点已断开 %s: %v", p, reason)
+		} else {
+			log.Printf("节点已断开 %s", p)
+		}
+		syncManager.DonePeer(p)
+	}
+	managerCfg.OnMessage = syncManager.HandleMessage
+
+	manager, err := network.NewPeerManager(managerCfg)
+	if err != nil {
+		return err
+	}
+	if err := manager.Start(); err != nil {
+		return err
+	}
+	defer manager.Stop()
+
+	rpcServer, err := rpc.New(rpcConfig(cfg, manager, banList, txPool))
+	if err != nil {
+		return err
+	}
+	if err := rpcServer.Start(); err != nil {
+		return err
+	}
+	defer rpcServer.Stop()
+
+	best := c.BestSnapshot()
+	log.Printf("节点%s启动 网络=%s 监听=%s RPC=%s 主链高度=%d", Version, params.Name, manager.Addr(), rpcServer.Addr(), best.Height)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.Mining.Enabled {
+		payout, err := payoutScript(cfg.Mining.MinerAddress, params)
+		if err != nil {
+			return err
+		}
+		miner, err := mining.New(&mining.Config{
+			Chain:        c,
+			Events:       bus,
+			TxSource:     txPool,
+			PayoutScript: payout,
+			Threads:      cfg.Mining.Threads,
+		})
+		if err != nil {
+			return err
+		}
+
+		minerDone := make(chan struct{})
+		defer func() { <-minerDone }()
+		go func() {
+			defer close(minerDone)
+			if err := miner.Run(ctx); err != nil {
+				log.Printf("挖矿停止: %v", err)
+			}
+		}()
+		log.Printf("挖矿已启动 线程=%d", miner.Threads())
+	}
+
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("节点正在停止")
+			return nil
+		case <-ticker.C:
+			progress := syncManager.Progress()
+			log.Printf("已连接节点=%d 已知地址=%d 区块头高度=%d 主链高度=%d 下载中=%d 内存池交易=%d",
+				manager.ConnectedCount(), addrManager.NumAddresses(), progress.HeadersHeight, progress.BlocksHeight, progress.BlocksInFlight, txPool.Count())
+		}
+	}
+}
+
+// managerConfig 根据网络配置构造连接管理器配置
+//
+// MaxConnections为入站和出站连接的总数，其中出站连接最多占用
+// network.DefaultMaxOutbound个名额，其余留给入站连接。
+// 端口为0时使用网络参数的默认端口，封禁阈值或封禁时长为0时使用network包的默认值。
+func managerConfig(netCfg utils.NetworkConfig, params *blockchain.ChainParams) network.ManagerConfig {
+	port := netCfg.Port
+	if port == 0 {
+		port = params.DefaultPort
+	}
+
+	maxOutbound := network.DefaultMaxOutbound
+	if netCfg.MaxConnections < maxOutbound {
+		maxOutbound = netCfg.MaxConnections
+	}
+
+	return network.ManagerConfig{
+		Params:            params,
+		ListenAddr:        fmt.Sprintf(":%d", port),
+		Seeds:             netCfg.Seeds,
+		MaxInbound:        netCfg.MaxConnections - maxOutbound,
+		MaxOutbound:       maxOutbound,
+		ConnectionTimeout: time.Duration(netCfg.ConnectionTimeout) * time.Second,
+		HeartbeatInterval: time.Duration(netCfg.HeartbeatInterval) * time.Second,
+		BanThreshold:      netCfg.BanThreshold,
+		BanDuration:       time.Duration(netCfg.BanDuration) * time.Second,
+		Services:          network.SFNodeNetwork,
+	}
+}
+
+// rpcConfig 根据配置构造RPC服务配置
+//
+// 只有启用认证时才使用配置中的认证密钥。手动封禁的默认时长与网络配置的封禁时长相同。
+func rpcConfig(cfg *utils.Config, manager *network.PeerManager, banList *banman.BanManager, txPool *mempool.TxPool) *rpc.Config {
+	rpcCfg := &rpc.Config{
+		ListenAddr:     net.JoinHostPort(cfg.RPC.Host, strconv.Itoa(cfg.RPC.Port)),
+		Peers:          manager,
+		BanList:        banList,
+		Mempool:        txPool,
+		BanDuration:    cfg.GetBanDuration(),
+		EnableCORS:     cfg.RPC.EnableCORS,
+		MaxRequestSize: int64(cfg.Security.MaxRequestSize),
+		RequestTimeout: time.Duration(cfg.Security.RequestTimeout) * time.Second,
+	}
+	if cfg.RPC.EnableAuth {
+		rpcCfg.AuthKey = cfg.RPC.AuthKey
+	}
+	return rpcCfg
+}
+
+// payoutScript 根据矿工地址构造锁定脚本
+//
+// 地址必须属于当前网络，可以是P2PKH、P2SH或隔离见证地址。
+func payoutScript(addr string, params *blockchain.ChainParams) ([]byte, error) {
+	if addr == "" {
+		return nil, fmt.Errorf("未配置矿工地址(mining.miner_address)")
+	}
+
+	decoded, err := address.Decode(addr, params)
+	if err != nil {
+		return nil, fmt.Errorf("解析矿工地址失败: %v", err)
+	}
+	return decoded.PkScript(), nil
 }
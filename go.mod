@@ -3,10 +3,10 @@ module simplied-bitcoin-network-go
 go 1.24.4
 
 require (
-	github.com/boltdb/bolt v1.3.1 // indirect
-	github.com/gorilla/mux v1.8.1 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
-	golang.org/x/crypto v0.41.0 // indirect
-	golang.org/x/sys v0.35.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/boltdb/bolt v1.3.1
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.4.0
+	golang.org/x/crypto v0.41.0
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sys v0.35.0 // indirect
@@ -0,0 +1,216 @@
+// Package address 实现了比特币地址的编码和解析
+//
+// 地址是锁定脚本的紧凑文本形式。P2PKH地址编码公钥的Hash160，P2SH地址编码
+// 赎回脚本的Hash160，两者都使用Base58Check编码，版本字节取自网络参数，
+// 因此一个网络的地址在其他网络上会被拒绝（测试网和回归测试网共用版本字节）。
+// 隔离见证地址使用Bech32/Bech32m编码，人类可读部分同样取自网络参数。
+package address
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+
+	"simplied-bitcoin-network-go/pkg/bech32"
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/script"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// HashSize 公钥哈希和脚本哈希的长度
+const HashSize = 20
+
+var (
+	// ErrInvalidAddress 地址编码、校验和或哈希长度错误
+	ErrInvalidAddress = errors.New("无效的地址")
+
+	// ErrWrongNetwork 地址版本字节或Bech32前缀不属于当前网络
+	ErrWrongNetwork = errors.New("地址不属于当前网络")
+
+	// ErrInvalidPubKey 公钥格式错误
+	ErrInvalidPubKey = errors.New("无效的公钥")
+
+	// ErrUnknownScript 锁定脚本不是可以表示为地址的标准脚本
+	ErrUnknownScript = errors.New("不支持的锁定脚本")
+)
+
+// Address 地址
+type Address interface {
+	// String 返回地址的文本编码
+	String() string
+
+	// ScriptAddress 返回地址编码的哈希
+	ScriptAddress() []byte
+
+	// PkScript 返回向该地址支付的锁定脚本
+	PkScript() []byte
+
+	// IsForNet 判断地址是否属于指定网络
+	IsForNet(params *blockchain.ChainParams) bool
+}
+
+// AddressPubKeyHash P2PKH地址
+type AddressPubKeyHash struct {
+	hash    [HashSize]byte
+	version byte
+}
+
+// NewAddressPubKeyHash 由20字节公钥哈希创建P2PKH地址
+func NewAddressPubKeyHash(pubKeyHash []byte, params *blockchain.ChainParams) (*AddressPubKeyHash, error) {
+	if len(pubKeyHash) != HashSize {
+		return nil, fmt.Errorf("%w: 公钥哈希长度%d", ErrInvalidAddress, len(pubKeyHash))
+	}
+	addr := &AddressPubKeyHash{version: params.PubKeyHashAddrID}
+	copy(addr.hash[:], pubKeyHash)
+	return addr, nil
+}
+
+// NewAddressPubKey 由序列化公钥（压缩或非压缩）创建P2PKH地址
+//
+// 同一私钥的压缩和非压缩公钥对应不同的地址。
+func NewAddressPubKey(serializedPubKey []byte, params *blockchain.ChainParams) (*AddressPubKeyHash, error) {
+	if _, err := secp256k1.ParsePubKey(serializedPubKey); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPubKey, err)
+	}
+	return NewAddressPubKeyHash(utils.Hash160(serializedPubKey), params)
+}
+
+// String 返回Base58Check编码
+func (a *AddressPubKeyHash) String() string {
+	return utils.Base58CheckEncode(a.hash[:], a.version)
+}
+
+// ScriptAddress 返回公钥哈希
+func (a *AddressPubKeyHash) ScriptAddress() []byte {
+	return append([]byte(nil), a.hash[:]...)
+}
+
+// PkScript 返回P2PKH锁定脚本
+//
+// 脚本格式：OP_DUP OP_HASH160 <20字节公钥哈希> OP_EQUALVERIFY OP_CHECKSIG
+func (a *AddressPubKeyHash) PkScript() []byte {
+	pkScript := make([]byte, 0, 25)
+	pkScript = append(pkScript, script.OpDup, script.OpHash160, script.OpData20)
+	pkScript = append(pkScript, a.hash[:]...)
+	return append(pkScript, script.OpEqualVerify, script.OpCheckSig)
+}
+
+// IsForNet 判断地址是否属于指定网络
+func (a *AddressPubKeyHash) IsForNet(params *blockchain.ChainParams) bool {
+	return a.version == params.PubKeyHashAddrID
+}
+
+// AddressScriptHash P2SH地址
+type AddressScriptHash struct {
+	hash    [HashSize]byte
+	version byte
+}
+
+// NewAddressScriptHash 由赎回脚本创建P2SH地址
+func NewAddressScriptHash(redeemScript []byte, params *blockchain.ChainParams) (*AddressScriptHash, error) {
+	if len(redeemScript) == 0 || len(redeemScript) > blockchain.MaxScriptSize {
+		return nil, fmt.Errorf("%w: 赎回脚本长度%d", ErrInvalidAddress, len(redeemScript))
+	}
+	return NewAddressScriptHashFromHash(utils.Hash160(redeemScript), params)
+}
+
+// NewAddressScriptHashFromHash 由20字节脚本哈希创建P2SH地址
+func NewAddressScriptHashFromHash(scriptHash []byte, params *blockchain.ChainParams) (*AddressScriptHash, error) {
+	if len(scriptHash) != HashSize {
+		return nil, fmt.Errorf("%w: 脚本哈希长度%d", ErrInvalidAddress, len(scriptHash))
+	}
+	addr := &AddressScriptHash{version: params.ScriptHashAddrID}
+	copy(addr.hash[:], scriptHash)
+	return addr, nil
+}
+
+// String 返回Base58Check编码
+func (a *AddressScriptHash) String() string {
+	return utils.Base58CheckEncode(a.hash[:], a.version)
+}
+
+// ScriptAddress 返回脚本哈希
+func (a *AddressScriptHash) ScriptAddress() []byte {
+	return append([]byte(nil), a.hash[:]...)
+}
+
+// PkScript 返回P2SH锁定脚本
+//
+// 脚本格式：OP_HASH160 <20字节脚本哈希> OP_EQUAL
+func (a *AddressScriptHash) PkScript() []byte {
+	pkScript := make([]byte, 0, 23)
+	pkScript = append(pkScript, script.OpHash160, script.OpData20)
+	pkScript = append(pkScript, a.hash[:]...)
+	return append(pkScript, script.OpEqual)
+}
+
+// IsForNet 判断地址是否属于指定网络
+func (a *AddressScriptHash) IsForNet(params *blockchain.ChainParams) bool {
+	return a.version == params.ScriptHashAddrID
+}
+
+// Decode 解析地址，地址版本或前缀必须属于指定网络
+//
+// 以网络的Bech32前缀开头的地址按隔离见证地址解析，其他地址按Base58Check解析。
+func Decode(addr string, params *blockchain.ChainParams) (Address, error) {
+	if len(addr) == 0 {
+		return nil, fmt.Errorf("%w: 地址为空", ErrInvalidAddress)
+	}
+	if isSegWitAddress(addr, params) {
+		return decodeSegWit(addr, params)
+	}
+	if hrp, _, _, err := bech32.Decode(addr); err == nil {
+		return nil, fmt.Errorf("%w: 前缀%s不属于%s网络", ErrWrongNetwork, hrp, params.Name)
+	}
+
+	hash, version, err := utils.Base58CheckDecode(addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+	if len(hash) != HashSize {
+		return nil, fmt.Errorf("%w: 哈希长度%d", ErrInvalidAddress, len(hash))
+	}
+
+	switch version {
+	case params.PubKeyHashAddrID:
+		return NewAddressPubKeyHash(hash, params)
+	case params.ScriptHashAddrID:
+		return NewAddressScriptHashFromHash(hash, params)
+	default:
+		return nil, fmt.Errorf("%w: 版本0x%02x不属于%s网络", ErrWrongNetwork, version, params.Name)
+	}
+}
+
+// FromPkScript 从标准锁定脚本提取地址
+//
+// 识别P2PKH、P2SH和见证锁定脚本，其他脚本返回ErrUnknownScript。
+func FromPkScript(pkScript []byte, params *blockchain.ChainParams) (Address, error) {
+	switch {
+	case isPubKeyHashScript(pkScript):
+		return NewAddressPubKeyHash(pkScript[3:23], params)
+	case isScriptHashScript(pkScript):
+		return NewAddressScriptHashFromHash(pkScript[2:22], params)
+	}
+	if version, program, ok := extractWitnessProgram(pkScript); ok {
+		addr, err := NewAddressWitness(version, program, params)
+		if err != nil {
+			return nil, ErrUnknownScript
+		}
+		return addr, nil
+	}
+	return nil, ErrUnknownScript
+}
+
+// isPubKeyHashScript 判断是否为P2PKH锁定脚本
+func isPubKeyHashScript(pkScript []byte) bool {
+	return len(pkScript) == 25 &&
+		bytes.Equal(pkScript[:3], []byte{script.OpDup, script.OpHash160, script.OpData20}) &&
+		pkScript[23] == script.OpEqualVerify && pkScript[24] == script.OpCheckSig
+}
+
+// isScriptHashScript 判断是否为P2SH锁定脚本
+func isScriptHashScript(pkScript []byte) bool {
+	return len(pkScript) == 23 && pkScript[0] == script.OpHash160 && pkScript[1] == script.OpData20 && pkScript[22] == script.OpEqual
+}
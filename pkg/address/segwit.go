@@ -0,0 +1,179 @@
+package address
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"simplied-bitcoin-network-go/pkg/bech32"
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/script"
+)
+
+// 见证程序参数
+const (
+	// MaxWitnessVersion 见证版本的最大值
+	MaxWitnessVersion = 16
+
+	// MinWitnessProgramSize 见证程序的最小长度
+	MinWitnessProgramSize = 2
+
+	// MaxWitnessProgramSize 见证程序的最大长度
+	MaxWitnessProgramSize = 40
+
+	// WitnessScriptHashSize 版本0脚本哈希和版本1输出公钥的长度
+	WitnessScriptHashSize = 32
+)
+
+// AddressWitness 隔离见证地址
+//
+// 版本0地址的见证程序是20字节公钥哈希（P2WPKH）或32字节脚本SHA-256哈希
+// （P2WSH），使用Bech32编码；版本1及以上使用Bech32m编码，
+// 其中版本1的32字节见证程序为Taproot输出公钥。
+type AddressWitness struct {
+	hrp     string
+	version byte
+	program []byte
+}
+
+// NewAddressWitness 由见证版本和见证程序创建隔离见证地址
+func NewAddressWitness(version byte, program []byte, params *blockchain.ChainParams) (*AddressWitness, error) {
+	if version > MaxWitnessVersion {
+		return nil, fmt.Errorf("%w: 见证版本%d", ErrInvalidAddress, version)
+	}
+	if len(program) < MinWitnessProgramSize || len(program) > MaxWitnessProgramSize {
+		return nil, fmt.Errorf("%w: 见证程序长度%d", ErrInvalidAddress, len(program))
+	}
+	if version == 0 && len(program) != HashSize && len(program) != WitnessScriptHashSize {
+		return nil, fmt.Errorf("%w: 版本0见证程序长度%d", ErrInvalidAddress, len(program))
+	}
+
+	return &AddressWitness{
+		hrp:     params.Bech32HRPSegwit,
+		version: version,
+		program: append([]byte(nil), program...),
+	}, nil
+}
+
+// NewAddressWitnessPubKey 由压缩公钥创建版本0的P2WPKH地址
+func NewAddressWitnessPubKey(serializedPubKey []byte, params *blockchain.ChainParams) (*AddressWitness, error) {
+	// 隔离见证只允许压缩公钥
+	if len(serializedPubKey) != 33 {
+		return nil, fmt.Errorf("%w: 隔离见证要求压缩公钥", ErrInvalidPubKey)
+	}
+	pkh, err := NewAddressPubKey(serializedPubKey, params)
+	if err != nil {
+		return nil, err
+	}
+	return NewAddressWitness(0, pkh.hash[:], params)
+}
+
+// NewAddressWitnessScript 由见证脚本创建版本0的P2WSH地址
+func NewAddressWitnessScript(witnessScript []byte, params *blockchain.ChainParams) (*AddressWitness, error) {
+	if len(witnessScript) == 0 || len(witnessScript) > blockchain.MaxScriptSize {
+		return nil, fmt.Errorf("%w: 见证脚本长度%d", ErrInvalidAddress, len(witnessScript))
+	}
+	hash := sha256.Sum256(witnessScript)
+	return NewAddressWitness(0, hash[:], params)
+}
+
+// WitnessVersion 返回见证版本
+func (a *AddressWitness) WitnessVersion() byte {
+	return a.version
+}
+
+// String 返回Bech32（版本0）或Bech32m（版本1及以上）编码
+func (a *AddressWitness) String() string {
+	data, _ := bech32.ConvertBits(a.program, 8, 5, true)
+	encoded, err := bech32.Encode(a.hrp, append([]byte{a.version}, data...), checksumVersion(a.version))
+	if err != nil {
+		// 构造函数已检查见证程序长度，编码不会失败
+		panic(err)
+	}
+	return encoded
+}
+
+// ScriptAddress 返回见证程序
+func (a *AddressWitness) ScriptAddress() []byte {
+	return append([]byte(nil), a.program...)
+}
+
+// PkScript 返回见证锁定脚本
+//
+// 脚本格式：<见证版本操作码> <见证程序>
+func (a *AddressWitness) PkScript() []byte {
+	pkScript := make([]byte, 0, 2+len(a.program))
+	pkScript = append(pkScript, versionOpcode(a.version), byte(len(a.program)))
+	return append(pkScript, a.program...)
+}
+
+// IsForNet 判断地址是否属于指定网络
+func (a *AddressWitness) IsForNet(params *blockchain.ChainParams) bool {
+	return a.hrp == params.Bech32HRPSegwit
+}
+
+// decodeSegWit 解析隔离见证地址
+//
+// 字符错误返回的错误链中包含*bech32.Error，可用errors.As取出出错位置。
+func decodeSegWit(addr string, params *blockchain.ChainParams) (*AddressWitness, error) {
+	hrp, data, checksum, err := bech32.Decode(addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAddress, err)
+	}
+	if hrp != params.Bech32HRPSegwit {
+		return nil, fmt.Errorf("%w: 前缀%s不属于%s网络", ErrWrongNetwork, hrp, params.Name)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: 缺少见证版本", ErrInvalidAddress)
+	}
+
+	version := data[0]
+	if checksum != checksumVersion(version) {
+		return nil, fmt.Errorf("%w: 见证版本%d必须使用%s校验和", ErrInvalidAddress, version, checksumVersion(version))
+	}
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+	return NewAddressWitness(version, program, params)
+}
+
+// isSegWitAddress 判断字符串是否具有指定网络隔离见证地址的前缀
+func isSegWitAddress(addr string, params *blockchain.ChainParams) bool {
+	return strings.HasPrefix(strings.ToLower(addr), params.Bech32HRPSegwit+string(bech32.Separator))
+}
+
+// extractWitnessProgram 从见证锁定脚本中提取见证版本和见证程序
+func extractWitnessProgram(pkScript []byte) (byte, []byte, bool) {
+	if len(pkScript) < 2+MinWitnessProgramSize || len(pkScript) > 2+MaxWitnessProgramSize {
+		return 0, nil, false
+	}
+	if int(pkScript[1]) != len(pkScript)-2 {
+		return 0, nil, false
+	}
+
+	switch op := pkScript[0]; {
+	case op == script.Op0:
+		return 0, pkScript[2:], true
+	case op >= script.Op1 && op < script.Op1+MaxWitnessVersion:
+		return op - script.Op1 + 1, pkScript[2:], true
+	default:
+		return 0, nil, false
+	}
+}
+
+// checksumVersion 返回见证版本使用的校验和版本
+func checksumVersion(version byte) bech32.Version {
+	if version == 0 {
+		return bech32.Bech32
+	}
+	return bech32.Bech32m
+}
+
+// versionOpcode 返回见证版本对应的操作码
+func versionOpcode(version byte) byte {
+	if version == 0 {
+		return script.Op0
+	}
+	return script.Op1 + version - 1
+}
@@ -0,0 +1,628 @@
+// Package addrmgr 实现了节点地址簿
+//
+// 地址簿从addr消息和种子节点学习地址，分为两张表：新地址表保存听说过
+// 但从未成功连接的地址，已验证表保存成功连接过的地址。两张表都划分为
+// 固定数量、固定容量的桶，地址所在的桶由本地随机密钥、地址所在网段和
+// 来源节点所在网段共同决定。来自同一网段的节点只能把地址放入少数几个桶，
+// 因此单个来源无法挤占整个地址簿。
+//
+// 选择出站连接候选时优先选择已验证表中的地址，并降低刚尝试过或连续失败的
+// 地址被选中的概率。长时间未活跃或反复连接失败的地址会被淘汰。
+// 地址簿定期并在停止时保存到数据库的peers桶中。
+package addrmgr
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	mrand "math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"simplied-bitcoin-network-go/pkg/network"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// 地址表参数
+const (
+	// newBucketCount 新地址表的桶数
+	newBucketCount = 256
+
+	// newBucketSize 每个新地址桶的容量
+	newBucketSize = 64
+
+	// newBucketsPerGroup 同一来源网段的地址最多分布到的新地址桶数
+	newBucketsPerGroup = 16
+
+	// newBucketsPerAddress 同一地址最多出现在的新地址桶数
+	newBucketsPerAddress = 4
+
+	// triedBucketCount 已验证表的桶数
+	triedBucketCount = 64
+
+	// triedBucketSize 每个已验证桶的容量
+	triedBucketSize = 64
+
+	// triedBucketsPerGroup 同一网段的地址最多分布到的已验证桶数
+	triedBucketsPerGroup = 8
+
+	// triedBias 选择出站候选时从已验证表中选取的概率
+	triedBias = 0.7
+
+	// getAddrMin 通告地址时至少返回的地址数
+	getAddrMin = 50
+
+	// getAddrPercent 通告地址时返回的地址占比
+	getAddrPercent = 23
+
+	// dumpInterval 定期保存地址簿的间隔
+	dumpInterval = 10 * time.Minute
+)
+
+// 数据库桶和键定义
+var (
+	peersBucket = []byte(utils.PeersBucket) // 地址簿根桶
+	addrsBucket = []byte("addrs")           // 地址字符串 -> 地址记录
+	keyKey      = []byte("key")             // 分桶用的随机密钥
+)
+
+// AddrManager 节点地址簿
+//
+// 所有方法都可以并发调用。
+type AddrManager struct {
+	db *bolt.DB
+
+	mu        sync.Mutex
+	key       [32]byte
+	rand      *mrand.Rand
+	addrIndex map[string]*knownAddress
+	addrNew   [newBucketCount]map[string]*knownAddress
+	addrTried [triedBucketCount]map[string]*knownAddress
+	nNew      int
+	nTried    int
+
+	quit    chan struct{}
+	wg      sync.WaitGroup
+	started bool
+	stopped bool
+}
+
+// New 创建地址簿并加载数据库中保存的地址
+//
+// 数据库可以与区块存储共享，首次使用时生成分桶用的随机密钥。
+//
+// 参数：
+// - db: 已打开的BoltDB实例
+func New(db *bolt.DB) (*AddrManager, error) {
+	return NewWithRand(db, rand.Reader)
+}
+
+// NewWithRand 使用指定的随机源创建地址簿
+//
+// 随机源用于生成首次使用时的分桶密钥和选择地址的随机数种子，
+// 传入固定种子的随机源可以得到确定的分桶结果，便于测试。
+//
+// 参数：
+// - db: 已打开的BoltDB实例
+// - random: 随机源，生产环境应使用crypto/rand.Reader
+func NewWithRand(db *bolt.DB, random io.Reader) (*AddrManager, error) {
+	if db == nil {
+		return nil, errors.New("地址簿缺少数据库")
+	}
+
+	var seed [8]byte
+	if _, err := io.ReadFull(random, seed[:]); err != nil {
+		return nil, fmt.Errorf("生成地址簿随机数种子失败: %v", err)
+	}
+
+	a := &AddrManager{
+		db:        db,
+		rand:      mrand.New(mrand.NewSource(int64(binary.LittleEndian.Uint64(seed[:])))),
+		addrIndex: make(map[string]*knownAddress),
+		quit:      make(chan struct{}),
+	}
+	for i := range a.addrNew {
+		a.addrNew[i] = make(map[string]*knownAddress)
+	}
+	for i := range a.addrTried {
+		a.addrTried[i] = make(map[string]*knownAddress)
+	}
+
+	if err := a.load(random); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Start 启动定期保存地址簿的协程
+func (a *AddrManager) Start() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.started || a.stopped {
+		return
+	}
+	a.started = true
+
+	a.wg.Add(1)
+	go a.saveHandler()
+}
+
+// Stop 停止定期保存并将地址簿写入数据库
+func (a *AddrManager) Stop() error {
+	a.mu.Lock()
+	if a.stopped {
+		a.mu.Unlock()
+		return nil
+	}
+	a.stopped = true
+	close(a.quit)
+	a.mu.Unlock()
+
+	a.wg.Wait()
+	return a.Save()
+}
+
+// saveHandler 按dumpInterval定期保存地址簿
+func (a *AddrManager) saveHandler() {
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(dumpInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.Save(); err != nil {
+				log.Printf("保存地址簿失败: %v", err)
+			}
+		case <-a.quit:
+			return
+		}
+	}
+}
+
+// AddAddresses 添加从src得知的一组地址
+func (a *AddrManager) AddAddresses(addrs []*network.NetAddress, src *network.NetAddress) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, na := range addrs {
+		a.updateAddress(na, src)
+	}
+}
+
+// AddAddress 添加从src得知的地址
+func (a *AddrManager) AddAddress(na, src *network.NetAddress) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.updateAddress(na, src)
+}
+
+// updateAddress 将地址加入新地址表
+//
+// 已知地址只更新时间戳和服务；尚在新地址表中的地址以逐次减半的概率
+// 加入来源对应的另一个桶，使被多个来源通告的地址更容易被保留。
+func (a *AddrManager) updateAddress(na, src *network.NetAddress) {
+	if !isUsable(na) {
+		return
+	}
+
+	key := na.String()
+	ka := a.addrIndex[key]
+	if ka != nil {
+		if na.Timestamp.After(ka.na.Timestamp) {
+			ka.na.Timestamp = na.Timestamp
+		}
+		ka.na.Services |= na.Services
+
+		if ka.tried || ka.refs >= newBucketsPerAddress {
+			return
+		}
+		if a.rand.Intn(1<<ka.refs) != 0 {
+			return
+		}
+	} else {
+		ka = &knownAddress{na: copyNetAddress(na), srcAddr: copyNetAddress(src)}
+		// 来自未来的时间戳按当前时间记录，避免地址永远不过期
+		if now := time.Now(); ka.na.Timestamp.After(now) {
+			ka.na.Timestamp = time.Unix(now.Unix(), 0)
+		}
+		a.addrIndex[key] = ka
+		a.nNew++
+	}
+
+	bucket := a.newBucket(na, src)
+	if _, ok := a.addrNew[bucket][key]; ok {
+		return
+	}
+	if len(a.addrNew[bucket]) >= newBucketSize {
+		a.expireNew(bucket)
+	}
+	a.addrNew[bucket][key] = ka
+	ka.refs++
+}
+
+// expireNew 为新地址桶腾出空间
+//
+// 先淘汰桶中所有失效地址，仍然没有空间时淘汰时间戳最旧的地址。
+func (a *AddrManager) expireNew(bucket int) {
+	now := time.Now()
+	var oldest *knownAddress
+	for key, ka := range a.addrNew[bucket] {
+		if ka.isBad(now) {
+			a.removeFromNew(bucket, key, ka)
+			continue
+		}
+		if oldest == nil || ka.na.Timestamp.Before(oldest.na.Timestamp) {
+			oldest = ka
+		}
+	}
+
+	if len(a.addrNew[bucket]) >= newBucketSize && oldest != nil {
+		a.removeFromNew(bucket, oldest.na.String(), oldest)
+	}
+}
+
+// removeFromNew 将地址移出新地址桶，没有桶引用时从地址簿删除
+func (a *AddrManager) removeFromNew(bucket int, key string, ka *knownAddress) {
+	delete(a.addrNew[bucket], key)
+	ka.refs--
+	if ka.refs == 0 {
+		delete(a.addrIndex, key)
+		a.nNew--
+	}
+}
+
+// Attempt 记录一次对该地址的连接尝试
+func (a *AddrManager) Attempt(na *network.NetAddress) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if ka := a.addrIndex[na.String()]; ka != nil {
+		ka.attempts++
+		ka.lastAttempt = time.Now()
+	}
+}
+
+// Good 记录与该地址成功建立连接，并将其移入已验证表
+//
+// 目标桶已满时，桶中最久未成功连接的地址被移回新地址表。
+func (a *AddrManager) Good(na *network.NetAddress) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := na.String()
+	ka := a.addrIndex[key]
+	if ka == nil {
+		return
+	}
+
+	now := time.Now()
+	ka.lastSuccess = now
+	ka.lastAttempt = now
+	ka.attempts = 0
+	ka.na.Timestamp = time.Unix(now.Unix(), 0)
+	ka.na.Services |= na.Services
+
+	if ka.tried {
+		return
+	}
+
+	for i := range a.addrNew {
+		delete(a.addrNew[i], key)
+	}
+	ka.refs = 0
+	a.nNew--
+
+	bucket := a.triedBucket(ka.na)
+	if len(a.addrTried[bucket]) >= triedBucketSize {
+		a.evictTried(bucket)
+	}
+	a.addrTried[bucket][key] = ka
+	ka.tried = true
+	a.nTried++
+}
+
+// evictTried 将已验证桶中最久未成功连接的地址移回新地址表
+func (a *AddrManager) evictTried(bucket int) {
+	var oldest *knownAddress
+	for _, ka := range a.addrTried[bucket] {
+		if oldest == nil || ka.lastSuccess.Before(oldest.lastSuccess) {
+			oldest = ka
+		}
+	}
+
+	key := oldest.na.String()
+	delete(a.addrTried[bucket], key)
+	oldest.tried = false
+	a.nTried--
+
+	newBucket := a.newBucket(oldest.na, oldest.srcAddr)
+	if len(a.addrNew[newBucket]) >= newBucketSize {
+		a.expireNew(newBucket)
+	}
+	a.addrNew[newBucket][key] = oldest
+	oldest.refs = 1
+	a.nNew++
+}
+
+// GetAddress 选择一个出站连接候选地址，地址簿为空时返回nil
+//
+// 已验证表非空时以triedBias的概率从中选取，其余从新地址表选取。
+// 在表内随机挑选地址并按其chance决定是否接受，每次拒绝后放宽接受条件，
+// 保证最终能选出地址。
+func (a *AddrManager) GetAddress() *network.NetAddress {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.nNew+a.nTried == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	useTried := a.nTried > 0 && (a.nNew == 0 || a.rand.Float64() < triedBias)
+
+	factor := 1.0
+	for {
+		var ka *knownAddress
+		if useTried {
+			ka = a.randomFrom(a.addrTried[a.rand.Intn(triedBucketCount)])
+		} else {
+			ka = a.randomFrom(a.addrNew[a.rand.Intn(newBucketCount)])
+		}
+		if ka == nil {
+			continue
+		}
+		if a.rand.Float64() < ka.chance(now)*factor {
+			return copyNetAddress(ka.na)
+		}
+		factor *= 1.2
+	}
+}
+
+// randomFrom 从桶中随机选取一个地址，桶为空时返回nil
+func (a *AddrManager) randomFrom(bucket map[string]*knownAddress) *knownAddress {
+	if len(bucket) == 0 {
+		return nil
+	}
+
+	n := a.rand.Intn(len(bucket))
+	for _, ka := range bucket {
+		if n == 0 {
+			return ka
+		}
+		n--
+	}
+	return nil
+}
+
+// AddressCache 返回用于向其他节点通告的随机地址列表
+//
+// 返回地址簿中getAddrPercent的有效地址，至少getAddrMin个，
+// 最多network.MaxAddrPerMsg个。
+func (a *AddrManager) AddressCache() []*network.NetAddress {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	addrs := make([]*network.NetAddress, 0, len(a.addrIndex))
+	for _, ka := range a.addrIndex {
+		if !ka.isBad(now) {
+			addrs = append(addrs, copyNetAddress(ka.na))
+		}
+	}
+
+	count := len(addrs) * getAddrPercent / 100
+	if count < getAddrMin {
+		count = getAddrMin
+	}
+	if count > network.MaxAddrPerMsg {
+		count = network.MaxAddrPerMsg
+	}
+	if count > len(addrs) {
+		count = len(addrs)
+	}
+
+	// 部分Fisher-Yates洗牌，只打乱需要返回的前count个
+	for i := 0; i < count; i++ {
+		j := i + a.rand.Intn(len(addrs)-i)
+		addrs[i], addrs[j] = addrs[j], addrs[i]
+	}
+	return addrs[:count]
+}
+
+// NumAddresses 返回地址簿中的地址数
+func (a *AddrManager) NumAddresses() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.nNew + a.nTried
+}
+
+// NumTried 返回已验证表中的地址数
+func (a *AddrManager) NumTried() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.nTried
+}
+
+// NewBucketIndex 返回来源src通告的地址na在新地址表中所属的桶
+//
+// 桶由本地分桶密钥决定，可用于检查地址簿的分布。
+func (a *AddrManager) NewBucketIndex(na, src *network.NetAddress) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.newBucket(na, src)
+}
+
+// newBucket 计算地址在新地址表中的桶
+//
+// 先由地址网段和来源网段选出来源网段可用的newBucketsPerGroup个桶之一，
+// 再与来源网段一起映射到全表，使同一来源只能填充少数几个桶。
+func (a *AddrManager) newBucket(na, src *network.NetAddress) int {
+	srcGroup := groupKey(src)
+
+	h1 := a.hash([]byte(groupKey(na)), []byte(srcGroup))
+	var slot [8]byte
+	binary.LittleEndian.PutUint64(slot[:], h1%newBucketsPerGroup)
+
+	h2 := a.hash([]byte(srcGroup), slot[:])
+	return int(h2 % newBucketCount)
+}
+
+// triedBucket 计算地址在已验证表中的桶
+//
+// 同一网段的地址最多分布到triedBucketsPerGroup个桶中。
+func (a *AddrManager) triedBucket(na *network.NetAddress) int {
+	h1 := a.hash([]byte(na.String()))
+	var slot [8]byte
+	binary.LittleEndian.PutUint64(slot[:], h1%triedBucketsPerGroup)
+
+	h2 := a.hash([]byte(groupKey(na)), slot[:])
+	return int(h2 % triedBucketCount)
+}
+
+// hash 计算密钥与数据拼接后的SHA256，取前8字节
+func (a *AddrManager) hash(parts ...[]byte) uint64 {
+	h := sha256.New()
+	h.Write(a.key[:])
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return binary.LittleEndian.Uint64(h.Sum(nil)[:8])
+}
+
+// Save 将地址簿写入数据库，新地址表中的失效地址不再保存
+func (a *AddrManager) Save() error {
+	a.mu.Lock()
+	now := time.Now()
+	records := make(map[string][]byte, len(a.addrIndex))
+	for key, ka := range a.addrIndex {
+		if !ka.tried && ka.isBad(now) {
+			continue
+		}
+		records[key] = ka.serialize()
+	}
+	a.mu.Unlock()
+
+	return a.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(peersBucket)
+		if err := root.DeleteBucket(addrsBucket); err != nil && err != bolt.ErrBucketNotFound {
+			return fmt.Errorf("清空地址桶失败: %v", err)
+		}
+		addrs, err := root.CreateBucket(addrsBucket)
+		if err != nil {
+			return fmt.Errorf("创建桶%s失败: %v", addrsBucket, err)
+		}
+		for key, data := range records {
+			if err := addrs.Put([]byte(key), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// load 创建所需的桶并加载保存的密钥和地址
+//
+// 数据库中没有密钥时从random生成。已验证地址放回原来的已验证桶，
+// 桶满时退回新地址表。
+func (a *AddrManager) load(random io.Reader) error {
+	var records []*knownAddress
+	err := a.db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(peersBucket)
+		if err != nil {
+			return fmt.Errorf("创建桶%s失败: %v", peersBucket, err)
+		}
+
+		if key := root.Get(keyKey); len(key) == len(a.key) {
+			copy(a.key[:], key)
+		} else {
+			if _, err := io.ReadFull(random, a.key[:]); err != nil {
+				return fmt.Errorf("生成地址簿密钥失败: %v", err)
+			}
+			if err := root.Put(keyKey, a.key[:]); err != nil {
+				return err
+			}
+		}
+
+		addrs := root.Bucket(addrsBucket)
+		if addrs == nil {
+			return nil
+		}
+		return addrs.ForEach(func(k, v []byte) error {
+			ka, err := deserializeKnownAddress(v)
+			if err != nil {
+				return fmt.Errorf("地址记录%s损坏: %v", k, err)
+			}
+			records = append(records, ka)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ka := range records {
+		key := ka.na.String()
+		if ka.tried {
+			bucket := a.triedBucket(ka.na)
+			if len(a.addrTried[bucket]) < triedBucketSize {
+				a.addrTried[bucket][key] = ka
+				a.addrIndex[key] = ka
+				a.nTried++
+				continue
+			}
+			ka.tried = false
+		}
+
+		bucket := a.newBucket(ka.na, ka.srcAddr)
+		if len(a.addrNew[bucket]) >= newBucketSize {
+			continue
+		}
+		a.addrNew[bucket][key] = ka
+		ka.refs = 1
+		a.addrIndex[key] = ka
+		a.nNew++
+	}
+	return nil
+}
+
+// isUsable 判断地址能否用于连接
+func isUsable(na *network.NetAddress) bool {
+	return na != nil && na.Port != 0 && na.IP != nil && !na.IP.IsUnspecified()
+}
+
+// groupKey 返回地址所在网段，用于分桶
+//
+// IPv4按/16划分，IPv6按/32划分；本地和内网地址归为同一组。
+func groupKey(na *network.NetAddress) string {
+	ip := na.IP
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() {
+		return "local"
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(16, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(32, 128)).String()
+}
+
+// copyNetAddress 复制网络地址，避免与调用方共享可变字段
+func copyNetAddress(na *network.NetAddress) *network.NetAddress {
+	ip := make(net.IP, len(na.IP))
+	copy(ip, na.IP)
+	return &network.NetAddress{
+		Timestamp: na.Timestamp,
+		Services:  na.Services,
+		IP:        ip,
+		Port:      na.Port,
+	}
+}
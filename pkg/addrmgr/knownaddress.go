@@ -0,0 +1,158 @@
+package addrmgr
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/network"
+)
+
+// 地址质量判断参数
+const (
+	// numMissingDays 超过该天数未得知活跃的地址视为失效
+	numMissingDays = 30
+
+	// numRetries 从未成功连接的地址最多尝试的次数
+	numRetries = 3
+
+	// maxFailures 最近minBadDays天内没有成功连接时允许的最多失败次数
+	maxFailures = 10
+
+	// minBadDays 判断连续失败的时间窗口
+	minBadDays = 7
+)
+
+// knownAddressSize 已知地址的序列化长度
+// 地址(34) + 来源(34) + 尝试次数(4) + 最后尝试(8) + 最后成功(8) + 是否已验证(1)
+const knownAddressSize = 34*2 + 4 + 8 + 8 + 1
+
+// knownAddress 地址簿中的一条地址记录
+type knownAddress struct {
+	na          *network.NetAddress
+	srcAddr     *network.NetAddress // 告知该地址的节点
+	attempts    int                 // 上次成功后的连接尝试次数
+	lastAttempt time.Time
+	lastSuccess time.Time
+	tried       bool // 是否在已验证表中
+	refs        int  // 引用该地址的新地址桶数量
+}
+
+// chance 返回选中该地址的相对概率
+//
+// 刚尝试过的地址和连续失败的地址被选中的概率降低。
+func (ka *knownAddress) chance(now time.Time) float64 {
+	c := 1.0
+
+	if now.Sub(ka.lastAttempt) < 10*time.Minute {
+		c *= 0.01
+	}
+	for i := 0; i < ka.attempts && i < 8; i++ {
+		c /= 1.5
+	}
+	return c
+}
+
+// isBad 判断地址是否已失效，应当从新地址表中淘汰
+//
+// 满足以下任一条件即为失效：
+// - 地址时间戳在未来超过10分钟或超过numMissingDays天未更新
+// - 从未成功连接且已尝试numRetries次
+// - 最近minBadDays天内没有成功连接且失败了maxFailures次
+//
+// 最近一分钟内尝试过的地址不视为失效，避免正在连接时被淘汰。
+func (ka *knownAddress) isBad(now time.Time) bool {
+	if now.Sub(ka.lastAttempt) < time.Minute {
+		return false
+	}
+
+	if ka.na.Timestamp.After(now.Add(10 * time.Minute)) {
+		return true
+	}
+	if ka.na.Timestamp.Before(now.Add(-numMissingDays * 24 * time.Hour)) {
+		return true
+	}
+	if ka.lastSuccess.IsZero() && ka.attempts >= numRetries {
+		return true
+	}
+	if now.Sub(ka.lastSuccess) > minBadDays*24*time.Hour && ka.attempts >= maxFailures {
+		return true
+	}
+	return false
+}
+
+// serialize 序列化地址记录
+func (ka *knownAddress) serialize() []byte {
+	buf := make([]byte, 0, knownAddressSize)
+	buf = appendNetAddress(buf, ka.na)
+	buf = appendNetAddress(buf, ka.srcAddr)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(ka.attempts))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(unixTime(ka.lastAttempt)))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(unixTime(ka.lastSuccess)))
+
+	var tried byte
+	if ka.tried {
+		tried = 1
+	}
+	return append(buf, tried)
+}
+
+// deserializeKnownAddress 反序列化地址记录
+func deserializeKnownAddress(data []byte) (*knownAddress, error) {
+	if len(data) != knownAddressSize {
+		return nil, errors.New("地址记录长度错误")
+	}
+
+	ka := &knownAddress{
+		na:      readNetAddress(data[0:34]),
+		srcAddr: readNetAddress(data[34:68]),
+	}
+	ka.attempts = int(binary.LittleEndian.Uint32(data[68:72]))
+	ka.lastAttempt = fromUnixTime(int64(binary.LittleEndian.Uint64(data[72:80])))
+	ka.lastSuccess = fromUnixTime(int64(binary.LittleEndian.Uint64(data[80:88])))
+	ka.tried = data[88] == 1
+	return ka, nil
+}
+
+// appendNetAddress 追加网络地址编码
+// 时间戳(8) + 服务(8) + IP(16) + 端口(2)
+func appendNetAddress(buf []byte, na *network.NetAddress) []byte {
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(unixTime(na.Timestamp)))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(na.Services))
+
+	var ip [16]byte
+	copy(ip[:], na.IP.To16())
+	buf = append(buf, ip[:]...)
+
+	return binary.BigEndian.AppendUint16(buf, na.Port)
+}
+
+// readNetAddress 读取34字节的网络地址编码
+func readNetAddress(data []byte) *network.NetAddress {
+	ip := make(net.IP, 16)
+	copy(ip, data[16:32])
+
+	return &network.NetAddress{
+		Timestamp: fromUnixTime(int64(binary.LittleEndian.Uint64(data[0:8]))),
+		Services:  network.ServiceFlag(binary.LittleEndian.Uint64(data[8:16])),
+		IP:        ip,
+		Port:      binary.BigEndian.Uint16(data[32:34]),
+	}
+}
+
+// unixTime 返回Unix时间戳，零值时间编码为0
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// fromUnixTime 将Unix时间戳还原为时间，0还原为零值时间
+func fromUnixTime(ts int64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
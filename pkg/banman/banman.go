@@ -0,0 +1,246 @@
+// Package banman 实现了持久化的节点封禁列表
+//
+// 封禁以IP为单位，每条记录包含封禁原因和到期时间。记录在添加和删除时
+// 立即写入数据库peers桶下的子桶，节点重启后仍然有效；过期的记录在
+// 查询和加载时被清除。
+package banman
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// 数据库桶定义
+var (
+	peersBucket = []byte(utils.PeersBucket) // 节点数据根桶
+	bansBucket  = []byte("bans")            // 16字节IP -> 封禁记录
+)
+
+var (
+	// ErrNotBanned IP不在封禁列表中
+	ErrNotBanned = errors.New("IP未被封禁")
+
+	// ErrInvalidDuration 封禁时长不是正数
+	ErrInvalidDuration = errors.New("无效的封禁时长")
+)
+
+// BanEntry 封禁记录
+type BanEntry struct {
+	IP        net.IP
+	CreatedAt time.Time // 封禁时间
+	Until     time.Time // 到期时间
+	Reason    string    // 封禁原因
+}
+
+// BanManager 封禁列表
+//
+// 所有方法都可以并发调用。
+type BanManager struct {
+	db *bolt.DB
+
+	mu   sync.Mutex
+	bans map[string]*BanEntry // 以16字节IP为键
+}
+
+// New 创建封禁列表并加载数据库中尚未到期的记录
+//
+// 参数：
+// - db: 已打开的BoltDB实例，可以与区块存储共享
+func New(db *bolt.DB) (*BanManager, error) {
+	if db == nil {
+		return nil, errors.New("封禁列表缺少数据库")
+	}
+
+	b := &BanManager{db: db, bans: make(map[string]*BanEntry)}
+	now := time.Now()
+	var expired [][]byte
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(peersBucket)
+		if err != nil {
+			return fmt.Errorf("创建桶%s失败: %v", peersBucket, err)
+		}
+		bans, err := root.CreateBucketIfNotExists(bansBucket)
+		if err != nil {
+			return fmt.Errorf("创建桶%s失败: %v", bansBucket, err)
+		}
+
+		err = bans.ForEach(func(k, v []byte) error {
+			entry, err := deserializeEntry(k, v)
+			if err != nil {
+				return fmt.Errorf("封禁记录%x损坏: %v", k, err)
+			}
+			if !entry.Until.After(now) {
+				expired = append(expired, append([]byte(nil), k...))
+				return nil
+			}
+			b.bans[string(k)] = entry
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := bans.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Ban 封禁IP指定时长，已封禁的IP按新的时长和原因覆盖
+func (b *BanManager) Ban(ip net.IP, duration time.Duration, reason string) error {
+	key := ipKey(ip)
+	if key == nil {
+		return fmt.Errorf("无效的IP: %v", ip)
+	}
+	if duration <= 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidDuration, duration)
+	}
+
+	now := time.Unix(time.Now().Unix(), 0)
+	entry := &BanEntry{
+		IP:        net.IP(key),
+		CreatedAt: now,
+		Until:     now.Add(duration),
+		Reason:    reason,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(peersBucket).Bucket(bansBucket).Put(key, serializeEntry(entry))
+	})
+	if err != nil {
+		return fmt.Errorf("保存封禁记录失败: %v", err)
+	}
+	b.bans[string(key)] = entry
+	return nil
+}
+
+// Unban 解除IP的封禁，IP未被封禁时返回ErrNotBanned
+func (b *BanManager) Unban(ip net.IP) error {
+	key := ipKey(ip)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.bans[string(key)]
+	if !ok || !entry.Until.After(time.Now()) {
+		return fmt.Errorf("%w: %v", ErrNotBanned, ip)
+	}
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(peersBucket).Bucket(bansBucket).Delete(key)
+	})
+	if err != nil {
+		return fmt.Errorf("删除封禁记录失败: %v", err)
+	}
+	delete(b.bans, string(key))
+	return nil
+}
+
+// Clear 解除所有封禁
+func (b *BanManager) Clear() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(peersBucket)
+		if err := root.DeleteBucket(bansBucket); err != nil {
+			return err
+		}
+		_, err := root.CreateBucket(bansBucket)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("清空封禁列表失败: %v", err)
+	}
+	b.bans = make(map[string]*BanEntry)
+	return nil
+}
+
+// IsBanned 判断IP是否处于封禁期
+func (b *BanManager) IsBanned(ip net.IP) bool {
+	return b.Lookup(ip) != nil
+}
+
+// Lookup 返回IP未到期的封禁记录，未被封禁时返回nil
+func (b *BanManager) Lookup(ip net.IP) *BanEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.bans[string(ipKey(ip))]
+	if !ok || !entry.Until.After(time.Now()) {
+		return nil
+	}
+	copied := *entry
+	return &copied
+}
+
+// List 返回所有未到期的封禁记录，按到期时间排序
+func (b *BanManager) List() []*BanEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	entries := make([]*BanEntry, 0, len(b.bans))
+	for _, entry := range b.bans {
+		if entry.Until.After(now) {
+			copied := *entry
+			entries = append(entries, &copied)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Until.Before(entries[j].Until)
+	})
+	return entries
+}
+
+// ipKey 返回IP的16字节形式，用作数据库键和索引键
+func ipKey(ip net.IP) []byte {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return nil
+	}
+	return append([]byte(nil), ip16...)
+}
+
+// serializeEntry 序列化封禁记录：封禁时间(8) + 到期时间(8) + 原因
+func serializeEntry(entry *BanEntry) []byte {
+	buf := make([]byte, 0, 16+len(entry.Reason))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(entry.CreatedAt.Unix()))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(entry.Until.Unix()))
+	return append(buf, entry.Reason...)
+}
+
+// deserializeEntry 反序列化封禁记录
+func deserializeEntry(key, data []byte) (*BanEntry, error) {
+	if len(key) != net.IPv6len || len(data) < 16 {
+		return nil, errors.New("封禁记录长度错误")
+	}
+
+	return &BanEntry{
+		IP:        net.IP(append([]byte(nil), key...)),
+		CreatedAt: time.Unix(int64(binary.LittleEndian.Uint64(data[0:8])), 0),
+		Until:     time.Unix(int64(binary.LittleEndian.Uint64(data[8:16])), 0),
+		Reason:    string(data[16:]),
+	}, nil
+}
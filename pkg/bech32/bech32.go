@@ -0,0 +1,363 @@
+// Package bech32 实现了BIP173定义的Bech32编码和BIP350定义的Bech32m编码
+//
+// 编码结果由人类可读部分（HRP）、分隔符1和数据部分组成，数据部分的最后6个
+// 字符是BCH校验和。校验和保证检测出任意不超过4个字符的错误，
+// 解码失败时本包会尝试定位1到2个错误字符，便于提示用户地址哪里输错了。
+package bech32
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 编码长度限制
+const (
+	// MaxLength 编码字符串的最大长度
+	MaxLength = 90
+
+	// ChecksumLength 校验和字符数
+	ChecksumLength = 6
+
+	// MaxHRPLength 人类可读部分的最大长度
+	MaxHRPLength = 83
+
+	// Separator 人类可读部分与数据部分的分隔符
+	Separator = '1'
+)
+
+// charset 数据部分的字符集，每个字符表示5位
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// gen BCH校验和生成多项式
+var gen = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+// charsetRev 小写字符到5位值的映射，不在字符集中的字符为-1
+var charsetRev = func() [128]int8 {
+	var rev [128]int8
+	for i := range rev {
+		rev[i] = -1
+	}
+	for i, c := range charset {
+		rev[c] = int8(i)
+	}
+	return rev
+}()
+
+// Version 校验和版本
+type Version int
+
+const (
+	// Bech32 BIP173定义的原始校验和
+	Bech32 Version = iota
+
+	// Bech32m BIP350定义的校验和，用于版本1及以上的隔离见证地址
+	Bech32m
+)
+
+// checksumConst 返回校验和版本对应的常数
+func (v Version) checksumConst() uint32 {
+	if v == Bech32m {
+		return 0x2bc830a3
+	}
+	return 1
+}
+
+// String 返回校验和版本的名称
+func (v Version) String() string {
+	if v == Bech32m {
+		return "Bech32m"
+	}
+	return "Bech32"
+}
+
+var (
+	// ErrInvalidLength 编码字符串或数据部分长度超出范围
+	ErrInvalidLength = errors.New("Bech32长度无效")
+
+	// ErrInvalidCharacter 包含字符集以外的字符
+	ErrInvalidCharacter = errors.New("Bech32包含无效字符")
+
+	// ErrMixedCase 大小写混用
+	ErrMixedCase = errors.New("Bech32大小写混用")
+
+	// ErrMissingSeparator 缺少分隔符或人类可读部分为空
+	ErrMissingSeparator = errors.New("Bech32缺少分隔符")
+
+	// ErrInvalidChecksum 校验和错误
+	ErrInvalidChecksum = errors.New("Bech32校验和错误")
+
+	// ErrInvalidData 数据值超过5位或位转换的填充无效
+	ErrInvalidData = errors.New("Bech32数据无效")
+)
+
+// Error 解码错误，包含出错字符的位置
+//
+// Err可以用errors.Is与本包的错误类别比较。
+type Error struct {
+	Err       error
+	Positions []int // 出错字符在字符串中的位置（从0开始），无法定位时为空
+}
+
+// Error 返回错误描述
+func (e *Error) Error() string {
+	if len(e.Positions) == 0 {
+		return e.Err.Error()
+	}
+	positions := make([]string, len(e.Positions))
+	for i, p := range e.Positions {
+		positions[i] = fmt.Sprint(p)
+	}
+	return fmt.Sprintf("%v（位置%s）", e.Err, strings.Join(positions, ", "))
+}
+
+// Unwrap 返回错误类别
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Encode 将5位分组的数据编码为Bech32或Bech32m字符串
+//
+// 人类可读部分被转换为小写。
+func Encode(hrp string, data []byte, version Version) (string, error) {
+	hrp = strings.ToLower(hrp)
+	if len(hrp) == 0 || len(hrp) > MaxHRPLength {
+		return "", fmt.Errorf("%w: 人类可读部分长度%d", ErrInvalidLength, len(hrp))
+	}
+	if total := len(hrp) + 1 + len(data) + ChecksumLength; total > MaxLength {
+		return "", fmt.Errorf("%w: 编码长度%d超过%d", ErrInvalidLength, total, MaxLength)
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", fmt.Errorf("%w: 人类可读部分包含字符0x%02x", ErrInvalidCharacter, hrp[i])
+		}
+	}
+	for _, b := range data {
+		if b >= 32 {
+			return "", fmt.Errorf("%w: 数据值%d超过5位", ErrInvalidData, b)
+		}
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(hrp) + 1 + len(data) + ChecksumLength)
+	sb.WriteString(hrp)
+	sb.WriteByte(Separator)
+	for _, b := range data {
+		sb.WriteByte(charset[b])
+	}
+	for _, b := range createChecksum(hrp, data, version) {
+		sb.WriteByte(charset[b])
+	}
+	return sb.String(), nil
+}
+
+// Decode 解码Bech32或Bech32m字符串，返回小写的人类可读部分、
+// 不含校验和的5位分组数据以及校验和版本
+//
+// 返回的错误总是*Error类型；校验和错误时会尝试定位出错的字符。
+func Decode(s string) (string, []byte, Version, error) {
+	if len(s) < 1+1+ChecksumLength || len(s) > MaxLength {
+		return "", nil, 0, &Error{Err: fmt.Errorf("%w: %d", ErrInvalidLength, len(s))}
+	}
+
+	var invalid, lower, upper []int
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c < 33 || c > 126:
+			invalid = append(invalid, i)
+		case c >= 'a' && c <= 'z':
+			lower = append(lower, i)
+		case c >= 'A' && c <= 'Z':
+			upper = append(upper, i)
+		}
+	}
+	if len(invalid) > 0 {
+		return "", nil, 0, &Error{Err: ErrInvalidCharacter, Positions: invalid}
+	}
+	if len(lower) > 0 && len(upper) > 0 {
+		// 少数的一方被视为输错的字符
+		positions := upper
+		if len(lower) < len(upper) {
+			positions = lower
+		}
+		return "", nil, 0, &Error{Err: ErrMixedCase, Positions: positions}
+	}
+
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, Separator)
+	if sep < 1 {
+		return "", nil, 0, &Error{Err: ErrMissingSeparator}
+	}
+	if len(s)-sep-1 < ChecksumLength {
+		return "", nil, 0, &Error{Err: fmt.Errorf("%w: 数据部分不足%d个字符", ErrInvalidLength, ChecksumLength),
+			Positions: []int{sep}}
+	}
+	hrp := s[:sep]
+
+	data := make([]byte, len(s)-sep-1)
+	for i := range data {
+		v := charsetRev[s[sep+1+i]]
+		if v < 0 {
+			invalid = append(invalid, sep+1+i)
+			continue
+		}
+		data[i] = byte(v)
+	}
+	if len(invalid) > 0 {
+		return "", nil, 0, &Error{Err: ErrInvalidCharacter, Positions: invalid}
+	}
+
+	residue := polymod(hrp, data)
+	switch residue {
+	case Bech32.checksumConst():
+		return hrp, data[:len(data)-ChecksumLength], Bech32, nil
+	case Bech32m.checksumConst():
+		return hrp, data[:len(data)-ChecksumLength], Bech32m, nil
+	}
+
+	// 分别按两种校验和版本定位错误，取需要修改字符最少的结果
+	var positions []int
+	for _, version := range []Version{Bech32, Bech32m} {
+		found := locateErrors(residue^version.checksumConst(), len(data))
+		if len(found) > 0 && (positions == nil || len(found) < len(positions)) {
+			positions = found
+		}
+	}
+	for i := range positions {
+		positions[i] += sep + 1
+	}
+	return "", nil, 0, &Error{Err: ErrInvalidChecksum, Positions: positions}
+}
+
+// ConvertBits 在不同位宽的分组之间转换数据，例如将字节转换为5位分组
+//
+// pad为true时用零填充最后一个不完整的分组；为false时要求填充位不超过
+// 一个输入分组且全为零，否则返回ErrInvalidData。
+func ConvertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	if fromBits < 1 || fromBits > 8 || toBits < 1 || toBits > 8 {
+		return nil, fmt.Errorf("%w: 无效的位宽%d->%d", ErrInvalidData, fromBits, toBits)
+	}
+
+	var acc uint32
+	var bits uint
+	maxv := uint32(1)<<toBits - 1
+	result := make([]byte, 0, (len(data)*int(fromBits)+int(toBits)-1)/int(toBits))
+	for _, b := range data {
+		if uint32(b)>>fromBits != 0 {
+			return nil, fmt.Errorf("%w: 值%d超过%d位", ErrInvalidData, b, fromBits)
+		}
+		acc = acc<<fromBits | uint32(b)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			result = append(result, byte(acc>>bits&maxv))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			result = append(result, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil, fmt.Errorf("%w: 填充位无效", ErrInvalidData)
+	}
+	return result, nil
+}
+
+// polymodStep 将一个5位值加入校验和计算
+func polymodStep(chk uint32, v byte) uint32 {
+	b := chk >> 25
+	chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+	for i := 0; i < 5; i++ {
+		if (b>>i)&1 == 1 {
+			chk ^= gen[i]
+		}
+	}
+	return chk
+}
+
+// polymod 计算人类可读部分和数据的BCH余数
+func polymod(hrp string, data []byte) uint32 {
+	chk := uint32(1)
+	for i := 0; i < len(hrp); i++ {
+		chk = polymodStep(chk, hrp[i]>>5)
+	}
+	chk = polymodStep(chk, 0)
+	for i := 0; i < len(hrp); i++ {
+		chk = polymodStep(chk, hrp[i]&31)
+	}
+	for _, v := range data {
+		chk = polymodStep(chk, v)
+	}
+	return chk
+}
+
+// createChecksum 计算6个字符的校验和
+func createChecksum(hrp string, data []byte, version Version) []byte {
+	values := make([]byte, len(data)+ChecksumLength)
+	copy(values, data)
+	mod := polymod(hrp, values) ^ version.checksumConst()
+
+	checksum := make([]byte, ChecksumLength)
+	for i := range checksum {
+		checksum[i] = byte(mod >> (5 * (5 - i)) & 31)
+	}
+	return checksum
+}
+
+// locateErrors 根据校验余数与期望值的差定位数据部分中1到2个出错的字符
+//
+// 余数对字符值的异或是线性的：位置k上的字符异或e使余数异或一个只取决于
+// e和k之后字符数的值。据此枚举所有单字符修改，并用哈希表查找能消除差值的
+// 两处修改。在Bech32保证的长度内，不超过2个错误的解是唯一的。
+// 返回数据部分内的位置，无法定位时返回nil。
+func locateErrors(syndrome uint32, length int) []int {
+	if syndrome == 0 {
+		return nil
+	}
+
+	// effects[m][b] 为距末尾m个字符的位置上异或1<<b产生的余数变化
+	effects := make([][5]uint32, length)
+	for b := 0; b < 5; b++ {
+		effects[0][b] = 1 << b
+	}
+	for m := 1; m < length; m++ {
+		for b := 0; b < 5; b++ {
+			effects[m][b] = polymodStep(effects[m-1][b], 0)
+		}
+	}
+	effect := func(pos int, e byte) uint32 {
+		var v uint32
+		for b := 0; b < 5; b++ {
+			if e&(1<<b) != 0 {
+				v ^= effects[length-1-pos][b]
+			}
+		}
+		return v
+	}
+
+	// 单个错误
+	single := make(map[uint32]int, length*31)
+	for pos := 0; pos < length; pos++ {
+		for e := byte(1); e < 32; e++ {
+			v := effect(pos, e)
+			if v == syndrome {
+				return []int{pos}
+			}
+			single[v] = pos
+		}
+	}
+
+	// 两个错误
+	for pos := 0; pos < length; pos++ {
+		for e := byte(1); e < 32; e++ {
+			if other, ok := single[syndrome^effect(pos, e)]; ok && other != pos {
+				positions := []int{pos, other}
+				sort.Ints(positions)
+				return positions
+			}
+		}
+	}
+	return nil
+}
@@ -13,9 +13,10 @@ import (
 //
 // 本文件定义了简化版比特币网络的核心数据结构，包括：
 // - BlockHeader: 区块头结构，包含区块的元数据信息
-// - Transaction: 简化的交易结构，用于Step 1.3阶段
 // - Block: 完整的区块结构，包含区块头和交易列表
 //
+// 交易结构定义见transaction.go。
+//
 // 主要功能模块：
 // 1. 数据结构定义：定义区块链的基本数据类型
 // 2. 序列化/反序列化：支持区块数据的持久化存储
@@ -44,19 +45,6 @@ type BlockHeader struct {
 	Nonce         uint32   // 4字节 - 工作量证明随机数，范围0到2^32-1
 }
 
-// Transaction 简化的交易结构（Step 1.3阶段使用）
-//
-// 这是一个简化的交易实现，用于区块链开发的早期阶段。
-// 在完整实现中，交易结构会包含输入、输出、脚本等复杂字段。
-//
-// 字段说明：
-// - Hash: 交易的SHA-256哈希值，用作交易的唯一标识符
-// - Data: 交易的原始数据，包含所有交易信息的序列化结果
-type Transaction struct {
-	Hash [32]byte // 交易的SHA-256哈希值，32字节固定长度
-	Data []byte   // 交易的序列化数据，变长字段
-}
-
 // Block 区块结构
 //
 // 区块是区块链的基本单位，包含区块头和交易列表。
@@ -190,28 +178,37 @@ func (bh *BlockHeader) Deserialize(data []byte) error {
 	return nil
 }
 
-// IsValid 验证区块头基础有效性
+// Validate 验证区块头基础有效性
 //
-// 验证区块头的版本号、时间戳和难度目标是否有效。
-func (bh *BlockHeader) IsValid() bool {
+// 验证区块头的版本号、时间戳和难度位格式，失败时返回标识具体规则的RuleError。
+// 该检查不依赖区块链上下文，前块链接和中位时间由区块链引擎验证。
+func (bh *BlockHeader) Validate() error {
 	// 检查版本号
 	if bh.Version < BlockVersion1 {
-		return false
+		return NewRuleError(ErrCodeBlockVersionTooOld, fmt.Sprintf("%s: 版本%d低于%d",
+			ErrBlockVersionTooOld, bh.Version, BlockVersion1))
 	}
 
 	// 检查时间戳（不能太远的未来）
-	now := uint32(time.Now().Unix())
-	maxFutureTime := now + uint32(MaxTimeOffset.Seconds())
-	if bh.Timestamp > maxFutureTime {
-		return false
+	maxFutureTime := time.Now().Add(MaxTimeOffset).Unix()
+	if int64(bh.Timestamp) > maxFutureTime {
+		return NewRuleError(ErrCodeTimeTooNew, fmt.Sprintf("%s: 时间戳%d超过允许的最大值%d",
+			ErrInvalidTimestamp, bh.Timestamp, maxFutureTime))
 	}
 
 	// 检查难度位格式
 	if bh.Bits == 0 {
-		return false
+		return NewRuleError(ErrCodeInvalidDifficulty, fmt.Sprintf("%s: 难度位不能为0", ErrInvalidDifficulty))
 	}
 
-	return true
+	return nil
+}
+
+// IsValid 验证区块头基础有效性
+//
+// 验证区块头的版本号、时间戳和难度目标是否有效。
+func (bh *BlockHeader) IsValid() bool {
+	return bh.Validate() == nil
 }
 
 // GetDifficulty 获取区块难度值
@@ -250,7 +247,7 @@ func (b *Block) Size() int {
 
 	// 添加所有交易的大小
 	for _, tx := range b.Transactions {
-		size += len(tx.Data)
+		size += tx.SerializeSize()
 	}
 
 	return size
@@ -273,7 +270,7 @@ func (b *Block) Serialize() []byte {
 
 	// 序列化所有交易
 	for _, tx := range b.Transactions {
-		buf.Write(tx.Data)
+		buf.Write(tx.Serialize())
 	}
 
 	return buf.Bytes()
@@ -314,37 +311,25 @@ func (b *Block) Deserialize(data []byte) error {
 		return fmt.Errorf("交易数量超过限制: %d > %d", txCount, MaxTransactionsPerBlock)
 	}
 
-	// 初始化交易列表
-	b.Transactions = make([]*Transaction, txCount)
+	// 每笔交易至少包含 Version(4) + 输入数量(1) + 输出数量(1) + LockTime(4)
+	if txCount > uint64((len(data)-offset)/minTxSize) {
+		return fmt.Errorf("交易数量与数据长度不符: %d", txCount)
+	}
 
-	// 反序列化所有交易（简化版本）
+	// 逐笔反序列化交易，每笔交易的长度由其自身结构决定
+	b.Transactions = make([]*Transaction, txCount)
 	for i := uint64(0); i < txCount; i++ {
-		if offset >= len(data) {
-			return fmt.Errorf("数据长度不足，无法读取交易%d", i)
-		}
-
-		// 简化处理：假设每个交易数据长度为剩余数据平均分配
-		// 在完整实现中，每个交易都有自己的长度字段
-		remainingTxs := txCount - i
-		remainingData := len(data) - offset
-		txDataLen := remainingData / int(remainingTxs)
-
-		if txDataLen <= 0 || offset+txDataLen > len(data) {
-			return fmt.Errorf("交易%d数据长度计算错误", i)
+		tx, n, err := DeserializeTransaction(data[offset:])
+		if err != nil {
+			return fmt.Errorf("反序列化交易%d失败: %v", i, err)
 		}
 
-		txData := data[offset : offset+txDataLen]
-		tx := &Transaction{
-			Data: make([]byte, len(txData)),
-		}
-		copy(tx.Data, txData)
-
-		// 计算交易哈希
-		hash := utils.DoubleSHA256(txData)
-		copy(tx.Hash[:], hash)
-
 		b.Transactions[i] = tx
-		offset += txDataLen
+		offset += n
+	}
+
+	if offset != len(data) {
+		return fmt.Errorf("区块数据存在多余字节: 解析%d字节, 实际%d字节", offset, len(data))
 	}
 
 	return nil
@@ -352,35 +337,62 @@ func (b *Block) Deserialize(data []byte) error {
 
 // Validate 验证区块完整性
 //
-// 验证区块的有效性，包括区块头、交易数量和Merkle根。
+// 验证区块的有效性，包括区块头、区块大小、交易数量、Coinbase位置、交易唯一性和Merkle根。
+// Merkle树将奇数个节点的最后一个与自身配对，重复末尾的交易不会改变Merkle根，
+// 因此必须拒绝包含重复交易的区块，否则同一区块头可以对应被篡改的交易列表。
+// 失败时返回标识具体规则的RuleError。
 func (b *Block) Validate() error {
 	// 验证区块头
 	if b.Header == nil {
-		return fmt.Errorf(ErrInvalidBlockHeader)
+		return NewRuleError(ErrCodeInvalidBlockHeader, ErrInvalidBlockHeader)
 	}
 
-	if !b.Header.IsValid() {
-		return fmt.Errorf(ErrInvalidBlockHeader)
+	if err := b.Header.Validate(); err != nil {
+		return err
 	}
 
 	// 验证区块大小
-	if b.Size() > MaxBlockSize {
-		return fmt.Errorf(ErrBlockTooLarge)
+	if size := b.Size(); size > MaxBlockSize {
+		return NewRuleError(ErrCodeBlockTooLarge, fmt.Sprintf("%s: %d > %d",
+			ErrBlockTooLarge, size, MaxBlockSize))
 	}
 
 	// 验证交易数量
 	if len(b.Transactions) == 0 {
-		return fmt.Errorf(ErrEmptyBlock)
+		return NewRuleError(ErrCodeEmptyBlock, ErrEmptyBlock)
 	}
 
 	if len(b.Transactions) > MaxTransactionsPerBlock {
-		return fmt.Errorf(ErrTooManyTransactions)
+		return NewRuleError(ErrCodeTooManyTransactions, fmt.Sprintf("%s: %d > %d",
+			ErrTooManyTransactions, len(b.Transactions), MaxTransactionsPerBlock))
+	}
+
+	// 验证Coinbase交易：第一笔必须是Coinbase，其余不能是Coinbase
+	if !b.Transactions[0].IsCoinbase() {
+		return NewRuleError(ErrCodeInvalidCoinbase, fmt.Sprintf("%s: 第一笔交易不是Coinbase交易", ErrInvalidCoinbase))
+	}
+	for i, tx := range b.Transactions[1:] {
+		if tx.IsCoinbase() {
+			return NewRuleError(ErrCodeInvalidCoinbase, fmt.Sprintf("%s: 第%d笔交易是多余的Coinbase交易",
+				ErrInvalidCoinbase, i+1))
+		}
+	}
+
+	// 验证交易唯一性
+	seen := make(map[[32]byte]struct{}, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		txHash := tx.Hash()
+		if _, exists := seen[txHash]; exists {
+			return NewRuleError(ErrCodeDuplicateTx, fmt.Sprintf("%s: 第%d笔交易%x", ErrDuplicateTx, i, txHash))
+		}
+		seen[txHash] = struct{}{}
 	}
 
 	// 验证Merkle根
 	calculatedMerkleRoot := b.GetMerkleRoot()
 	if !bytes.Equal(calculatedMerkleRoot[:], b.Header.MerkleRoot[:]) {
-		return fmt.Errorf(ErrInvalidMerkleRoot)
+		return NewRuleError(ErrCodeInvalidMerkleRoot, fmt.Sprintf("%s: 期望%x, 实际%x",
+			ErrInvalidMerkleRoot, calculatedMerkleRoot, b.Header.MerkleRoot))
 	}
 
 	return nil
@@ -397,7 +409,8 @@ func (b *Block) GetMerkleRoot() [32]byte {
 	// 收集所有交易哈希
 	txHashes := make([][]byte, len(b.Transactions))
 	for i, tx := range b.Transactions {
-		txHashes[i] = tx.Hash[:]
+		txHash := tx.Hash()
+		txHashes[i] = txHash[:]
 	}
 
 	// 计算Merkle根
@@ -413,7 +426,7 @@ func (b *Block) GetMerkleRoot() [32]byte {
 func (b *Block) GetTransactionHashes() [][32]byte {
 	hashes := make([][32]byte, len(b.Transactions))
 	for i, tx := range b.Transactions {
-		hashes[i] = tx.Hash
+		hashes[i] = tx.Hash()
 	}
 	return hashes
 }
@@ -423,7 +436,7 @@ func (b *Block) GetTransactionHashes() [][32]byte {
 // 根据交易哈希值检查区块是否包含指定交易。
 func (b *Block) HasTransaction(txHash [32]byte) bool {
 	for _, tx := range b.Transactions {
-		if bytes.Equal(tx.Hash[:], txHash[:]) {
+		if tx.Hash() == txHash {
 			return true
 		}
 	}
@@ -435,7 +448,7 @@ func (b *Block) HasTransaction(txHash [32]byte) bool {
 // 根据交易哈希值获取交易结构。
 func (b *Block) GetTransaction(txHash [32]byte) *Transaction {
 	for _, tx := range b.Transactions {
-		if bytes.Equal(tx.Hash[:], txHash[:]) {
+		if tx.Hash() == txHash {
 			return tx
 		}
 	}
@@ -457,20 +470,3 @@ func (bh *BlockHeader) String() string {
 	return fmt.Sprintf("BlockHeader{Version: %d, PrevHash: %x, MerkleRoot: %x, Timestamp: %d, Bits: %x, Nonce: %d}",
 		bh.Version, bh.PrevBlockHash, bh.MerkleRoot, bh.Timestamp, bh.Bits, bh.Nonce)
 }
-
-// NewTransaction 创建新交易（简化版本）
-//
-// 根据给定的交易数据创建新交易结构。
-//
-// 参数：
-// - data: 交易数据
-func NewTransaction(data []byte) *Transaction {
-	hash := utils.DoubleSHA256(data)
-	var txHash [32]byte
-	copy(txHash[:], hash)
-
-	return &Transaction{
-		Hash: txHash,
-		Data: data,
-	}
-}
@@ -137,12 +137,12 @@ const (
 	// GenesisBlockNonce 创世区块的工作量证明随机数
 	//
 	// 实现说明：
-	// - 使用比特币创世区块的原始Nonce值
-	// - 经过工作量证明计算得出的有效随机数
+	// - 本网络创世区块的Coinbase与比特币不同，不能沿用比特币的原始Nonce值
+	// - 以原始时间戳和难度位重新计算得出的有效随机数
 	// - 确保创世区块哈希满足难度要求
 	// - 所有节点必须使用相同的Nonce值
 	// - 用于创世区块的完整性验证
-	GenesisBlockNonce = 2083236893
+	GenesisBlockNonce = 1019827020
 
 	// GenesisBlockBits 创世区块的难度目标位
 	//
@@ -213,6 +213,14 @@ const (
 	// - 允许快速出块但维持时间逻辑
 	// - 用于区块链时间序列的完整性验证
 	MinTimestampDelta = 1 * time.Second
+
+	// MedianTimeBlocks 计算中位时间使用的区块数 (11个)
+	//
+	// 实现说明：
+	// - 新区块时间戳必须大于前11个区块时间戳的中位数
+	// - 中位数不受个别矿工时间戳偏差的影响
+	// - 与比特币主网保持一致的参数
+	MedianTimeBlocks = 11
 )
 
 // ==================== 区块和交易验证规则 ====================
@@ -250,6 +258,51 @@ const (
 	MaxTxSize = 100000
 )
 
+// ==================== 交易结构常量 ====================
+// 以下常量定义了交易序列化格式中的固定取值，
+// 与比特币交易格式保持一致。
+const (
+	// TxVersion 当前交易版本号
+	//
+	// 实现说明：
+	// - 新创建的交易默认使用此版本号
+	// - 与创世区块Coinbase交易的版本号一致
+	TxVersion = 1
+
+	// MaxPrevOutIndex 前置输出索引的最大值
+	//
+	// 实现说明：
+	// - Coinbase交易的输入使用此索引配合全零哈希
+	// - 普通交易不会引用此索引
+	MaxPrevOutIndex = 0xFFFFFFFF
+
+	// MaxTxInSequenceNum 交易输入序列号的最大值
+	//
+	// 实现说明：
+	// - 新建交易输入的默认序列号
+	// - 表示该输入已最终确定
+	MaxTxInSequenceNum = 0xFFFFFFFF
+
+	// LockTimeThreshold 锁定时间的区块高度与时间戳分界值
+	//
+	// 实现说明：
+	// - 锁定时间小于此值时表示区块高度，否则表示Unix时间戳
+	// - 取值对应1985年11月5日，区块高度不会达到此值
+	LockTimeThreshold = 500000000
+
+	// minTxInSize 序列化交易输入的最小字节数
+	// PrevHash(32) + PrevIndex(4) + ScriptLen(1) + Sequence(4)
+	minTxInSize = 41
+
+	// minTxOutSize 序列化交易输出的最小字节数
+	// Value(8) + ScriptLen(1)
+	minTxOutSize = 9
+
+	// minTxSize 序列化交易的最小字节数
+	// Version(4) + TxInCount(1) + TxOutCount(1) + LockTime(4)
+	minTxSize = 10
+)
+
 // ==================== 网络协议标识符 ====================
 // 以下常量定义了不同网络环境的魔数标识符，
 // 用于区分主网、测试网和开发网络。
@@ -283,6 +336,13 @@ const (
 	// - 适合单元测试和集成测试
 	// - 提供可重复的测试条件
 	RegTestMagic = 0xFABFB5DA
+
+	// SimNetMagic 模拟测试网络的魔数标识符
+	//
+	// 实现说明：
+	// - 用于私有的多节点模拟网络
+	// - 与回归测试网络一样使用最低难度，但保留难度调整
+	SimNetMagic = 0x12141C16
 )
 
 // ==================== 标准化错误消息 ====================
@@ -302,4 +362,16 @@ const (
 	ErrTooManyTransactions  = "交易数量超过限制"      // 区块包含的交易数超过上限
 	ErrEmptyBlock           = "空区块"           // 区块不包含任何交易
 	ErrInvalidCoinbase      = "无效的Coinbase交易" // Coinbase交易格式错误
+	ErrDuplicateTx          = "区块包含重复的交易"     // 区块中存在哈希相同的交易
+
+	// 交易级别错误消息
+	ErrMissingTxOut      = "引用的交易输出不存在"         // 交易输入引用的输出不存在或已被花费
+	ErrOverwriteTx       = "交易输出已存在"            // 交易创建的输出与未花费输出重复
+	ErrInvalidTxOutValue = "无效的交易输出金额"          // 输出金额为负或超过最大供应量
+	ErrSpendTooHigh      = "交易输出总额超过输入总额"       // 交易花费的金额超过输入金额
+	ErrBadCoinbaseValue  = "Coinbase输出超过奖励与手续费" // Coinbase输出总额超过区块奖励加手续费
+	ErrUnfinalizedTx     = "交易尚未达到锁定时间"         // 交易锁定时间晚于区块高度或时间
+	ErrScriptValidation  = "交易脚本验证失败"           // 输入的解锁脚本不满足被花费输出的锁定脚本
+	ErrImmatureSpend     = "花费了未成熟的Coinbase输出"  // Coinbase输出的确认数不足CoinbaseMaturity
+	ErrTooManySigOps     = "区块签名操作数超过限制"        // 区块中所有脚本的签名操作数超过MaxBlockSigOps
 )
@@ -0,0 +1,147 @@
+package blockchain
+
+import "fmt"
+
+// errors.go - 共识规则错误定义文件
+//
+// 区块或交易违反共识规则时返回RuleError，其中ErrorCode精确标识被违反的规则，
+// Description给出包含具体数值的人类可读说明。调用方可以通过errors.As
+// 取得RuleError并根据ErrorCode决定后续处理（例如对发送方节点计分）。
+
+// ErrorCode 共识规则错误码
+type ErrorCode int
+
+// 共识规则错误码定义
+const (
+	// ErrCodeDuplicateBlock 区块已经存在
+	ErrCodeDuplicateBlock ErrorCode = iota
+
+	// ErrCodeBlockTooLarge 区块序列化大小超过MaxBlockSize
+	ErrCodeBlockTooLarge
+
+	// ErrCodeBlockVersionTooOld 区块版本低于网络要求
+	ErrCodeBlockVersionTooOld
+
+	// ErrCodeInvalidTimestamp 时间戳不大于前11个区块的中位时间
+	ErrCodeInvalidTimestamp
+
+	// ErrCodeTimeTooNew 时间戳超过当前时间加MaxTimeOffset
+	ErrCodeTimeTooNew
+
+	// ErrCodeInvalidDifficulty 难度位格式错误或超出工作量证明上限
+	ErrCodeInvalidDifficulty
+
+	// ErrCodeInvalidBlockHash 区块哈希不满足难度目标
+	ErrCodeInvalidBlockHash
+
+	// ErrCodeInvalidPrevBlockHash 前块哈希指向未知区块
+	ErrCodeInvalidPrevBlockHash
+
+	// ErrCodeInvalidAncestor 区块的某个祖先区块无效
+	ErrCodeInvalidAncestor
+
+	// ErrCodeEmptyBlock 区块不包含任何交易
+	ErrCodeEmptyBlock
+
+	// ErrCodeTooManyTransactions 区块交易数超过MaxTransactionsPerBlock
+	ErrCodeTooManyTransactions
+
+	// ErrCodeInvalidMerkleRoot Merkle根与交易列表不匹配
+	ErrCodeInvalidMerkleRoot
+
+	// ErrCodeInvalidCoinbase 第一笔交易不是Coinbase或存在多笔Coinbase
+	ErrCodeInvalidCoinbase
+
+	// ErrCodeInvalidBlockHeader 区块头缺失
+	ErrCodeInvalidBlockHeader
+
+	// ErrCodeMissingTxOut 交易输入引用的输出不存在或已被花费
+	ErrCodeMissingTxOut
+
+	// ErrCodeOverwriteTx 交易创建的输出与尚未花费的输出重复
+	ErrCodeOverwriteTx
+
+	// ErrCodeInvalidTxOutValue 交易输出金额为负或超过最大供应量
+	ErrCodeInvalidTxOutValue
+
+	// ErrCodeSpendTooHigh 交易输出总额超过输入总额
+	ErrCodeSpendTooHigh
+
+	// ErrCodeBadCoinbaseValue Coinbase输出总额超过区块奖励加手续费
+	ErrCodeBadCoinbaseValue
+
+	// ErrCodeUnfinalizedTx 交易锁定时间晚于所在区块的高度或时间
+	ErrCodeUnfinalizedTx
+
+	// ErrCodeScriptValidation 交易输入的脚本执行失败
+	ErrCodeScriptValidation
+
+	// ErrCodeImmatureSpend 交易花费了尚未达到成熟确认数的Coinbase输出
+	ErrCodeImmatureSpend
+
+	// ErrCodeTooManySigOps 区块中的签名操作数超过MaxBlockSigOps
+	ErrCodeTooManySigOps
+
+	// ErrCodeDuplicateTx 区块中包含哈希相同的交易
+	ErrCodeDuplicateTx
+)
+
+// errorCodeStrings 错误码名称映射，用于日志输出
+var errorCodeStrings = map[ErrorCode]string{
+	ErrCodeDuplicateBlock:       "ErrCodeDuplicateBlock",
+	ErrCodeBlockTooLarge:        "ErrCodeBlockTooLarge",
+	ErrCodeBlockVersionTooOld:   "ErrCodeBlockVersionTooOld",
+	ErrCodeInvalidTimestamp:     "ErrCodeInvalidTimestamp",
+	ErrCodeTimeTooNew:           "ErrCodeTimeTooNew",
+	ErrCodeInvalidDifficulty:    "ErrCodeInvalidDifficulty",
+	ErrCodeInvalidBlockHash:     "ErrCodeInvalidBlockHash",
+	ErrCodeInvalidPrevBlockHash: "ErrCodeInvalidPrevBlockHash",
+	ErrCodeInvalidAncestor:      "ErrCodeInvalidAncestor",
+	ErrCodeEmptyBlock:           "ErrCodeEmptyBlock",
+	ErrCodeTooManyTransactions:  "ErrCodeTooManyTransactions",
+	ErrCodeInvalidMerkleRoot:    "ErrCodeInvalidMerkleRoot",
+	ErrCodeInvalidCoinbase:      "ErrCodeInvalidCoinbase",
+	ErrCodeInvalidBlockHeader:   "ErrCodeInvalidBlockHeader",
+	ErrCodeMissingTxOut:         "ErrCodeMissingTxOut",
+	ErrCodeOverwriteTx:          "ErrCodeOverwriteTx",
+	ErrCodeInvalidTxOutValue:    "ErrCodeInvalidTxOutValue",
+	ErrCodeSpendTooHigh:         "ErrCodeSpendTooHigh",
+	ErrCodeBadCoinbaseValue:     "ErrCodeBadCoinbaseValue",
+	ErrCodeUnfinalizedTx:        "ErrCodeUnfinalizedTx",
+	ErrCodeScriptValidation:     "ErrCodeScriptValidation",
+	ErrCodeImmatureSpend:        "ErrCodeImmatureSpend",
+	ErrCodeTooManySigOps:        "ErrCodeTooManySigOps",
+	ErrCodeDuplicateTx:          "ErrCodeDuplicateTx",
+}
+
+// String 返回错误码名称
+func (e ErrorCode) String() string {
+	if s, ok := errorCodeStrings[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("未知错误码(%d)", int(e))
+}
+
+// RuleError 共识规则错误
+//
+// 字段说明：
+// - ErrorCode: 被违反的共识规则
+// - Description: 错误的详细描述
+type RuleError struct {
+	ErrorCode   ErrorCode // 被违反的共识规则
+	Description string    // 错误详细描述
+}
+
+// Error 实现error接口
+func (e RuleError) Error() string {
+	return e.Description
+}
+
+// NewRuleError 创建共识规则错误
+//
+// 参数：
+// - code: 错误码
+// - desc: 错误描述
+func NewRuleError(code ErrorCode, desc string) RuleError {
+	return RuleError{ErrorCode: code, Description: desc}
+}
@@ -50,6 +50,14 @@ func init() {
 // 返回值：
 // *Block - 完整的创世区块实例，包含区块头和交易列表
 func CreateGenesisBlock() *Block {
+	return newGenesisBlock(GenesisBlockTimestamp, GenesisBlockBits, GenesisBlockNonce)
+}
+
+// newGenesisBlock 使用指定的时间戳、难度位和Nonce创建创世区块
+//
+// 各网络的创世区块共用同一个Coinbase交易，只有区块头参数不同，
+// 因此不同网络的创世区块哈希互不相同。
+func newGenesisBlock(timestamp, bits, nonce uint32) *Block {
 	// 创世区块的前块哈希为全零
 	var prevBlockHash [32]byte
 
@@ -57,25 +65,24 @@ func CreateGenesisBlock() *Block {
 	coinbaseTx := createGenesisCoinbaseTransaction()
 
 	// 计算Merkle根（创世区块只有一个交易）
-	txHashes := [][]byte{coinbaseTx.Hash[:]}
+	coinbaseHash := coinbaseTx.Hash()
+	txHashes := [][]byte{coinbaseHash[:]}
 	merkleRoot := utils.MerkleRoot(txHashes)
 	var merkleRootArray [32]byte
 	copy(merkleRootArray[:], merkleRoot)
 
 	// 创建创世区块头
 	header := NewBlockHeader(
-		CurrentBlockVersion,   // Version
-		prevBlockHash,         // PrevBlockHash (全零)
-		merkleRootArray,       // MerkleRoot
-		GenesisBlockTimestamp, // Timestamp
-		GenesisBlockBits,      // Bits (难度)
-		GenesisBlockNonce,     // Nonce
+		CurrentBlockVersion, // Version
+		prevBlockHash,       // PrevBlockHash (全零)
+		merkleRootArray,     // MerkleRoot
+		timestamp,           // Timestamp
+		bits,                // Bits (难度)
+		nonce,               // Nonce
 	)
 
 	// 创建创世区块
-	block := NewBlock(header, []*Transaction{coinbaseTx})
-
-	return block
+	return NewBlock(header, []*Transaction{coinbaseTx})
 }
 
 // createGenesisCoinbaseTransaction 创建创世区块的Coinbase交易
@@ -88,14 +95,14 @@ func CreateGenesisBlock() *Block {
 // - 包含特殊的脚本消息
 // - 输出50 BTC的初始奖励
 //
-// 实现细节：
-// 1. 构建交易版本号（4字节小端序）
-// 2. 设置输入数量为1
-// 3. 添加Coinbase输入（全零哈希 + 0xFFFFFFFF索引）
-// 4. 插入包含创世消息的脚本
-// 5. 设置输出数量为1
-// 6. 添加50 BTC的输出金额
-// 7. 设置锁定时间为0
+// 序列化布局：
+// 1. 交易版本号（4字节小端序）
+// 2. 输入数量为1
+// 3. Coinbase输入（全零哈希 + 0xFFFFFFFF索引）
+// 4. 包含创世消息的脚本
+// 5. 输出数量为1
+// 6. 50 BTC的输出金额
+// 7. 锁定时间为0
 //
 // 返回值：
 // *Transaction - 创世区块的Coinbase交易实例
@@ -103,45 +110,8 @@ func createGenesisCoinbaseTransaction() *Transaction {
 	// 创世区块Coinbase交易的输入脚本包含特殊消息
 	coinbaseScript := []byte(GenesisCoinbaseMessage)
 
-	// 创建简化的Coinbase交易数据
-	// 在完整实现中，这里会包含完整的交易结构
-	coinbaseData := make([]byte, 0, len(coinbaseScript)+100)
-
-	// 添加版本号 (4字节)
-	coinbaseData = append(coinbaseData, utils.Uint32ToLittleEndian(1)...)
-
-	// 添加输入数量 (1字节，VarInt)
-	coinbaseData = append(coinbaseData, utils.EncodeVarInt(1)...)
-
-	// 添加输入：前置交易哈希 (32字节全零)
-	coinbaseData = append(coinbaseData, make([]byte, 32)...)
-
-	// 添加输入：输出索引 (4字节，0xFFFFFFFF表示Coinbase)
-	coinbaseData = append(coinbaseData, 0xFF, 0xFF, 0xFF, 0xFF)
-
-	// 添加脚本长度
-	coinbaseData = append(coinbaseData, utils.EncodeVarInt(uint64(len(coinbaseScript)))...)
-
-	// 添加脚本内容
-	coinbaseData = append(coinbaseData, coinbaseScript...)
-
-	// 添加序列号 (4字节)
-	coinbaseData = append(coinbaseData, utils.Uint32ToLittleEndian(0xFFFFFFFF)...)
-
-	// 添加输出数量 (1字节)
-	coinbaseData = append(coinbaseData, utils.EncodeVarInt(1)...)
-
-	// 添加输出：金额 (8字节，50 BTC = 5000000000 satoshis)
-	coinbaseData = append(coinbaseData, utils.Uint64ToLittleEndian(5000000000)...)
-
-	// 添加输出脚本长度 (简化为空脚本)
-	coinbaseData = append(coinbaseData, utils.EncodeVarInt(0)...)
-
-	// 添加锁定时间 (4字节)
-	coinbaseData = append(coinbaseData, utils.Uint32ToLittleEndian(0)...)
-
-	// 创建交易
-	return NewTransaction(coinbaseData)
+	// 输出：50 BTC = 5000000000 satoshis，输出脚本为空
+	return NewCoinbaseTransaction(coinbaseScript, 5000000000, []byte{})
 }
 
 // GetGenesisBlock 获取全局创世区块实例
@@ -377,11 +347,11 @@ func CreateTestGenesisBlock() *Block {
 
 	// 创建测试Coinbase交易
 	testMessage := "Test Genesis Block for Simplified Bitcoin Network"
-	coinbaseData := []byte(testMessage)
-	coinbaseTx := NewTransaction(coinbaseData)
+	coinbaseTx := NewCoinbaseTransaction([]byte(testMessage), 5000000000, []byte{})
 
 	// 计算Merkle根
-	txHashes := [][]byte{coinbaseTx.Hash[:]}
+	coinbaseHash := coinbaseTx.Hash()
+	txHashes := [][]byte{coinbaseHash[:]}
 	merkleRoot := utils.MerkleRoot(txHashes)
 	var merkleRootArray [32]byte
 	copy(merkleRootArray[:], merkleRoot)
@@ -0,0 +1,231 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// params.go - 网络参数文件
+//
+// ChainParams汇集了一个网络的全部共识与编码参数：创世区块、工作量证明上限、
+// 难度调整规则、区块奖励减半周期、地址前缀和默认端口。验证、挖矿和地址编码
+// 都通过参数访问这些值，因此同一个程序可以同时运行主网、测试网和私有的
+// 回归测试链。
+
+// regTestPowLimitBits 回归测试网络和模拟网络使用的最低难度位
+//
+// 对应的目标值约为2^255，平均每两次哈希即可找到满足难度的区块。
+const regTestPowLimitBits = 0x207fffff
+
+// ChainParams 网络参数
+type ChainParams struct {
+	// Name 网络名称，同时用作数据目录的子目录名
+	Name string
+
+	// Net 网络魔数，用于P2P消息的网络识别
+	Net uint32
+
+	// DefaultPort P2P默认监听端口
+	DefaultPort int
+
+	// RPCPort RPC默认监听端口
+	RPCPort int
+
+	// GenesisBlock 创世区块
+	GenesisBlock *Block
+
+	// GenesisHash 创世区块哈希
+	GenesisHash [32]byte
+
+	// PowLimit 工作量证明上限（最低难度对应的目标值）
+	PowLimit *big.Int
+
+	// PowLimitBits 工作量证明上限的难度位
+	PowLimitBits uint32
+
+	// TargetTimePerBlock 目标出块时间
+	TargetTimePerBlock time.Duration
+
+	// RetargetInterval 难度调整间隔（区块数）
+	RetargetInterval int32
+
+	// NoRetargeting 禁用难度调整，所有区块沿用创世区块的难度
+	NoRetargeting bool
+
+	// SubsidyHalvingInterval 区块奖励减半间隔（区块数）
+	SubsidyHalvingInterval int32
+
+	// CoinbaseMaturity Coinbase输出可被花费前需要的确认数
+	CoinbaseMaturity int32
+
+	// PubKeyHashAddrID P2PKH地址的版本字节
+	PubKeyHashAddrID byte
+
+	// ScriptHashAddrID P2SH地址的版本字节
+	ScriptHashAddrID byte
+
+	// PrivateKeyID WIF私钥的版本字节
+	PrivateKeyID byte
+
+	// Bech32HRPSegwit 隔离见证地址的Bech32人类可读部分
+	Bech32HRPSegwit string
+
+	// HDPrivateKeyID BIP32扩展私钥的版本前缀
+	HDPrivateKeyID [4]byte
+
+	// HDPublicKeyID BIP32扩展公钥的版本前缀
+	HDPublicKeyID [4]byte
+
+	// HDCoinType BIP44路径中的币种编号
+	HDCoinType uint32
+}
+
+// TargetTimespan 返回一个难度调整窗口的目标时间跨度
+func (p *ChainParams) TargetTimespan() time.Duration {
+	return time.Duration(p.RetargetInterval) * p.TargetTimePerBlock
+}
+
+// IsGenesisHash 判断哈希是否为本网络的创世区块哈希
+func (p *ChainParams) IsGenesisHash(hash [32]byte) bool {
+	return hash == p.GenesisHash
+}
+
+// newChainParams 填充由创世区块推导出的参数
+func newChainParams(p ChainParams) *ChainParams {
+	p.GenesisHash = p.GenesisBlock.Hash()
+	p.PowLimit = utils.BitsToTarget(p.PowLimitBits)
+	return &p
+}
+
+// MainNetParams 主网参数
+var MainNetParams = newChainParams(ChainParams{
+	Name:        "mainnet",
+	Net:         MainNetMagic,
+	DefaultPort: utils.DefaultNetworkPort,
+	RPCPort:     utils.DefaultRPCPort,
+
+	GenesisBlock: CreateGenesisBlock(),
+	PowLimitBits: MaxTargetBits,
+
+	TargetTimePerBlock: TargetBlockTime,
+	RetargetInterval:   DifficultyAdjustmentInterval,
+
+	SubsidyHalvingInterval: utils.HalvingInterval,
+	CoinbaseMaturity:       utils.CoinbaseMaturity,
+
+	PubKeyHashAddrID: 0x00,
+	ScriptHashAddrID: 0x05,
+	PrivateKeyID:     0x80,
+	Bech32HRPSegwit:  "bc",
+
+	HDPrivateKeyID: [4]byte{0x04, 0x88, 0xad, 0xe4}, // xprv
+	HDPublicKeyID:  [4]byte{0x04, 0x88, 0xb2, 0x1e}, // xpub
+	HDCoinType:     0,
+})
+
+// TestNetParams 公共测试网参数
+//
+// 共识规则与主网相同，只有创世区块、魔数、端口和地址前缀不同。
+var TestNetParams = newChainParams(ChainParams{
+	Name:        "testnet",
+	Net:         TestNetMagic,
+	DefaultPort: 18080,
+	RPCPort:     18545,
+
+	GenesisBlock: newGenesisBlock(1296688602, MaxTargetBits, 848785529),
+	PowLimitBits: MaxTargetBits,
+
+	TargetTimePerBlock: TargetBlockTime,
+	RetargetInterval:   DifficultyAdjustmentInterval,
+
+	SubsidyHalvingInterval: utils.HalvingInterval,
+	CoinbaseMaturity:       utils.CoinbaseMaturity,
+
+	PubKeyHashAddrID: 0x6F,
+	ScriptHashAddrID: 0xC4,
+	PrivateKeyID:     0xEF,
+	Bech32HRPSegwit:  "tb",
+
+	HDPrivateKeyID: [4]byte{0x04, 0x35, 0x83, 0x94}, // tprv
+	HDPublicKeyID:  [4]byte{0x04, 0x35, 0x87, 0xcf}, // tpub
+	HDCoinType:     1,
+})
+
+// RegTestParams 回归测试网络参数
+//
+// 使用最低难度且不调整难度，奖励每150个区块减半，适合本地开发和自动化测试。
+var RegTestParams = newChainParams(ChainParams{
+	Name:        "regtest",
+	Net:         RegTestMagic,
+	DefaultPort: 28080,
+	RPCPort:     28545,
+
+	GenesisBlock: newGenesisBlock(1296688602, regTestPowLimitBits, 0),
+	PowLimitBits: regTestPowLimitBits,
+
+	TargetTimePerBlock: TargetBlockTime,
+	RetargetInterval:   DifficultyAdjustmentInterval,
+	NoRetargeting:      true,
+
+	SubsidyHalvingInterval: 150,
+	CoinbaseMaturity:       utils.CoinbaseMaturity,
+
+	PubKeyHashAddrID: 0x6F,
+	ScriptHashAddrID: 0xC4,
+	PrivateKeyID:     0xEF,
+	Bech32HRPSegwit:  "bcrt",
+
+	HDPrivateKeyID: [4]byte{0x04, 0x35, 0x83, 0x94}, // tprv
+	HDPublicKeyID:  [4]byte{0x04, 0x35, 0x87, 0xcf}, // tpub
+	HDCoinType:     1,
+})
+
+// SimNetParams 模拟网络参数
+//
+// 使用最低难度但保留难度调整，适合搭建私有的多节点网络。
+var SimNetParams = newChainParams(ChainParams{
+	Name:        "simnet",
+	Net:         SimNetMagic,
+	DefaultPort: 38080,
+	RPCPort:     38545,
+
+	GenesisBlock: newGenesisBlock(1401292357, regTestPowLimitBits, 3),
+	PowLimitBits: regTestPowLimitBits,
+
+	TargetTimePerBlock: TargetBlockTime,
+	RetargetInterval:   DifficultyAdjustmentInterval,
+
+	SubsidyHalvingInterval: utils.HalvingInterval,
+	CoinbaseMaturity:       utils.CoinbaseMaturity,
+
+	PubKeyHashAddrID: 0x3F,
+	ScriptHashAddrID: 0x7B,
+	PrivateKeyID:     0x64,
+	Bech32HRPSegwit:  "sb",
+
+	HDPrivateKeyID: [4]byte{0x04, 0x20, 0xb9, 0x00}, // sprv
+	HDPublicKeyID:  [4]byte{0x04, 0x20, 0xbd, 0x3a}, // spub
+	HDCoinType:     115,
+})
+
+// ParamsForNetwork 根据网络名称返回网络参数
+//
+// 参数：
+// - name: 网络名称（mainnet、testnet、regtest或simnet），空字符串表示mainnet
+func ParamsForNetwork(name string) (*ChainParams, error) {
+	switch name {
+	case "", MainNetParams.Name:
+		return MainNetParams, nil
+	case TestNetParams.Name:
+		return TestNetParams, nil
+	case RegTestParams.Name:
+		return RegTestParams, nil
+	case SimNetParams.Name:
+		return SimNetParams, nil
+	default:
+		return nil, fmt.Errorf("未知的网络: %s", name)
+	}
+}
@@ -0,0 +1,54 @@
+package blockchain
+
+import "simplied-bitcoin-network-go/pkg/utils"
+
+// subsidy.go - 区块奖励计算文件
+//
+// 区块奖励从InitialBlockReward个比特币开始，每经过网络参数中的
+// SubsidyHalvingInterval个区块减半，减半64次后奖励为0。
+// 主网全部奖励之和略低于MaxSupply。
+
+// maxHalvings 奖励减半的最大次数，超过后右移结果恒为0
+const maxHalvings = 64
+
+// CalcBlockSubsidy 计算指定高度区块的奖励（聪）
+//
+// 参数：
+// - height: 区块高度，创世区块为0
+// - params: 网络参数
+func CalcBlockSubsidy(height int32, params *ChainParams) int64 {
+	if height < 0 {
+		return 0
+	}
+
+	halvings := height / params.SubsidyHalvingInterval
+	if halvings >= maxHalvings {
+		return 0
+	}
+
+	return (utils.InitialBlockReward * utils.SatoshiPerBitcoin) >> uint(halvings)
+}
+
+// CalcTotalSubsidy 计算从创世区块到指定高度（含）的累计区块奖励（聪）
+//
+// 按减半周期分段求和，不需要逐块累加。
+func CalcTotalSubsidy(height int32, params *ChainParams) int64 {
+	if height < 0 {
+		return 0
+	}
+
+	var total int64
+	for start := int32(0); start <= height; start += params.SubsidyHalvingInterval {
+		subsidy := CalcBlockSubsidy(start, params)
+		if subsidy == 0 {
+			break
+		}
+
+		end := start + params.SubsidyHalvingInterval - 1
+		if end > height {
+			end = height
+		}
+		total += subsidy * int64(end-start+1)
+	}
+	return total
+}
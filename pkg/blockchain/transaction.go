@@ -0,0 +1,421 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// transaction.go - 交易数据结构定义文件
+//
+// 本文件定义了比特币兼容的交易结构，包括：
+// - OutPoint: 交易输出引用（前置交易哈希 + 输出索引）
+// - TxIn: 交易输入，引用一个待花费的输出并携带解锁脚本
+// - TxOut: 交易输出，包含金额和锁定脚本
+// - Transaction: 完整交易，包含版本、输入列表、输出列表和锁定时间
+//
+// 序列化格式与createGenesisCoinbaseTransaction中手工拼接的布局完全一致：
+//
+//	Version(4) | TxInCount(VarInt) | TxIn... | TxOutCount(VarInt) | TxOut... | LockTime(4)
+//	TxIn:  PrevHash(32) | PrevIndex(4) | ScriptLen(VarInt) | Script | Sequence(4)
+//	TxOut: Value(8) | ScriptLen(VarInt) | Script
+//
+// 所有整数字段均使用小端序，变长字段使用VarInt长度前缀，
+// 因此任意一笔交易都可以在不依赖外部长度信息的情况下被完整解析。
+
+// OutPoint 交易输出引用
+//
+// 唯一标识某笔交易中的某个输出，用于交易输入引用被花费的UTXO。
+type OutPoint struct {
+	Hash  [32]byte // 被引用交易的哈希值
+	Index uint32   // 被引用输出在交易输出列表中的索引
+}
+
+// TxIn 交易输入
+//
+// 字段说明：
+// - PreviousOutPoint: 被花费的前置输出
+// - SignatureScript: 解锁脚本，证明花费者有权使用该输出
+// - Sequence: 序列号，默认为MaxTxInSequenceNum
+type TxIn struct {
+	PreviousOutPoint OutPoint // 被花费的前置输出引用
+	SignatureScript  []byte   // 解锁脚本（Coinbase交易中为任意数据）
+	Sequence         uint32   // 输入序列号
+}
+
+// TxOut 交易输出
+//
+// 字段说明：
+// - Value: 输出金额，单位为聪（satoshi）
+// - PkScript: 锁定脚本，定义花费该输出需要满足的条件
+type TxOut struct {
+	Value    int64  // 输出金额（聪）
+	PkScript []byte // 锁定脚本
+}
+
+// Transaction 交易结构
+//
+// 字段说明：
+// - Version: 交易版本号
+// - TxIn: 交易输入列表
+// - TxOut: 交易输出列表
+// - LockTime: 锁定时间（区块高度或Unix时间戳）
+type Transaction struct {
+	Version  uint32   // 4字节 - 交易版本号
+	TxIn     []*TxIn  // 交易输入列表
+	TxOut    []*TxOut // 交易输出列表
+	LockTime uint32   // 4字节 - 锁定时间
+}
+
+// NewOutPoint 创建新的交易输出引用
+//
+// 参数：
+// - hash: 被引用交易的哈希值
+// - index: 被引用输出的索引
+func NewOutPoint(hash [32]byte, index uint32) *OutPoint {
+	return &OutPoint{
+		Hash:  hash,
+		Index: index,
+	}
+}
+
+// String 返回输出引用的字符串表示（显示格式哈希:索引）
+func (op OutPoint) String() string {
+	return fmt.Sprintf("%s:%d", utils.HashToString(op.Hash[:]), op.Index)
+}
+
+// NewTxIn 创建新的交易输入
+//
+// 序列号默认设置为MaxTxInSequenceNum。
+//
+// 参数：
+// - prevOut: 被花费的前置输出引用
+// - signatureScript: 解锁脚本
+func NewTxIn(prevOut *OutPoint, signatureScript []byte) *TxIn {
+	return &TxIn{
+		PreviousOutPoint: *prevOut,
+		SignatureScript:  signatureScript,
+		Sequence:         MaxTxInSequenceNum,
+	}
+}
+
+// NewTxOut 创建新的交易输出
+//
+// 参数：
+// - value: 输出金额（聪）
+// - pkScript: 锁定脚本
+func NewTxOut(value int64, pkScript []byte) *TxOut {
+	return &TxOut{
+		Value:    value,
+		PkScript: pkScript,
+	}
+}
+
+// NewTransaction 创建新交易
+//
+// 参数：
+// - version: 交易版本号
+// - txIn: 交易输入列表
+// - txOut: 交易输出列表
+// - lockTime: 锁定时间
+func NewTransaction(version uint32, txIn []*TxIn, txOut []*TxOut, lockTime uint32) *Transaction {
+	return &Transaction{
+		Version:  version,
+		TxIn:     txIn,
+		TxOut:    txOut,
+		LockTime: lockTime,
+	}
+}
+
+// NewCoinbaseTransaction 创建Coinbase交易
+//
+// Coinbase交易只有一个输入，该输入引用全零哈希和0xFFFFFFFF索引，
+// 其解锁脚本可以携带任意数据（如创世消息或区块高度）。
+//
+// 参数：
+// - coinbaseScript: Coinbase输入脚本
+// - value: 输出金额（聪）
+// - pkScript: 输出锁定脚本
+func NewCoinbaseTransaction(coinbaseScript []byte, value int64, pkScript []byte) *Transaction {
+	prevOut := NewOutPoint([32]byte{}, MaxPrevOutIndex)
+	txIn := NewTxIn(prevOut, coinbaseScript)
+	txOut := NewTxOut(value, pkScript)
+	return NewTransaction(TxVersion, []*TxIn{txIn}, []*TxOut{txOut}, 0)
+}
+
+// Hash 计算交易哈希
+//
+// 交易哈希为序列化数据的双重SHA-256哈希，用作交易的唯一标识符。
+func (tx *Transaction) Hash() [32]byte {
+	hash := utils.DoubleSHA256(tx.Serialize())
+	var result [32]byte
+	copy(result[:], hash)
+	return result
+}
+
+// IsCoinbase 判断是否为Coinbase交易
+//
+// Coinbase交易有且只有一个输入，并且该输入引用全零哈希和最大索引。
+func (tx *Transaction) IsCoinbase() bool {
+	if len(tx.TxIn) != 1 {
+		return false
+	}
+
+	prevOut := &tx.TxIn[0].PreviousOutPoint
+	return prevOut.Index == MaxPrevOutIndex && prevOut.Hash == [32]byte{}
+}
+
+// IsFinal 判断交易在指定区块高度和时间是否已达到锁定时间
+//
+// 锁定时间为0，或小于LockTimeThreshold时小于区块高度、否则小于区块时间的交易
+// 已经最终确定。锁定时间未到时，所有输入的序列号都为MaxTxInSequenceNum的交易
+// 同样视为最终确定，即交易锁定时间只在至少一个输入未设置最大序列号时生效。
+func (tx *Transaction) IsFinal(height int32, blockTime int64) bool {
+	if tx.LockTime == 0 {
+		return true
+	}
+
+	limit := int64(height)
+	if tx.LockTime >= LockTimeThreshold {
+		limit = blockTime
+	}
+	if int64(tx.LockTime) < limit {
+		return true
+	}
+
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence != MaxTxInSequenceNum {
+			return false
+		}
+	}
+	return true
+}
+
+// TotalOutputValue 计算交易所有输出金额之和
+func (tx *Transaction) TotalOutputValue() int64 {
+	var total int64
+	for _, txOut := range tx.TxOut {
+		total += txOut.Value
+	}
+	return total
+}
+
+// SerializeSize 计算交易序列化后的字节数
+func (tx *Transaction) SerializeSize() int {
+	// Version(4) + LockTime(4) + 输入输出数量的VarInt
+	size := 8 + utils.VarIntSize(uint64(len(tx.TxIn))) + utils.VarIntSize(uint64(len(tx.TxOut)))
+
+	for _, txIn := range tx.TxIn {
+		size += txIn.SerializeSize()
+	}
+	for _, txOut := range tx.TxOut {
+		size += txOut.SerializeSize()
+	}
+
+	return size
+}
+
+// SerializeSize 计算交易输入序列化后的字节数
+func (ti *TxIn) SerializeSize() int {
+	// PrevHash(32) + PrevIndex(4) + Sequence(4) + 脚本长度与内容
+	return 40 + utils.VarIntSize(uint64(len(ti.SignatureScript))) + len(ti.SignatureScript)
+}
+
+// SerializeSize 计算交易输出序列化后的字节数
+func (to *TxOut) SerializeSize() int {
+	// Value(8) + 脚本长度与内容
+	return 8 + utils.VarIntSize(uint64(len(to.PkScript))) + len(to.PkScript)
+}
+
+// Serialize 序列化交易
+//
+// 按照比特币交易格式将交易序列化为字节数组。
+func (tx *Transaction) Serialize() []byte {
+	var buf bytes.Buffer
+	buf.Grow(tx.SerializeSize())
+
+	// Version (4字节，小端序)
+	buf.Write(utils.Uint32ToLittleEndian(tx.Version))
+
+	// 输入数量及所有输入
+	buf.Write(utils.EncodeVarInt(uint64(len(tx.TxIn))))
+	for _, txIn := range tx.TxIn {
+		buf.Write(txIn.PreviousOutPoint.Hash[:])
+		buf.Write(utils.Uint32ToLittleEndian(txIn.PreviousOutPoint.Index))
+		buf.Write(utils.EncodeVarInt(uint64(len(txIn.SignatureScript))))
+		buf.Write(txIn.SignatureScript)
+		buf.Write(utils.Uint32ToLittleEndian(txIn.Sequence))
+	}
+
+	// 输出数量及所有输出
+	buf.Write(utils.EncodeVarInt(uint64(len(tx.TxOut))))
+	for _, txOut := range tx.TxOut {
+		buf.Write(utils.Uint64ToLittleEndian(uint64(txOut.Value)))
+		buf.Write(utils.EncodeVarInt(uint64(len(txOut.PkScript))))
+		buf.Write(txOut.PkScript)
+	}
+
+	// LockTime (4字节，小端序)
+	buf.Write(utils.Uint32ToLittleEndian(tx.LockTime))
+
+	return buf.Bytes()
+}
+
+// Deserialize 从字节数组反序列化交易
+//
+// 要求数据恰好包含一笔完整交易，存在多余字节时返回错误。
+//
+// 参数：
+// - data: 字节数组
+func (tx *Transaction) Deserialize(data []byte) error {
+	n, err := tx.deserialize(data)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return fmt.Errorf("交易数据存在多余字节: 解析%d字节, 实际%d字节", n, len(data))
+	}
+	return nil
+}
+
+// DeserializeTransaction 从字节数组头部解析一笔交易
+//
+// 用于从区块等连续数据中逐笔读取交易。
+//
+// 返回值：
+// - *Transaction: 解析得到的交易
+// - int: 本次解析消耗的字节数
+// - error: 数据格式错误时返回
+func DeserializeTransaction(data []byte) (*Transaction, int, error) {
+	tx := &Transaction{}
+	n, err := tx.deserialize(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tx, n, nil
+}
+
+// deserialize 解析交易并返回消耗的字节数
+func (tx *Transaction) deserialize(data []byte) (int, error) {
+	offset := 0
+
+	// Version (4字节，小端序)
+	if len(data) < 4 {
+		return 0, fmt.Errorf("数据长度不足，无法读取交易版本")
+	}
+	tx.Version = binary.LittleEndian.Uint32(data[offset:])
+	offset += 4
+
+	// 输入数量
+	txInCount, bytesRead, err := utils.DecodeVarInt(data[offset:])
+	if err != nil {
+		return 0, fmt.Errorf("解码输入数量失败: %v", err)
+	}
+	offset += bytesRead
+
+	// 每个输入至少41字节，以此限制预分配大小，防止恶意数据耗尽内存
+	if txInCount > uint64((len(data)-offset)/minTxInSize) {
+		return 0, fmt.Errorf("输入数量与数据长度不符: %d", txInCount)
+	}
+
+	tx.TxIn = make([]*TxIn, txInCount)
+	for i := uint64(0); i < txInCount; i++ {
+		if len(data)-offset < 36 {
+			return 0, fmt.Errorf("数据长度不足，无法读取输入%d", i)
+		}
+
+		txIn := &TxIn{}
+		copy(txIn.PreviousOutPoint.Hash[:], data[offset:offset+32])
+		offset += 32
+		txIn.PreviousOutPoint.Index = binary.LittleEndian.Uint32(data[offset:])
+		offset += 4
+
+		script, n, err := readScript(data[offset:])
+		if err != nil {
+			return 0, fmt.Errorf("读取输入%d脚本失败: %v", i, err)
+		}
+		txIn.SignatureScript = script
+		offset += n
+
+		if len(data)-offset < 4 {
+			return 0, fmt.Errorf("数据长度不足，无法读取输入%d序列号", i)
+		}
+		txIn.Sequence = binary.LittleEndian.Uint32(data[offset:])
+		offset += 4
+
+		tx.TxIn[i] = txIn
+	}
+
+	// 输出数量
+	txOutCount, bytesRead, err := utils.DecodeVarInt(data[offset:])
+	if err != nil {
+		return 0, fmt.Errorf("解码输出数量失败: %v", err)
+	}
+	offset += bytesRead
+
+	// 每个输出至少9字节
+	if txOutCount > uint64((len(data)-offset)/minTxOutSize) {
+		return 0, fmt.Errorf("输出数量与数据长度不符: %d", txOutCount)
+	}
+
+	tx.TxOut = make([]*TxOut, txOutCount)
+	for i := uint64(0); i < txOutCount; i++ {
+		if len(data)-offset < 8 {
+			return 0, fmt.Errorf("数据长度不足，无法读取输出%d金额", i)
+		}
+
+		txOut := &TxOut{}
+		txOut.Value = int64(binary.LittleEndian.Uint64(data[offset:]))
+		offset += 8
+
+		script, n, err := readScript(data[offset:])
+		if err != nil {
+			return 0, fmt.Errorf("读取输出%d脚本失败: %v", i, err)
+		}
+		txOut.PkScript = script
+		offset += n
+
+		tx.TxOut[i] = txOut
+	}
+
+	// LockTime (4字节，小端序)
+	if len(data)-offset < 4 {
+		return 0, fmt.Errorf("数据长度不足，无法读取锁定时间")
+	}
+	tx.LockTime = binary.LittleEndian.Uint32(data[offset:])
+	offset += 4
+
+	return offset, nil
+}
+
+// readScript 读取带VarInt长度前缀的脚本
+//
+// 返回脚本内容的副本以及消耗的字节数。
+func readScript(data []byte) ([]byte, int, error) {
+	scriptLen, bytesRead, err := utils.DecodeVarInt(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("解码脚本长度失败: %v", err)
+	}
+
+	if scriptLen > MaxScriptSize {
+		return nil, 0, fmt.Errorf("脚本长度超过限制: %d > %d", scriptLen, MaxScriptSize)
+	}
+
+	if uint64(len(data)-bytesRead) < scriptLen {
+		return nil, 0, fmt.Errorf("数据长度不足，无法读取%d字节脚本", scriptLen)
+	}
+
+	script := make([]byte, scriptLen)
+	copy(script, data[bytesRead:bytesRead+int(scriptLen)])
+
+	return script, bytesRead + int(scriptLen), nil
+}
+
+// String 返回交易的字符串表示
+func (tx *Transaction) String() string {
+	hash := tx.Hash()
+	return fmt.Sprintf("Transaction{Hash: %s, Inputs: %d, Outputs: %d, Size: %d bytes}",
+		utils.HashToString(hash[:]), len(tx.TxIn), len(tx.TxOut), tx.SerializeSize())
+}
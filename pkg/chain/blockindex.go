@@ -0,0 +1,112 @@
+package chain
+
+import (
+	"math/big"
+	"sort"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// blockStatus 区块索引节点状态标志
+type blockStatus byte
+
+const (
+	// statusDataStored 完整区块数据已保存
+	statusDataStored blockStatus = 1 << iota
+
+	// statusInvalid 区块或其祖先违反共识规则
+	statusInvalid
+)
+
+// oneLsh256 2^256，用于计算区块工作量
+var oneLsh256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// blockNode 区块索引节点
+//
+// 每个已知区块（包括侧链区块）在内存中都有一个节点，
+// 通过parent指针构成以创世区块为根的树。
+type blockNode struct {
+	parent  *blockNode             // 父区块节点，创世区块为nil
+	hash    [32]byte               // 区块哈希
+	height  int32                  // 区块高度
+	header  blockchain.BlockHeader // 区块头
+	workSum *big.Int               // 从创世区块到本区块的累计工作量
+	status  blockStatus            // 状态标志
+}
+
+// newBlockNode 创建区块索引节点
+//
+// 累计工作量为父节点累计工作量加上本区块难度位对应的工作量。
+func newBlockNode(header *blockchain.BlockHeader, parent *blockNode) *blockNode {
+	node := &blockNode{
+		hash:    header.Hash(),
+		header:  *header,
+		workSum: calcWork(header.Bits),
+	}
+	if parent != nil {
+		node.parent = parent
+		node.height = parent.height + 1
+		node.workSum.Add(node.workSum, parent.workSum)
+	}
+	return node
+}
+
+// calcWork 计算难度位对应的工作量
+//
+// 工作量为找到满足目标值的哈希所需的期望尝试次数：2^256 / (target + 1)。
+func calcWork(bits uint32) *big.Int {
+	target := utils.BitsToTarget(bits)
+	if target.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+
+	denominator := new(big.Int).Add(target, big.NewInt(1))
+	return new(big.Int).Div(oneLsh256, denominator)
+}
+
+// Ancestor 获取指定高度的祖先节点
+//
+// 高度超出范围时返回nil。
+func (n *blockNode) Ancestor(height int32) *blockNode {
+	if height < 0 || height > n.height {
+		return nil
+	}
+
+	node := n
+	for node != nil && node.height != height {
+		node = node.parent
+	}
+	return node
+}
+
+// CalcPastMedianTime 计算包括本区块在内前MedianTimeBlocks个区块时间戳的中位数
+func (n *blockNode) CalcPastMedianTime() uint32 {
+	timestamps := make([]uint32, 0, blockchain.MedianTimeBlocks)
+	for node := n; node != nil && len(timestamps) < blockchain.MedianTimeBlocks; node = node.parent {
+		timestamps = append(timestamps, node.header.Timestamp)
+	}
+
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return timestamps[len(timestamps)/2]
+}
+
+// isInvalid 判断节点是否被标记为无效
+func (n *blockNode) isInvalid() bool {
+	return n.status&statusInvalid != 0
+}
+
+// findFork 查找两个节点的最近公共祖先
+func findFork(a, b *blockNode) *blockNode {
+	for a != nil && b != nil && a.height > b.height {
+		a = a.parent
+	}
+	for a != nil && b != nil && b.height > a.height {
+		b = b.parent
+	}
+	for a != nil && b != nil && a != b {
+		a = a.parent
+		b = b.parent
+	}
+	return a
+}
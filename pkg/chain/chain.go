@@ -0,0 +1,437 @@
+// Package chain 实现了区块链引擎
+//
+// 区块链引擎负责接收区块并完成上下文相关的共识验证：
+// - 前块哈希必须指向已知区块
+// - 时间戳必须大于前11个区块时间戳的中位数
+// - 难度位必须等于网络参数的难度规则给出的值，区块哈希必须满足难度目标
+//
+// 引擎在内存中维护所有已知区块（包括侧链）的索引树，
+// 并选择累计工作量最大的分支作为主链。侧链工作量超过主链时，
+// 引擎断开旧主链上分叉点之后的区块并连接新分支，同时维护UTXO集合和
+// 存储中的高度索引，并通过事件总线发布区块连接、断开和重组事件。
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/events"
+	"simplied-bitcoin-network-go/pkg/storage"
+	"simplied-bitcoin-network-go/pkg/utils"
+	"simplied-bitcoin-network-go/pkg/utxo"
+)
+
+// Config 区块链引擎配置
+type Config struct {
+	Store   *storage.Store // 区块存储
+	UTXOSet *utxo.Set      // UTXO集合，随主链连接和断开区块同步更新
+	Events  *events.Bus    // 事件总线，为空时不发布事件
+
+	// Params 网络参数，提供创世区块、工作量证明上限、难度调整和奖励规则
+	Params *blockchain.ChainParams
+}
+
+// BestState 主链末端状态快照
+type BestState struct {
+	Hash       [32]byte // 主链末端区块哈希
+	Height     int32    // 主链高度
+	Bits       uint32   // 主链末端难度位
+	WorkSum    *big.Int // 主链累计工作量
+	MedianTime uint32   // 主链末端的中位时间
+}
+
+// ChainTip 区块树中的一个末端（没有子区块的区块）
+type ChainTip struct {
+	Hash      [32]byte // 末端区块哈希
+	Height    int32    // 末端区块高度
+	BranchLen int32    // 与主链分叉后的分支长度，主链末端为0
+	Active    bool     // 是否为主链末端
+}
+
+// Chain 区块链引擎
+//
+// 所有公开方法都是并发安全的。事件在区块处理完成、状态锁释放后发布，
+// 订阅者可以在处理函数中查询引擎状态，但不能再调用ProcessBlock。
+type Chain struct {
+	store   *storage.Store
+	utxoSet *utxo.Set
+	events  *events.Bus
+	params  *blockchain.ChainParams
+
+	processLock sync.Mutex // 串行化区块处理及其事件发布
+
+	mu         sync.RWMutex
+	index      map[[32]byte]*blockNode // 所有已知区块
+	mainChain  []*blockNode            // 主链，下标为高度
+	bestHeader *blockNode              // 累计工作量最大的有效区块头，可能尚无区块数据
+}
+
+// New 创建区块链引擎
+//
+// 从存储中加载所有区块头重建内存索引，恢复存储记录的主链末端，
+// 并将UTXO集合同步到主链末端。存储中的创世区块必须与网络参数一致。
+//
+// 参数：
+// - cfg: 引擎配置，Store、UTXOSet和Params不能为空
+func New(cfg *Config) (*Chain, error) {
+	if cfg == nil || cfg.Store == nil {
+		return nil, errors.New("区块链引擎配置缺少区块存储")
+	}
+	if cfg.UTXOSet == nil {
+		return nil, errors.New("区块链引擎配置缺少UTXO集合")
+	}
+	if cfg.Params == nil {
+		return nil, errors.New("区块链引擎配置缺少网络参数")
+	}
+
+	c := &Chain{
+		store:   cfg.Store,
+		utxoSet: cfg.UTXOSet,
+		events:  cfg.Events,
+		params:  cfg.Params,
+
+		index: make(map[[32]byte]*blockNode),
+	}
+
+	if err := c.loadIndex(); err != nil {
+		return nil, err
+	}
+	if err := c.syncUTXOSet(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// loadIndex 从存储中加载区块索引和主链
+func (c *Chain) loadIndex() error {
+	type entry struct {
+		header *blockchain.BlockHeader
+		height int32
+	}
+
+	var entries []entry
+	err := c.store.ForEachHeader(func(header *blockchain.BlockHeader, height int32) error {
+		entries = append(entries, entry{header: header, height: height})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("加载区块头失败: %v", err)
+	}
+
+	// 按高度排序，保证父区块先于子区块加入索引
+	sort.Slice(entries, func(i, j int) bool { return entries[i].height < entries[j].height })
+
+	for _, e := range entries {
+		var parent *blockNode
+		if e.height == 0 {
+			if !c.params.IsGenesisHash(e.header.Hash()) {
+				return fmt.Errorf("存储中的创世区块与当前网络不一致: %x", e.header.Hash())
+			}
+		} else {
+			parent = c.index[e.header.PrevBlockHash]
+			if parent == nil {
+				return fmt.Errorf("区块索引损坏: 区块%x的父区块不存在", e.header.Hash())
+			}
+		}
+
+		node := newBlockNode(e.header, parent)
+		if node.height != e.height {
+			return fmt.Errorf("区块索引损坏: 区块%x高度记录为%d, 实际为%d", node.hash, e.height, node.height)
+		}
+
+		hasData, err := c.store.HasBlock(node.hash)
+		if err != nil {
+			return err
+		}
+		if hasData {
+			node.status |= statusDataStored
+		}
+
+		c.index[node.hash] = node
+		if c.bestHeader == nil || node.workSum.Cmp(c.bestHeader.workSum) > 0 {
+			c.bestHeader = node
+		}
+	}
+
+	tipHash, _, err := c.store.Tip()
+	if err != nil {
+		return fmt.Errorf("读取主链末端失败: %v", err)
+	}
+	tip := c.index[tipHash]
+	if tip == nil {
+		return fmt.Errorf("主链末端%x不在区块索引中", tipHash)
+	}
+	c.setMainChain(tip)
+
+	return nil
+}
+
+// setMainChain 以指定节点为末端重建内存中的主链
+func (c *Chain) setMainChain(tip *blockNode) {
+	mainChain := make([]*blockNode, tip.height+1)
+	for node := tip; node != nil; node = node.parent {
+		mainChain[node.height] = node
+	}
+	c.mainChain = mainChain
+}
+
+// tip 返回主链末端节点
+func (c *Chain) tip() *blockNode {
+	return c.mainChain[len(c.mainChain)-1]
+}
+
+// ProcessBlock 处理新区块
+//
+// 依次执行上下文无关验证、工作量证明验证和上下文相关验证，
+// 通过后保存区块。区块头可以已经由ProcessBlockHeader加入索引，
+// 但父区块必须已有完整数据，否则返回ErrMissingParentData。若新区块所在分支的累计工作量严格大于当前主链，
+// 则将主链重组到该分支。验证失败时返回RuleError，ErrorCode标识被违反的规则。
+//
+// 返回值：
+// - bool: 新区块是否成为主链末端
+// - error: 验证或存储错误
+func (c *Chain) ProcessBlock(block *blockchain.Block) (bool, error) {
+	c.processLock.Lock()
+	defer c.processLock.Unlock()
+
+	isMainChain, notifications, err := c.processBlock(block)
+	if c.events != nil {
+		for _, event := range notifications {
+			c.events.Publish(event)
+		}
+	}
+	return isMainChain, err
+}
+
+// processBlock 在状态锁内处理区块，返回待发布的事件
+func (c *Chain) processBlock(block *blockchain.Block) (bool, []events.Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if block == nil || block.Header == nil {
+		return false, nil, blockchain.NewRuleError(blockchain.ErrCodeInvalidBlockHeader, blockchain.ErrInvalidBlockHeader)
+	}
+
+	hash := block.Hash()
+	if node, exists := c.index[hash]; exists && node.status&statusDataStored != 0 {
+		return false, nil, blockchain.NewRuleError(blockchain.ErrCodeDuplicateBlock,
+			fmt.Sprintf("区块%x已经存在", hash))
+	}
+
+	// 上下文无关验证，Merkle根不符或包含重复交易的区块体可能是同一区块头被篡改后的版本，
+	// 必须在创建节点和保存数据之前拒绝，避免真实区块被当作已存在或无效
+	if err := block.Validate(); err != nil {
+		return false, nil, err
+	}
+	if err := c.checkProofOfWork(block.Header); err != nil {
+		return false, nil, err
+	}
+
+	// 上下文相关验证
+	parent := c.index[block.Header.PrevBlockHash]
+	if parent == nil {
+		return false, nil, blockchain.NewRuleError(blockchain.ErrCodeInvalidPrevBlockHash,
+			fmt.Sprintf("%s: 未知的前块%x", blockchain.ErrInvalidPrevBlockHash, block.Header.PrevBlockHash))
+	}
+	if parent.isInvalid() {
+		return false, nil, blockchain.NewRuleError(blockchain.ErrCodeInvalidAncestor,
+			fmt.Sprintf("区块%x的祖先区块%x无效", hash, parent.hash))
+	}
+	if parent.status&statusDataStored == 0 {
+		return false, nil, fmt.Errorf("%w: 区块%x的父区块%x", ErrMissingParentData, hash, parent.hash)
+	}
+	if err := c.checkBlockContext(block.Header, parent); err != nil {
+		return false, nil, err
+	}
+
+	node, exists := c.index[hash]
+	if !exists {
+		node = newBlockNode(block.Header, parent)
+		c.addHeaderNode(node)
+	}
+
+	if err := c.store.PutBlock(block, node.height); err != nil {
+		return false, nil, fmt.Errorf("保存区块失败: %v", err)
+	}
+	node.status |= statusDataStored
+
+	// 仅当累计工作量严格更大时切换主链，工作量相同时保留先收到的分支
+	if node.workSum.Cmp(c.tip().workSum) <= 0 {
+		return false, nil, nil
+	}
+
+	notifications, err := c.reorganize(node, block)
+	if err != nil {
+		return false, nil, err
+	}
+	return true, notifications, nil
+}
+
+// checkProofOfWork 验证区块头的工作量证明
+//
+// 难度位对应的目标值必须为正且不超过工作量证明上限，区块哈希必须不大于目标值。
+func (c *Chain) checkProofOfWork(header *blockchain.BlockHeader) error {
+	target := utils.BitsToTarget(header.Bits)
+	if target.Sign() <= 0 {
+		return blockchain.NewRuleError(blockchain.ErrCodeInvalidDifficulty,
+			fmt.Sprintf("%s: 难度位%08x对应的目标值不是正数", blockchain.ErrInvalidDifficulty, header.Bits))
+	}
+	if target.Cmp(c.params.PowLimit) > 0 {
+		return blockchain.NewRuleError(blockchain.ErrCodeInvalidDifficulty,
+			fmt.Sprintf("%s: 难度位%08x对应的目标值超过工作量证明上限", blockchain.ErrInvalidDifficulty, header.Bits))
+	}
+
+	if !header.MeetsTarget() {
+		return blockchain.NewRuleError(blockchain.ErrCodeInvalidBlockHash,
+			fmt.Sprintf("%s: 区块哈希%x大于难度目标%064x", blockchain.ErrInvalidBlockHash, header.Hash(), target))
+	}
+
+	return nil
+}
+
+// checkBlockContext 验证区块头与父区块相关的规则
+//
+// 难度位必须等于难度调整规则要求的值，
+// 时间戳必须至少比父区块处的中位时间大MinTimestampDelta。
+func (c *Chain) checkBlockContext(header *blockchain.BlockHeader, parent *blockNode) error {
+	if expected := c.calcNextRequiredDifficulty(parent); header.Bits != expected {
+		return blockchain.NewRuleError(blockchain.ErrCodeInvalidDifficulty,
+			fmt.Sprintf("%s: 高度%d的难度位%08x, 期望%08x", blockchain.ErrInvalidDifficulty,
+				parent.height+1, header.Bits, expected))
+	}
+
+	medianTime := parent.CalcPastMedianTime()
+	minTimestamp := medianTime + uint32(blockchain.MinTimestampDelta.Seconds())
+	if header.Timestamp < minTimestamp {
+		return blockchain.NewRuleError(blockchain.ErrCodeInvalidTimestamp,
+			fmt.Sprintf("%s: 时间戳%d不大于前%d个区块的中位时间%d", blockchain.ErrInvalidTimestamp,
+				header.Timestamp, blockchain.MedianTimeBlocks, medianTime))
+	}
+
+	return nil
+}
+
+// BestSnapshot 获取主链末端状态快照
+func (c *Chain) BestSnapshot() *BestState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tip := c.tip()
+	return &BestState{
+		Hash:       tip.hash,
+		Height:     tip.height,
+		Bits:       tip.header.Bits,
+		WorkSum:    new(big.Int).Set(tip.workSum),
+		MedianTime: tip.CalcPastMedianTime(),
+	}
+}
+
+// Params 返回引擎使用的网络参数
+func (c *Chain) Params() *blockchain.ChainParams {
+	return c.params
+}
+
+// CalcNextRequiredDifficulty 计算延伸主链末端的下一个区块应使用的难度位
+func (c *Chain) CalcNextRequiredDifficulty() uint32 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.calcNextRequiredDifficulty(c.tip())
+}
+
+// HaveBlock 判断是否已拥有指定区块的完整数据
+func (c *Chain) HaveBlock(hash [32]byte) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	node, exists := c.index[hash]
+	return exists && node.status&statusDataStored != 0
+}
+
+// MainChainHasBlock 判断指定区块是否在主链上
+func (c *Chain) MainChainHasBlock(hash [32]byte) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	node, exists := c.index[hash]
+	return exists && c.inMainChain(node)
+}
+
+// inMainChain 判断节点是否在主链上
+func (c *Chain) inMainChain(node *blockNode) bool {
+	return node.height < int32(len(c.mainChain)) && c.mainChain[node.height] == node
+}
+
+// BlockHeightByHash 获取指定区块的高度
+//
+// 对侧链区块同样有效。
+func (c *Chain) BlockHeightByHash(hash [32]byte) (int32, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	node, exists := c.index[hash]
+	if !exists {
+		return 0, fmt.Errorf("区块%x不存在", hash)
+	}
+	return node.height, nil
+}
+
+// BlockByHash 根据哈希获取区块
+func (c *Chain) BlockByHash(hash [32]byte) (*blockchain.Block, error) {
+	return c.store.GetBlock(hash)
+}
+
+// BlockByHeight 获取主链指定高度的区块
+func (c *Chain) BlockByHeight(height int32) (*blockchain.Block, error) {
+	c.mu.RLock()
+	if height < 0 || height >= int32(len(c.mainChain)) {
+		c.mu.RUnlock()
+		return nil, fmt.Errorf("主链高度%d超出范围", height)
+	}
+	hash := c.mainChain[height].hash
+	c.mu.RUnlock()
+
+	return c.store.GetBlock(hash)
+}
+
+// ChainTips 获取区块树中所有末端
+//
+// 结果按高度从高到低排序，主链末端的Active为true。
+func (c *Chain) ChainTips() []ChainTip {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	hasChild := make(map[*blockNode]bool, len(c.index))
+	for _, node := range c.index {
+		if node.parent != nil {
+			hasChild[node.parent] = true
+		}
+	}
+
+	var tips []ChainTip
+	for _, node := range c.index {
+		if hasChild[node] {
+			continue
+		}
+
+		fork := node
+		for !c.inMainChain(fork) {
+			fork = fork.parent
+		}
+
+		tips = append(tips, ChainTip{
+			Hash:      node.hash,
+			Height:    node.height,
+			BranchLen: node.height - fork.height,
+			Active:    node == c.tip(),
+		})
+	}
+
+	sort.Slice(tips, func(i, j int) bool { return tips[i].Height > tips[j].Height })
+	return tips
+}
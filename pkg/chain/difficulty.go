@@ -0,0 +1,36 @@
+package chain
+
+import "simplied-bitcoin-network-go/pkg/utils"
+
+// calcNextRequiredDifficulty 计算延伸parent的区块应使用的难度位
+//
+// 每RetargetInterval个区块调整一次难度：取调整窗口内第一个区块到parent的
+// 实际时间跨度，与网络参数的目标时间跨度比较后按比例调整目标值。
+// 实际时间跨度被限制在目标的1/4到4倍之间，新目标值不超过工作量证明上限。
+// 非调整高度以及禁用难度调整时沿用parent的难度位。
+func (c *Chain) calcNextRequiredDifficulty(parent *blockNode) uint32 {
+	if parent == nil {
+		return c.params.PowLimitBits
+	}
+	interval := c.params.RetargetInterval
+	if c.params.NoRetargeting || (parent.height+1)%interval != 0 {
+		return parent.header.Bits
+	}
+
+	// 与比特币一致，窗口包含parent在内的RetargetInterval个区块
+	first := parent.Ancestor(parent.height - interval + 1)
+	if first == nil {
+		return parent.header.Bits
+	}
+
+	targetTimespan := int64(c.params.TargetTimespan().Seconds())
+	actualTimespan := int64(parent.header.Timestamp) - int64(first.header.Timestamp)
+	if minTimespan := targetTimespan / 4; actualTimespan < minTimespan {
+		actualTimespan = minTimespan
+	}
+	if maxTimespan := targetTimespan * 4; actualTimespan > maxTimespan {
+		actualTimespan = maxTimespan
+	}
+
+	return utils.AdjustDifficultyWithLimit(actualTimespan, targetTimespan, parent.header.Bits, c.params.PowLimit)
+}
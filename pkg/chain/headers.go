@@ -0,0 +1,178 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+)
+
+// ErrMissingParentData 父区块只有区块头，尚未下载完整数据
+//
+// 先同步区块头时，区块数据可能乱序到达，调用方应在父区块处理完成后重新提交。
+var ErrMissingParentData = errors.New("父区块数据尚未下载")
+
+// locatorDenseBlocks 区块定位器中逐个列出的最近区块数，之后步长逐次翻倍
+const locatorDenseBlocks = 10
+
+// ProcessBlockHeader 处理区块头
+//
+// 执行与ProcessBlock相同的区块头验证（格式、工作量证明、难度位和中位时间），
+// 通过后将区块头加入索引并保存，但不改变主链。区块头已存在时直接返回。
+// 验证失败时返回RuleError。
+func (c *Chain) ProcessBlockHeader(header *blockchain.BlockHeader) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if header == nil {
+		return blockchain.NewRuleError(blockchain.ErrCodeInvalidBlockHeader, blockchain.ErrInvalidBlockHeader)
+	}
+
+	hash := header.Hash()
+	if node, exists := c.index[hash]; exists {
+		if node.isInvalid() {
+			return blockchain.NewRuleError(blockchain.ErrCodeInvalidAncestor,
+				fmt.Sprintf("区块%x已被标记为无效", hash))
+		}
+		return nil
+	}
+
+	if err := header.Validate(); err != nil {
+		return err
+	}
+	if err := c.checkProofOfWork(header); err != nil {
+		return err
+	}
+
+	parent := c.index[header.PrevBlockHash]
+	if parent == nil {
+		return blockchain.NewRuleError(blockchain.ErrCodeInvalidPrevBlockHash,
+			fmt.Sprintf("%s: 未知的前块%x", blockchain.ErrInvalidPrevBlockHash, header.PrevBlockHash))
+	}
+	if parent.isInvalid() {
+		return blockchain.NewRuleError(blockchain.ErrCodeInvalidAncestor,
+			fmt.Sprintf("区块%x的祖先区块%x无效", hash, parent.hash))
+	}
+	if err := c.checkBlockContext(header, parent); err != nil {
+		return err
+	}
+
+	node := newBlockNode(header, parent)
+	if err := c.store.PutHeader(header, node.height); err != nil {
+		return fmt.Errorf("保存区块头失败: %v", err)
+	}
+	c.addHeaderNode(node)
+	return nil
+}
+
+// addHeaderNode 将节点加入索引，并在累计工作量更大时更新最佳区块头
+func (c *Chain) addHeaderNode(node *blockNode) {
+	c.index[node.hash] = node
+	if node.workSum.Cmp(c.bestHeader.workSum) > 0 {
+		c.bestHeader = node
+	}
+}
+
+// HaveHeader 判断索引中是否存在指定区块头
+func (c *Chain) HaveHeader(hash [32]byte) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, exists := c.index[hash]
+	return exists
+}
+
+// BestHeader 返回累计工作量最大的有效区块头的哈希和高度
+//
+// 区块头同步领先于区块下载时，其高度大于主链高度。
+func (c *Chain) BestHeader() ([32]byte, int32) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.bestHeader.hash, c.bestHeader.height
+}
+
+// BlockLocator 构造从指定区块回溯到创世区块的区块定位器
+//
+// 先逐个列出最近的10个区块，之后每一步的间隔翻倍，最后一项总是创世区块。
+// 对端据此找到双方最近的公共区块。区块未知时返回nil。
+func (c *Chain) BlockLocator(hash [32]byte) [][32]byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	node := c.index[hash]
+	if node == nil {
+		return nil
+	}
+
+	var locator [][32]byte
+	step := int32(1)
+	for node != nil {
+		locator = append(locator, node.hash)
+		if node.height == 0 {
+			break
+		}
+
+		height := node.height - step
+		if height < 0 {
+			height = 0
+		}
+		node = node.Ancestor(height)
+		if len(locator) >= locatorDenseBlocks {
+			step *= 2
+		}
+	}
+	return locator
+}
+
+// LocateHeaders 根据区块定位器返回主链上的后续区块头
+//
+// 从定位器中第一个位于主链上的区块之后开始，最多返回maxHeaders个区块头，
+// 遇到hashStop时停止（包含该区块）。定位器中没有主链区块时从创世区块之后开始。
+func (c *Chain) LocateHeaders(locator [][32]byte, hashStop [32]byte, maxHeaders int) []*blockchain.BlockHeader {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	start := int32(1)
+	for _, hash := range locator {
+		if node, exists := c.index[hash]; exists && c.inMainChain(node) {
+			start = node.height + 1
+			break
+		}
+	}
+
+	var headers []*blockchain.BlockHeader
+	for height := start; height < int32(len(c.mainChain)) && len(headers) < maxHeaders; height++ {
+		node := c.mainChain[height]
+		header := node.header
+		headers = append(headers, &header)
+		if node.hash == hashStop {
+			break
+		}
+	}
+	return headers
+}
+
+// BlocksToDownload 返回最佳区块头所在分支上尚未下载数据的区块
+//
+// 结果从与主链的分叉点之后开始按高度递增排列，最多maxBlocks个。
+func (c *Chain) BlocksToDownload(maxBlocks int) [][32]byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var missing []*blockNode
+	for node := c.bestHeader; !c.inMainChain(node); node = node.parent {
+		if node.status&statusDataStored == 0 {
+			missing = append(missing, node)
+		}
+	}
+
+	if len(missing) > maxBlocks {
+		missing = missing[len(missing)-maxBlocks:]
+	}
+	hashes := make([][32]byte, len(missing))
+	for i, node := range missing {
+		hashes[len(missing)-1-i] = node.hash
+	}
+	return hashes
+}
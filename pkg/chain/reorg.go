@@ -0,0 +1,238 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/events"
+	"simplied-bitcoin-network-go/pkg/utxo"
+)
+
+// reorganize 将主链切换到以node为末端的分支
+//
+// 先从旧末端开始逐个断开分叉点之后的区块，再从分叉点开始逐个连接新分支。
+// 新分支上的区块违反共识规则时，将其及其后代标记为无效，
+// 并恢复原主链。node延伸当前末端时不需要断开任何区块。
+//
+// 参数：
+// - node: 新的主链末端
+// - block: node对应的区块，避免从存储中重新读取
+//
+// 返回值：
+// - []events.Event: 待发布的区块断开、连接和重组事件
+// - error: 连接失败或存储错误
+func (c *Chain) reorganize(node *blockNode, block *blockchain.Block) ([]events.Event, error) {
+	oldTip := c.tip()
+	fork := findFork(oldTip, node)
+
+	var detach []*blockNode
+	for n := oldTip; n != fork; n = n.parent {
+		detach = append(detach, n)
+	}
+
+	var attach []*blockNode
+	for n := node; n != fork; n = n.parent {
+		attach = append([]*blockNode{n}, attach...)
+	}
+
+	detachBlocks, err := c.fetchBlocks(detach)
+	if err != nil {
+		return nil, err
+	}
+	attachBlocks, err := c.fetchBlocks(attach[:len(attach)-1])
+	if err != nil {
+		return nil, err
+	}
+	attachBlocks = append(attachBlocks, block)
+
+	var notifications []events.Event
+	for i, n := range detach {
+		if err := c.disconnectBlock(n, detachBlocks[i]); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, events.Event{
+			Type: events.BlockDisconnected,
+			Data: &events.BlockEvent{Block: detachBlocks[i], Height: n.height},
+		})
+	}
+
+	for i, n := range attach {
+		if err := c.connectBlock(n, attachBlocks[i]); err != nil {
+			var ruleErr blockchain.RuleError
+			if errors.As(err, &ruleErr) {
+				c.markInvalid(n)
+			}
+			if rerr := c.restoreChain(attach[:i], attachBlocks[:i], detach, detachBlocks); rerr != nil {
+				return nil, fmt.Errorf("连接区块%x失败(%v)且无法恢复原主链: %v", n.hash, err, rerr)
+			}
+			return nil, err
+		}
+		notifications = append(notifications, events.Event{
+			Type: events.BlockConnected,
+			Data: &events.BlockEvent{Block: attachBlocks[i], Height: n.height},
+		})
+	}
+
+	if len(detach) > 0 {
+		reorg := &events.ReorgEvent{ForkHash: fork.hash, ForkHeight: fork.height}
+		for _, n := range detach {
+			reorg.Removed = append(reorg.Removed, n.hash)
+		}
+		for _, n := range attach {
+			reorg.Added = append(reorg.Added, n.hash)
+		}
+		notifications = append(notifications, events.Event{Type: events.ChainReorganized, Data: reorg})
+	}
+
+	return notifications, nil
+}
+
+// restoreChain 撤销已连接的新分支区块并重新连接原主链区块
+//
+// 参数：
+// - attached: 已连接的新分支节点（按连接顺序）
+// - detached: 已断开的原主链节点（按断开顺序）
+func (c *Chain) restoreChain(attached []*blockNode, attachedBlocks []*blockchain.Block,
+	detached []*blockNode, detachedBlocks []*blockchain.Block) error {
+	for i := len(attached) - 1; i >= 0; i-- {
+		if err := c.disconnectBlock(attached[i], attachedBlocks[i]); err != nil {
+			return err
+		}
+	}
+	for i := len(detached) - 1; i >= 0; i-- {
+		if err := c.connectBlock(detached[i], detachedBlocks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connectBlock 将区块连接到主链末端
+//
+// 验证交易金额和Coinbase奖励后更新UTXO集合和存储中的高度索引。
+// 违反共识规则时返回RuleError。
+func (c *Chain) connectBlock(node *blockNode, block *blockchain.Block) error {
+	if _, err := c.checkConnectBlock(node, block); err != nil {
+		return err
+	}
+
+	if err := c.utxoSet.ConnectBlock(block, node.height); err != nil {
+		switch {
+		case errors.Is(err, utxo.ErrMissingInput):
+			return blockchain.NewRuleError(blockchain.ErrCodeMissingTxOut,
+				fmt.Sprintf("%s: 区块%x: %v", blockchain.ErrMissingTxOut, node.hash, err))
+		case errors.Is(err, utxo.ErrOutputExists):
+			return blockchain.NewRuleError(blockchain.ErrCodeOverwriteTx,
+				fmt.Sprintf("%s: 区块%x: %v", blockchain.ErrOverwriteTx, node.hash, err))
+		}
+		return fmt.Errorf("连接区块%x到UTXO集合失败: %v", node.hash, err)
+	}
+
+	if err := c.store.SetMainChainTip(node.hash, node.height); err != nil {
+		if derr := c.utxoSet.DisconnectBlock(block); derr != nil {
+			return fmt.Errorf("更新主链末端失败(%v)且无法回滚UTXO集合: %v", err, derr)
+		}
+		return fmt.Errorf("更新主链末端失败: %v", err)
+	}
+
+	c.mainChain = append(c.mainChain, node)
+	return nil
+}
+
+// disconnectBlock 从主链末端断开区块
+//
+// 利用撤销数据恢复UTXO集合，并将存储中的主链末端回退到父区块。
+func (c *Chain) disconnectBlock(node *blockNode, block *blockchain.Block) error {
+	if err := c.utxoSet.DisconnectBlock(block); err != nil {
+		return fmt.Errorf("从UTXO集合断开区块%x失败: %v", node.hash, err)
+	}
+
+	if err := c.store.SetMainChainTip(node.parent.hash, node.parent.height); err != nil {
+		if cerr := c.utxoSet.ConnectBlock(block, node.height); cerr != nil {
+			return fmt.Errorf("回退主链末端失败(%v)且无法恢复UTXO集合: %v", err, cerr)
+		}
+		return fmt.Errorf("回退主链末端失败: %v", err)
+	}
+
+	c.mainChain = c.mainChain[:node.height]
+	return nil
+}
+
+// fetchBlocks 从存储中读取一组节点对应的区块
+func (c *Chain) fetchBlocks(nodes []*blockNode) ([]*blockchain.Block, error) {
+	blocks := make([]*blockchain.Block, len(nodes))
+	for i, n := range nodes {
+		block, err := c.store.GetBlock(n.hash)
+		if err != nil {
+			return nil, fmt.Errorf("读取区块%x失败: %v", n.hash, err)
+		}
+		blocks[i] = block
+	}
+	return blocks, nil
+}
+
+// markInvalid 将节点及其所有后代标记为无效
+//
+// 最佳区块头因此失效时，重新选择累计工作量最大的有效区块头。
+func (c *Chain) markInvalid(node *blockNode) {
+	node.status |= statusInvalid
+	for _, n := range c.index {
+		if n.height > node.height && n.Ancestor(node.height) == node {
+			n.status |= statusInvalid
+		}
+	}
+
+	if c.bestHeader.isInvalid() {
+		c.bestHeader = c.tip()
+		for _, n := range c.index {
+			if !n.isInvalid() && n.workSum.Cmp(c.bestHeader.workSum) > 0 {
+				c.bestHeader = n
+			}
+		}
+	}
+}
+
+// syncUTXOSet 将UTXO集合同步到主链末端
+//
+// 节点在重组过程中退出时，UTXO集合可能停留在侧链区块上，
+// 此时先断开这些区块直到回到主链，再依次连接主链上缺失的区块。
+// 全新的UTXO集合会从创世区块开始连接。
+func (c *Chain) syncUTXOSet() error {
+	bestHash, bestHeight, err := c.utxoSet.BestBlock()
+	if err != nil {
+		return fmt.Errorf("读取UTXO集合状态失败: %v", err)
+	}
+
+	if bestHeight >= 0 {
+		node := c.index[bestHash]
+		if node == nil {
+			return fmt.Errorf("UTXO集合的最新区块%x不在区块索引中", bestHash)
+		}
+
+		for !c.inMainChain(node) {
+			block, err := c.store.GetBlock(node.hash)
+			if err != nil {
+				return fmt.Errorf("读取区块%x失败: %v", node.hash, err)
+			}
+			if err := c.utxoSet.DisconnectBlock(block); err != nil {
+				return fmt.Errorf("从UTXO集合断开区块%x失败: %v", node.hash, err)
+			}
+			node = node.parent
+		}
+		bestHeight = node.height
+	}
+
+	for height := bestHeight + 1; height < int32(len(c.mainChain)); height++ {
+		node := c.mainChain[height]
+		block, err := c.store.GetBlock(node.hash)
+		if err != nil {
+			return fmt.Errorf("读取区块%x失败: %v", node.hash, err)
+		}
+		if err := c.utxoSet.ConnectBlock(block, height); err != nil {
+			return fmt.Errorf("连接区块%x到UTXO集合失败: %v", node.hash, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package chain
+
+import (
+	"fmt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+)
+
+// SupplyAudit 货币供应量审计结果，金额单位均为聪
+type SupplyAudit struct {
+	Height    int32 // 审计到的主链高度
+	Issued    int64 // 实际发行总量（各区块Coinbase输出减去手续费之和）
+	Scheduled int64 // 按奖励计划到该高度允许的最大发行量
+	UTXOTotal int64 // UTXO集合中的总金额
+}
+
+// AuditSupply 遍历主链审计货币发行总量
+//
+// 从创世区块开始重放主链上的全部交易，计算每个区块实际新发行的金额
+// （Coinbase输出总额减去区块内手续费），并验证：
+// - 任意高度的累计发行量都不超过maxSupply
+// - 任意高度的累计发行量都不超过奖励计划允许的累计奖励
+// - 主链末端的累计发行量与UTXO集合总金额一致
+//
+// 审计期间持有区块处理锁，保证重放的主链与UTXO集合处于同一状态。
+//
+// 配置中的BlockchainConfig.MaxSupply以比特币为单位，以聪为单位的值由Config.GetMaxSupply返回。
+//
+// 参数：
+// - maxSupply: 最大供应量（聪）
+func (c *Chain) AuditSupply(maxSupply int64) (*SupplyAudit, error) {
+	c.processLock.Lock()
+	defer c.processLock.Unlock()
+
+	c.mu.RLock()
+	mainChain := append([]*blockNode(nil), c.mainChain...)
+	c.mu.RUnlock()
+
+	// 重放过程中的未花费输出金额
+	unspent := make(map[blockchain.OutPoint]int64)
+
+	audit := &SupplyAudit{Height: -1}
+	for _, node := range mainChain {
+		block, err := c.store.GetBlock(node.hash)
+		if err != nil {
+			return nil, fmt.Errorf("读取高度%d的区块失败: %v", node.height, err)
+		}
+
+		var fees int64
+		for txIndex, tx := range block.Transactions {
+			if txIndex > 0 {
+				var totalIn int64
+				for _, txIn := range tx.TxIn {
+					value, exists := unspent[txIn.PreviousOutPoint]
+					if !exists {
+						return nil, fmt.Errorf("高度%d的交易%x引用了不存在的输出%s",
+							node.height, tx.Hash(), txIn.PreviousOutPoint)
+					}
+					delete(unspent, txIn.PreviousOutPoint)
+					totalIn += value
+				}
+				fees += totalIn - tx.TotalOutputValue()
+			}
+
+			txHash := tx.Hash()
+			for i, txOut := range tx.TxOut {
+				unspent[blockchain.OutPoint{Hash: txHash, Index: uint32(i)}] = txOut.Value
+			}
+		}
+
+		audit.Height = node.height
+		audit.Issued += block.Transactions[0].TotalOutputValue() - fees
+		audit.Scheduled += blockchain.CalcBlockSubsidy(node.height, c.params)
+
+		if audit.Issued > maxSupply {
+			return audit, fmt.Errorf("高度%d的累计发行量%d超过最大供应量%d", node.height, audit.Issued, maxSupply)
+		}
+		if audit.Issued > audit.Scheduled {
+			return audit, fmt.Errorf("高度%d的累计发行量%d超过奖励计划%d", node.height, audit.Issued, audit.Scheduled)
+		}
+	}
+
+	total, err := c.utxoSet.TotalValue()
+	if err != nil {
+		return audit, fmt.Errorf("统计UTXO集合总金额失败: %v", err)
+	}
+	audit.UTXOTotal = total
+	if total != audit.Issued {
+		return audit, fmt.Errorf("UTXO集合总金额%d与累计发行量%d不一致", total, audit.Issued)
+	}
+
+	return audit, nil
+}
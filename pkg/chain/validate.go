@@ -0,0 +1,139 @@
+package chain
+
+import (
+	"fmt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/script"
+	"simplied-bitcoin-network-go/pkg/utils"
+	"simplied-bitcoin-network-go/pkg/utxo"
+)
+
+// checkConnectBlock 验证区块能否连接到当前主链末端
+//
+// 每笔交易都必须在区块高度和前一区块的中位时间（BIP113）达到锁定时间。
+// 检查每笔交易的输出金额范围，输入引用的输出必须存在于UTXO集合
+// 或同一区块中更早的交易，且不能被重复花费；被花费的Coinbase输出必须
+// 达到CoinbaseMaturity个确认，输入总额不能小于输出总额，
+// 每个输入的解锁脚本都必须满足被花费输出的锁定脚本。
+// 区块内所有脚本的签名操作总数不能超过MaxBlockSigOps。
+// Coinbase交易的输出总额不能超过区块奖励加全部手续费。
+//
+// 返回值：
+// - int64: 区块内交易的手续费总额
+// - error: 违反规则时返回RuleError
+func (c *Chain) checkConnectBlock(node *blockNode, block *blockchain.Block) (int64, error) {
+	// 同一区块内创建的输出，后面的交易可以花费前面交易的输出
+	blockOutputs := make(map[blockchain.OutPoint]*utxo.Entry)
+	spent := make(map[blockchain.OutPoint]bool)
+	medianTime := int64(node.parent.CalcPastMedianTime())
+
+	var totalFees int64
+	var sigOps int
+	for txIndex, tx := range block.Transactions {
+		txHash := tx.Hash()
+
+		sigOps += script.CountTxSigOps(tx)
+		if sigOps > blockchain.MaxBlockSigOps {
+			return 0, blockchain.NewRuleError(blockchain.ErrCodeTooManySigOps,
+				fmt.Sprintf("%s: 交易%x之后累计%d, 最大值%d", blockchain.ErrTooManySigOps,
+					txHash, sigOps, blockchain.MaxBlockSigOps))
+		}
+
+		if !tx.IsFinal(node.height, medianTime) {
+			return 0, blockchain.NewRuleError(blockchain.ErrCodeUnfinalizedTx,
+				fmt.Sprintf("%s: 交易%x锁定时间%d, 区块高度%d, 中位时间%d", blockchain.ErrUnfinalizedTx,
+					txHash, tx.LockTime, node.height, medianTime))
+		}
+
+		totalOut, err := checkTxOutValues(tx)
+		if err != nil {
+			return 0, err
+		}
+
+		if txIndex > 0 {
+			var totalIn int64
+			for i, txIn := range tx.TxIn {
+				op := txIn.PreviousOutPoint
+				if spent[op] {
+					return 0, blockchain.NewRuleError(blockchain.ErrCodeMissingTxOut,
+						fmt.Sprintf("%s: 交易%x重复花费%s", blockchain.ErrMissingTxOut, txHash, op))
+				}
+
+				entry, exists := blockOutputs[op]
+				if !exists {
+					entry, err = c.utxoSet.FetchEntry(op)
+					if err != nil {
+						return 0, fmt.Errorf("查询UTXO条目%s失败: %v", op, err)
+					}
+					if entry == nil {
+						return 0, blockchain.NewRuleError(blockchain.ErrCodeMissingTxOut,
+							fmt.Sprintf("%s: 交易%x引用%s", blockchain.ErrMissingTxOut, txHash, op))
+					}
+				}
+				if !entry.IsMature(node.height, c.params) {
+					return 0, blockchain.NewRuleError(blockchain.ErrCodeImmatureSpend,
+						fmt.Sprintf("%s: 交易%x花费高度%d的%s, 区块高度%d, 需要%d个确认", blockchain.ErrImmatureSpend,
+							txHash, entry.Height, op, node.height, c.params.CoinbaseMaturity))
+				}
+
+				if err := checkInputScript(entry.PkScript, tx, i); err != nil {
+					return 0, blockchain.NewRuleError(blockchain.ErrCodeScriptValidation,
+						fmt.Sprintf("%s: 交易%x输入%d: %v", blockchain.ErrScriptValidation, txHash, i, err))
+				}
+
+				spent[op] = true
+				totalIn += entry.Value
+			}
+
+			if totalIn < totalOut {
+				return 0, blockchain.NewRuleError(blockchain.ErrCodeSpendTooHigh,
+					fmt.Sprintf("%s: 交易%x输入%d, 输出%d", blockchain.ErrSpendTooHigh, txHash, totalIn, totalOut))
+			}
+			totalFees += totalIn - totalOut
+		}
+
+		for i, txOut := range tx.TxOut {
+			blockOutputs[blockchain.OutPoint{Hash: txHash, Index: uint32(i)}] = utxo.NewEntry(txOut, node.height, txIndex == 0)
+		}
+	}
+
+	coinbaseValue := block.Transactions[0].TotalOutputValue()
+	maxValue := blockchain.CalcBlockSubsidy(node.height, c.params) + totalFees
+	if coinbaseValue > maxValue {
+		return 0, blockchain.NewRuleError(blockchain.ErrCodeBadCoinbaseValue,
+			fmt.Sprintf("%s: Coinbase输出%d, 允许的最大值%d(奖励%d + 手续费%d)", blockchain.ErrBadCoinbaseValue,
+				coinbaseValue, maxValue, blockchain.CalcBlockSubsidy(node.height, c.params), totalFees))
+	}
+
+	return totalFees, nil
+}
+
+// checkInputScript 以被花费输出的锁定脚本验证交易第idx个输入的解锁脚本
+func checkInputScript(pkScript []byte, tx *blockchain.Transaction, idx int) error {
+	vm, err := script.NewEngine(pkScript, tx, idx)
+	if err != nil {
+		return err
+	}
+	return vm.Execute()
+}
+
+// checkTxOutValues 检查交易输出金额范围并返回输出总额
+//
+// 每个输出以及输出总额都必须在0到MaxSatoshi之间。
+func checkTxOutValues(tx *blockchain.Transaction) (int64, error) {
+	var total int64
+	for i, txOut := range tx.TxOut {
+		if txOut.Value < 0 || txOut.Value > utils.MaxSatoshi {
+			return 0, blockchain.NewRuleError(blockchain.ErrCodeInvalidTxOutValue,
+				fmt.Sprintf("%s: 交易%x输出%d金额%d", blockchain.ErrInvalidTxOutValue, tx.Hash(), i, txOut.Value))
+		}
+
+		total += txOut.Value
+		if total > utils.MaxSatoshi {
+			return 0, blockchain.NewRuleError(blockchain.ErrCodeInvalidTxOutValue,
+				fmt.Sprintf("%s: 交易%x输出总额超过%d", blockchain.ErrInvalidTxOutValue, tx.Hash(), utils.MaxSatoshi))
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,138 @@
+// Package events 实现了节点内部的事件通知总线
+//
+// 区块链引擎等组件通过Bus发布事件，钱包、内存池和浏览器等下游服务
+// 订阅感兴趣的事件类型。事件按发布顺序同步分发给订阅者。
+package events
+
+import (
+	"sync"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+)
+
+// Type 事件类型
+type Type int
+
+// 事件类型定义
+const (
+	// BlockConnected 区块连接到主链，Data为*BlockEvent
+	BlockConnected Type = iota
+
+	// BlockDisconnected 区块从主链断开，Data为*BlockEvent
+	BlockDisconnected
+
+	// ChainReorganized 主链发生重组，Data为*ReorgEvent
+	ChainReorganized
+
+	// TransactionReplaced 内存池中的交易被替换，Data为*TxReplacedEvent
+	TransactionReplaced
+)
+
+// typeStrings 事件类型名称映射
+var typeStrings = map[Type]string{
+	BlockConnected:      "BlockConnected",
+	BlockDisconnected:   "BlockDisconnected",
+	ChainReorganized:    "ChainReorganized",
+	TransactionReplaced: "TransactionReplaced",
+}
+
+// String 返回事件类型名称
+func (t Type) String() string {
+	if s, ok := typeStrings[t]; ok {
+		return s
+	}
+	return "Unknown"
+}
+
+// Event 事件
+type Event struct {
+	Type Type        // 事件类型
+	Data interface{} // 事件数据，具体类型由事件类型决定
+}
+
+// BlockEvent 区块连接或断开事件数据
+type BlockEvent struct {
+	Block  *blockchain.Block // 区块
+	Height int32             // 区块高度
+}
+
+// ReorgEvent 主链重组事件数据
+//
+// Removed按断开顺序排列（从旧末端到分叉点），
+// Added按连接顺序排列（从分叉点到新末端）。
+type ReorgEvent struct {
+	ForkHash   [32]byte   // 分叉点区块哈希
+	ForkHeight int32      // 分叉点高度
+	Removed    [][32]byte // 从主链移除的区块哈希
+	Added      [][32]byte // 加入主链的区块哈希
+}
+
+// TxReplacedEvent 交易替换事件数据
+//
+// Replaced包括与替换交易直接冲突的交易以及它们在内存池中的后代交易。
+type TxReplacedEvent struct {
+	Replacement *blockchain.Transaction   // 替换交易
+	Replaced    []*blockchain.Transaction // 被移出内存池的交易
+}
+
+// Handler 事件处理函数
+type Handler func(Event)
+
+// subscription 订阅记录
+type subscription struct {
+	id      uint64
+	handler Handler
+}
+
+// Bus 事件总线
+//
+// 并发安全。处理函数在Publish调用方的goroutine中同步执行，
+// 耗时的处理应由订阅者自行转交到其他goroutine。
+type Bus struct {
+	mu       sync.RWMutex
+	nextID   uint64
+	handlers map[Type][]subscription
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[Type][]subscription)}
+}
+
+// Subscribe 订阅指定类型的事件
+//
+// 返回取消订阅的函数，可重复调用。
+func (b *Bus) Subscribe(t Type, handler Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+	b.handlers[t] = append(b.handlers[t], subscription{id: id, handler: handler})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		subs := b.handlers[t]
+		for i, sub := range subs {
+			if sub.id == id {
+				b.handlers[t] = append(subs[:i:i], subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish 发布事件
+//
+// 按订阅顺序依次调用该类型的所有处理函数。
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	subs := b.handlers[event.Type]
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.handler(event)
+	}
+}
@@ -0,0 +1,766 @@
+// Package mempool 实现了未确认交易的内存池
+//
+// 交易加入内存池前要通过基本检查，并以当前主链的UTXO集合和池内交易验证其输入：
+// 交易必须能被下一个区块打包（已达到锁定时间），引用的输出必须存在且已成熟，
+// 不能与池内交易重复花费同一输出，手续费不能低于最低交易费，
+// 每个输入的解锁脚本都必须满足被花费输出的锁定脚本。内存池按手续费率（每字节手续费）排序，
+// 总大小超过上限时移除手续费率最低的交易及其后代交易。
+//
+// 交易可以花费池内其他交易的输出。内存池记录池内交易之间的父子关系，
+// 并为每笔交易维护祖先交易和后代交易的数量、大小和手续费，用于限制
+// 未确认交易链的长度和大小。被移除的交易的后代交易随之移除，
+// 但被区块确认的交易的后代交易保留在内存池中。
+//
+// 与池内交易冲突的交易只有在冲突交易声明允许替换（输入序列号不超过MaxRBFSequence）
+// 且支付更高的手续费和手续费率时才被接受，被替换的交易通过事件总线通知订阅者。
+//
+// 内存池订阅区块事件：区块连接到主链时移除其中已确认的交易、与之冲突的交易
+// 以及超过保留时间的交易，区块断开时尝试把其中的交易重新加入内存池。
+//
+// 节点停止时通过Dump把内存池保存到文件，启动时用Load重新加载并验证。
+package mempool
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/events"
+	"simplied-bitcoin-network-go/pkg/mining"
+	"simplied-bitcoin-network-go/pkg/script"
+	"simplied-bitcoin-network-go/pkg/utils"
+	"simplied-bitcoin-network-go/pkg/utxo"
+)
+
+// 内存池默认限制
+const (
+	// DefaultMaxSize 交易总字节数上限
+	DefaultMaxSize = 64 * 1024 * 1024
+
+	// DefaultMaxAncestors 交易及其池内祖先交易的数量上限
+	DefaultMaxAncestors = 25
+
+	// DefaultMaxAncestorSize 交易及其池内祖先交易的总字节数上限
+	DefaultMaxAncestorSize = 101 * 1000
+
+	// DefaultMaxDescendants 交易及其池内后代交易的数量上限
+	DefaultMaxDescendants = 25
+
+	// DefaultMaxDescendantSize 交易及其池内后代交易的总字节数上限
+	DefaultMaxDescendantSize = 101 * 1000
+
+	// DefaultExpiry 交易在内存池中保留的最长时间
+	DefaultExpiry = 14 * 24 * time.Hour
+)
+
+var (
+	// ErrTxNotFound 交易不在内存池中
+	ErrTxNotFound = errors.New("交易不在内存池中")
+
+	// ErrDuplicateTx 交易已在内存池中
+	ErrDuplicateTx = errors.New("交易已在内存池中")
+
+	// ErrCoinbase Coinbase交易不能单独广播
+	ErrCoinbase = errors.New("Coinbase交易不能加入内存池")
+
+	// ErrInvalidTx 交易格式或金额无效
+	ErrInvalidTx = errors.New("无效的交易")
+
+	// ErrTxTooLarge 交易超过最大交易大小
+	ErrTxTooLarge = errors.New("交易过大")
+
+	// ErrDoubleSpend 交易花费的输出已被池内不允许替换的交易花费
+	ErrDoubleSpend = errors.New("交易与内存池中的交易重复花费")
+
+	// ErrMissingInputs 交易引用的输出不存在或已被花费
+	ErrMissingInputs = errors.New("交易引用的输出不存在")
+
+	// ErrImmatureSpend 交易花费了尚未成熟的Coinbase输出
+	ErrImmatureSpend = errors.New("交易花费了未成熟的Coinbase输出")
+
+	// ErrInsufficientFee 交易手续费低于最低交易费
+	ErrInsufficientFee = errors.New("交易手续费不足")
+
+	// ErrMempoolFull 内存池已满且交易手续费率不足以替换池内交易
+	ErrMempoolFull = errors.New("内存池已满")
+
+	// ErrTooLongMempoolChain 交易使未确认交易链超过数量或大小上限
+	ErrTooLongMempoolChain = errors.New("未确认交易链过长")
+
+	// ErrNonFinal 交易尚未达到锁定时间，不能被下一个区块打包
+	ErrNonFinal = errors.New("交易尚未达到锁定时间")
+
+	// ErrScriptValidation 交易输入的脚本执行失败，错误链中包含script.Error
+	ErrScriptValidation = errors.New("交易脚本验证失败")
+)
+
+// Config 内存池配置
+type Config struct {
+	Chain   *chain.Chain // 区块链引擎，提供主链高度和网络参数
+	UTXOSet *utxo.Set    // UTXO集合，用于验证交易输入
+	Events  *events.Bus  // 事件总线，订阅区块事件并发布交易替换事件，可为空
+
+	MaxSize int           // 交易总字节数上限，0表示DefaultMaxSize
+	MinFee  int64         // 最低交易费（聪），0表示utils.MinTransactionFee
+	Expiry  time.Duration // 交易保留的最长时间，0表示DefaultExpiry
+
+	MaxAncestors      int // 祖先交易数量上限（包括交易本身），0表示默认值
+	MaxAncestorSize   int // 祖先交易总字节数上限，0表示默认值
+	MaxDescendants    int // 后代交易数量上限（包括交易本身），0表示默认值
+	MaxDescendantSize int // 后代交易总字节数上限，0表示默认值
+
+	MaxReplacementEvictions int // 一笔替换交易最多移除的交易数，0表示默认值
+}
+
+// TxDesc 内存池中的交易
+type TxDesc struct {
+	mining.TxDesc
+
+	Added   time.Time // 加入内存池的时间
+	Height  int32     // 加入时的主链高度
+	Size    int       // 序列化大小
+	FeeRate float64   // 每字节手续费
+
+	// 池内祖先交易的统计，包括交易本身
+	AncestorCount int
+	AncestorSize  int
+	AncestorFees  int64
+
+	// 池内后代交易的统计，包括交易本身
+	DescendantCount int
+	DescendantSize  int
+	DescendantFees  int64
+
+	parents  map[[32]byte]*TxDesc // 池内父交易
+	children map[[32]byte]*TxDesc // 花费本交易输出的池内子交易
+}
+
+// AncestorFeeRate 返回交易与其池内祖先交易组成的交易包的手续费率
+func (desc *TxDesc) AncestorFeeRate() float64 {
+	return float64(desc.AncestorFees) / float64(desc.AncestorSize)
+}
+
+// DescendantFeeRate 返回交易与其池内后代交易组成的交易包的手续费率
+func (desc *TxDesc) DescendantFeeRate() float64 {
+	return float64(desc.DescendantFees) / float64(desc.DescendantSize)
+}
+
+// evictionScore 返回交易被移除的优先级，数值越小越先被移除
+//
+// 取交易本身和后代交易包手续费率中的较大值，手续费率高的子交易可以保护其父交易。
+func (desc *TxDesc) evictionScore() float64 {
+	if rate := desc.DescendantFeeRate(); rate > desc.FeeRate {
+		return rate
+	}
+	return desc.FeeRate
+}
+
+// TxPool 交易内存池
+//
+// 所有方法都可以并发调用。
+type TxPool struct {
+	chain   *chain.Chain
+	utxoSet *utxo.Set
+	events  *events.Bus
+	maxSize int
+	minFee  int64
+	expiry  time.Duration
+
+	maxAncestors      int
+	maxAncestorSize   int
+	maxDescendants    int
+	maxDescendantSize int
+
+	maxReplacementEvictions int
+
+	mu        sync.RWMutex
+	pool      map[[32]byte]*TxDesc
+	outpoints map[blockchain.OutPoint]*blockchain.Transaction // 池内交易花费的输出
+	totalSize int
+}
+
+// New 创建内存池
+func New(cfg *Config) (*TxPool, error) {
+	if cfg == nil || cfg.Chain == nil || cfg.UTXOSet == nil {
+		return nil, errors.New("内存池配置缺少区块链引擎或UTXO集合")
+	}
+
+	mp := &TxPool{
+		chain:     cfg.Chain,
+		utxoSet:   cfg.UTXOSet,
+		events:    cfg.Events,
+		maxSize:   cfg.MaxSize,
+		minFee:    cfg.MinFee,
+		expiry:    cfg.Expiry,
+		pool:      make(map[[32]byte]*TxDesc),
+		outpoints: make(map[blockchain.OutPoint]*blockchain.Transaction),
+
+		maxAncestors:      cfg.MaxAncestors,
+		maxAncestorSize:   cfg.MaxAncestorSize,
+		maxDescendants:    cfg.MaxDescendants,
+		maxDescendantSize: cfg.MaxDescendantSize,
+
+		maxReplacementEvictions: cfg.MaxReplacementEvictions,
+	}
+	if mp.maxSize <= 0 {
+		mp.maxSize = DefaultMaxSize
+	}
+	if mp.minFee <= 0 {
+		mp.minFee = utils.MinTransactionFee
+	}
+	if mp.expiry <= 0 {
+		mp.expiry = DefaultExpiry
+	}
+	if mp.maxAncestors <= 0 {
+		mp.maxAncestors = DefaultMaxAncestors
+	}
+	if mp.maxAncestorSize <= 0 {
+		mp.maxAncestorSize = DefaultMaxAncestorSize
+	}
+	if mp.maxDescendants <= 0 {
+		mp.maxDescendants = DefaultMaxDescendants
+	}
+	if mp.maxDescendantSize <= 0 {
+		mp.maxDescendantSize = DefaultMaxDescendantSize
+	}
+	if mp.maxReplacementEvictions <= 0 {
+		mp.maxReplacementEvictions = DefaultMaxReplacementEvictions
+	}
+
+	if cfg.Events != nil {
+		cfg.Events.Subscribe(events.BlockConnected, func(e events.Event) {
+			mp.blockConnected(e.Data.(*events.BlockEvent).Block)
+		})
+		cfg.Events.Subscribe(events.BlockDisconnected, func(e events.Event) {
+			mp.blockDisconnected(e.Data.(*events.BlockEvent).Block)
+		})
+	}
+	return mp, nil
+}
+
+// ProcessTransaction 验证交易并加入内存池
+//
+// 交易替换了池内交易时，在释放内存池锁之后发布TransactionReplaced事件，
+// 事件处理函数可以调用内存池的方法。
+func (mp *TxPool) ProcessTransaction(tx *blockchain.Transaction) error {
+	mp.mu.Lock()
+	_, replaced, err := mp.maybeAcceptTransaction(tx, time.Now())
+	mp.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if len(replaced) > 0 && mp.events != nil {
+		event := &events.TxReplacedEvent{Replacement: tx}
+		for _, desc := range replaced {
+			event.Replaced = append(event.Replaced, desc.Tx)
+		}
+		mp.events.Publish(events.Event{Type: events.TransactionReplaced, Data: event})
+	}
+	return nil
+}
+
+// HaveTransaction 判断交易是否在内存池中
+func (mp *TxPool) HaveTransaction(hash [32]byte) bool {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	_, ok := mp.pool[hash]
+	return ok
+}
+
+// FetchTransaction 返回内存池中的交易
+func (mp *TxPool) FetchTransaction(hash [32]byte) (*blockchain.Transaction, error) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	desc, ok := mp.pool[hash]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTxNotFound, utils.HashToString(hash[:]))
+	}
+	return desc.Tx, nil
+}
+
+// MiningDescs 返回所有交易作为区块模板的候选交易，实现mining.TxSource接口
+func (mp *TxPool) MiningDescs() []*mining.TxDesc {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	descs := make([]*mining.TxDesc, 0, len(mp.pool))
+	for _, desc := range mp.pool {
+		descs = append(descs, &desc.TxDesc)
+	}
+	return descs
+}
+
+// TxDescs 返回所有交易，按手续费率从高到低排序
+func (mp *TxPool) TxDescs() []*TxDesc {
+	mp.mu.RLock()
+	descs := make([]*TxDesc, 0, len(mp.pool))
+	for _, desc := range mp.pool {
+		copied := *desc
+		copied.parents, copied.children = nil, nil
+		descs = append(descs, &copied)
+	}
+	mp.mu.RUnlock()
+
+	sort.Slice(descs, func(i, j int) bool { return feeRateLess(descs[j], descs[i]) })
+	return descs
+}
+
+// Count 返回内存池中的交易数
+func (mp *TxPool) Count() int {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	return len(mp.pool)
+}
+
+// Size 返回内存池中交易的总字节数
+func (mp *TxPool) Size() int {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	return mp.totalSize
+}
+
+// MaxSize 返回内存池交易总字节数上限
+func (mp *TxPool) MaxSize() int {
+	return mp.maxSize
+}
+
+// maybeAcceptTransaction 验证交易并加入内存池，返回加入后的交易描述和被替换的交易
+//
+// added为交易加入内存池的时间，重新加入的交易沿用原来的时间。
+// 调用方必须持有写锁。
+func (mp *TxPool) maybeAcceptTransaction(tx *blockchain.Transaction, added time.Time) (*TxDesc, []*TxDesc, error) {
+	hash := tx.Hash()
+	if _, ok := mp.pool[hash]; ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrDuplicateTx, utils.HashToString(hash[:]))
+	}
+	if err := checkTransactionSanity(tx); err != nil {
+		return nil, nil, err
+	}
+
+	// 与池内交易冲突时按替换规则检查
+	conflicts := make(map[[32]byte]*TxDesc)
+	for _, txIn := range tx.TxIn {
+		if conflict, ok := mp.outpoints[txIn.PreviousOutPoint]; ok {
+			conflictHash := conflict.Hash()
+			conflicts[conflictHash] = mp.pool[conflictHash]
+		}
+	}
+
+	best := mp.chain.BestSnapshot()
+	params := mp.chain.Params()
+	spendHeight := best.Height + 1
+	if !tx.IsFinal(spendHeight, int64(best.MedianTime)) {
+		return nil, nil, fmt.Errorf("%w: 锁定时间%d, 下一区块高度%d", ErrNonFinal, tx.LockTime, spendHeight)
+	}
+
+	var totalIn int64
+	parents := make(map[[32]byte]*TxDesc)
+	pkScripts := make([][]byte, len(tx.TxIn))
+	for i, txIn := range tx.TxIn {
+		op := txIn.PreviousOutPoint
+
+		// 花费池内交易的输出
+		if parent, ok := mp.pool[op.Hash]; ok {
+			if int(op.Index) >= len(parent.Tx.TxOut) {
+				return nil, nil, fmt.Errorf("%w: %s", ErrMissingInputs, op)
+			}
+			parents[op.Hash] = parent
+			pkScripts[i] = parent.Tx.TxOut[op.Index].PkScript
+			totalIn += parent.Tx.TxOut[op.Index].Value
+			if totalIn > utils.MaxSatoshi {
+				return nil, nil, fmt.Errorf("%w: 输入总额超过%d", ErrInvalidTx, utils.MaxSatoshi)
+			}
+			continue
+		}
+
+		entry, err := mp.utxoSet.FetchEntry(op)
+		if err != nil {
+			return nil, nil, fmt.Errorf("查询UTXO条目%s失败: %v", op, err)
+		}
+		if entry == nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrMissingInputs, op)
+		}
+		if !entry.IsMature(spendHeight, params) {
+			return nil, nil, fmt.Errorf("%w: %s位于高度%d", ErrImmatureSpend, op, entry.Height)
+		}
+
+		pkScripts[i] = entry.PkScript
+		totalIn += entry.Value
+		if totalIn > utils.MaxSatoshi {
+			return nil, nil, fmt.Errorf("%w: 输入总额超过%d", ErrInvalidTx, utils.MaxSatoshi)
+		}
+	}
+
+	totalOut := tx.TotalOutputValue()
+	if totalIn < totalOut {
+		return nil, nil, fmt.Errorf("%w: 输入总额%d小于输出总额%d", ErrInvalidTx, totalIn, totalOut)
+	}
+	fee := totalIn - totalOut
+	if fee < mp.minFee {
+		return nil, nil, fmt.Errorf("%w: 手续费%d低于%d", ErrInsufficientFee, fee, mp.minFee)
+	}
+
+	size := tx.SerializeSize()
+	ancestors := mp.calcAncestors(parents)
+	if err := mp.checkChainLimits(ancestors, size); err != nil {
+		return nil, nil, err
+	}
+
+	// 脚本验证开销最大，放在其他检查之后
+	for i, pkScript := range pkScripts {
+		vm, err := script.NewEngine(pkScript, tx, i)
+		if err == nil {
+			err = vm.Execute()
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: 输入%d: %w", ErrScriptValidation, i, err)
+		}
+	}
+
+	// 所有检查通过后才移除被替换的交易
+	var replaced []*TxDesc
+	if len(conflicts) > 0 {
+		if err := mp.checkReplacement(tx, fee, size, conflicts, parents); err != nil {
+			return nil, nil, err
+		}
+		for conflictHash := range conflicts {
+			replaced = append(replaced, mp.removeTransaction(conflictHash, true)...)
+		}
+		log.Printf("交易%s替换了%d笔交易", utils.HashToString(hash[:]), len(replaced))
+	}
+
+	desc := &TxDesc{
+		TxDesc:          mining.TxDesc{Tx: tx, Fee: fee},
+		Added:           added,
+		Height:          best.Height,
+		Size:            size,
+		FeeRate:         float64(fee) / float64(size),
+		AncestorCount:   1,
+		AncestorSize:    size,
+		AncestorFees:    fee,
+		DescendantCount: 1,
+		DescendantSize:  size,
+		DescendantFees:  fee,
+		parents:         parents,
+		children:        make(map[[32]byte]*TxDesc),
+	}
+	for _, ancestor := range ancestors {
+		desc.AncestorCount++
+		desc.AncestorSize += ancestor.Size
+		desc.AncestorFees += ancestor.Fee
+	}
+	mp.addTransaction(desc, ancestors)
+
+	mp.trimToSize()
+	if _, ok := mp.pool[hash]; !ok {
+		return nil, replaced, fmt.Errorf("%w: 交易%s手续费率%.2f过低", ErrMempoolFull, utils.HashToString(hash[:]), desc.FeeRate)
+	}
+	return desc, replaced, nil
+}
+
+// checkChainLimits 检查加入交易后未确认交易链是否超过上限
+func (mp *TxPool) checkChainLimits(ancestors map[[32]byte]*TxDesc, size int) error {
+	if len(ancestors)+1 > mp.maxAncestors {
+		return fmt.Errorf("%w: 祖先交易%d笔超过上限%d", ErrTooLongMempoolChain, len(ancestors)+1, mp.maxAncestors)
+	}
+
+	ancestorSize := size
+	for hash, ancestor := range ancestors {
+		ancestorSize += ancestor.Size
+		if ancestor.DescendantCount+1 > mp.maxDescendants {
+			return fmt.Errorf("%w: 交易%s的后代交易超过上限%d", ErrTooLongMempoolChain,
+				utils.HashToString(hash[:]), mp.maxDescendants)
+		}
+		if ancestor.DescendantSize+size > mp.maxDescendantSize {
+			return fmt.Errorf("%w: 交易%s的后代交易大小超过上限%d字节", ErrTooLongMempoolChain,
+				utils.HashToString(hash[:]), mp.maxDescendantSize)
+		}
+	}
+	if ancestorSize > mp.maxAncestorSize {
+		return fmt.Errorf("%w: 祖先交易大小%d字节超过上限%d字节", ErrTooLongMempoolChain, ancestorSize, mp.maxAncestorSize)
+	}
+	return nil
+}
+
+// calcAncestors 返回给定父交易及其所有池内祖先交易
+func (mp *TxPool) calcAncestors(parents map[[32]byte]*TxDesc) map[[32]byte]*TxDesc {
+	ancestors := make(map[[32]byte]*TxDesc)
+	stack := make([]*TxDesc, 0, len(parents))
+	for hash, parent := range parents {
+		ancestors[hash] = parent
+		stack = append(stack, parent)
+	}
+
+	for len(stack) > 0 {
+		desc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for hash, parent := range desc.parents {
+			if _, ok := ancestors[hash]; !ok {
+				ancestors[hash] = parent
+				stack = append(stack, parent)
+			}
+		}
+	}
+	return ancestors
+}
+
+// calcDescendants 返回交易的所有池内后代交易
+func (mp *TxPool) calcDescendants(desc *TxDesc) map[[32]byte]*TxDesc {
+	descendants := make(map[[32]byte]*TxDesc)
+	stack := []*TxDesc{desc}
+
+	for len(stack) > 0 {
+		d := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for hash, child := range d.children {
+			if _, ok := descendants[hash]; !ok {
+				descendants[hash] = child
+				stack = append(stack, child)
+			}
+		}
+	}
+	return descendants
+}
+
+// addTransaction 将已验证的交易加入内存池，并更新祖先交易的后代统计
+func (mp *TxPool) addTransaction(desc *TxDesc, ancestors map[[32]byte]*TxDesc) {
+	hash := desc.Tx.Hash()
+	mp.pool[hash] = desc
+	for _, txIn := range desc.Tx.TxIn {
+		mp.outpoints[txIn.PreviousOutPoint] = desc.Tx
+	}
+	mp.totalSize += desc.Size
+
+	for _, parent := range desc.parents {
+		parent.children[hash] = desc
+	}
+	for _, ancestor := range ancestors {
+		ancestor.DescendantCount++
+		ancestor.DescendantSize += desc.Size
+		ancestor.DescendantFees += desc.Fee
+	}
+}
+
+// removeTransaction 从内存池移除交易，返回被移除的交易
+//
+// removeDescendants为true时一并移除所有后代交易，用于交易被淘汰或与区块冲突；
+// 交易被区块确认时其后代交易仍然有效，只需解除父子关系。
+func (mp *TxPool) removeTransaction(hash [32]byte, removeDescendants bool) []*TxDesc {
+	desc, ok := mp.pool[hash]
+	if !ok {
+		return nil
+	}
+
+	removed := []*TxDesc{desc}
+	if removeDescendants {
+		for _, d := range mp.calcDescendants(desc) {
+			removed = append(removed, d)
+		}
+	}
+
+	// 先移除后代交易，保证移除每笔交易时其祖先交易关系仍然完整
+	sort.Slice(removed, func(i, j int) bool { return removed[i].AncestorCount > removed[j].AncestorCount })
+	for _, d := range removed {
+		mp.removeEntry(d)
+	}
+	return removed
+}
+
+// removeEntry 移除单笔交易，并更新仍在池内的祖先和后代交易的统计
+func (mp *TxPool) removeEntry(desc *TxDesc) {
+	hash := desc.Tx.Hash()
+
+	for _, ancestor := range mp.calcAncestors(desc.parents) {
+		ancestor.DescendantCount--
+		ancestor.DescendantSize -= desc.Size
+		ancestor.DescendantFees -= desc.Fee
+	}
+	for _, descendant := range mp.calcDescendants(desc) {
+		descendant.AncestorCount--
+		descendant.AncestorSize -= desc.Size
+		descendant.AncestorFees -= desc.Fee
+	}
+	for _, parent := range desc.parents {
+		delete(parent.children, hash)
+	}
+	for _, child := range desc.children {
+		delete(child.parents, hash)
+	}
+
+	for _, txIn := range desc.Tx.TxIn {
+		delete(mp.outpoints, txIn.PreviousOutPoint)
+	}
+	delete(mp.pool, hash)
+	mp.totalSize -= desc.Size
+}
+
+// trimToSize 移除交易直到总大小不超过上限
+//
+// 每次移除淘汰优先级最低的交易及其所有后代交易。
+func (mp *TxPool) trimToSize() {
+	for mp.totalSize > mp.maxSize {
+		var worst *TxDesc
+		for _, desc := range mp.pool {
+			if worst == nil || evictBefore(desc, worst) {
+				worst = desc
+			}
+		}
+
+		hash := worst.Tx.Hash()
+		removed := mp.removeTransaction(hash, true)
+		log.Printf("内存池已满，移除交易%s及其%d笔后代交易 手续费率%.2f",
+			utils.HashToString(hash[:]), len(removed)-1, worst.evictionScore())
+	}
+}
+
+// blockConnected 移除区块中已确认的交易以及与之重复花费的交易
+//
+// 已确认交易的后代交易保留在内存池中，与区块交易冲突的交易连同其后代交易一起移除。
+func (mp *TxPool) blockConnected(block *blockchain.Block) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	for _, tx := range block.Transactions[1:] {
+		hash := tx.Hash()
+		mp.removeTransaction(hash, false)
+
+		for _, txIn := range tx.TxIn {
+			conflict, ok := mp.outpoints[txIn.PreviousOutPoint]
+			if !ok {
+				continue
+			}
+			conflictHash := conflict.Hash()
+			removed := mp.removeTransaction(conflictHash, true)
+			log.Printf("移除与区块交易%s冲突的交易%s及其%d笔后代交易",
+				utils.HashToString(hash[:]), utils.HashToString(conflictHash[:]), len(removed)-1)
+		}
+	}
+
+	mp.expire(time.Now())
+}
+
+// expire 移除在内存池中超过保留时间的交易及其后代交易
+func (mp *TxPool) expire(now time.Time) {
+	var expired [][32]byte
+	for hash, desc := range mp.pool {
+		if now.Sub(desc.Added) > mp.expiry {
+			expired = append(expired, hash)
+		}
+	}
+
+	count := 0
+	for _, hash := range expired {
+		count += len(mp.removeTransaction(hash, true))
+	}
+	if count > 0 {
+		log.Printf("移除%d笔过期交易", count)
+	}
+}
+
+// sortedDescs 返回池内所有交易，父交易排在子交易之前
+//
+// 祖先交易数量少的排在前面，数量相同时按加入时间排序。
+func (mp *TxPool) sortedDescs() []*TxDesc {
+	descs := make([]*TxDesc, 0, len(mp.pool))
+	for _, desc := range mp.pool {
+		descs = append(descs, desc)
+	}
+	sort.Slice(descs, func(i, j int) bool {
+		if descs[i].AncestorCount != descs[j].AncestorCount {
+			return descs[i].AncestorCount < descs[j].AncestorCount
+		}
+		return descs[i].Added.Before(descs[j].Added)
+	})
+	return descs
+}
+
+// blockDisconnected 把断开区块中的交易重新加入内存池
+//
+// 池内交易可能花费断开区块中交易的输出，重新加入后两者之间的父子关系
+// 需要重建，因此先清空内存池，依次加入区块交易和原有交易。
+// 事件在整个主链切换完成后发布，交易以切换后的UTXO集合验证，
+// 已被新主链区块花费了输入的交易会被拒绝。
+func (mp *TxPool) blockDisconnected(block *blockchain.Block) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	existing := mp.sortedDescs()
+	mp.pool = make(map[[32]byte]*TxDesc)
+	mp.outpoints = make(map[blockchain.OutPoint]*blockchain.Transaction)
+	mp.totalSize = 0
+
+	for _, tx := range block.Transactions[1:] {
+		if _, _, err := mp.maybeAcceptTransaction(tx, time.Now()); err != nil {
+			hash := tx.Hash()
+			log.Printf("断开区块的交易%s未能重新加入内存池: %v", utils.HashToString(hash[:]), err)
+		}
+	}
+	for _, desc := range existing {
+		if _, _, err := mp.maybeAcceptTransaction(desc.Tx, desc.Added); err != nil && !errors.Is(err, ErrDuplicateTx) {
+			hash := desc.Tx.Hash()
+			log.Printf("移除失效的交易%s: %v", utils.HashToString(hash[:]), err)
+		}
+	}
+}
+
+// evictBefore 判断交易a是否应先于b被移除，优先级相同时较晚加入的交易先被移除
+func evictBefore(a, b *TxDesc) bool {
+	if sa, sb := a.evictionScore(), b.evictionScore(); sa != sb {
+		return sa < sb
+	}
+	return a.Added.After(b.Added)
+}
+
+// feeRateLess 按手续费率比较交易，手续费率相同时较晚加入的交易排在前面
+func feeRateLess(a, b *TxDesc) bool {
+	if a.FeeRate != b.FeeRate {
+		return a.FeeRate < b.FeeRate
+	}
+	return a.Added.After(b.Added)
+}
+
+// checkTransactionSanity 检查不依赖UTXO集合的交易规则
+func checkTransactionSanity(tx *blockchain.Transaction) error {
+	if tx.IsCoinbase() {
+		return ErrCoinbase
+	}
+	if len(tx.TxIn) == 0 || len(tx.TxOut) == 0 {
+		return fmt.Errorf("%w: 交易缺少输入或输出", ErrInvalidTx)
+	}
+	if len(tx.TxIn) > utils.MaxTransactionInputs || len(tx.TxOut) > utils.MaxTransactionOutputs {
+		return fmt.Errorf("%w: 输入%d个，输出%d个", ErrInvalidTx, len(tx.TxIn), len(tx.TxOut))
+	}
+	if size := tx.SerializeSize(); size > utils.MaxTransactionSize {
+		return fmt.Errorf("%w: %d字节超过%d字节", ErrTxTooLarge, size, utils.MaxTransactionSize)
+	}
+
+	var total int64
+	for i, txOut := range tx.TxOut {
+		if txOut.Value < 0 || txOut.Value > utils.MaxSatoshi {
+			return fmt.Errorf("%w: 输出%d金额%d", ErrInvalidTx, i, txOut.Value)
+		}
+		total += txOut.Value
+		if total > utils.MaxSatoshi {
+			return fmt.Errorf("%w: 输出总额超过%d", ErrInvalidTx, utils.MaxSatoshi)
+		}
+	}
+
+	seen := make(map[blockchain.OutPoint]struct{}, len(tx.TxIn))
+	for _, txIn := range tx.TxIn {
+		if _, ok := seen[txIn.PreviousOutPoint]; ok {
+			return fmt.Errorf("%w: 重复花费输入%s", ErrInvalidTx, txIn.PreviousOutPoint)
+		}
+		seen[txIn.PreviousOutPoint] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package mempool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// 内存池文件格式
+//
+//	版本(4) | 交易数(8) | 交易记录... | 校验和(4)
+//
+// 每条交易记录为加入时间(8，Unix秒) + 序列化交易，校验和为之前所有数据的
+// 双重SHA-256哈希的前4字节。所有整数均为小端序。
+const (
+	// DumpFileName 内存池文件名
+	DumpFileName = "mempool.dat"
+
+	// DumpVersion 当前内存池文件格式版本
+	DumpVersion = 1
+
+	// dumpHeaderSize 版本和交易数的长度
+	dumpHeaderSize = 4 + 8
+
+	// dumpChecksumSize 校验和长度
+	dumpChecksumSize = 4
+)
+
+var (
+	// ErrDumpVersion 内存池文件版本不受支持
+	ErrDumpVersion = errors.New("不支持的内存池文件版本")
+
+	// ErrDumpCorrupted 内存池文件格式错误或校验和不匹配
+	ErrDumpCorrupted = errors.New("内存池文件已损坏")
+)
+
+// LoadStats 加载内存池文件的统计
+type LoadStats struct {
+	Accepted int // 重新加入内存池的交易数
+	Expired  int // 超过保留时间被丢弃的交易数
+	Failed   int // 验证失败被丢弃的交易数
+}
+
+// Dump 将内存池中的交易写入文件
+//
+// 交易按依赖顺序写入，父交易在子交易之前。先写入临时文件再重命名，
+// 写入过程中断不会破坏已有的文件。
+func (mp *TxPool) Dump(path string) error {
+	mp.mu.RLock()
+	descs := mp.sortedDescs()
+	mp.mu.RUnlock()
+
+	var buf bytes.Buffer
+	buf.Write(binary.LittleEndian.AppendUint32(nil, DumpVersion))
+	buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(descs))))
+	for _, desc := range descs {
+		buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(desc.Added.Unix())))
+		buf.Write(desc.Tx.Serialize())
+	}
+	buf.Write(utils.DoubleSHA256(buf.Bytes())[:dumpChecksumSize])
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录%s失败: %v", filepath.Dir(path), err)
+	}
+	tmpPath := path + ".new"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("写入内存池文件失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("重命名内存池文件失败: %v", err)
+	}
+
+	log.Printf("已保存%d笔内存池交易到%s", len(descs), path)
+	return nil
+}
+
+// Load 从文件加载交易并重新验证
+//
+// 超过保留时间的交易以及不再有效（例如输入已被区块花费）的交易被丢弃。
+// 文件不存在时不做任何操作。
+func (mp *TxPool) Load(path string) (LoadStats, error) {
+	var stats LoadStats
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, fmt.Errorf("读取内存池文件失败: %v", err)
+	}
+
+	entries, err := parseDump(data)
+	if err != nil {
+		return stats, err
+	}
+
+	now := time.Now()
+	mp.mu.Lock()
+	for _, entry := range entries {
+		if now.Sub(entry.added) > mp.expiry {
+			stats.Expired++
+			continue
+		}
+		if _, _, err := mp.maybeAcceptTransaction(entry.tx, entry.added); err != nil {
+			stats.Failed++
+			continue
+		}
+		stats.Accepted++
+	}
+	mp.mu.Unlock()
+
+	log.Printf("从%s加载内存池: 接受%d笔 过期%d笔 无效%d笔", path, stats.Accepted, stats.Expired, stats.Failed)
+	return stats, nil
+}
+
+// dumpEntry 内存池文件中的交易记录
+type dumpEntry struct {
+	added time.Time
+	tx    *blockchain.Transaction
+}
+
+// parseDump 解析内存池文件
+func parseDump(data []byte) ([]dumpEntry, error) {
+	if len(data) < dumpHeaderSize+dumpChecksumSize {
+		return nil, fmt.Errorf("%w: 文件长度%d", ErrDumpCorrupted, len(data))
+	}
+
+	body, checksum := data[:len(data)-dumpChecksumSize], data[len(data)-dumpChecksumSize:]
+	if !bytes.Equal(utils.DoubleSHA256(body)[:dumpChecksumSize], checksum) {
+		return nil, fmt.Errorf("%w: 校验和不匹配", ErrDumpCorrupted)
+	}
+
+	if version := binary.LittleEndian.Uint32(body[0:4]); version != DumpVersion {
+		return nil, fmt.Errorf("%w: %d", ErrDumpVersion, version)
+	}
+	count := binary.LittleEndian.Uint64(body[4:12])
+
+	var entries []dumpEntry
+	offset := dumpHeaderSize
+	for i := uint64(0); i < count; i++ {
+		if offset+8 > len(body) {
+			return nil, fmt.Errorf("%w: 第%d笔交易记录不完整", ErrDumpCorrupted, i)
+		}
+		added := time.Unix(int64(binary.LittleEndian.Uint64(body[offset:offset+8])), 0)
+		offset += 8
+
+		tx, n, err := blockchain.DeserializeTransaction(body[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: 第%d笔交易: %v", ErrDumpCorrupted, i, err)
+		}
+		offset += n
+		entries = append(entries, dumpEntry{added: added, tx: tx})
+	}
+	if offset != len(body) {
+		return nil, fmt.Errorf("%w: 文件末尾有%d字节多余数据", ErrDumpCorrupted, len(body)-offset)
+	}
+	return entries, nil
+}
@@ -0,0 +1,100 @@
+package mempool
+
+import (
+	"errors"
+	"fmt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// 交易替换参数
+const (
+	// MaxRBFSequence 表示允许替换的最大输入序列号
+	//
+	// 交易的任一输入序列号不超过该值时，交易可以被手续费更高的冲突交易替换。
+	MaxRBFSequence = 0xfffffffd
+
+	// DefaultMaxReplacementEvictions 一笔替换交易最多移除的交易数（包括后代交易）
+	DefaultMaxReplacementEvictions = 100
+)
+
+// ErrReplacement 替换交易不满足替换规则
+var ErrReplacement = errors.New("替换交易被拒绝")
+
+// SignalsReplacement 判断交易是否通过输入序列号声明允许被替换
+func SignalsReplacement(tx *blockchain.Transaction) bool {
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence <= MaxRBFSequence {
+			return true
+		}
+	}
+	return false
+}
+
+// isReplaceable 判断池内交易是否允许被替换
+//
+// 交易本身或任一池内祖先交易声明允许替换时，交易都可以被替换。
+func (mp *TxPool) isReplaceable(desc *TxDesc) bool {
+	if SignalsReplacement(desc.Tx) {
+		return true
+	}
+	for _, ancestor := range mp.calcAncestors(desc.parents) {
+		if SignalsReplacement(ancestor.Tx) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkReplacement 检查交易能否替换与其冲突的池内交易
+//
+// 替换规则：
+// - 每笔冲突交易都必须允许被替换，否则视为重复花费
+// - 冲突交易及其后代交易的总数不超过maxReplacementEvictions
+// - 替换交易不能花费将被移除的交易的输出
+// - 替换交易的手续费率高于每笔被移除的交易
+// - 替换交易的手续费高于被移除交易的手续费总和，且差额不低于最低交易费
+//
+// 调用方必须持有写锁。
+func (mp *TxPool) checkReplacement(tx *blockchain.Transaction, fee int64, size int,
+	conflicts map[[32]byte]*TxDesc, parents map[[32]byte]*TxDesc) error {
+
+	hash := tx.Hash()
+	evicted := make(map[[32]byte]*TxDesc)
+	for conflictHash, conflict := range conflicts {
+		if !mp.isReplaceable(conflict) {
+			return fmt.Errorf("%w: 交易%s与不允许替换的交易%s冲突", ErrDoubleSpend,
+				utils.HashToString(hash[:]), utils.HashToString(conflictHash[:]))
+		}
+		evicted[conflictHash] = conflict
+		for h, d := range mp.calcDescendants(conflict) {
+			evicted[h] = d
+		}
+		if len(evicted) > mp.maxReplacementEvictions {
+			return fmt.Errorf("%w: 需要移除的交易超过%d笔", ErrReplacement, mp.maxReplacementEvictions)
+		}
+	}
+
+	for parentHash := range parents {
+		if _, ok := evicted[parentHash]; ok {
+			return fmt.Errorf("%w: 交易花费了将被替换的交易%s的输出", ErrReplacement,
+				utils.HashToString(parentHash[:]))
+		}
+	}
+
+	feeRate := float64(fee) / float64(size)
+	var evictedFees int64
+	for h, d := range evicted {
+		if feeRate <= d.FeeRate {
+			return fmt.Errorf("%w: 手续费率%.2f不高于被替换交易%s的%.2f", ErrReplacement,
+				feeRate, utils.HashToString(h[:]), d.FeeRate)
+		}
+		evictedFees += d.Fee
+	}
+	if fee < evictedFees+mp.minFee {
+		return fmt.Errorf("%w: 手续费%d不足以替换手续费总额%d的交易，至少需要增加%d", ErrReplacement,
+			fee, evictedFees, mp.minFee)
+	}
+	return nil
+}
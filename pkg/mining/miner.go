@@ -0,0 +1,239 @@
+package mining
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/events"
+)
+
+const (
+	// hashCheckInterval 工作协程每计算多少次哈希检查一次停止条件并上报计数
+	hashCheckInterval = 1 << 12
+
+	// hashRateInterval 哈希率统计窗口
+	hashRateInterval = 5 * time.Second
+)
+
+// Config 矿工配置
+type Config struct {
+	Chain        *chain.Chain // 区块链引擎
+	Events       *events.Bus  // 事件总线，用于感知其他来源的新主链末端，可为空
+	TxSource     TxSource     // 候选交易来源，为空时只打包Coinbase交易
+	PayoutScript []byte       // 接收区块奖励的锁定脚本
+	Threads      int          // 挖矿协程数，0表示使用全部CPU核心
+}
+
+// Miner CPU矿工
+//
+// 多个协程分割32位Nonce空间并行搜索，Nonce空间耗尽后递增
+// Coinbase脚本中的ExtraNonce并重新计算Merkle根。主链末端变化时
+// 放弃当前模板并基于新末端重新构建。
+type Miner struct {
+	chain        *chain.Chain
+	events       *events.Bus
+	txSource     TxSource
+	payoutScript []byte
+	threads      int
+
+	hashes      atomic.Uint64 // 累计哈希次数
+	hashRate    atomic.Uint64 // 最近统计窗口的哈希率（float64位模式）
+	blocksFound atomic.Uint64 // 成功挖出并被接受的区块数
+	tipChanges  atomic.Uint64 // 收到的主链末端变化次数
+}
+
+// New 创建CPU矿工
+func New(cfg *Config) (*Miner, error) {
+	if cfg == nil || cfg.Chain == nil {
+		return nil, errors.New("矿工配置缺少区块链引擎")
+	}
+	if len(cfg.PayoutScript) == 0 {
+		return nil, errors.New("矿工配置缺少奖励锁定脚本")
+	}
+
+	threads := cfg.Threads
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+
+	return &Miner{
+		chain:        cfg.Chain,
+		events:       cfg.Events,
+		txSource:     cfg.TxSource,
+		payoutScript: cfg.PayoutScript,
+		threads:      threads,
+	}, nil
+}
+
+// Threads 返回挖矿协程数
+func (m *Miner) Threads() int {
+	return m.threads
+}
+
+// Hashes 返回累计哈希次数
+func (m *Miner) Hashes() uint64 {
+	return m.hashes.Load()
+}
+
+// HashRate 返回最近统计窗口内的哈希率（次/秒）
+func (m *Miner) HashRate() float64 {
+	return math.Float64frombits(m.hashRate.Load())
+}
+
+// BlocksFound 返回成功挖出并被区块链接受的区块数
+func (m *Miner) BlocksFound() uint64 {
+	return m.blocksFound.Load()
+}
+
+// Run 持续挖矿直到上下文被取消
+//
+// 每轮基于当前主链末端构建模板并求解，求解成功后提交给区块链引擎。
+// 上下文取消时所有工作协程退出后返回nil，构建模板失败时返回错误。
+func (m *Miner) Run(ctx context.Context) error {
+	if m.events != nil {
+		unsubscribe := m.events.Subscribe(events.BlockConnected, func(events.Event) {
+			m.tipChanges.Add(1)
+		})
+		defer unsubscribe()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.monitorHashRate(ctx)
+	}()
+	defer wg.Wait()
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		generation := m.tipChanges.Load()
+		template, err := NewBlockTemplate(m.chain, m.txSource, m.payoutScript)
+		if err != nil {
+			return err
+		}
+
+		block := m.solve(ctx, template, generation)
+		if block == nil {
+			continue
+		}
+
+		if _, err := m.chain.ProcessBlock(block); err != nil {
+			log.Printf("挖出的区块%x被拒绝: %v", block.Hash(), err)
+			continue
+		}
+		m.blocksFound.Add(1)
+	}
+}
+
+// solve 求解区块模板
+//
+// 返回满足难度目标的区块；上下文取消或主链末端变化时返回nil。
+func (m *Miner) solve(ctx context.Context, template *BlockTemplate, generation uint64) *blockchain.Block {
+	block := template.Block
+	for extraNonce := uint64(0); ; extraNonce++ {
+		if extraNonce > 0 {
+			block.Transactions[0].TxIn[0].SignatureScript = coinbaseScript(template.Height, extraNonce)
+			block.Header.MerkleRoot = calcMerkleRoot(block.Transactions)
+		}
+
+		header, stopped := m.searchNonces(ctx, *block.Header, generation)
+		if header != nil {
+			block.Header = header
+			return block
+		}
+		if stopped {
+			return nil
+		}
+	}
+}
+
+// searchNonces 使用多个协程搜索满足难度目标的Nonce
+//
+// 第i个协程依次尝试i, i+n, i+2n, ...，n为协程数，合起来覆盖完整的32位空间。
+//
+// 返回值：
+// - *blockchain.BlockHeader: 找到的区块头，未找到时为nil
+// - bool: 是否因上下文取消或主链末端变化而提前停止
+func (m *Miner) searchNonces(ctx context.Context, header blockchain.BlockHeader, generation uint64) (*blockchain.BlockHeader, bool) {
+	var (
+		wg      sync.WaitGroup
+		done    atomic.Bool
+		stopped atomic.Bool
+		once    sync.Once
+		found   *blockchain.BlockHeader
+	)
+
+	for i := 0; i < m.threads; i++ {
+		wg.Add(1)
+		go func(start uint64) {
+			defer wg.Done()
+
+			local := header
+			var count uint64
+			for nonce := start; nonce <= math.MaxUint32; nonce += uint64(m.threads) {
+				local.Nonce = uint32(nonce)
+				count++
+
+				if local.MeetsTarget() {
+					once.Do(func() {
+						solved := local
+						found = &solved
+					})
+					done.Store(true)
+					break
+				}
+
+				if count%hashCheckInterval == 0 {
+					m.hashes.Add(hashCheckInterval)
+					if done.Load() {
+						break
+					}
+					if ctx.Err() != nil || m.tipChanges.Load() != generation {
+						stopped.Store(true)
+						done.Store(true)
+						break
+					}
+				}
+			}
+			m.hashes.Add(count % hashCheckInterval)
+		}(uint64(i))
+	}
+
+	wg.Wait()
+	if found != nil {
+		return found, false
+	}
+	return nil, stopped.Load()
+}
+
+// monitorHashRate 按固定窗口统计哈希率
+func (m *Miner) monitorHashRate(ctx context.Context) {
+	ticker := time.NewTicker(hashRateInterval)
+	defer ticker.Stop()
+
+	last := m.hashes.Load()
+	lastTime := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			current := m.hashes.Load()
+			rate := float64(current-last) / now.Sub(lastTime).Seconds()
+			m.hashRate.Store(math.Float64bits(rate))
+			last, lastTime = current, now
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package mining
+
+import (
+	"container/heap"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/script"
+)
+
+// txPrioItem 交易挑选过程中的候选项
+type txPrioItem struct {
+	desc     *TxDesc
+	hash     [32]byte
+	size     int
+	sigOps   int
+	parents  []*txPrioItem // 同一来源中的父交易
+	children []*txPrioItem // 依赖本交易的同一来源中的子交易
+	selected bool          // 是否已选入区块
+
+	// 交易与其尚未选入区块的祖先交易组成交易包，按交易包的手续费率排序
+	ancestorFeeRate float64
+	index           int // 在优先队列中的位置，不在队列中时为-1
+}
+
+// ancestors 返回尚未选入区块的祖先交易和交易本身，父交易排在子交易之前
+func (item *txPrioItem) ancestors() []*txPrioItem {
+	var pkg []*txPrioItem
+	visited := make(map[*txPrioItem]struct{})
+
+	var visit func(*txPrioItem)
+	visit = func(it *txPrioItem) {
+		if _, ok := visited[it]; ok || it.selected {
+			return
+		}
+		visited[it] = struct{}{}
+		for _, parent := range it.parents {
+			visit(parent)
+		}
+		pkg = append(pkg, it)
+	}
+	visit(item)
+	return pkg
+}
+
+// updateAncestorFeeRate 重新计算交易包的手续费率
+func (item *txPrioItem) updateAncestorFeeRate() {
+	var fees int64
+	var size int
+	for _, it := range item.ancestors() {
+		fees += it.desc.Fee
+		size += it.size
+	}
+	item.ancestorFeeRate = float64(fees) / float64(size)
+}
+
+// txPriorityQueue 按交易包手续费率排序的最大堆
+type txPriorityQueue []*txPrioItem
+
+func (pq txPriorityQueue) Len() int { return len(pq) }
+
+func (pq txPriorityQueue) Less(i, j int) bool {
+	if pq[i].ancestorFeeRate != pq[j].ancestorFeeRate {
+		return pq[i].ancestorFeeRate > pq[j].ancestorFeeRate
+	}
+	// 手续费率相同时按哈希排序，保证模板构建结果确定
+	for k := range pq[i].hash {
+		if pq[i].hash[k] != pq[j].hash[k] {
+			return pq[i].hash[k] < pq[j].hash[k]
+		}
+	}
+	return false
+}
+
+func (pq txPriorityQueue) Swap(i, j int) {
+	pq[i], pq[j] = pq[j], pq[i]
+	pq[i].index = i
+	pq[j].index = j
+}
+
+func (pq *txPriorityQueue) Push(x interface{}) {
+	item := x.(*txPrioItem)
+	item.index = len(*pq)
+	*pq = append(*pq, item)
+}
+
+func (pq *txPriorityQueue) Pop() interface{} {
+	old := *pq
+	item := old[len(old)-1]
+	item.index = -1
+	*pq = old[:len(old)-1]
+	return item
+}
+
+// selectTransactions 按交易包手续费率挑选交易
+//
+// 每笔交易与其尚未选入区块的祖先交易组成交易包，按交易包的总手续费除以
+// 总大小排序，使手续费率高的子交易可以带动手续费率低的父交易被打包（CPFP）。
+// 交易包整体选入区块，选入后其后代交易的交易包随之更新。
+//
+// 参数blockSize和sigOps为已占用的区块大小和签名操作数（包括区块头和Coinbase交易）。
+// 返回按依赖顺序排列的交易、手续费总额以及最终的区块大小和签名操作数。
+func selectTransactions(descs []*TxDesc, blockSize, sigOps int) ([]*blockchain.Transaction, int64, int, int) {
+	items := make(map[[32]byte]*txPrioItem, len(descs))
+	for _, desc := range descs {
+		if desc == nil || desc.Tx == nil || desc.Tx.IsCoinbase() {
+			continue
+		}
+		hash := desc.Tx.Hash()
+		items[hash] = &txPrioItem{
+			desc:   desc,
+			hash:   hash,
+			size:   desc.Tx.SerializeSize(),
+			sigOps: script.CountTxSigOps(desc.Tx),
+			index:  -1,
+		}
+	}
+
+	// 建立同一来源内的依赖关系
+	for _, item := range items {
+		linked := make(map[[32]byte]struct{})
+		for _, txIn := range item.desc.Tx.TxIn {
+			parent, exists := items[txIn.PreviousOutPoint.Hash]
+			if !exists {
+				continue
+			}
+			if _, ok := linked[parent.hash]; !ok {
+				linked[parent.hash] = struct{}{}
+				item.parents = append(item.parents, parent)
+				parent.children = append(parent.children, item)
+			}
+		}
+	}
+
+	queue := make(txPriorityQueue, 0, len(items))
+	for _, item := range items {
+		item.updateAncestorFeeRate()
+		item.index = len(queue)
+		queue = append(queue, item)
+	}
+	heap.Init(&queue)
+
+	var selected []*blockchain.Transaction
+	var totalFees int64
+	for queue.Len() > 0 {
+		item := heap.Pop(&queue).(*txPrioItem)
+		pkg := item.ancestors()
+
+		// 超出限制的交易包被跳过，包内的祖先交易仍可随其他交易包选入
+		var pkgSize, pkgSigOps int
+		for _, it := range pkg {
+			pkgSize += it.size
+			pkgSigOps += it.sigOps
+		}
+		if len(selected)+len(pkg)+1 > blockchain.MaxTransactionsPerBlock {
+			continue
+		}
+		if blockSize+pkgSize > blockchain.MaxBlockSize {
+			continue
+		}
+		if sigOps+pkgSigOps > blockchain.MaxBlockSigOps {
+			continue
+		}
+
+		for _, it := range pkg {
+			it.selected = true
+			if it.index >= 0 {
+				heap.Remove(&queue, it.index)
+			}
+			selected = append(selected, it.desc.Tx)
+			totalFees += it.desc.Fee
+		}
+		blockSize += pkgSize
+		sigOps += pkgSigOps
+
+		// 交易包选入后，其后代交易的交易包不再包含这些交易
+		for _, it := range descendants(pkg) {
+			if it.index >= 0 {
+				it.updateAncestorFeeRate()
+				heap.Fix(&queue, it.index)
+			}
+		}
+	}
+
+	return selected, totalFees, blockSize, sigOps
+}
+
+// descendants 返回交易包中交易尚未选入区块的所有后代交易
+func descendants(pkg []*txPrioItem) []*txPrioItem {
+	var result []*txPrioItem
+	visited := make(map[*txPrioItem]struct{})
+
+	stack := append([]*txPrioItem(nil), pkg...)
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, child := range it.children {
+			if _, ok := visited[child]; ok || child.selected {
+				continue
+			}
+			visited[child] = struct{}{}
+			result = append(result, child)
+			stack = append(stack, child)
+		}
+	}
+	return result
+}
@@ -0,0 +1,152 @@
+// Package mining 实现了区块模板构建和基于CPU的工作量证明挖矿
+package mining
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/script"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// CoinbaseFlags 写入Coinbase脚本的矿工标识
+const CoinbaseFlags = "/simplied-bitcoin-network/"
+
+// BlockTemplate 区块模板
+//
+// 模板中的区块头尚未求解，矿工通过修改Nonce和ExtraNonce寻找满足难度目标的哈希。
+type BlockTemplate struct {
+	Block  *blockchain.Block // 待求解的区块
+	Height int32             // 区块高度
+	Fees   int64             // 区块内交易的手续费总额
+	SigOps int               // 区块内签名操作总数
+}
+
+// TxDesc 候选交易描述
+type TxDesc struct {
+	Tx  *blockchain.Transaction // 交易
+	Fee int64                   // 交易手续费（聪）
+}
+
+// TxSource 候选交易来源，通常由内存池实现
+//
+// 返回的交易必须已经通过验证，其输入引用主链UTXO集合或同一来源中的其他交易。
+type TxSource interface {
+	// MiningDescs 返回所有可以打包的候选交易
+	MiningDescs() []*TxDesc
+}
+
+// NewBlockTemplate 构建延伸当前主链末端的区块模板
+//
+// 从交易来源中按交易包手续费率从高到低挑选交易，交易包由交易及其在同一来源中
+// 尚未选入的祖先交易组成，父交易总是排在子交易之前。挑选过程遵守MaxBlockSize、
+// MaxTransactionsPerBlock和MaxBlockSigOps限制。Coinbase交易支付区块奖励
+// 加全部手续费，难度位由区块链的难度规则确定，时间戳取当前时间与中位时间
+// 加MinTimestampDelta中的较大值。
+//
+// 参数：
+// - c: 区块链引擎
+// - txSource: 候选交易来源，为nil时只打包Coinbase交易
+// - payoutScript: 接收区块奖励的锁定脚本
+func NewBlockTemplate(c *chain.Chain, txSource TxSource, payoutScript []byte) (*BlockTemplate, error) {
+	best := c.BestSnapshot()
+	height := best.Height + 1
+
+	// 先用区块奖励创建Coinbase交易占位，挑选完交易后再加上手续费
+	coinbase, err := createCoinbaseTransaction(height, 0, blockchain.CalcBlockSubsidy(height, c.Params()), payoutScript)
+	if err != nil {
+		return nil, err
+	}
+
+	blockSize := blockchain.BlockHeaderSize + utils.VarIntSize(blockchain.MaxTransactionsPerBlock) +
+		coinbase.SerializeSize()
+	sigOps := script.CountTxSigOps(coinbase)
+
+	var selected []*blockchain.Transaction
+	var totalFees int64
+	if txSource != nil {
+		selected, totalFees, blockSize, sigOps = selectTransactions(txSource.MiningDescs(), blockSize, sigOps)
+	}
+	coinbase.TxOut[0].Value += totalFees
+
+	timestamp := uint32(time.Now().Unix())
+	if minTimestamp := best.MedianTime + uint32(blockchain.MinTimestampDelta.Seconds()); timestamp < minTimestamp {
+		timestamp = minTimestamp
+	}
+
+	transactions := append([]*blockchain.Transaction{coinbase}, selected...)
+	header := blockchain.NewBlockHeader(blockchain.CurrentBlockVersion, best.Hash, [32]byte{},
+		timestamp, c.CalcNextRequiredDifficulty(), 0)
+	block := blockchain.NewBlock(header, transactions)
+	header.MerkleRoot = calcMerkleRoot(transactions)
+
+	return &BlockTemplate{Block: block, Height: height, Fees: totalFees, SigOps: sigOps}, nil
+}
+
+// calcMerkleRoot 使用Merkle树计算交易列表的Merkle根
+func calcMerkleRoot(transactions []*blockchain.Transaction) [32]byte {
+	txHashes := make([][]byte, len(transactions))
+	for i, tx := range transactions {
+		hash := tx.Hash()
+		txHashes[i] = hash[:]
+	}
+	return blockchain.NewMerkleTree(txHashes).GetRoot()
+}
+
+// createCoinbaseTransaction 创建Coinbase交易
+//
+// 交易结构与创世区块Coinbase交易一致，输入脚本依次包含
+// 区块高度、ExtraNonce和矿工标识，高度使不同区块的Coinbase交易哈希互不相同。
+//
+// 参数：
+// - height: 区块高度
+// - extraNonce: 额外随机数，Nonce空间耗尽时递增
+// - value: 输出金额（区块奖励加手续费）
+// - payoutScript: 接收奖励的锁定脚本
+func createCoinbaseTransaction(height int32, extraNonce uint64, value int64, payoutScript []byte) (*blockchain.Transaction, error) {
+	script := coinbaseScript(height, extraNonce)
+	if len(script) > blockchain.MaxScriptSize {
+		return nil, fmt.Errorf("Coinbase脚本过长: %d字节", len(script))
+	}
+	return blockchain.NewCoinbaseTransaction(script, value, payoutScript), nil
+}
+
+// coinbaseScript 构造Coinbase输入脚本
+//
+// 格式：push(高度，小端序最小编码) | push(ExtraNonce，8字节) | 矿工标识
+func coinbaseScript(height int32, extraNonce uint64) []byte {
+	heightBytes := minimalLittleEndian(int64(height))
+
+	script := make([]byte, 0, 1+len(heightBytes)+9+len(CoinbaseFlags))
+	script = append(script, byte(len(heightBytes)))
+	script = append(script, heightBytes...)
+
+	nonceBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(nonceBytes, extraNonce)
+	script = append(script, byte(len(nonceBytes)))
+	script = append(script, nonceBytes...)
+
+	return append(script, CoinbaseFlags...)
+}
+
+// minimalLittleEndian 将非负整数编码为最短的小端序字节
+//
+// 最高字节的最高位为1时追加0x00，保证按脚本数字解释时为正数。
+func minimalLittleEndian(n int64) []byte {
+	if n == 0 {
+		return []byte{0x00}
+	}
+
+	var result []byte
+	for n > 0 {
+		result = append(result, byte(n&0xff))
+		n >>= 8
+	}
+	if result[len(result)-1]&0x80 != 0 {
+		result = append(result, 0x00)
+	}
+	return result
+}
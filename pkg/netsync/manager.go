@@ -0,0 +1,634 @@
+// Package netsync 实现了节点之间的区块链同步
+//
+// 同步采用先区块头后区块的方式：先从一个同步节点用getheaders和区块定位器
+// 下载并验证完整的区块头链，再在最佳区块头所在分支上，从多个节点并行下载
+// 区块数据。下载范围限制在主链末端之后的滑动窗口内，窗口随区块连接向前移动；
+// 长时间未响应请求的节点会被断开，其未完成的请求转交其他节点。
+// 发送无效区块头或无效区块的节点会被记录不当行为分数，达到阈值后断开并封禁。
+//
+// 同步完成后，新连接到主链的区块和交易池接受的交易通过inv通告给其他节点，
+// 对端用getdata请求具体数据。每个节点记录对端已知的清单项，避免回传。
+// 交易请求超时或对端回复notfound时，改向其他通告过该交易的节点请求。
+package netsync
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/events"
+	"simplied-bitcoin-network-go/pkg/network"
+)
+
+// 同步默认参数
+const (
+	// DefaultMaxBlocksInFlight 每个节点同时请求中的区块数上限
+	DefaultMaxBlocksInFlight = 16
+
+	// DefaultBlockDownloadWindow 下载窗口大小
+	//
+	// 只请求主链末端之后这么多个区块以内的数据，
+	// 限制乱序到达、等待父区块的区块占用的内存。
+	DefaultBlockDownloadWindow = 1024
+
+	// DefaultStallTimeout 请求超过该时间未得到响应时认为节点停滞
+	DefaultStallTimeout = 30 * time.Second
+
+	// msgChanSize 同步协程消息队列长度
+	msgChanSize = 256
+)
+
+var (
+	// ErrInvalidHeader 节点发送了无效的区块头
+	ErrInvalidHeader = errors.New("节点发送了无效的区块头")
+
+	// ErrInvalidBlock 节点发送了无效的区块
+	ErrInvalidBlock = errors.New("节点发送了无效的区块")
+
+	// ErrStalled 节点长时间未响应区块头或区块请求
+	ErrStalled = errors.New("节点同步停滞")
+)
+
+// Config 同步管理器配置
+type Config struct {
+	Chain  *chain.Chain // 区块链引擎
+	Events *events.Bus  // 事件总线，订阅区块连接事件以通告新区块，可为空
+	TxPool TxPool       // 交易池，为空时不中继交易
+
+	MaxBlocksInFlight   int           // 每个节点同时请求中的区块数上限，0表示默认值
+	BlockDownloadWindow int           // 下载窗口大小，0表示默认值
+	StallTimeout        time.Duration // 请求超时时间，0表示默认值
+}
+
+// Progress 同步进度
+type Progress struct {
+	HeadersHeight  int32  // 最佳区块头高度
+	BlocksHeight   int32  // 主链高度
+	BlocksInFlight int    // 已请求尚未收到的区块数
+	BlocksPending  int    // 已收到、等待父区块的区块数
+	SyncPeer       string // 当前区块头同步节点，为空表示没有
+	Peers          int    // 参与同步的节点数
+}
+
+// IsCurrent 判断区块数据是否已追上区块头
+func (p Progress) IsCurrent() bool {
+	return p.BlocksHeight >= p.HeadersHeight
+}
+
+// peerState 同步协程维护的节点状态
+type peerState struct {
+	peer       *network.Peer
+	bestHeight int32                  // 节点已知拥有的最高区块高度
+	inFlight   map[[32]byte]time.Time // 已向该节点请求的区块及请求时间
+
+	headersRequested time.Time // 未完成的getheaders请求时间，零值表示没有
+}
+
+// txRequest 请求中的交易
+//
+// 请求超时或对端回复notfound时，改向其他通告过该交易的节点请求。
+type txRequest struct {
+	peer       *peerState   // 当前请求的节点
+	requested  time.Time    // 请求时间
+	announcers []*peerState // 尚未请求过的其他通告节点
+}
+
+// pendingBlock 已下载、等待父区块连接的区块
+type pendingBlock struct {
+	block *blockchain.Block
+	from  *peerState
+}
+
+// 发送给同步协程的消息
+type (
+	newPeerMsg  struct{ peer *network.Peer }
+	donePeerMsg struct{ peer *network.Peer }
+	peerMsg     struct {
+		peer *network.Peer
+		msg  network.Message
+	}
+	progressMsg struct{ reply chan Progress }
+)
+
+// SyncManager 区块链同步管理器
+//
+// 所有同步状态由单个协程维护，外部通过NewPeer、DonePeer和HandleMessage
+// 投递事件，通常分别挂接到network.ManagerConfig的对应回调上。
+type SyncManager struct {
+	chain             *chain.Chain
+	txPool            TxPool
+	maxBlocksInFlight int
+	downloadWindow    int
+	stallTimeout      time.Duration
+
+	msgChan chan interface{}
+	quit    chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+
+	// 待通告的清单项，由事件处理函数加入、同步协程取出。
+	// 区块链引擎可能在同步协程内发布事件，因此不能通过msgChan投递。
+	announceMu     sync.Mutex
+	announceQueue  []*network.InvVect
+	announceSignal chan struct{}
+
+	// 以下字段只在同步协程中访问
+	peers      map[*network.Peer]*peerState
+	syncPeer   *peerState
+	requested  map[[32]byte]*peerState      // 所有请求中的区块
+	pending    map[[32]byte][]*pendingBlock // 按父区块哈希索引的待连接区块
+	downloaded map[[32]byte]struct{}        // 待连接区块的哈希
+	txRequests map[[32]byte]*txRequest      // 请求中的交易
+}
+
+// New 创建同步管理器
+func New(cfg *Config) (*SyncManager, error) {
+	if cfg == nil || cfg.Chain == nil {
+		return nil, errors.New("同步管理器配置缺少区块链引擎")
+	}
+
+	sm := &SyncManager{
+		chain:             cfg.Chain,
+		txPool:            cfg.TxPool,
+		maxBlocksInFlight: cfg.MaxBlocksInFlight,
+		downloadWindow:    cfg.BlockDownloadWindow,
+		stallTimeout:      cfg.StallTimeout,
+
+		msgChan:        make(chan interface{}, msgChanSize),
+		quit:           make(chan struct{}),
+		announceSignal: make(chan struct{}, 1),
+
+		peers:      make(map[*network.Peer]*peerState),
+		requested:  make(map[[32]byte]*peerState),
+		pending:    make(map[[32]byte][]*pendingBlock),
+		downloaded: make(map[[32]byte]struct{}),
+		txRequests: make(map[[32]byte]*txRequest),
+	}
+	if sm.maxBlocksInFlight <= 0 {
+		sm.maxBlocksInFlight = DefaultMaxBlocksInFlight
+	}
+	if sm.downloadWindow <= 0 {
+		sm.downloadWindow = DefaultBlockDownloadWindow
+	}
+	if sm.stallTimeout <= 0 {
+		sm.stallTimeout = DefaultStallTimeout
+	}
+
+	if cfg.Events != nil {
+		cfg.Events.Subscribe(events.BlockConnected, func(e events.Event) {
+			block := e.Data.(*events.BlockEvent).Block
+			sm.AnnounceInventory(network.NewInvVect(network.InvTypeBlock, block.Hash()))
+		})
+	}
+	return sm, nil
+}
+
+// Start 启动同步协程
+func (sm *SyncManager) Start() {
+	sm.wg.Add(1)
+	go sm.handler()
+}
+
+// Stop 停止同步协程并等待其退出
+func (sm *SyncManager) Stop() {
+	sm.once.Do(func() { close(sm.quit) })
+	sm.wg.Wait()
+}
+
+// NewPeer 通知同步管理器有新节点完成握手
+func (sm *SyncManager) NewPeer(p *network.Peer) {
+	sm.send(&newPeerMsg{peer: p})
+}
+
+// DonePeer 通知同步管理器节点已断开
+func (sm *SyncManager) DonePeer(p *network.Peer) {
+	sm.send(&donePeerMsg{peer: p})
+}
+
+// HandleMessage 将节点消息交给同步管理器处理
+//
+// 处理headers、block、getheaders、getdata、inv、tx和notfound消息，其他消息被忽略。
+func (sm *SyncManager) HandleMessage(p *network.Peer, msg network.Message) {
+	switch msg.(type) {
+	case *network.MsgHeaders, *network.MsgBlock, *network.MsgGetHeaders, *network.MsgGetData,
+		*network.MsgInv, *network.MsgTx, *network.MsgNotFound:
+		sm.send(&peerMsg{peer: p, msg: msg})
+	}
+}
+
+// Progress 返回当前同步进度，同步管理器已停止时返回零值
+func (sm *SyncManager) Progress() Progress {
+	reply := make(chan Progress, 1)
+	if !sm.send(&progressMsg{reply: reply}) {
+		return Progress{}
+	}
+	select {
+	case p := <-reply:
+		return p
+	case <-sm.quit:
+		return Progress{}
+	}
+}
+
+// send 向同步协程投递消息，同步管理器已停止时返回false
+func (sm *SyncManager) send(msg interface{}) bool {
+	select {
+	case sm.msgChan <- msg:
+		return true
+	case <-sm.quit:
+		return false
+	}
+}
+
+// handler 同步协程，串行处理所有事件并定期检测停滞的节点
+func (sm *SyncManager) handler() {
+	defer sm.wg.Done()
+
+	ticker := time.NewTicker(sm.stallTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case m := <-sm.msgChan:
+			switch m := m.(type) {
+			case *newPeerMsg:
+				sm.handleNewPeer(m.peer)
+			case *donePeerMsg:
+				if state := sm.peers[m.peer]; state != nil {
+					sm.removePeer(state)
+				}
+			case *peerMsg:
+				sm.handlePeerMessage(m.peer, m.msg)
+			case *progressMsg:
+				m.reply <- sm.progress()
+			}
+		case <-sm.announceSignal:
+			sm.relayAnnouncements()
+		case <-ticker.C:
+			sm.checkStalls()
+		case <-sm.quit:
+			return
+		}
+	}
+}
+
+// handleNewPeer 登记新节点，必要时开始区块头同步并分配下载任务
+func (sm *SyncManager) handleNewPeer(p *network.Peer) {
+	if _, exists := sm.peers[p]; exists {
+		return
+	}
+	sm.peers[p] = &peerState{
+		peer:       p,
+		bestHeight: p.StartingHeight(),
+		inFlight:   make(map[[32]byte]time.Time),
+	}
+
+	sm.startHeadersSync()
+	sm.fetchBlocks()
+}
+
+// removePeer 移除节点，将其未完成的区块和交易请求交给其他节点
+func (sm *SyncManager) removePeer(state *peerState) {
+	delete(sm.peers, state.peer)
+	for hash := range state.inFlight {
+		delete(sm.requested, hash)
+	}
+	for hash, req := range sm.txRequests {
+		if req.peer == state {
+			sm.retryTxRequest(hash, req)
+		}
+	}
+	if sm.syncPeer == state {
+		sm.syncPeer = nil
+	}
+
+	sm.startHeadersSync()
+	sm.fetchBlocks()
+}
+
+// misbehaving 为发送无效数据的节点增加不当行为分数
+//
+// 增加的分数等于节点的封禁阈值，节点立即被断开，由连接管理器封禁其IP。
+func (sm *SyncManager) misbehaving(state *peerState, reason error) {
+	if state.peer.AddBanScore(state.peer.BanThreshold(), reason) {
+		log.Printf("断开节点%s: %v", state.peer, reason)
+		sm.removePeer(state)
+	}
+}
+
+// punish 断开行为异常的节点
+func (sm *SyncManager) punish(state *peerState, reason error) {
+	log.Printf("断开节点%s: %v", state.peer, reason)
+	state.peer.DisconnectWithReason(reason)
+	sm.removePeer(state)
+}
+
+// handlePeerMessage 分发节点消息
+func (sm *SyncManager) handlePeerMessage(p *network.Peer, msg network.Message) {
+	state := sm.peers[p]
+	if state == nil {
+		return
+	}
+
+	switch m := msg.(type) {
+	case *network.MsgHeaders:
+		sm.handleHeaders(state, m)
+	case *network.MsgBlock:
+		sm.handleBlock(state, m.Block)
+	case *network.MsgGetHeaders:
+		sm.handleGetHeaders(state, m)
+	case *network.MsgGetData:
+		sm.handleGetData(state, m)
+	case *network.MsgInv:
+		sm.handleInv(state, m)
+	case *network.MsgTx:
+		sm.handleTx(state, m.Tx)
+	case *network.MsgNotFound:
+		sm.handleNotFound(state, m)
+	}
+}
+
+// startHeadersSync 在没有进行中的区块头同步时，选择高度最高的节点开始同步
+//
+// 只选择声称高度超过本节点最佳区块头的节点。
+func (sm *SyncManager) startHeadersSync() {
+	if sm.syncPeer != nil && !sm.syncPeer.headersRequested.IsZero() {
+		return
+	}
+
+	_, headersHeight := sm.chain.BestHeader()
+	var best *peerState
+	for _, state := range sm.peers {
+		if state.bestHeight > headersHeight && (best == nil || state.bestHeight > best.bestHeight) {
+			best = state
+		}
+	}
+	if best == nil {
+		return
+	}
+
+	sm.syncPeer = best
+	sm.requestHeaders(best)
+}
+
+// requestHeaders 以最佳区块头构造定位器，向节点请求后续区块头
+func (sm *SyncManager) requestHeaders(state *peerState) {
+	hash, _ := sm.chain.BestHeader()
+	if state.peer.SendMessage(&network.MsgGetHeaders{
+		ProtocolVersion:    network.ProtocolVersion,
+		BlockLocatorHashes: sm.chain.BlockLocator(hash),
+	}) {
+		state.headersRequested = time.Now()
+	}
+}
+
+// handleHeaders 验证并保存区块头
+//
+// 区块头必须依次衔接，任何一个验证失败都会断开该节点。
+// 收到满额的区块头时继续向该节点请求，否则认为该节点的区块头已全部下载。
+func (sm *SyncManager) handleHeaders(state *peerState, msg *network.MsgHeaders) {
+	state.headersRequested = time.Time{}
+
+	headers := msg.Headers
+	if len(headers) > 0 && !sm.chain.HaveHeader(headers[0].PrevBlockHash) {
+		// 对端的链可能与本节点的定位器不相交，不视为违规
+		log.Printf("节点%s发送的区块头无法衔接到已知区块%x", state.peer, headers[0].PrevBlockHash)
+		if state == sm.syncPeer {
+			sm.syncPeer = nil
+		}
+		return
+	}
+
+	for i, header := range headers {
+		if i > 0 && header.PrevBlockHash != headers[i-1].Hash() {
+			sm.misbehaving(state, fmt.Errorf("%w: 第%d个区块头与前一个不衔接", ErrInvalidHeader, i))
+			return
+		}
+
+		if err := sm.chain.ProcessBlockHeader(header); err != nil {
+			var ruleErr blockchain.RuleError
+			if errors.As(err, &ruleErr) {
+				sm.misbehaving(state, fmt.Errorf("%w: %v", ErrInvalidHeader, err))
+			} else {
+				log.Printf("处理节点%s的区块头失败: %v", state.peer, err)
+			}
+			return
+		}
+	}
+
+	// 区块头不足一个消息的上限时，最后一个就是对端的链末端
+	if len(headers) > 0 {
+		if height, err := sm.chain.BlockHeightByHash(headers[len(headers)-1].Hash()); err == nil {
+			if height > state.bestHeight || len(headers) < network.MaxBlockHeadersPerMsg {
+				state.bestHeight = height
+			}
+		}
+	} else if _, height := sm.chain.BestHeader(); state.bestHeight > height {
+		state.bestHeight = height
+	}
+
+	if len(headers) == network.MaxBlockHeadersPerMsg {
+		sm.requestHeaders(state)
+	} else if state == sm.syncPeer {
+		sm.startHeadersSync()
+	}
+	sm.fetchBlocks()
+}
+
+// fetchBlocks 将下载窗口内尚未请求的区块分配给空闲的节点
+//
+// 每个区块分配给拥有该区块、请求数未达上限且请求数最少的节点，
+// 使下载分散到多个节点并行进行。
+func (sm *SyncManager) fetchBlocks() {
+	if len(sm.peers) == 0 {
+		return
+	}
+
+	requests := make(map[*peerState]*network.MsgGetData)
+	for _, hash := range sm.chain.BlocksToDownload(sm.downloadWindow) {
+		if _, ok := sm.requested[hash]; ok {
+			continue
+		}
+		if _, ok := sm.downloaded[hash]; ok {
+			continue
+		}
+		height, err := sm.chain.BlockHeightByHash(hash)
+		if err != nil {
+			continue
+		}
+
+		var target *peerState
+		for _, state := range sm.peers {
+			if state.bestHeight < height || len(state.inFlight) >= sm.maxBlocksInFlight {
+				continue
+			}
+			if target == nil || len(state.inFlight) < len(target.inFlight) {
+				target = state
+			}
+		}
+		if target == nil {
+			continue
+		}
+
+		if requests[target] == nil {
+			requests[target] = &network.MsgGetData{}
+		}
+		requests[target].AddInvVect(network.NewInvVect(network.InvTypeBlock, hash))
+		target.inFlight[hash] = time.Now()
+		sm.requested[hash] = target
+	}
+
+	// 发送失败的请求保留在inFlight中，节点移除时转交其他节点
+	for state, msg := range requests {
+		state.peer.SendMessage(msg)
+	}
+}
+
+// handleBlock 处理请求的区块
+//
+// 父区块已有数据时立即提交给区块链引擎，并依次提交等待该区块的后代；
+// 否则暂存到父区块到达。未请求的区块被忽略。
+func (sm *SyncManager) handleBlock(state *peerState, block *blockchain.Block) {
+	hash := block.Hash()
+	if _, ok := state.inFlight[hash]; !ok {
+		return
+	}
+	delete(state.inFlight, hash)
+	delete(sm.requested, hash)
+
+	pb := &pendingBlock{block: block, from: state}
+	if !sm.chain.HaveBlock(block.Header.PrevBlockHash) {
+		prev := block.Header.PrevBlockHash
+		sm.pending[prev] = append(sm.pending[prev], pb)
+		sm.downloaded[hash] = struct{}{}
+		sm.fetchBlocks()
+		return
+	}
+
+	queue := []*pendingBlock{pb}
+	for len(queue) > 0 {
+		pb, queue = queue[0], queue[1:]
+		hash := pb.block.Hash()
+		delete(sm.downloaded, hash)
+
+		if _, err := sm.chain.ProcessBlock(pb.block); err != nil {
+			var ruleErr blockchain.RuleError
+			if errors.As(err, &ruleErr) && ruleErr.ErrorCode == blockchain.ErrCodeDuplicateBlock {
+				continue
+			}
+			if errors.As(err, &ruleErr) {
+				// 无效区块的后代永远无法连接，丢弃后可以从其他分支重新下载
+				sm.dropPending(hash)
+				if _, connected := sm.peers[pb.from.peer]; connected {
+					sm.misbehaving(pb.from, fmt.Errorf("%w: %v", ErrInvalidBlock, err))
+				}
+			} else {
+				// 后代区块等待重新下载的父区块
+				log.Printf("处理区块%x失败: %v", hash, err)
+			}
+			continue
+		}
+
+		queue = append(queue, sm.pending[hash]...)
+		delete(sm.pending, hash)
+	}
+
+	sm.fetchBlocks()
+}
+
+// dropPending 递归丢弃等待parent的全部待连接区块
+func (sm *SyncManager) dropPending(parent [32]byte) {
+	children := sm.pending[parent]
+	delete(sm.pending, parent)
+	for _, pb := range children {
+		hash := pb.block.Hash()
+		delete(sm.downloaded, hash)
+		sm.dropPending(hash)
+	}
+}
+
+// handleGetHeaders 从主链返回定位器之后的区块头
+func (sm *SyncManager) handleGetHeaders(state *peerState, msg *network.MsgGetHeaders) {
+	reply := &network.MsgHeaders{}
+	for _, header := range sm.chain.LocateHeaders(msg.BlockLocatorHashes, msg.HashStop, network.MaxBlockHeadersPerMsg) {
+		reply.AddBlockHeader(header)
+	}
+	state.peer.SendMessage(reply)
+}
+
+// handleGetData 返回请求的区块和交易，本节点没有的对象以notfound回复
+func (sm *SyncManager) handleGetData(state *peerState, msg *network.MsgGetData) {
+	notFound := &network.MsgNotFound{}
+	for _, iv := range msg.InvList {
+		var reply network.Message
+		switch iv.Type {
+		case network.InvTypeBlock:
+			if block, err := sm.chain.BlockByHash(iv.Hash); err == nil {
+				reply = &network.MsgBlock{Block: block}
+			}
+
+		case network.InvTypeTx:
+			if sm.txPool == nil {
+				break
+			}
+			if tx, err := sm.txPool.FetchTransaction(iv.Hash); err == nil {
+				reply = &network.MsgTx{Tx: tx}
+			}
+		}
+
+		if reply == nil {
+			notFound.AddInvVect(iv)
+			continue
+		}
+		if !state.peer.SendMessage(reply) {
+			return
+		}
+	}
+
+	if len(notFound.InvList) > 0 {
+		state.peer.SendMessage(notFound)
+	}
+}
+
+// checkStalls 断开区块头或区块请求超时的节点，超时的交易请求改向其他节点发送
+func (sm *SyncManager) checkStalls() {
+	now := time.Now()
+	for hash, req := range sm.txRequests {
+		if now.Sub(req.requested) > sm.stallTimeout {
+			sm.retryTxRequest(hash, req)
+		}
+	}
+
+	for _, state := range sm.peers {
+		if !state.headersRequested.IsZero() && now.Sub(state.headersRequested) > sm.stallTimeout {
+			sm.punish(state, fmt.Errorf("%w: 区块头请求超时", ErrStalled))
+			continue
+		}
+		for hash, requested := range state.inFlight {
+			if now.Sub(requested) > sm.stallTimeout {
+				sm.punish(state, fmt.Errorf("%w: 区块%x请求超时", ErrStalled, hash))
+				break
+			}
+		}
+	}
+}
+
+// progress 汇总当前同步进度
+func (sm *SyncManager) progress() Progress {
+	_, headersHeight := sm.chain.BestHeader()
+	p := Progress{
+		HeadersHeight:  headersHeight,
+		BlocksHeight:   sm.chain.BestSnapshot().Height,
+		BlocksInFlight: len(sm.requested),
+		BlocksPending:  len(sm.downloaded),
+		Peers:          len(sm.peers),
+	}
+	if sm.syncPeer != nil {
+		p.SyncPeer = sm.syncPeer.peer.String()
+	}
+	return p
+}
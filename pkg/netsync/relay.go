@@ -0,0 +1,171 @@
+package netsync
+
+import (
+	"log"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/network"
+)
+
+// TxPool 交易池接口，通常由内存池实现
+type TxPool interface {
+	// HaveTransaction 判断交易池中是否已有该交易
+	HaveTransaction(hash [32]byte) bool
+
+	// FetchTransaction 获取交易池中的交易
+	FetchTransaction(hash [32]byte) (*blockchain.Transaction, error)
+
+	// ProcessTransaction 验证交易并加入交易池，被拒绝时返回错误
+	ProcessTransaction(tx *blockchain.Transaction) error
+}
+
+// AnnounceInventory 向所有已连接节点通告清单项
+//
+// 可以在任意协程中调用，包括区块链引擎的事件处理函数。区块只在本节点
+// 已追上最佳区块头时通告，避免初始同步期间向其他节点通告大量旧区块。
+func (sm *SyncManager) AnnounceInventory(iv *network.InvVect) {
+	sm.announceMu.Lock()
+	sm.announceQueue = append(sm.announceQueue, iv)
+	sm.announceMu.Unlock()
+
+	select {
+	case sm.announceSignal <- struct{}{}:
+	default:
+	}
+}
+
+// relayAnnouncements 取出待通告的清单项并发送给对端未知的节点
+func (sm *SyncManager) relayAnnouncements() {
+	sm.announceMu.Lock()
+	queue := sm.announceQueue
+	sm.announceQueue = nil
+	sm.announceMu.Unlock()
+
+	current := sm.isCurrent()
+	for _, iv := range queue {
+		if iv.Type == network.InvTypeBlock && !current {
+			continue
+		}
+		sm.relay(iv, nil)
+	}
+}
+
+// relay 向除except以外的节点通告清单项，对端已知的清单项由节点自行跳过
+func (sm *SyncManager) relay(iv *network.InvVect, except *peerState) {
+	for _, state := range sm.peers {
+		if state != except {
+			state.peer.QueueInventory(iv)
+		}
+	}
+}
+
+// isCurrent 判断主链是否已追上最佳区块头
+func (sm *SyncManager) isCurrent() bool {
+	_, headersHeight := sm.chain.BestHeader()
+	return sm.chain.BestSnapshot().Height >= headersHeight
+}
+
+// handleInv 处理对端通告的清单项
+//
+// 未知区块通过getheaders获取区块头，再由下载流程请求区块数据；
+// 已知区块头的区块只更新对端高度。未知交易直接用getdata请求，
+// 已在请求中的交易记录通告节点以便请求失败时重试，初始同步期间忽略交易通告。
+func (sm *SyncManager) handleInv(state *peerState, msg *network.MsgInv) {
+	var needHeaders bool
+	getData := &network.MsgGetData{}
+	current := sm.isCurrent()
+
+	for _, iv := range msg.InvList {
+		switch iv.Type {
+		case network.InvTypeBlock:
+			if !sm.chain.HaveHeader(iv.Hash) {
+				needHeaders = true
+				continue
+			}
+			if height, err := sm.chain.BlockHeightByHash(iv.Hash); err == nil && height > state.bestHeight {
+				state.bestHeight = height
+			}
+
+		case network.InvTypeTx:
+			if sm.txPool == nil || !current || sm.txPool.HaveTransaction(iv.Hash) {
+				continue
+			}
+			if req, requested := sm.txRequests[iv.Hash]; requested {
+				req.addAnnouncer(state)
+				continue
+			}
+			getData.AddInvVect(iv)
+			sm.txRequests[iv.Hash] = &txRequest{peer: state, requested: time.Now()}
+		}
+	}
+
+	if needHeaders && state.headersRequested.IsZero() {
+		sm.requestHeaders(state)
+	}
+	if len(getData.InvList) > 0 {
+		state.peer.SendMessage(getData)
+	}
+	sm.fetchBlocks()
+}
+
+// handleTx 将交易提交给交易池，被接受后通告给其他节点
+func (sm *SyncManager) handleTx(state *peerState, tx *blockchain.Transaction) {
+	hash := tx.Hash()
+	delete(sm.txRequests, hash)
+
+	if sm.txPool == nil || sm.txPool.HaveTransaction(hash) {
+		return
+	}
+	if err := sm.txPool.ProcessTransaction(tx); err != nil {
+		log.Printf("拒绝节点%s的交易%x: %v", state.peer, hash, err)
+		return
+	}
+	sm.relay(network.NewInvVect(network.InvTypeTx, hash), state)
+}
+
+// handleNotFound 对端没有请求的交易时，改向其他通告节点请求
+func (sm *SyncManager) handleNotFound(state *peerState, msg *network.MsgNotFound) {
+	for _, iv := range msg.InvList {
+		if iv.Type != network.InvTypeTx {
+			continue
+		}
+		if req, ok := sm.txRequests[iv.Hash]; ok && req.peer == state {
+			sm.retryTxRequest(iv.Hash, req)
+		}
+	}
+}
+
+// retryTxRequest 向下一个仍然连接的通告节点重新请求交易
+//
+// 没有其他通告节点时放弃请求，之后收到的通告会重新发起请求。
+func (sm *SyncManager) retryTxRequest(hash [32]byte, req *txRequest) {
+	for len(req.announcers) > 0 {
+		next := req.announcers[0]
+		req.announcers = req.announcers[1:]
+		if _, connected := sm.peers[next.peer]; !connected {
+			continue
+		}
+
+		req.peer = next
+		req.requested = time.Now()
+		getData := &network.MsgGetData{}
+		getData.AddInvVect(network.NewInvVect(network.InvTypeTx, hash))
+		next.peer.SendMessage(getData)
+		return
+	}
+	delete(sm.txRequests, hash)
+}
+
+// addAnnouncer 记录通告了请求中交易的节点，已记录的节点被跳过
+func (req *txRequest) addAnnouncer(state *peerState) {
+	if state == req.peer {
+		return
+	}
+	for _, announcer := range req.announcers {
+		if announcer == state {
+			return
+		}
+	}
+	req.announcers = append(req.announcers, state)
+}
@@ -0,0 +1,150 @@
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// codec.go - 负载编解码辅助函数
+//
+// 所有多字节整数使用小端序，变长字段使用VarInt长度前缀，与区块和交易的序列化格式一致。
+
+// payloadReader 按顺序读取消息负载
+//
+// 每次读取都检查剩余长度，数据不足时返回错误而不是越界访问，
+// 因此可以安全地处理任意输入。
+type payloadReader struct {
+	data   []byte
+	offset int
+}
+
+// newPayloadReader 创建负载读取器
+func newPayloadReader(data []byte) *payloadReader {
+	return &payloadReader{data: data}
+}
+
+// remaining 返回尚未读取的字节数
+func (r *payloadReader) remaining() int {
+	return len(r.data) - r.offset
+}
+
+// readBytes 读取n个字节，返回底层数据的切片
+func (r *payloadReader) readBytes(n int, field string) ([]byte, error) {
+	if n < 0 || r.remaining() < n {
+		return nil, fmt.Errorf("数据长度不足，无法读取%s", field)
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b, nil
+}
+
+// readUint8 读取1字节整数
+func (r *payloadReader) readUint8(field string) (uint8, error) {
+	b, err := r.readBytes(1, field)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// readUint16BE 读取2字节大端序整数（网络地址端口）
+func (r *payloadReader) readUint16BE(field string) (uint16, error) {
+	b, err := r.readBytes(2, field)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// readUint32 读取4字节小端序整数
+func (r *payloadReader) readUint32(field string) (uint32, error) {
+	b, err := r.readBytes(4, field)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// readUint64 读取8字节小端序整数
+func (r *payloadReader) readUint64(field string) (uint64, error) {
+	b, err := r.readBytes(8, field)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// readHash 读取32字节哈希
+func (r *payloadReader) readHash(field string) ([32]byte, error) {
+	var hash [32]byte
+	b, err := r.readBytes(32, field)
+	if err != nil {
+		return hash, err
+	}
+	copy(hash[:], b)
+	return hash, nil
+}
+
+// readCount 读取VarInt编码的列表长度
+//
+// 长度不能超过max，并且剩余数据至少能容纳count个minItemSize字节的元素，
+// 防止恶意数据通过巨大的长度字段耗尽内存。
+func (r *payloadReader) readCount(max uint64, minItemSize int, field string) (int, error) {
+	count, n, err := utils.DecodeVarInt(r.data[r.offset:])
+	if err != nil {
+		return 0, fmt.Errorf("解码%s数量失败: %v", field, err)
+	}
+	r.offset += n
+
+	if count > max {
+		return 0, fmt.Errorf("%s数量超过限制: %d > %d", field, count, max)
+	}
+	if minItemSize > 0 && count > uint64(r.remaining()/minItemSize) {
+		return 0, fmt.Errorf("%s数量与数据长度不符: %d", field, count)
+	}
+	return int(count), nil
+}
+
+// readVarString 读取VarInt长度前缀的字符串
+func (r *payloadReader) readVarString(maxLen uint64, field string) (string, error) {
+	length, err := r.readCount(maxLen, 1, field+"长度")
+	if err != nil {
+		return "", err
+	}
+	b, err := r.readBytes(length, field)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// finish 检查负载是否已全部读取
+func (r *payloadReader) finish() error {
+	if r.remaining() != 0 {
+		return fmt.Errorf("负载存在多余字节: 解析%d字节, 实际%d字节", r.offset, len(r.data))
+	}
+	return nil
+}
+
+// appendUint16BE 追加2字节大端序整数
+func appendUint16BE(buf []byte, v uint16) []byte {
+	return binary.BigEndian.AppendUint16(buf, v)
+}
+
+// appendUint32 追加4字节小端序整数
+func appendUint32(buf []byte, v uint32) []byte {
+	return binary.LittleEndian.AppendUint32(buf, v)
+}
+
+// appendUint64 追加8字节小端序整数
+func appendUint64(buf []byte, v uint64) []byte {
+	return binary.LittleEndian.AppendUint64(buf, v)
+}
+
+// appendVarString 追加VarInt长度前缀的字符串
+func appendVarString(buf []byte, s string) []byte {
+	buf = append(buf, utils.EncodeVarInt(uint64(len(s)))...)
+	return append(buf, s...)
+}
@@ -0,0 +1,56 @@
+package network
+
+import (
+	"container/list"
+	"sync"
+)
+
+// maxKnownInventory 每个节点记录的已知清单项数量上限
+const maxKnownInventory = 1000
+
+// knownInventory 对端已知清单项的滚动集合
+//
+// 记录对端发送过或本节点已向其通告过的区块和交易，避免重复通告和回传。
+// 达到容量上限后淘汰最早加入的清单项。
+type knownInventory struct {
+	mu    sync.Mutex
+	items map[InvVect]*list.Element
+	order *list.List // 按加入顺序排列，最早的在前
+	limit int
+}
+
+// newKnownInventory 创建指定容量的已知清单项集合
+func newKnownInventory(limit int) *knownInventory {
+	return &knownInventory{
+		items: make(map[InvVect]*list.Element),
+		order: list.New(),
+		limit: limit,
+	}
+}
+
+// Add 加入清单项，已存在时移到最新位置
+func (k *knownInventory) Add(iv InvVect) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if elem, ok := k.items[iv]; ok {
+		k.order.MoveToBack(elem)
+		return
+	}
+
+	if k.order.Len() >= k.limit {
+		oldest := k.order.Front()
+		delete(k.items, oldest.Value.(InvVect))
+		k.order.Remove(oldest)
+	}
+	k.items[iv] = k.order.PushBack(iv)
+}
+
+// Contains 判断清单项是否已知
+func (k *knownInventory) Contains(iv InvVect) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	_, ok := k.items[iv]
+	return ok
+}
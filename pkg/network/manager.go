@@ -0,0 +1,615 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+)
+
+// 连接管理默认值
+const (
+	// DefaultMaxOutbound 默认的出站连接数上限
+	DefaultMaxOutbound = 8
+
+	// maxAddrTries 每个心跳间隔从地址簿选取候选地址的最多次数
+	maxAddrTries = 30
+
+	// idleTimeoutMultiplier 空闲超时为心跳间隔的倍数
+	//
+	// 双方每个心跳间隔都会发送ping，超过三个间隔没有任何消息说明对端已失联。
+	idleTimeoutMultiplier = 3
+)
+
+var (
+	// ErrManagerStopped 连接管理器已停止
+	ErrManagerStopped = errors.New("连接管理器已停止")
+
+	// ErrOutboundSlotsFull 出站连接数已达上限
+	ErrOutboundSlotsFull = errors.New("出站连接数已达上限")
+
+	// ErrAlreadyConnected 已经与该地址建立或正在建立出站连接
+	ErrAlreadyConnected = errors.New("已连接到该地址")
+
+	// ErrBanned 地址已被封禁
+	ErrBanned = errors.New("地址已被封禁")
+)
+
+// AddressBook 地址簿，由addrmgr.AddrManager实现
+type AddressBook interface {
+	// AddAddresses 添加从src得知的一组地址
+	AddAddresses(addrs []*NetAddress, src *NetAddress)
+
+	// GetAddress 选择一个出站连接候选地址，没有地址时返回nil
+	GetAddress() *NetAddress
+
+	// Attempt 记录一次对该地址的连接尝试
+	Attempt(na *NetAddress)
+
+	// Good 记录与该地址成功建立连接
+	Good(na *NetAddress)
+
+	// AddressCache 返回用于向其他节点通告的地址列表
+	AddressCache() []*NetAddress
+}
+
+// BanList 封禁列表，由banman.BanManager实现
+type BanList interface {
+	// IsBanned 判断IP是否处于封禁期
+	IsBanned(ip net.IP) bool
+
+	// Ban 封禁IP指定时长
+	Ban(ip net.IP, duration time.Duration, reason string) error
+}
+
+// ManagerConfig 连接管理器配置
+type ManagerConfig struct {
+	Params     *blockchain.ChainParams // 网络参数，提供魔数
+	ListenAddr string                  // 监听地址，为空时不接受入站连接
+	Seeds      []string                // 启动后主动连接并在断开后重连的种子节点
+
+	// AddrBook 地址簿，为空时只连接种子节点
+	//
+	// 设置后从addr消息和种子节点学习地址，建立连接后向对端通告本节点
+	// 监听地址和已知地址，并用地址簿中的地址填充空闲的出站名额。
+	AddrBook AddressBook
+
+	// BanList 封禁列表，为空时不拒绝任何地址，不当行为分数达到阈值的节点只会被断开
+	BanList      BanList
+	BanThreshold uint32        // 封禁阈值，0表示DefaultBanThreshold
+	BanDuration  time.Duration // 封禁时长，0表示DefaultBanDuration
+
+	MaxInbound  int // 入站连接数上限
+	MaxOutbound int // 出站连接数上限
+
+	ConnectionTimeout time.Duration // 建立TCP连接、完成握手和写入单条消息的超时时间
+	HeartbeatInterval time.Duration // 发送ping、重连种子节点和补充出站连接的间隔
+
+	// IdleTimeout 未收到任何消息超过该时间时断开连接，0表示心跳间隔的3倍
+	IdleTimeout time.Duration
+
+	Services   ServiceFlag  // 本节点提供的服务
+	UserAgent  string       // 本节点用户代理
+	BestHeight func() int32 // 返回本节点主链高度
+
+	OnPeerConnected    func(p *Peer)              // 握手完成后、处理该连接的消息前调用
+	OnPeerDisconnected func(p *Peer)              // 已握手的连接断开后调用
+	OnMessage          func(p *Peer, msg Message) // 收到业务消息时调用
+}
+
+// PeerManager 节点连接管理器
+//
+// 负责监听入站连接、连接种子节点并在断开后按心跳间隔重连，配置地址簿时
+// 还从地址簿补充出站连接。对入站和出站连接分别执行数量限制。连接在握手完成前就占用名额，
+// 避免大量半开连接绕过限制。
+type PeerManager struct {
+	cfg      ManagerConfig
+	peerCfg  PeerConfig
+	listener net.Listener
+
+	mu       sync.Mutex
+	peers    map[*Peer]struct{}
+	inbound  int                 // 入站连接数（含握手中）
+	outbound int                 // 出站连接数（含握手中）
+	dialing  map[string]struct{} // 正在建立或已建立出站连接的地址
+	started  bool
+	stopped  bool
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewPeerManager 创建连接管理器
+func NewPeerManager(cfg ManagerConfig) (*PeerManager, error) {
+	if cfg.Params == nil {
+		return nil, errors.New("连接管理器配置缺少网络参数")
+	}
+	if cfg.MaxOutbound < 0 || cfg.MaxInbound < 0 {
+		return nil, fmt.Errorf("无效的连接数上限: 入站%d 出站%d", cfg.MaxInbound, cfg.MaxOutbound)
+	}
+
+	idleTimeout := cfg.IdleTimeout
+	if idleTimeout == 0 {
+		idleTimeout = cfg.HeartbeatInterval * idleTimeoutMultiplier
+	}
+
+	m := &PeerManager{
+		cfg: cfg,
+		peerCfg: PeerConfig{
+			Magic:            cfg.Params.Net,
+			Services:         cfg.Services,
+			UserAgent:        cfg.UserAgent,
+			Nonce:            randomUint64(),
+			BestHeight:       cfg.BestHeight,
+			HandshakeTimeout: cfg.ConnectionTimeout,
+			WriteTimeout:     cfg.ConnectionTimeout,
+			PingInterval:     cfg.HeartbeatInterval,
+			IdleTimeout:      idleTimeout,
+			BanThreshold:     cfg.BanThreshold,
+		},
+		peers:   make(map[*Peer]struct{}),
+		dialing: make(map[string]struct{}),
+		quit:    make(chan struct{}),
+	}
+	m.peerCfg.OnMessage = m.handleMessage
+	return m, nil
+}
+
+// Start 开始监听并连接种子节点和地址簿中的地址
+func (m *PeerManager) Start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.started {
+		return errors.New("连接管理器已启动")
+	}
+	if m.stopped {
+		return ErrManagerStopped
+	}
+
+	if m.cfg.ListenAddr != "" {
+		listener, err := net.Listen("tcp", m.cfg.ListenAddr)
+		if err != nil {
+			return fmt.Errorf("监听%s失败: %v", m.cfg.ListenAddr, err)
+		}
+		m.listener = listener
+
+		m.wg.Add(1)
+		go m.acceptLoop()
+	}
+
+	if book := m.cfg.AddrBook; book != nil {
+		for _, seed := range m.cfg.Seeds {
+			if na, err := NewNetAddressFromString(seed, 0); err == nil {
+				book.AddAddresses([]*NetAddress{na}, na)
+			}
+		}
+	}
+
+	if len(m.cfg.Seeds) > 0 || m.cfg.AddrBook != nil {
+		m.wg.Add(1)
+		go m.connectLoop()
+	}
+
+	m.started = true
+	return nil
+}
+
+// Stop 关闭监听并断开所有连接，等待所有协程退出
+func (m *PeerManager) Stop() {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return
+	}
+	m.stopped = true
+	close(m.quit)
+	if m.listener != nil {
+		m.listener.Close()
+	}
+	peers := make([]*Peer, 0, len(m.peers))
+	for p := range m.peers {
+		peers = append(peers, p)
+	}
+	m.mu.Unlock()
+
+	for _, p := range peers {
+		p.Disconnect()
+	}
+	m.wg.Wait()
+}
+
+// Addr 返回监听地址，未监听时返回nil
+func (m *PeerManager) Addr() net.Addr {
+	if m.listener == nil {
+		return nil
+	}
+	return m.listener.Addr()
+}
+
+// Peers 返回所有已完成握手的连接
+func (m *PeerManager) Peers() []*Peer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	peers := make([]*Peer, 0, len(m.peers))
+	for p := range m.peers {
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// ConnectedCount 返回已完成握手的连接数
+func (m *PeerManager) ConnectedCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.peers)
+}
+
+// Broadcast 向除except以外的所有连接发送消息
+func (m *PeerManager) Broadcast(msg Message, except *Peer) {
+	for _, p := range m.Peers() {
+		if p != except {
+			p.SendMessage(msg)
+		}
+	}
+}
+
+// Connect 主动连接指定地址并完成握手
+//
+// 出站名额已满、已连接到该地址或管理器已停止时返回错误。
+func (m *PeerManager) Connect(addr string) (*Peer, error) {
+	m.mu.Lock()
+	switch {
+	case m.stopped:
+		m.mu.Unlock()
+		return nil, ErrManagerStopped
+	case m.outbound >= m.cfg.MaxOutbound:
+		m.mu.Unlock()
+		return nil, ErrOutboundSlotsFull
+	}
+	if _, ok := m.dialing[addr]; ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyConnected, addr)
+	}
+	if m.isBannedAddr(addr) {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrBanned, addr)
+	}
+	m.outbound++
+	m.dialing[addr] = struct{}{}
+	m.wg.Add(1)
+	m.mu.Unlock()
+
+	release := func() {
+		m.mu.Lock()
+		m.outbound--
+		delete(m.dialing, addr)
+		m.mu.Unlock()
+		m.wg.Done()
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, m.cfg.ConnectionTimeout)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("连接%s失败: %v", addr, err)
+	}
+
+	p := NewOutboundPeer(conn, m.peerCfg)
+	if err := m.startPeer(p, release); err != nil {
+		return nil, fmt.Errorf("与%s握手失败: %w", addr, err)
+	}
+	return p, nil
+}
+
+// acceptLoop 接受入站连接
+func (m *PeerManager) acceptLoop() {
+	defer m.wg.Done()
+
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			select {
+			case <-m.quit:
+				return
+			default:
+			}
+			log.Printf("接受连接失败: %v", err)
+			continue
+		}
+
+		if m.isBannedAddr(conn.RemoteAddr().String()) {
+			log.Printf("拒绝已封禁地址%s的连接", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+
+		m.mu.Lock()
+		if m.stopped || m.inbound >= m.cfg.MaxInbound {
+			m.mu.Unlock()
+			conn.Close()
+			continue
+		}
+		m.inbound++
+		m.wg.Add(1)
+		m.mu.Unlock()
+
+		go func() {
+			release := func() {
+				m.mu.Lock()
+				m.inbound--
+				m.mu.Unlock()
+				m.wg.Done()
+			}
+			p := NewInboundPeer(conn, m.peerCfg)
+			if err := m.startPeer(p, release); err != nil {
+				log.Printf("与入站节点%s握手失败: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// startPeer 完成握手并登记连接
+//
+// 登记和OnPeerConnected回调在启动读取协程之前完成，
+// 保证上层先得知新连接，再收到该连接的业务消息。
+// release在连接结束（握手失败或断开）后调用，用于归还连接名额。
+func (m *PeerManager) startPeer(p *Peer, release func()) error {
+	if err := p.negotiate(); err != nil {
+		release()
+		return err
+	}
+
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		p.Disconnect()
+		p.WaitForDisconnect()
+		release()
+		return ErrManagerStopped
+	}
+	m.peers[p] = struct{}{}
+	m.mu.Unlock()
+
+	if m.cfg.OnPeerConnected != nil {
+		m.cfg.OnPeerConnected(p)
+	}
+	p.run()
+
+	if book := m.cfg.AddrBook; book != nil {
+		if !p.Inbound() {
+			na := p.NetAddress()
+			book.AddAddresses([]*NetAddress{na}, na)
+			book.Good(na)
+		}
+		m.pushAddresses(p)
+	}
+
+	go func() {
+		p.WaitForDisconnect()
+
+		m.mu.Lock()
+		delete(m.peers, p)
+		m.mu.Unlock()
+
+		if reason := p.DisconnectReason(); errors.Is(reason, ErrBanScoreExceeded) {
+			m.banPeer(p, reason)
+		}
+		if m.cfg.OnPeerDisconnected != nil {
+			m.cfg.OnPeerDisconnected(p)
+		}
+		release()
+	}()
+	return nil
+}
+
+// DisconnectIP 断开与该IP的所有连接，返回断开的连接数
+//
+// 通过RPC手动封禁地址后调用，使封禁对已建立的连接立即生效。
+func (m *PeerManager) DisconnectIP(ip net.IP) int {
+	var count int
+	for _, p := range m.Peers() {
+		if p.NetAddress().IP.Equal(ip) {
+			p.DisconnectWithReason(fmt.Errorf("%w: %s", ErrBanned, ip))
+			count++
+		}
+	}
+	return count
+}
+
+// banPeer 封禁不当行为分数达到阈值的节点IP
+func (m *PeerManager) banPeer(p *Peer, reason error) {
+	if m.cfg.BanList == nil {
+		return
+	}
+
+	duration := m.cfg.BanDuration
+	if duration == 0 {
+		duration = DefaultBanDuration
+	}
+	ip := p.NetAddress().IP
+	if err := m.cfg.BanList.Ban(ip, duration, reason.Error()); err != nil {
+		log.Printf("封禁节点%s失败: %v", p, err)
+		return
+	}
+	log.Printf("封禁%s %v: %v", ip, duration, reason)
+}
+
+// isBannedAddr 判断"host:port"格式的地址是否已被封禁
+func (m *PeerManager) isBannedAddr(addr string) bool {
+	if m.cfg.BanList == nil {
+		return false
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && m.cfg.BanList.IsBanned(ip)
+}
+
+// connectLoop 连接种子节点，并在每个心跳间隔重连已断开的种子节点、
+// 从地址簿补充出站连接
+func (m *PeerManager) connectLoop() {
+	defer m.wg.Done()
+
+	interval := m.cfg.HeartbeatInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.connectSeeds()
+		if m.cfg.AddrBook != nil {
+			m.connectAddresses()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// connectSeeds 并行连接当前未连接的种子节点
+func (m *PeerManager) connectSeeds() {
+	var wg sync.WaitGroup
+	for _, seed := range m.cfg.Seeds {
+		if m.isListenAddr(seed) {
+			continue
+		}
+
+		m.mu.Lock()
+		_, connected := m.dialing[seed]
+		full := m.outbound >= m.cfg.MaxOutbound
+		m.mu.Unlock()
+		if connected || full {
+			continue
+		}
+
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			if _, err := m.Connect(addr); err != nil && !errors.Is(err, ErrSelfConnection) {
+				log.Printf("连接种子节点失败: %v", err)
+			}
+		}(seed)
+	}
+	wg.Wait()
+}
+
+// connectAddresses 从地址簿选取候选地址，并行连接以填充空闲的出站名额
+func (m *PeerManager) connectAddresses() {
+	book := m.cfg.AddrBook
+
+	m.mu.Lock()
+	free := m.cfg.MaxOutbound - m.outbound
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	selected := make(map[string]struct{})
+	for tries := 0; free > 0 && tries < maxAddrTries; tries++ {
+		na := book.GetAddress()
+		if na == nil {
+			break
+		}
+		addr := na.String()
+		if _, ok := selected[addr]; ok {
+			continue
+		}
+
+		m.mu.Lock()
+		_, connected := m.dialing[addr]
+		m.mu.Unlock()
+		if connected || m.isListenAddr(addr) {
+			continue
+		}
+
+		selected[addr] = struct{}{}
+		book.Attempt(na)
+		free--
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Connect(addr); err != nil && !errors.Is(err, ErrSelfConnection) {
+				log.Printf("连接节点失败: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// handleMessage 将addr消息中的地址加入地址簿，并将所有消息交给OnMessage
+func (m *PeerManager) handleMessage(p *Peer, msg Message) {
+	if addrMsg, ok := msg.(*MsgAddr); ok && m.cfg.AddrBook != nil {
+		m.cfg.AddrBook.AddAddresses(addrMsg.AddrList, p.NetAddress())
+	}
+	if m.cfg.OnMessage != nil {
+		m.cfg.OnMessage(p, msg)
+	}
+}
+
+// pushAddresses 向新连接通告本节点监听地址和地址簿中的地址
+func (m *PeerManager) pushAddresses(p *Peer) {
+	msg := &MsgAddr{}
+	if local := m.localAddress(p); local != nil {
+		msg.AddAddress(local)
+	}
+	for _, na := range m.cfg.AddrBook.AddressCache() {
+		if msg.AddAddress(na) != nil {
+			break
+		}
+	}
+	if len(msg.AddrList) > 0 {
+		p.SendMessage(msg)
+	}
+}
+
+// localAddress 返回对端可以连接到本节点的地址，未监听时返回nil
+//
+// IP取该连接的本地IP，端口取监听端口。
+func (m *PeerManager) localAddress(p *Peer) *NetAddress {
+	if m.listener == nil {
+		return nil
+	}
+	listenAddr, ok := m.listener.Addr().(*net.TCPAddr)
+	if !ok {
+		return nil
+	}
+	connAddr, ok := p.conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return nil
+	}
+	return NewNetAddress(connAddr.IP, uint16(listenAddr.Port), m.cfg.Services)
+}
+
+// isListenAddr 判断地址是否指向本节点的本地监听端口
+//
+// 多个节点共用同一份种子列表时，种子中通常包含本节点自己，跳过以免反复自连接。
+func (m *PeerManager) isListenAddr(addr string) bool {
+	if m.listener == nil {
+		return false
+	}
+	listenAddr, ok := m.listener.Addr().(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || port != strconv.Itoa(listenAddr.Port) {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified() || ip.Equal(listenAddr.IP))
+}
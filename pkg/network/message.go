@@ -0,0 +1,232 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// Message P2P消息
+//
+// 实现只负责负载的编解码，消息头由ReadMessage和WriteMessage处理。
+type Message interface {
+	// Command 返回消息命令
+	Command() string
+
+	// Serialize 序列化消息负载
+	Serialize() []byte
+
+	// Deserialize 从负载反序列化消息，负载必须被完整消耗
+	Deserialize(payload []byte) error
+
+	// MaxPayloadLength 返回该类型消息负载的最大长度
+	MaxPayloadLength() uint32
+}
+
+// MessageHeader 消息头
+type MessageHeader struct {
+	Magic    uint32  // 网络魔数
+	Command  string  // 消息命令
+	Length   uint32  // 负载长度
+	Checksum [4]byte // 负载校验和
+}
+
+// Serialize 序列化消息头为24字节数组
+func (h *MessageHeader) Serialize() []byte {
+	buf := make([]byte, 0, MessageHeaderSize)
+	buf = appendUint32(buf, h.Magic)
+
+	var command [CommandSize]byte
+	copy(command[:], h.Command)
+	buf = append(buf, command[:]...)
+
+	buf = appendUint32(buf, h.Length)
+	return append(buf, h.Checksum[:]...)
+}
+
+// Deserialize 从24字节数组反序列化消息头
+//
+// 命令字段必须由可打印ASCII字符组成，右侧以0填充。
+func (h *MessageHeader) Deserialize(data []byte) error {
+	if len(data) != MessageHeaderSize {
+		return fmt.Errorf("消息头长度错误: 期望%d字节, 实际%d字节", MessageHeaderSize, len(data))
+	}
+
+	h.Magic = binary.LittleEndian.Uint32(data[0:4])
+
+	command := data[4 : 4+CommandSize]
+	end := bytes.IndexByte(command, 0)
+	if end < 0 {
+		end = CommandSize
+	}
+	for i, c := range command {
+		if i < end && (c < 0x20 || c > 0x7e) {
+			return fmt.Errorf("消息命令包含非法字符: %x", command)
+		}
+		if i >= end && c != 0 {
+			return fmt.Errorf("消息命令填充字节必须为0: %x", command)
+		}
+	}
+	h.Command = string(command[:end])
+
+	h.Length = binary.LittleEndian.Uint32(data[16:20])
+	copy(h.Checksum[:], data[20:24])
+	return nil
+}
+
+// makeEmptyMessage 根据命令创建空消息
+func makeEmptyMessage(command string) (Message, error) {
+	switch command {
+	case CmdVersion:
+		return &MsgVersion{}, nil
+	case CmdVerAck:
+		return &MsgVerAck{}, nil
+	case CmdPing:
+		return &MsgPing{}, nil
+	case CmdPong:
+		return &MsgPong{}, nil
+	case CmdInv:
+		return &MsgInv{}, nil
+	case CmdGetData:
+		return &MsgGetData{}, nil
+	case CmdNotFound:
+		return &MsgNotFound{}, nil
+	case CmdGetHeaders:
+		return &MsgGetHeaders{}, nil
+	case CmdHeaders:
+		return &MsgHeaders{}, nil
+	case CmdBlock:
+		return &MsgBlock{}, nil
+	case CmdTx:
+		return &MsgTx{}, nil
+	case CmdAddr:
+		return &MsgAddr{}, nil
+	case CmdReject:
+		return &MsgReject{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCommand, command)
+	}
+}
+
+// DecodePayload 根据命令解码不含消息头的负载
+//
+// 不检查魔数和校验和，供消息编解码的独立测试使用。
+func DecodePayload(command string, payload []byte) (Message, error) {
+	msg, err := makeEmptyMessage(command)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(payload)) > uint64(msg.MaxPayloadLength()) {
+		return nil, fmt.Errorf("%w: %s消息%d字节", ErrPayloadTooLarge, command, len(payload))
+	}
+	if err := msg.Deserialize(payload); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrMalformedMessage, command, err)
+	}
+	return msg, nil
+}
+
+// EncodeMessage 将消息编码为完整的网络帧
+//
+// 参数：
+// - msg: 待编码的消息
+// - magic: 网络魔数
+func EncodeMessage(msg Message, magic uint32) ([]byte, error) {
+	command := msg.Command()
+	if len(command) > CommandSize {
+		return nil, fmt.Errorf("消息命令过长: %s", command)
+	}
+
+	payload := msg.Serialize()
+	if len(payload) > MaxMessagePayload || uint32(len(payload)) > msg.MaxPayloadLength() {
+		return nil, fmt.Errorf("%w: %s消息%d字节", ErrPayloadTooLarge, command, len(payload))
+	}
+
+	header := MessageHeader{
+		Magic:   magic,
+		Command: command,
+		Length:  uint32(len(payload)),
+	}
+	copy(header.Checksum[:], utils.Checksum(payload))
+
+	return append(header.Serialize(), payload...), nil
+}
+
+// WriteMessage 编码消息并写入w
+func WriteMessage(w io.Writer, msg Message, magic uint32) error {
+	frame, err := EncodeMessage(msg, magic)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(frame)
+	return err
+}
+
+// ReadMessage 从r读取并解码一条消息
+//
+// 依次检查魔数、负载长度上限和校验和，全部通过后才解码负载。
+// 负载长度在读取负载之前检查，超长的消息不会被读入内存。
+// 返回的错误可以用errors.Is与ErrMalformedHeader、ErrWrongNetwork、ErrPayloadTooLarge、
+// ErrChecksumMismatch、ErrUnknownCommand和ErrMalformedMessage比较；
+// 后三种错误发生时负载已被完整读取，调用方可以忽略该消息继续读取，
+// 其余错误发生时负载尚未读取，调用方必须放弃该数据流。
+//
+// 参数：
+// - r: 数据来源，通常为网络连接
+// - magic: 本节点的网络魔数
+func ReadMessage(r io.Reader, magic uint32) (Message, error) {
+	var headerBytes [MessageHeaderSize]byte
+	if _, err := io.ReadFull(r, headerBytes[:]); err != nil {
+		return nil, err
+	}
+
+	var header MessageHeader
+	if err := header.Deserialize(headerBytes[:]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedHeader, err)
+	}
+	if header.Magic != magic {
+		return nil, fmt.Errorf("%w: 0x%08x", ErrWrongNetwork, header.Magic)
+	}
+	if header.Length > MaxMessagePayload {
+		return nil, fmt.Errorf("%w: %s消息%d字节", ErrPayloadTooLarge, header.Command, header.Length)
+	}
+
+	msg, msgErr := makeEmptyMessage(header.Command)
+	if msgErr == nil && header.Length > msg.MaxPayloadLength() {
+		return nil, fmt.Errorf("%w: %s消息%d字节 > %d", ErrPayloadTooLarge, header.Command,
+			header.Length, msg.MaxPayloadLength())
+	}
+
+	payload := make([]byte, header.Length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if !utils.VerifyChecksum(payload, header.Checksum[:]) {
+		return nil, fmt.Errorf("%w: %s消息", ErrChecksumMismatch, header.Command)
+	}
+	if msgErr != nil {
+		return nil, msgErr
+	}
+
+	if err := msg.Deserialize(payload); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrMalformedMessage, header.Command, err)
+	}
+	return msg, nil
+}
+
+// DecodeMessage 从完整的网络帧解码消息
+//
+// 帧必须恰好包含一条消息。
+func DecodeMessage(frame []byte, magic uint32) (Message, error) {
+	r := bytes.NewReader(frame)
+	msg, err := ReadMessage(r, magic)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%w: 帧存在%d字节多余数据", ErrMalformedMessage, r.Len())
+	}
+	return msg, nil
+}
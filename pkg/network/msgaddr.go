@@ -0,0 +1,157 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// netAddressSize 不含时间戳的网络地址编码长度
+// Services(8) + IP(16) + Port(2)
+const netAddressSize = 26
+
+// NetAddress 节点网络地址
+//
+// IPv4地址以IPv4映射的IPv6形式编码为16字节，端口使用大端序。
+type NetAddress struct {
+	Timestamp time.Time   // 最后一次得知该地址活跃的时间，仅在addr消息中编码
+	Services  ServiceFlag // 节点提供的服务
+	IP        net.IP      // IP地址
+	Port      uint16      // 端口
+}
+
+// NewNetAddress 创建网络地址
+func NewNetAddress(ip net.IP, port uint16, services ServiceFlag) *NetAddress {
+	return &NetAddress{
+		Timestamp: time.Unix(time.Now().Unix(), 0),
+		Services:  services,
+		IP:        ip,
+		Port:      port,
+	}
+}
+
+// NewNetAddressFromString 解析"host:port"格式的地址
+//
+// host必须是IP地址，不进行DNS解析。
+func NewNetAddressFromString(addr string, services ServiceFlag) (*NetAddress, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("解析地址%s失败: %v", addr, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("地址%s不是IP地址", addr)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("解析端口%s失败: %v", portStr, err)
+	}
+	return NewNetAddress(ip, uint16(port), services), nil
+}
+
+// String 返回"host:port"格式的地址
+func (na *NetAddress) String() string {
+	return net.JoinHostPort(na.IP.String(), strconv.Itoa(int(na.Port)))
+}
+
+// appendNetAddress 追加网络地址编码
+//
+// withTimestamp为true时先写入4字节时间戳（addr消息格式）。
+func appendNetAddress(buf []byte, na *NetAddress, withTimestamp bool) []byte {
+	if withTimestamp {
+		buf = appendUint32(buf, uint32(na.Timestamp.Unix()))
+	}
+	buf = appendUint64(buf, uint64(na.Services))
+
+	var ip [16]byte
+	if ip16 := na.IP.To16(); ip16 != nil {
+		copy(ip[:], ip16)
+	}
+	buf = append(buf, ip[:]...)
+
+	return appendUint16BE(buf, na.Port)
+}
+
+// readNetAddress 读取网络地址
+func readNetAddress(r *payloadReader, withTimestamp bool) (*NetAddress, error) {
+	na := &NetAddress{}
+	if withTimestamp {
+		ts, err := r.readUint32("地址时间戳")
+		if err != nil {
+			return nil, err
+		}
+		na.Timestamp = time.Unix(int64(ts), 0)
+	}
+
+	services, err := r.readUint64("地址服务标识")
+	if err != nil {
+		return nil, err
+	}
+	na.Services = ServiceFlag(services)
+
+	ip, err := r.readBytes(16, "IP地址")
+	if err != nil {
+		return nil, err
+	}
+	na.IP = make(net.IP, 16)
+	copy(na.IP, ip)
+
+	if na.Port, err = r.readUint16BE("端口"); err != nil {
+		return nil, err
+	}
+	return na, nil
+}
+
+// MsgAddr addr消息，向对端通告已知的节点地址
+type MsgAddr struct {
+	AddrList []*NetAddress
+}
+
+// AddAddress 添加地址，超过MaxAddrPerMsg时返回错误
+func (m *MsgAddr) AddAddress(na *NetAddress) error {
+	if len(m.AddrList) >= MaxAddrPerMsg {
+		return fmt.Errorf("addr消息地址数量超过限制: %d", MaxAddrPerMsg)
+	}
+	m.AddrList = append(m.AddrList, na)
+	return nil
+}
+
+// Command 返回消息命令
+func (m *MsgAddr) Command() string {
+	return CmdAddr
+}
+
+// Serialize 序列化消息负载
+func (m *MsgAddr) Serialize() []byte {
+	buf := make([]byte, 0, utils.VarIntSize(uint64(len(m.AddrList)))+len(m.AddrList)*(4+netAddressSize))
+	buf = append(buf, utils.EncodeVarInt(uint64(len(m.AddrList)))...)
+	for _, na := range m.AddrList {
+		buf = appendNetAddress(buf, na, true)
+	}
+	return buf
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgAddr) Deserialize(payload []byte) error {
+	r := newPayloadReader(payload)
+	count, err := r.readCount(MaxAddrPerMsg, 4+netAddressSize, "地址")
+	if err != nil {
+		return err
+	}
+
+	m.AddrList = make([]*NetAddress, count)
+	for i := range m.AddrList {
+		if m.AddrList[i], err = readNetAddress(r, true); err != nil {
+			return err
+		}
+	}
+	return r.finish()
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgAddr) MaxPayloadLength() uint32 {
+	return 9 + MaxAddrPerMsg*(4+netAddressSize)
+}
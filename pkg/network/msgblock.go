@@ -0,0 +1,66 @@
+package network
+
+import (
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// MsgBlock block消息，传输完整区块
+type MsgBlock struct {
+	Block *blockchain.Block
+}
+
+// Command 返回消息命令
+func (m *MsgBlock) Command() string {
+	return CmdBlock
+}
+
+// Serialize 序列化消息负载
+func (m *MsgBlock) Serialize() []byte {
+	return m.Block.Serialize()
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgBlock) Deserialize(payload []byte) error {
+	block := &blockchain.Block{}
+	if err := block.Deserialize(payload); err != nil {
+		return err
+	}
+	m.Block = block
+	return nil
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgBlock) MaxPayloadLength() uint32 {
+	return blockchain.MaxBlockSize
+}
+
+// MsgTx tx消息，传输单笔交易
+type MsgTx struct {
+	Tx *blockchain.Transaction
+}
+
+// Command 返回消息命令
+func (m *MsgTx) Command() string {
+	return CmdTx
+}
+
+// Serialize 序列化消息负载
+func (m *MsgTx) Serialize() []byte {
+	return m.Tx.Serialize()
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgTx) Deserialize(payload []byte) error {
+	tx := &blockchain.Transaction{}
+	if err := tx.Deserialize(payload); err != nil {
+		return err
+	}
+	m.Tx = tx
+	return nil
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgTx) MaxPayloadLength() uint32 {
+	return utils.MaxTransactionSize
+}
@@ -0,0 +1,145 @@
+package network
+
+import (
+	"fmt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// MsgGetHeaders getheaders消息，请求从定位点之后开始的区块头
+//
+// BlockLocatorHashes从请求方主链末端开始、间隔逐渐增大地回溯到创世区块，
+// 对端从其中第一个位于自身主链上的区块之后开始返回区块头，
+// 直到HashStop（全零表示尽可能多）或达到MaxBlockHeadersPerMsg。
+type MsgGetHeaders struct {
+	ProtocolVersion    uint32
+	BlockLocatorHashes [][32]byte
+	HashStop           [32]byte
+}
+
+// AddBlockLocatorHash 添加区块定位哈希
+func (m *MsgGetHeaders) AddBlockLocatorHash(hash [32]byte) error {
+	if len(m.BlockLocatorHashes) >= MaxBlockLocatorsPerMsg {
+		return fmt.Errorf("getheaders消息定位哈希数量超过限制: %d", MaxBlockLocatorsPerMsg)
+	}
+	m.BlockLocatorHashes = append(m.BlockLocatorHashes, hash)
+	return nil
+}
+
+// Command 返回消息命令
+func (m *MsgGetHeaders) Command() string {
+	return CmdGetHeaders
+}
+
+// Serialize 序列化消息负载
+func (m *MsgGetHeaders) Serialize() []byte {
+	count := uint64(len(m.BlockLocatorHashes))
+	buf := make([]byte, 0, 4+utils.VarIntSize(count)+(len(m.BlockLocatorHashes)+1)*32)
+	buf = appendUint32(buf, m.ProtocolVersion)
+	buf = append(buf, utils.EncodeVarInt(count)...)
+	for _, hash := range m.BlockLocatorHashes {
+		buf = append(buf, hash[:]...)
+	}
+	return append(buf, m.HashStop[:]...)
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgGetHeaders) Deserialize(payload []byte) error {
+	r := newPayloadReader(payload)
+
+	var err error
+	if m.ProtocolVersion, err = r.readUint32("协议版本"); err != nil {
+		return err
+	}
+
+	count, err := r.readCount(MaxBlockLocatorsPerMsg, 32, "定位哈希")
+	if err != nil {
+		return err
+	}
+	m.BlockLocatorHashes = make([][32]byte, count)
+	for i := range m.BlockLocatorHashes {
+		if m.BlockLocatorHashes[i], err = r.readHash("定位哈希"); err != nil {
+			return err
+		}
+	}
+
+	if m.HashStop, err = r.readHash("停止哈希"); err != nil {
+		return err
+	}
+	return r.finish()
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgGetHeaders) MaxPayloadLength() uint32 {
+	return 4 + 9 + (MaxBlockLocatorsPerMsg+1)*32
+}
+
+// MsgHeaders headers消息，回复getheaders消息
+//
+// 每个区块头后跟一个值为0的交易数量字段，与block消息中的区块头格式保持一致。
+type MsgHeaders struct {
+	Headers []*blockchain.BlockHeader
+}
+
+// AddBlockHeader 添加区块头
+func (m *MsgHeaders) AddBlockHeader(header *blockchain.BlockHeader) error {
+	if len(m.Headers) >= MaxBlockHeadersPerMsg {
+		return fmt.Errorf("headers消息区块头数量超过限制: %d", MaxBlockHeadersPerMsg)
+	}
+	m.Headers = append(m.Headers, header)
+	return nil
+}
+
+// Command 返回消息命令
+func (m *MsgHeaders) Command() string {
+	return CmdHeaders
+}
+
+// Serialize 序列化消息负载
+func (m *MsgHeaders) Serialize() []byte {
+	count := uint64(len(m.Headers))
+	buf := make([]byte, 0, utils.VarIntSize(count)+len(m.Headers)*(blockchain.BlockHeaderSize+1))
+	buf = append(buf, utils.EncodeVarInt(count)...)
+	for _, header := range m.Headers {
+		buf = append(buf, header.Serialize()...)
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgHeaders) Deserialize(payload []byte) error {
+	r := newPayloadReader(payload)
+	count, err := r.readCount(MaxBlockHeadersPerMsg, blockchain.BlockHeaderSize+1, "区块头")
+	if err != nil {
+		return err
+	}
+
+	m.Headers = make([]*blockchain.BlockHeader, count)
+	for i := range m.Headers {
+		data, err := r.readBytes(blockchain.BlockHeaderSize, "区块头")
+		if err != nil {
+			return err
+		}
+		header := &blockchain.BlockHeader{}
+		if err := header.Deserialize(data); err != nil {
+			return err
+		}
+
+		txCount, err := r.readUint8("交易数量")
+		if err != nil {
+			return err
+		}
+		if txCount != 0 {
+			return fmt.Errorf("headers消息中区块头%d的交易数量必须为0, 实际%d", i, txCount)
+		}
+		m.Headers[i] = header
+	}
+	return r.finish()
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgHeaders) MaxPayloadLength() uint32 {
+	return 9 + MaxBlockHeadersPerMsg*(blockchain.BlockHeaderSize+1)
+}
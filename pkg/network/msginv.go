@@ -0,0 +1,203 @@
+package network
+
+import (
+	"fmt"
+
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// invVectSize 清单项的编码长度
+// Type(4) + Hash(32)
+const invVectSize = 36
+
+// InvType 清单项类型
+type InvType uint32
+
+const (
+	// InvTypeError 无效类型
+	InvTypeError InvType = 0
+
+	// InvTypeTx 交易
+	InvTypeTx InvType = 1
+
+	// InvTypeBlock 区块
+	InvTypeBlock InvType = 2
+)
+
+// String 返回清单项类型名称
+func (t InvType) String() string {
+	switch t {
+	case InvTypeError:
+		return "ERROR"
+	case InvTypeTx:
+		return "MSG_TX"
+	case InvTypeBlock:
+		return "MSG_BLOCK"
+	default:
+		return fmt.Sprintf("Unknown InvType (%d)", uint32(t))
+	}
+}
+
+// InvVect 清单项，标识一个区块或交易
+type InvVect struct {
+	Type InvType  // 对象类型
+	Hash [32]byte // 对象哈希
+}
+
+// NewInvVect 创建清单项
+func NewInvVect(typ InvType, hash [32]byte) *InvVect {
+	return &InvVect{Type: typ, Hash: hash}
+}
+
+// serializeInvList 序列化清单列表
+func serializeInvList(list []*InvVect) []byte {
+	buf := make([]byte, 0, utils.VarIntSize(uint64(len(list)))+len(list)*invVectSize)
+	buf = append(buf, utils.EncodeVarInt(uint64(len(list)))...)
+	for _, iv := range list {
+		buf = appendUint32(buf, uint32(iv.Type))
+		buf = append(buf, iv.Hash[:]...)
+	}
+	return buf
+}
+
+// deserializeInvList 反序列化清单列表
+func deserializeInvList(payload []byte) ([]*InvVect, error) {
+	r := newPayloadReader(payload)
+	count, err := r.readCount(MaxInvPerMsg, invVectSize, "清单项")
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*InvVect, count)
+	for i := range list {
+		typ, err := r.readUint32("清单项类型")
+		if err != nil {
+			return nil, err
+		}
+		hash, err := r.readHash("清单项哈希")
+		if err != nil {
+			return nil, err
+		}
+		list[i] = &InvVect{Type: InvType(typ), Hash: hash}
+	}
+	return list, r.finish()
+}
+
+// addInvVect 添加清单项，超过MaxInvPerMsg时返回错误
+func addInvVect(list []*InvVect, iv *InvVect, command string) ([]*InvVect, error) {
+	if len(list) >= MaxInvPerMsg {
+		return list, fmt.Errorf("%s消息清单项数量超过限制: %d", command, MaxInvPerMsg)
+	}
+	return append(list, iv), nil
+}
+
+// MsgInv inv消息，通告本节点拥有的区块或交易
+type MsgInv struct {
+	InvList []*InvVect
+}
+
+// AddInvVect 添加清单项
+func (m *MsgInv) AddInvVect(iv *InvVect) error {
+	var err error
+	m.InvList, err = addInvVect(m.InvList, iv, CmdInv)
+	return err
+}
+
+// Command 返回消息命令
+func (m *MsgInv) Command() string {
+	return CmdInv
+}
+
+// Serialize 序列化消息负载
+func (m *MsgInv) Serialize() []byte {
+	return serializeInvList(m.InvList)
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgInv) Deserialize(payload []byte) error {
+	list, err := deserializeInvList(payload)
+	if err != nil {
+		return err
+	}
+	m.InvList = list
+	return nil
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgInv) MaxPayloadLength() uint32 {
+	return 9 + MaxInvPerMsg*invVectSize
+}
+
+// MsgGetData getdata消息，请求对端发送指定的区块或交易
+type MsgGetData struct {
+	InvList []*InvVect
+}
+
+// AddInvVect 添加清单项
+func (m *MsgGetData) AddInvVect(iv *InvVect) error {
+	var err error
+	m.InvList, err = addInvVect(m.InvList, iv, CmdGetData)
+	return err
+}
+
+// Command 返回消息命令
+func (m *MsgGetData) Command() string {
+	return CmdGetData
+}
+
+// Serialize 序列化消息负载
+func (m *MsgGetData) Serialize() []byte {
+	return serializeInvList(m.InvList)
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgGetData) Deserialize(payload []byte) error {
+	list, err := deserializeInvList(payload)
+	if err != nil {
+		return err
+	}
+	m.InvList = list
+	return nil
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgGetData) MaxPayloadLength() uint32 {
+	return 9 + MaxInvPerMsg*invVectSize
+}
+
+// MsgNotFound notfound消息，回应getdata中本节点没有的区块或交易
+type MsgNotFound struct {
+	InvList []*InvVect
+}
+
+// AddInvVect 添加清单项
+func (m *MsgNotFound) AddInvVect(iv *InvVect) error {
+	var err error
+	m.InvList, err = addInvVect(m.InvList, iv, CmdNotFound)
+	return err
+}
+
+// Command 返回消息命令
+func (m *MsgNotFound) Command() string {
+	return CmdNotFound
+}
+
+// Serialize 序列化消息负载
+func (m *MsgNotFound) Serialize() []byte {
+	return serializeInvList(m.InvList)
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgNotFound) Deserialize(payload []byte) error {
+	list, err := deserializeInvList(payload)
+	if err != nil {
+		return err
+	}
+	m.InvList = list
+	return nil
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgNotFound) MaxPayloadLength() uint32 {
+	return 9 + MaxInvPerMsg*invVectSize
+}
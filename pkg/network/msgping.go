@@ -0,0 +1,63 @@
+package network
+
+// MsgPing ping消息，用于检测连接是否存活
+//
+// 对端收到后以相同Nonce的pong消息回复。
+type MsgPing struct {
+	Nonce uint64
+}
+
+// Command 返回消息命令
+func (m *MsgPing) Command() string {
+	return CmdPing
+}
+
+// Serialize 序列化消息负载
+func (m *MsgPing) Serialize() []byte {
+	return appendUint64(make([]byte, 0, 8), m.Nonce)
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgPing) Deserialize(payload []byte) error {
+	r := newPayloadReader(payload)
+	var err error
+	if m.Nonce, err = r.readUint64("Nonce"); err != nil {
+		return err
+	}
+	return r.finish()
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgPing) MaxPayloadLength() uint32 {
+	return 8
+}
+
+// MsgPong pong消息，回复ping消息
+type MsgPong struct {
+	Nonce uint64
+}
+
+// Command 返回消息命令
+func (m *MsgPong) Command() string {
+	return CmdPong
+}
+
+// Serialize 序列化消息负载
+func (m *MsgPong) Serialize() []byte {
+	return appendUint64(make([]byte, 0, 8), m.Nonce)
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgPong) Deserialize(payload []byte) error {
+	r := newPayloadReader(payload)
+	var err error
+	if m.Nonce, err = r.readUint64("Nonce"); err != nil {
+		return err
+	}
+	return r.finish()
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgPong) MaxPayloadLength() uint32 {
+	return 8
+}
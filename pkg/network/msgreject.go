@@ -0,0 +1,121 @@
+package network
+
+import (
+	"fmt"
+)
+
+// RejectCode 拒绝原因代码
+type RejectCode uint8
+
+const (
+	RejectMalformed       RejectCode = 0x01 // 消息格式错误
+	RejectInvalid         RejectCode = 0x10 // 区块或交易无效
+	RejectObsolete        RejectCode = 0x11 // 协议版本过旧
+	RejectDuplicate       RejectCode = 0x12 // 重复的区块或交易
+	RejectNonstandard     RejectCode = 0x40 // 非标准交易
+	RejectDust            RejectCode = 0x41 // 输出金额低于灰尘阈值
+	RejectInsufficientFee RejectCode = 0x42 // 手续费不足
+	RejectCheckpoint      RejectCode = 0x43 // 与检查点冲突
+)
+
+// String 返回拒绝代码名称
+func (code RejectCode) String() string {
+	switch code {
+	case RejectMalformed:
+		return "REJECT_MALFORMED"
+	case RejectInvalid:
+		return "REJECT_INVALID"
+	case RejectObsolete:
+		return "REJECT_OBSOLETE"
+	case RejectDuplicate:
+		return "REJECT_DUPLICATE"
+	case RejectNonstandard:
+		return "REJECT_NONSTANDARD"
+	case RejectDust:
+		return "REJECT_DUST"
+	case RejectInsufficientFee:
+		return "REJECT_INSUFFICIENTFEE"
+	case RejectCheckpoint:
+		return "REJECT_CHECKPOINT"
+	default:
+		return fmt.Sprintf("Unknown RejectCode (%d)", uint8(code))
+	}
+}
+
+// MsgReject reject消息，通知对端其发送的消息被拒绝
+//
+// 被拒绝的是block或tx消息时，Hash为对应区块或交易的哈希，其他情况下不编码Hash。
+type MsgReject struct {
+	Cmd    string     // 被拒绝消息的命令
+	Code   RejectCode // 拒绝原因代码
+	Reason string     // 可读的拒绝原因
+	Hash   [32]byte   // 被拒绝的区块或交易哈希
+}
+
+// NewMsgReject 创建reject消息
+func NewMsgReject(command string, code RejectCode, reason string) *MsgReject {
+	return &MsgReject{Cmd: command, Code: code, Reason: reason}
+}
+
+// hasHash 判断被拒绝的消息是否携带哈希
+func (m *MsgReject) hasHash() bool {
+	return m.Cmd == CmdBlock || m.Cmd == CmdTx
+}
+
+// Command 返回消息命令
+func (m *MsgReject) Command() string {
+	return CmdReject
+}
+
+// Serialize 序列化消息负载
+func (m *MsgReject) Serialize() []byte {
+	buf := make([]byte, 0, 1+len(m.Cmd)+1+1+len(m.Reason)+32)
+	buf = appendVarString(buf, m.Cmd)
+	buf = append(buf, byte(m.Code))
+	buf = appendVarString(buf, m.Reason)
+	if m.hasHash() {
+		buf = append(buf, m.Hash[:]...)
+	}
+	return buf
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgReject) Deserialize(payload []byte) error {
+	r := newPayloadReader(payload)
+
+	var err error
+	if m.Cmd, err = r.readVarString(CommandSize, "被拒绝的命令"); err != nil {
+		return err
+	}
+
+	code, err := r.readUint8("拒绝代码")
+	if err != nil {
+		return err
+	}
+	m.Code = RejectCode(code)
+
+	if m.Reason, err = r.readVarString(MaxRejectReasonLen, "拒绝原因"); err != nil {
+		return err
+	}
+
+	m.Hash = [32]byte{}
+	if m.hasHash() {
+		if m.Hash, err = r.readHash("被拒绝对象哈希"); err != nil {
+			return err
+		}
+	}
+	return r.finish()
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgReject) MaxPayloadLength() uint32 {
+	return 1 + CommandSize + 1 + 9 + MaxRejectReasonLen + 32
+}
+
+// String 返回reject消息的可读表示
+func (m *MsgReject) String() string {
+	if m.hasHash() {
+		return fmt.Sprintf("reject %s %s: %s (%x)", m.Cmd, m.Code, m.Reason, m.Hash)
+	}
+	return fmt.Sprintf("reject %s %s: %s", m.Cmd, m.Code, m.Reason)
+}
@@ -0,0 +1,128 @@
+package network
+
+import (
+	"time"
+)
+
+// MsgVersion version消息，建立连接后双方首先交换
+//
+// 对端通过魔数、协议版本和Nonce判断是否接受连接，
+// Nonce用于识别连接到自己的情况。
+type MsgVersion struct {
+	ProtocolVersion uint32      // 协议版本
+	Services        ServiceFlag // 本节点提供的服务
+	Timestamp       time.Time   // 本节点当前时间
+	AddrRecv        NetAddress  // 对端地址
+	AddrFrom        NetAddress  // 本节点地址
+	Nonce           uint64      // 随机数，用于检测自连接
+	UserAgent       string      // 用户代理
+	StartHeight     int32       // 本节点主链高度
+}
+
+// NewMsgVersion 创建使用当前协议版本的version消息
+func NewMsgVersion(me, you *NetAddress, nonce uint64, startHeight int32) *MsgVersion {
+	return &MsgVersion{
+		ProtocolVersion: ProtocolVersion,
+		Services:        me.Services,
+		Timestamp:       time.Unix(time.Now().Unix(), 0),
+		AddrRecv:        *you,
+		AddrFrom:        *me,
+		Nonce:           nonce,
+		StartHeight:     startHeight,
+	}
+}
+
+// Command 返回消息命令
+func (m *MsgVersion) Command() string {
+	return CmdVersion
+}
+
+// Serialize 序列化消息负载
+func (m *MsgVersion) Serialize() []byte {
+	buf := make([]byte, 0, 4+8+8+2*netAddressSize+8+1+len(m.UserAgent)+4)
+	buf = appendUint32(buf, m.ProtocolVersion)
+	buf = appendUint64(buf, uint64(m.Services))
+	buf = appendUint64(buf, uint64(m.Timestamp.Unix()))
+	buf = appendNetAddress(buf, &m.AddrRecv, false)
+	buf = appendNetAddress(buf, &m.AddrFrom, false)
+	buf = appendUint64(buf, m.Nonce)
+	buf = appendVarString(buf, m.UserAgent)
+	return appendUint32(buf, uint32(m.StartHeight))
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgVersion) Deserialize(payload []byte) error {
+	r := newPayloadReader(payload)
+
+	var err error
+	if m.ProtocolVersion, err = r.readUint32("协议版本"); err != nil {
+		return err
+	}
+
+	services, err := r.readUint64("服务标识")
+	if err != nil {
+		return err
+	}
+	m.Services = ServiceFlag(services)
+
+	timestamp, err := r.readUint64("时间戳")
+	if err != nil {
+		return err
+	}
+	m.Timestamp = time.Unix(int64(timestamp), 0)
+
+	addrRecv, err := readNetAddress(r, false)
+	if err != nil {
+		return err
+	}
+	m.AddrRecv = *addrRecv
+
+	addrFrom, err := readNetAddress(r, false)
+	if err != nil {
+		return err
+	}
+	m.AddrFrom = *addrFrom
+
+	if m.Nonce, err = r.readUint64("Nonce"); err != nil {
+		return err
+	}
+	if m.UserAgent, err = r.readVarString(MaxUserAgentLen, "用户代理"); err != nil {
+		return err
+	}
+
+	startHeight, err := r.readUint32("起始高度")
+	if err != nil {
+		return err
+	}
+	m.StartHeight = int32(startHeight)
+
+	return r.finish()
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgVersion) MaxPayloadLength() uint32 {
+	return 4 + 8 + 8 + 2*netAddressSize + 8 + 9 + MaxUserAgentLen + 4
+}
+
+// MsgVerAck verack消息，确认收到对端的version消息，负载为空
+type MsgVerAck struct{}
+
+// Command 返回消息命令
+func (m *MsgVerAck) Command() string {
+	return CmdVerAck
+}
+
+// Serialize 序列化消息负载
+func (m *MsgVerAck) Serialize() []byte {
+	return nil
+}
+
+// Deserialize 从负载反序列化消息
+func (m *MsgVerAck) Deserialize(payload []byte) error {
+	return newPayloadReader(payload).finish()
+}
+
+// MaxPayloadLength 返回负载最大长度
+func (m *MsgVerAck) MaxPayloadLength() uint32 {
+	return 0
+}
@@ -0,0 +1,617 @@
+package network
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// outQueueSize 每个节点待发送消息队列的长度
+const outQueueSize = 256
+
+// 不当行为分数
+const (
+	// DefaultBanThreshold 默认的封禁阈值，不当行为分数累计达到该值时断开连接
+	DefaultBanThreshold = 100
+
+	// DefaultBanDuration 默认的封禁时长
+	DefaultBanDuration = 24 * time.Hour
+
+	// BanScoreMalformedMessage 发送格式错误或校验和错误的消息
+	BanScoreMalformedMessage = 20
+)
+
+var (
+	// ErrSelfConnection 连接到了本节点自身
+	ErrSelfConnection = errors.New("连接到了本节点自身")
+
+	// ErrObsoleteVersion 对端协议版本过低
+	ErrObsoleteVersion = errors.New("对端协议版本过低")
+
+	// ErrHandshake 握手阶段收到不符合协议的消息
+	ErrHandshake = errors.New("握手失败")
+
+	// ErrPeerIdle 对端长时间未发送任何消息
+	ErrPeerIdle = errors.New("对端长时间未发送消息")
+
+	// ErrBanScoreExceeded 对端的不当行为分数达到封禁阈值
+	ErrBanScoreExceeded = errors.New("不当行为分数达到封禁阈值")
+
+	// ErrSendQueueFull 对端长时间不读取数据，待发送消息队列已满
+	ErrSendQueueFull = errors.New("节点待发送消息队列已满")
+)
+
+// PeerConfig 节点连接配置
+type PeerConfig struct {
+	Magic     uint32      // 网络魔数
+	Services  ServiceFlag // 本节点提供的服务
+	UserAgent string      // 本节点用户代理
+
+	// Nonce 本节点的随机数，写入version消息并用于检测自连接
+	Nonce uint64
+
+	// BestHeight 返回本节点主链高度，为空时通告高度0
+	BestHeight func() int32
+
+	HandshakeTimeout time.Duration // 完成握手的最长时间
+	WriteTimeout     time.Duration // 写入单条消息的最长时间，0表示不限制
+	PingInterval     time.Duration // 发送ping的间隔
+	IdleTimeout      time.Duration // 未收到任何消息超过该时间时断开连接
+
+	// BanThreshold 不当行为分数达到该值时断开连接，0表示DefaultBanThreshold
+	BanThreshold uint32
+
+	// OnMessage 握手完成后收到ping、pong以外的消息时调用
+	//
+	// 在该节点的读取协程中同步调用，同一节点的消息按接收顺序处理。
+	OnMessage func(p *Peer, msg Message)
+}
+
+// Peer 与远端节点的一条连接
+//
+// 握手完成后由三个协程服务：读取协程解码消息并分发，写入协程发送队列中的消息，
+// ping协程按心跳间隔发送ping。任何一个协程出错都会断开整个连接。
+type Peer struct {
+	conn    net.Conn
+	cfg     PeerConfig
+	inbound bool
+
+	outQueue       chan Message
+	knownInventory *knownInventory
+	quit           chan struct{}
+	disconnectOnce sync.Once
+	wg             sync.WaitGroup
+
+	mu              sync.Mutex
+	disconnectErr   error
+	protocolVersion uint32
+	services        ServiceFlag
+	userAgent       string
+	startingHeight  int32
+	lastRecv        time.Time
+	lastSend        time.Time
+	pingNonce       uint64
+	pingTime        time.Time
+	lastPingRTT     time.Duration
+	banScore        uint32
+}
+
+// newPeer 创建节点连接
+func newPeer(conn net.Conn, cfg PeerConfig, inbound bool) *Peer {
+	return &Peer{
+		conn:           conn,
+		cfg:            cfg,
+		inbound:        inbound,
+		outQueue:       make(chan Message, outQueueSize),
+		knownInventory: newKnownInventory(maxKnownInventory),
+		quit:           make(chan struct{}),
+	}
+}
+
+// NewInboundPeer 为对端发起的连接创建节点
+func NewInboundPeer(conn net.Conn, cfg PeerConfig) *Peer {
+	return newPeer(conn, cfg, true)
+}
+
+// NewOutboundPeer 为本节点发起的连接创建节点
+func NewOutboundPeer(conn net.Conn, cfg PeerConfig) *Peer {
+	return newPeer(conn, cfg, false)
+}
+
+// Start 完成版本握手并启动读写协程
+//
+// 握手失败时关闭连接并返回错误。
+func (p *Peer) Start() error {
+	if err := p.negotiate(); err != nil {
+		return err
+	}
+	p.run()
+	return nil
+}
+
+// negotiate 完成版本握手，失败时关闭连接
+func (p *Peer) negotiate() error {
+	if err := p.handshake(); err != nil {
+		p.disconnect(err)
+		return err
+	}
+	return nil
+}
+
+// run 启动读写协程和ping协程
+func (p *Peer) run() {
+	p.wg.Add(3)
+	go p.inHandler()
+	go p.outHandler()
+	go p.pingHandler()
+}
+
+// handshake 交换version和verack消息
+//
+// 出站连接先发送version；入站连接收到对端的version后再发送自己的version。
+// 双方收到version后回复verack，收到对方的version和verack后握手完成。
+func (p *Peer) handshake() error {
+	if p.cfg.HandshakeTimeout > 0 {
+		p.conn.SetDeadline(time.Now().Add(p.cfg.HandshakeTimeout))
+		defer p.conn.SetDeadline(time.Time{})
+	}
+
+	if !p.inbound {
+		if err := p.writeMessage(p.localVersion()); err != nil {
+			return err
+		}
+	}
+
+	var gotVersion, gotVerAck bool
+	for !gotVersion || !gotVerAck {
+		msg, err := p.readMessage()
+		if err != nil {
+			return err
+		}
+
+		switch m := msg.(type) {
+		case *MsgVersion:
+			if gotVersion {
+				return fmt.Errorf("%w: 重复的version消息", ErrHandshake)
+			}
+			if err := p.handleVersion(m); err != nil {
+				return err
+			}
+			if p.inbound {
+				if err := p.writeMessage(p.localVersion()); err != nil {
+					return err
+				}
+			}
+			if err := p.writeMessage(&MsgVerAck{}); err != nil {
+				return err
+			}
+			gotVersion = true
+
+		case *MsgVerAck:
+			if !gotVersion {
+				return fmt.Errorf("%w: 收到version前收到verack", ErrHandshake)
+			}
+			gotVerAck = true
+
+		default:
+			return fmt.Errorf("%w: 握手完成前收到%s消息", ErrHandshake, msg.Command())
+		}
+	}
+	return nil
+}
+
+// handleVersion 检查并记录对端的version消息
+func (p *Peer) handleVersion(m *MsgVersion) error {
+	if m.Nonce == p.cfg.Nonce {
+		return ErrSelfConnection
+	}
+	if m.ProtocolVersion < MinProtocolVersion {
+		reason := fmt.Sprintf("协议版本%d低于%d", m.ProtocolVersion, MinProtocolVersion)
+		p.writeMessage(NewMsgReject(CmdVersion, RejectObsolete, reason))
+		return fmt.Errorf("%w: %s", ErrObsoleteVersion, reason)
+	}
+
+	p.mu.Lock()
+	p.protocolVersion = m.ProtocolVersion
+	if p.protocolVersion > ProtocolVersion {
+		p.protocolVersion = ProtocolVersion
+	}
+	p.services = m.Services
+	p.userAgent = m.UserAgent
+	p.startingHeight = m.StartHeight
+	p.mu.Unlock()
+	return nil
+}
+
+// localVersion 构造本节点的version消息
+func (p *Peer) localVersion() *MsgVersion {
+	var height int32
+	if p.cfg.BestHeight != nil {
+		height = p.cfg.BestHeight()
+	}
+
+	me := netAddressFromAddr(p.conn.LocalAddr(), p.cfg.Services)
+	you := netAddressFromAddr(p.conn.RemoteAddr(), 0)
+	msg := NewMsgVersion(me, you, p.cfg.Nonce, height)
+	msg.UserAgent = p.cfg.UserAgent
+	return msg
+}
+
+// netAddressFromAddr 将net.Addr转换为NetAddress，无法解析时返回零地址
+func netAddressFromAddr(addr net.Addr, services ServiceFlag) *NetAddress {
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return NewNetAddress(tcpAddr.IP, uint16(tcpAddr.Port), services)
+	}
+	return NewNetAddress(net.IPv4zero, 0, services)
+}
+
+// readMessage 读取一条消息并更新最后接收时间
+func (p *Peer) readMessage() (Message, error) {
+	msg, err := ReadMessage(p.conn, p.cfg.Magic)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.lastRecv = time.Now()
+	p.mu.Unlock()
+	return msg, nil
+}
+
+// writeMessage 直接写入一条消息并更新最后发送时间
+//
+// 设置写入超时，对端停止读取时写入协程不会永久阻塞。
+func (p *Peer) writeMessage(msg Message) error {
+	if p.cfg.WriteTimeout > 0 {
+		p.conn.SetWriteDeadline(time.Now().Add(p.cfg.WriteTimeout))
+	}
+	if err := WriteMessage(p.conn, msg, p.cfg.Magic); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.lastSend = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// inHandler 读取协程，持续读取并分发消息
+func (p *Peer) inHandler() {
+	defer p.wg.Done()
+
+	for {
+		if p.cfg.IdleTimeout > 0 {
+			p.conn.SetReadDeadline(time.Now().Add(p.cfg.IdleTimeout))
+		}
+
+		msg, err := p.readMessage()
+		if err != nil {
+			if errors.Is(err, ErrUnknownCommand) {
+				continue
+			}
+			// 负载已完整读出，消息流仍然同步，只记录不当行为；
+			// 消息头错误时负载未被读取，与其他错误一样断开连接
+			if errors.Is(err, ErrMalformedMessage) || errors.Is(err, ErrChecksumMismatch) {
+				if p.AddBanScore(BanScoreMalformedMessage, err) {
+					return
+				}
+				continue
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				err = ErrPeerIdle
+			}
+			p.disconnect(err)
+			return
+		}
+
+		switch m := msg.(type) {
+		case *MsgPing:
+			if !p.SendMessage(&MsgPong{Nonce: m.Nonce}) {
+				return
+			}
+		case *MsgPong:
+			p.handlePong(m)
+		case *MsgVersion, *MsgVerAck:
+			p.disconnect(fmt.Errorf("%w: 握手完成后收到%s消息", ErrHandshake, msg.Command()))
+			return
+		default:
+			p.markKnown(msg)
+			if p.cfg.OnMessage != nil {
+				p.cfg.OnMessage(p, msg)
+			}
+		}
+	}
+}
+
+// markKnown 记录对端通告或发送过的区块和交易，之后不再向其通告
+func (p *Peer) markKnown(msg Message) {
+	switch m := msg.(type) {
+	case *MsgInv:
+		for _, iv := range m.InvList {
+			p.AddKnownInventory(iv)
+		}
+	case *MsgBlock:
+		p.AddKnownInventory(NewInvVect(InvTypeBlock, m.Block.Hash()))
+	case *MsgTx:
+		p.AddKnownInventory(NewInvVect(InvTypeTx, m.Tx.Hash()))
+	}
+}
+
+// AddKnownInventory 将清单项记录为对端已知
+func (p *Peer) AddKnownInventory(iv *InvVect) {
+	p.knownInventory.Add(*iv)
+}
+
+// IsKnownInventory 判断对端是否已知该清单项
+func (p *Peer) IsKnownInventory(iv *InvVect) bool {
+	return p.knownInventory.Contains(*iv)
+}
+
+// QueueInventory 向对端通告清单项
+//
+// 对端已知的清单项被跳过，否则记录为已知并以SendMessage发送只含该项的inv消息。
+func (p *Peer) QueueInventory(iv *InvVect) bool {
+	if p.IsKnownInventory(iv) {
+		return true
+	}
+	p.AddKnownInventory(iv)
+
+	msg := &MsgInv{}
+	msg.AddInvVect(iv)
+	return p.SendMessage(msg)
+}
+
+// handlePong 处理pong消息，Nonce与最近一次ping一致时记录往返时间
+func (p *Peer) handlePong(m *MsgPong) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pingNonce != 0 && m.Nonce == p.pingNonce {
+		p.lastPingRTT = time.Since(p.pingTime)
+		p.pingNonce = 0
+	}
+}
+
+// outHandler 写入协程，依次发送队列中的消息
+func (p *Peer) outHandler() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case msg := <-p.outQueue:
+			if err := p.writeMessage(msg); err != nil {
+				p.disconnect(err)
+				return
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// pingHandler 按心跳间隔发送ping
+func (p *Peer) pingHandler() {
+	defer p.wg.Done()
+
+	if p.cfg.PingInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.cfg.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			nonce := randomUint64()
+			p.mu.Lock()
+			p.pingNonce = nonce
+			p.pingTime = time.Now()
+			p.mu.Unlock()
+			if !p.SendMessage(&MsgPing{Nonce: nonce}) {
+				return
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// SendMessage 以不阻塞的方式发送消息
+//
+// 调用者不能等待单个节点的发送队列。队列已满说明对端长时间不读取数据，
+// 此时以ErrSendQueueFull断开连接。消息未能加入队列时返回false。
+func (p *Peer) SendMessage(msg Message) bool {
+	if p.TryQueueMessage(msg) {
+		return true
+	}
+	p.disconnect(ErrSendQueueFull)
+	return false
+}
+
+// TryQueueMessage 尝试将消息加入发送队列，不阻塞
+//
+// 队列已满或连接已断开时返回false，消息被丢弃。
+func (p *Peer) TryQueueMessage(msg Message) bool {
+	select {
+	case <-p.quit:
+		return false
+	default:
+	}
+
+	select {
+	case p.outQueue <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// Disconnect 断开连接
+func (p *Peer) Disconnect() {
+	p.disconnect(nil)
+}
+
+// DisconnectWithReason 因对端违反协议或行为异常断开连接，并记录原因
+func (p *Peer) DisconnectWithReason(reason error) {
+	p.disconnect(reason)
+}
+
+// disconnect 断开连接并记录原因，只有第一次调用生效
+func (p *Peer) disconnect(err error) {
+	p.disconnectOnce.Do(func() {
+		p.mu.Lock()
+		p.disconnectErr = err
+		p.mu.Unlock()
+
+		close(p.quit)
+		p.conn.Close()
+	})
+}
+
+// AddBanScore 增加对端的不当行为分数
+//
+// 累计分数达到封禁阈值时以ErrBanScoreExceeded断开连接并返回true，
+// 断开原因同时包装reason。连接管理器据此封禁对端IP。
+func (p *Peer) AddBanScore(score uint32, reason error) bool {
+	threshold := p.BanThreshold()
+
+	p.mu.Lock()
+	p.banScore += score
+	total := p.banScore
+	p.mu.Unlock()
+
+	if total < threshold {
+		log.Printf("节点%s不当行为分数增加%d至%d: %v", p, score, total, reason)
+		return false
+	}
+	p.disconnect(fmt.Errorf("%w(%d): %w", ErrBanScoreExceeded, total, reason))
+	return true
+}
+
+// BanThreshold 返回断开连接的不当行为分数阈值
+//
+// 发送无效区块或区块头的对端直接增加该分数，无论阈值如何配置都立即断开。
+func (p *Peer) BanThreshold() uint32 {
+	if p.cfg.BanThreshold == 0 {
+		return DefaultBanThreshold
+	}
+	return p.cfg.BanThreshold
+}
+
+// BanScore 返回对端累计的不当行为分数
+func (p *Peer) BanScore() uint32 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.banScore
+}
+
+// WaitForDisconnect 阻塞直到连接断开且所有协程退出
+func (p *Peer) WaitForDisconnect() {
+	<-p.quit
+	p.wg.Wait()
+}
+
+// Done 返回连接断开时关闭的通道
+func (p *Peer) Done() <-chan struct{} {
+	return p.quit
+}
+
+// DisconnectReason 返回断开连接的原因，主动断开或尚未断开时为nil
+func (p *Peer) DisconnectReason() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.disconnectErr
+}
+
+// Addr 返回对端地址
+func (p *Peer) Addr() string {
+	return p.conn.RemoteAddr().String()
+}
+
+// NetAddress 返回对端的网络地址和服务
+//
+// 出站连接的地址即对端的监听地址；入站连接的端口通常是对端的临时端口。
+func (p *Peer) NetAddress() *NetAddress {
+	return netAddressFromAddr(p.conn.RemoteAddr(), p.Services())
+}
+
+// Inbound 返回是否为对端发起的连接
+func (p *Peer) Inbound() bool {
+	return p.inbound
+}
+
+// ProtocolVersion 返回协商后的协议版本
+func (p *Peer) ProtocolVersion() uint32 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.protocolVersion
+}
+
+// Services 返回对端提供的服务
+func (p *Peer) Services() ServiceFlag {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.services
+}
+
+// UserAgent 返回对端用户代理
+func (p *Peer) UserAgent() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.userAgent
+}
+
+// StartingHeight 返回对端握手时通告的主链高度
+func (p *Peer) StartingHeight() int32 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.startingHeight
+}
+
+// LastRecv 返回最后一次收到消息的时间
+func (p *Peer) LastRecv() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastRecv
+}
+
+// LastSend 返回最后一次发送消息的时间
+func (p *Peer) LastSend() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastSend
+}
+
+// LastPingRTT 返回最近一次ping的往返时间，尚未测得时为0
+func (p *Peer) LastPingRTT() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastPingRTT
+}
+
+// String 返回节点的可读表示
+func (p *Peer) String() string {
+	direction := "outbound"
+	if p.inbound {
+		direction = "inbound"
+	}
+	return fmt.Sprintf("%s (%s)", p.Addr(), direction)
+}
+
+// randomUint64 生成非零随机数
+func randomUint64() uint64 {
+	var buf [8]byte
+	for {
+		if _, err := rand.Read(buf[:]); err != nil {
+			return uint64(time.Now().UnixNano())
+		}
+		if n := binary.LittleEndian.Uint64(buf[:]); n != 0 {
+			return n
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package network 实现了节点之间的P2P通信协议
+//
+// 每条消息由24字节的消息头和负载组成：
+//
+//	魔数(4字节，小端序) | 命令(12字节，右侧补0) | 负载长度(4字节，小端序) | 校验和(4字节) | 负载
+//
+// 魔数区分主网、测试网等不同网络，校验和为负载双重SHA-256的前4字节。
+// 各消息类型的负载编解码只依赖字节数组，不依赖网络连接，可以独立测试和模糊测试。
+package network
+
+import (
+	"errors"
+
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// 消息头格式
+const (
+	// CommandSize 消息头中命令字段的固定长度
+	CommandSize = 12
+
+	// MessageHeaderSize 消息头长度
+	// Magic(4) + Command(12) + Length(4) + Checksum(4)
+	MessageHeaderSize = 24
+
+	// MaxMessagePayload 任意消息负载的最大长度
+	MaxMessagePayload = utils.MaxMessageSize
+)
+
+// 协议版本
+const (
+	// ProtocolVersion 当前协议版本
+	ProtocolVersion = utils.ProtocolVersion
+
+	// MinProtocolVersion 可以建立连接的最低协议版本
+	MinProtocolVersion = 1
+)
+
+// 消息命令
+const (
+	CmdVersion    = "version"
+	CmdVerAck     = "verack"
+	CmdPing       = "ping"
+	CmdPong       = "pong"
+	CmdInv        = "inv"
+	CmdGetData    = "getdata"
+	CmdNotFound   = "notfound"
+	CmdGetHeaders = "getheaders"
+	CmdHeaders    = "headers"
+	CmdBlock      = "block"
+	CmdTx         = "tx"
+	CmdAddr       = "addr"
+	CmdReject     = "reject"
+)
+
+// ServiceFlag 节点提供的服务标识
+type ServiceFlag uint64
+
+const (
+	// SFNodeNetwork 节点保存完整区块链并可以提供区块
+	SFNodeNetwork ServiceFlag = 1 << iota
+)
+
+// 消息列表长度限制
+const (
+	// MaxInvPerMsg inv、getdata和notfound消息中清单项的最大数量
+	MaxInvPerMsg = 50000
+
+	// MaxBlockLocatorsPerMsg getheaders消息中区块定位哈希的最大数量
+	MaxBlockLocatorsPerMsg = 500
+
+	// MaxBlockHeadersPerMsg headers消息中区块头的最大数量
+	MaxBlockHeadersPerMsg = 2000
+
+	// MaxAddrPerMsg addr消息中地址的最大数量
+	MaxAddrPerMsg = 1000
+
+	// MaxUserAgentLen version消息中用户代理字符串的最大长度
+	MaxUserAgentLen = 256
+
+	// MaxRejectReasonLen reject消息中原因字符串的最大长度
+	MaxRejectReasonLen = 256
+)
+
+var (
+	// ErrWrongNetwork 消息魔数与本节点网络不一致
+	ErrWrongNetwork = errors.New("消息魔数与当前网络不一致")
+
+	// ErrPayloadTooLarge 消息负载超过长度限制
+	ErrPayloadTooLarge = errors.New("消息负载超过长度限制")
+
+	// ErrChecksumMismatch 消息负载校验和错误
+	ErrChecksumMismatch = errors.New("消息校验和错误")
+
+	// ErrUnknownCommand 未知的消息命令
+	ErrUnknownCommand = errors.New("未知的消息命令")
+
+	// ErrMalformedHeader 消息头格式错误，负载尚未读取，消息流已无法同步
+	ErrMalformedHeader = errors.New("消息头格式错误")
+
+	// ErrMalformedMessage 消息负载格式错误
+	ErrMalformedMessage = errors.New("消息格式错误")
+)
@@ -0,0 +1,129 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/banman"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// registerHandlers 注册所有接口
+//
+//	GET    /bans       列出封禁记录
+//	POST   /bans       添加封禁，并断开该IP的现有连接（需要认证）
+//	DELETE /bans       清空封禁列表（需要认证）
+//	DELETE /bans/{ip}  解除封禁（需要认证）
+//	GET    /mempool    内存池交易，按手续费率从高到低排列
+func (s *Server) registerHandlers(mux *http.ServeMux) {
+	base := utils.APIBasePath
+	mux.HandleFunc("GET "+base+"/bans", s.handleListBans)
+	mux.HandleFunc("POST "+base+"/bans", s.requireAuth(s.handleAddBan))
+	mux.HandleFunc("DELETE "+base+"/bans", s.requireAuth(s.handleClearBans))
+	mux.HandleFunc("DELETE "+base+"/bans/{ip}", s.requireAuth(s.handleRemoveBan))
+
+	if s.cfg.Mempool != nil {
+		mux.HandleFunc("GET "+base+"/mempool", s.handleMempool)
+	}
+}
+
+// handleListBans 列出所有未到期的封禁记录
+func (s *Server) handleListBans(w http.ResponseWriter, r *http.Request) {
+	entries := s.cfg.BanList.List()
+	bans := make([]BanInfo, 0, len(entries))
+	for _, entry := range entries {
+		bans = append(bans, newBanInfo(entry))
+	}
+	writeJSON(w, http.StatusOK, bans)
+}
+
+// handleAddBan 封禁IP并断开其现有连接
+func (s *Server) handleAddBan(w http.ResponseWriter, r *http.Request) {
+	var req BanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("解析请求失败: %v", err))
+		return
+	}
+
+	ip := net.ParseIP(req.IP)
+	if ip == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("无效的IP: %q", req.IP))
+		return
+	}
+	if req.Duration < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("无效的封禁时长: %d", req.Duration))
+		return
+	}
+	duration := s.cfg.BanDuration
+	if req.Duration > 0 {
+		duration = time.Duration(req.Duration) * time.Second
+	}
+	reason := req.Reason
+	if reason == "" {
+		reason = "手动封禁"
+	}
+
+	if err := s.cfg.BanList.Ban(ip, duration, reason); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if s.cfg.Peers != nil {
+		s.cfg.Peers.DisconnectIP(ip)
+	}
+
+	// 封禁时长很短时记录可能已经到期
+	info := BanInfo{IP: ip.String(), Reason: reason}
+	if entry := s.cfg.BanList.Lookup(ip); entry != nil {
+		info = newBanInfo(entry)
+	}
+	writeJSON(w, http.StatusCreated, info)
+}
+
+// handleRemoveBan 解除IP的封禁
+func (s *Server) handleRemoveBan(w http.ResponseWriter, r *http.Request) {
+	ip := net.ParseIP(r.PathValue("ip"))
+	if ip == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("无效的IP: %q", r.PathValue("ip")))
+		return
+	}
+
+	if err := s.cfg.BanList.Unban(ip); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, banman.ErrNotBanned) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleClearBans 清空封禁列表
+func (s *Server) handleClearBans(w http.ResponseWriter, r *http.Request) {
+	if err := s.cfg.BanList.Clear(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMempool 返回内存池状态和其中的交易
+func (s *Server) handleMempool(w http.ResponseWriter, r *http.Request) {
+	pool := s.cfg.Mempool
+	descs := pool.TxDescs()
+
+	info := MempoolInfo{
+		Count:        len(descs),
+		MaxSize:      pool.MaxSize(),
+		Transactions: make([]MempoolEntry, 0, len(descs)),
+	}
+	for _, desc := range descs {
+		info.Size += desc.Size
+		info.Transactions = append(info.Transactions, newMempoolEntry(desc))
+	}
+	writeJSON(w, http.StatusOK, info)
+}
@@ -0,0 +1,191 @@
+// Package rpc 实现了节点的HTTP JSON接口
+//
+// 接口路径以utils.APIBasePath为前缀，请求和响应均为JSON。
+// 出错时返回相应的HTTP状态码和ErrorResponse。配置了认证密钥时，
+// 请求必须携带"Authorization: Bearer <密钥>"请求头；未配置认证密钥时，
+// 修改封禁列表的接口一律拒绝，只开放只读接口。
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/banman"
+	"simplied-bitcoin-network-go/pkg/mempool"
+	"simplied-bitcoin-network-go/pkg/network"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// shutdownTimeout 停止服务时等待进行中请求的最长时间
+const shutdownTimeout = 5 * time.Second
+
+// Config RPC服务配置
+type Config struct {
+	ListenAddr string // 监听地址
+
+	Peers   *network.PeerManager // 连接管理器，封禁地址时断开其连接，可为空
+	BanList *banman.BanManager   // 封禁列表
+	Mempool *mempool.TxPool      // 交易内存池，为空时不提供内存池接口
+
+	BanDuration time.Duration // 手动封禁的默认时长，0表示network.DefaultBanDuration
+
+	AuthKey    string // 认证密钥，为空时不认证且拒绝修改封禁列表
+	EnableCORS bool   // 是否允许跨域请求
+
+	MaxRequestSize int64         // 请求体最大字节数，0表示utils.MaxRequestSize
+	RequestTimeout time.Duration // 读写超时时间，0表示utils.RequestTimeout
+}
+
+// Server RPC服务
+type Server struct {
+	cfg      Config
+	server   *http.Server
+	listener net.Listener
+
+	mu      sync.Mutex
+	started bool
+	wg      sync.WaitGroup
+}
+
+// New 创建RPC服务
+func New(cfg *Config) (*Server, error) {
+	if cfg == nil || cfg.BanList == nil {
+		return nil, errors.New("RPC配置缺少封禁列表")
+	}
+
+	s := &Server{cfg: *cfg}
+	if s.cfg.MaxRequestSize == 0 {
+		s.cfg.MaxRequestSize = utils.MaxRequestSize
+	}
+	if s.cfg.RequestTimeout == 0 {
+		s.cfg.RequestTimeout = utils.RequestTimeout
+	}
+	if s.cfg.BanDuration == 0 {
+		s.cfg.BanDuration = network.DefaultBanDuration
+	}
+
+	mux := http.NewServeMux()
+	s.registerHandlers(mux)
+	s.server = &http.Server{
+		Handler:      s.middleware(mux),
+		ReadTimeout:  s.cfg.RequestTimeout,
+		WriteTimeout: s.cfg.RequestTimeout,
+	}
+	return s, nil
+}
+
+// Start 开始监听并处理请求
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return errors.New("RPC服务已启动")
+	}
+	listener, err := net.Listen("tcp", s.cfg.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("监听%s失败: %v", s.cfg.ListenAddr, err)
+	}
+	s.listener = listener
+	s.started = true
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("RPC服务退出: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop 停止服务，等待进行中的请求完成
+func (s *Server) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := s.server.Shutdown(ctx)
+	s.wg.Wait()
+	return err
+}
+
+// Addr 返回监听地址，未启动时返回nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// middleware 处理跨域、认证和请求体大小限制
+//
+// 未配置认证密钥时跨域请求只允许只读方法。
+func (s *Server) middleware(next http.Handler) http.Handler {
+	methods := "GET, OPTIONS"
+	if s.cfg.AuthKey != "" {
+		methods = "GET, POST, DELETE, OPTIONS"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.EnableCORS {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(utils.CORSMaxAge))
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if s.cfg.AuthKey != "" {
+			want := "Bearer " + s.cfg.AuthKey
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(want)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("认证失败"))
+				return
+			}
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxRequestSize)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAuth 只在配置了认证密钥时开放修改节点状态的接口
+//
+// 未配置密钥时，任何能访问RPC端口的主机或网页都能调用这些接口。
+func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.AuthKey == "" {
+			writeError(w, http.StatusForbidden, errors.New("未配置认证密钥，禁止修改节点状态"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// writeJSON 以指定状态码写入JSON响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("写入RPC响应失败: %v", err)
+	}
+}
+
+// writeError 写入错误响应
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
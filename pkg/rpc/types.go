@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/banman"
+	"simplied-bitcoin-network-go/pkg/mempool"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// BanRequest 添加封禁的请求
+type BanRequest struct {
+	IP       string `json:"ip"`
+	Duration int64  `json:"duration,omitempty"` // 封禁秒数，0表示默认时长
+	Reason   string `json:"reason,omitempty"`
+}
+
+// BanInfo 封禁记录
+type BanInfo struct {
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	Until     time.Time `json:"until"`
+	Reason    string    `json:"reason"`
+}
+
+// newBanInfo 将封禁记录转换为响应格式
+func newBanInfo(entry *banman.BanEntry) BanInfo {
+	return BanInfo{
+		IP:        entry.IP.String(),
+		CreatedAt: entry.CreatedAt,
+		Until:     entry.Until,
+		Reason:    entry.Reason,
+	}
+}
+
+// MempoolInfo 内存池状态
+type MempoolInfo struct {
+	Count        int            `json:"count"`
+	Size         int            `json:"size"`     // 交易总字节数
+	MaxSize      int            `json:"max_size"` // 交易总字节数上限
+	Transactions []MempoolEntry `json:"transactions"`
+}
+
+// MempoolEntry 内存池中的交易
+type MempoolEntry struct {
+	Hash    string    `json:"hash"`
+	Size    int       `json:"size"`
+	Fee     int64     `json:"fee"`
+	FeeRate float64   `json:"fee_rate"` // 每字节手续费
+	Height  int32     `json:"height"`   // 加入时的主链高度
+	Added   time.Time `json:"added"`
+
+	AncestorCount   int     `json:"ancestor_count"`    // 池内祖先交易数，包括交易本身
+	AncestorFeeRate float64 `json:"ancestor_fee_rate"` // 与祖先交易组成的交易包的手续费率
+	DescendantCount int     `json:"descendant_count"`  // 池内后代交易数，包括交易本身
+}
+
+// newMempoolEntry 将内存池交易转换为响应格式
+func newMempoolEntry(desc *mempool.TxDesc) MempoolEntry {
+	hash := desc.Tx.Hash()
+	return MempoolEntry{
+		Hash:    utils.HashToString(hash[:]),
+		Size:    desc.Size,
+		Fee:     desc.Fee,
+		FeeRate: desc.FeeRate,
+		Height:  desc.Height,
+		Added:   desc.Added,
+
+		AncestorCount:   desc.AncestorCount,
+		AncestorFeeRate: desc.AncestorFeeRate(),
+		DescendantCount: desc.DescendantCount,
+	}
+}
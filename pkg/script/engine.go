@@ -0,0 +1,479 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// 条件分支状态
+const (
+	condFalse = iota // 分支条件为假，不执行
+	condTrue         // 分支条件为真，执行
+	condSkip         // 外层分支未执行，内层分支无论条件如何都不执行
+)
+
+// Engine 脚本执行引擎
+//
+// 一个Engine验证交易的一个输入：依次执行解锁脚本、被花费输出的锁定脚本，
+// 以及P2SH输出的赎回脚本。脚本之间共享数据栈，每个脚本的操作数单独计算。
+type Engine struct {
+	scripts    [][]parsedOpcode // 待执行的脚本
+	rawScripts [][]byte         // 脚本原始字节，用于计算签名哈希
+	scriptIdx  int              // 当前脚本索引
+	opIdx      int              // 当前操作码在脚本中的索引
+	numOps     int              // 当前脚本已执行的非推送操作数
+	dstack     stack            // 数据栈
+	condStack  []int            // 条件分支状态栈
+	tx         *blockchain.Transaction
+	txIdx      int
+
+	bip16      bool     // 锁定脚本为P2SH格式
+	savedStack [][]byte // 解锁脚本执行后的栈，P2SH验证时从中取出赎回脚本
+}
+
+// NewEngine 创建验证交易第txIdx个输入的脚本执行引擎
+//
+// 参数：
+// - pkScript: 被花费输出的锁定脚本
+// - tx: 花费该输出的交易
+// - txIdx: 输入索引
+//
+// 解锁脚本必须只包含数据推送操作，否则返回ErrCodeNotPushOnly。
+func NewEngine(pkScript []byte, tx *blockchain.Transaction, txIdx int) (*Engine, error) {
+	if txIdx < 0 || txIdx >= len(tx.TxIn) {
+		return nil, scriptError(ErrCodeInvalidIndex,
+			fmt.Sprintf("输入索引%d超出范围, 交易共%d个输入", txIdx, len(tx.TxIn)))
+	}
+	sigScript := tx.TxIn[txIdx].SignatureScript
+
+	vm := &Engine{tx: tx, txIdx: txIdx, bip16: IsPayToScriptHash(pkScript)}
+	for _, script := range [][]byte{sigScript, pkScript} {
+		if len(script) > blockchain.MaxScriptSize {
+			return nil, scriptError(ErrCodeScriptTooBig,
+				fmt.Sprintf("脚本长度%d超过上限%d", len(script), blockchain.MaxScriptSize))
+		}
+		ops, err := parseScript(script)
+		if err != nil {
+			return nil, err
+		}
+		vm.scripts = append(vm.scripts, ops)
+		vm.rawScripts = append(vm.rawScripts, script)
+	}
+
+	for i := range vm.scripts[0] {
+		if !vm.scripts[0][i].isPush() {
+			return nil, scriptError(ErrCodeNotPushOnly,
+				fmt.Sprintf("解锁脚本包含%s", OpcodeName(vm.scripts[0][i].opcode)))
+		}
+	}
+
+	vm.skipEmptyScripts()
+	return vm, nil
+}
+
+// Execute 执行全部脚本
+//
+// 执行结束时栈不能为空且栈顶元素为真，否则分别返回ErrCodeEmptyStack和ErrCodeEvalFalse。
+func (vm *Engine) Execute() error {
+	for vm.scriptIdx < len(vm.scripts) {
+		done, err := vm.Step()
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+	}
+	return vm.checkFinalStack()
+}
+
+// Step 执行一个操作码
+//
+// 返回值：
+// - bool: 全部脚本是否已执行完毕
+// - error: 执行失败时返回Error
+func (vm *Engine) Step() (bool, error) {
+	if vm.scriptIdx >= len(vm.scripts) {
+		return true, nil
+	}
+
+	pop := &vm.scripts[vm.scriptIdx][vm.opIdx]
+	if err := vm.executeOpcode(pop); err != nil {
+		return true, err
+	}
+	if vm.dstack.depth() > MaxStackSize {
+		return true, scriptError(ErrCodeStackOverflow,
+			fmt.Sprintf("栈中元素数%d超过上限%d", vm.dstack.depth(), MaxStackSize))
+	}
+
+	vm.opIdx++
+	if vm.opIdx < len(vm.scripts[vm.scriptIdx]) {
+		return false, nil
+	}
+
+	// 当前脚本执行完毕
+	if len(vm.condStack) != 0 {
+		return true, scriptError(ErrCodeUnbalancedConditional, "脚本结束时条件分支未闭合")
+	}
+	switch {
+	case vm.bip16 && vm.scriptIdx == 0:
+		vm.savedStack = append([][]byte(nil), vm.dstack...)
+	case vm.bip16 && vm.scriptIdx == 1:
+		if err := vm.checkFinalStack(); err != nil {
+			return true, err
+		}
+		if err := vm.loadRedeemScript(); err != nil {
+			return true, err
+		}
+	}
+
+	vm.scriptIdx++
+	vm.opIdx = 0
+	vm.numOps = 0
+	vm.skipEmptyScripts()
+	return vm.scriptIdx >= len(vm.scripts), nil
+}
+
+// skipEmptyScripts 跳过空脚本
+func (vm *Engine) skipEmptyScripts() {
+	for vm.scriptIdx < len(vm.scripts) && len(vm.scripts[vm.scriptIdx]) == 0 {
+		vm.scriptIdx++
+	}
+}
+
+// loadRedeemScript 取出解锁脚本推送的赎回脚本作为下一个执行的脚本
+//
+// 赎回脚本以解锁脚本执行后的栈（去掉赎回脚本本身）为初始栈。
+func (vm *Engine) loadRedeemScript() error {
+	if len(vm.savedStack) == 0 {
+		return scriptError(ErrCodeEmptyStack, "P2SH解锁脚本没有推送赎回脚本")
+	}
+	redeemScript := vm.savedStack[len(vm.savedStack)-1]
+	ops, err := parseScript(redeemScript)
+	if err != nil {
+		return err
+	}
+
+	vm.scripts = append(vm.scripts, ops)
+	vm.rawScripts = append(vm.rawScripts, redeemScript)
+	vm.dstack = vm.savedStack[:len(vm.savedStack)-1]
+	return nil
+}
+
+// checkFinalStack 检查脚本执行结束时的栈顶元素
+func (vm *Engine) checkFinalStack() error {
+	if vm.dstack.depth() == 0 {
+		return scriptError(ErrCodeEmptyStack, "脚本执行结束时栈为空")
+	}
+	if v, _ := vm.dstack.peek(); !asBool(v) {
+		return scriptError(ErrCodeEvalFalse, "脚本执行结束时栈顶元素为假")
+	}
+	return nil
+}
+
+// isBranchExecuting 判断当前是否处于执行中的分支
+func (vm *Engine) isBranchExecuting() bool {
+	return len(vm.condStack) == 0 || vm.condStack[len(vm.condStack)-1] == condTrue
+}
+
+// executeOpcode 执行一个操作码
+//
+// 推送数据长度和操作数限制对未执行的分支同样有效。
+func (vm *Engine) executeOpcode(pop *parsedOpcode) error {
+	if len(pop.data) > MaxScriptElementSize {
+		return scriptError(ErrCodeElementTooBig,
+			fmt.Sprintf("推送数据%d字节超过上限%d", len(pop.data), MaxScriptElementSize))
+	}
+	if !pop.isPush() {
+		vm.numOps++
+		if vm.numOps > MaxOpsPerScript {
+			return scriptError(ErrCodeTooManyOperations,
+				fmt.Sprintf("脚本操作数超过上限%d", MaxOpsPerScript))
+		}
+	}
+	if !vm.isBranchExecuting() && !pop.isConditional() {
+		return nil
+	}
+
+	switch op := pop.opcode; {
+	case op == Op0:
+		vm.dstack.push(nil)
+	case op >= OpData1 && op <= OpPushData4:
+		vm.dstack.push(pop.data)
+	case op == Op1Negate:
+		vm.dstack.pushInt(-1)
+	case op >= Op1 && op <= Op16:
+		vm.dstack.pushInt(scriptNum(op - Op1 + 1))
+	case op == OpNop:
+	case op == OpIf || op == OpNotIf:
+		return vm.opIf(op == OpNotIf)
+	case op == OpElse:
+		return vm.opElse()
+	case op == OpEndIf:
+		return vm.opEndIf()
+	case op == OpVerify:
+		return vm.verify(ErrCodeVerify, op)
+	case op == OpReturn:
+		return scriptError(ErrCodeEarlyReturn, "脚本执行了OP_RETURN")
+	case op == OpDrop:
+		_, err := vm.dstack.pop()
+		return err
+	case op == OpDup:
+		v, err := vm.dstack.peek()
+		if err != nil {
+			return err
+		}
+		vm.dstack.push(v)
+	case op == OpEqual || op == OpEqualVerify:
+		items, err := vm.dstack.popN(2)
+		if err != nil {
+			return err
+		}
+		vm.dstack.pushBool(bytes.Equal(items[0], items[1]))
+		if op == OpEqualVerify {
+			return vm.verify(ErrCodeEqualVerify, op)
+		}
+	case op == OpRipemd160 || op == OpSha256 || op == OpHash160 || op == OpHash256:
+		return vm.opHash(op)
+	case op == OpCheckSig || op == OpCheckSigVerify:
+		if err := vm.opCheckSig(); err != nil {
+			return err
+		}
+		if op == OpCheckSigVerify {
+			return vm.verify(ErrCodeCheckSigVerify, op)
+		}
+	case op == OpCheckMultiSig || op == OpCheckMultiSigVerify:
+		if err := vm.opCheckMultiSig(); err != nil {
+			return err
+		}
+		if op == OpCheckMultiSigVerify {
+			return vm.verify(ErrCodeCheckMultiSigVerify, op)
+		}
+	case op == OpCheckLockTimeVerify:
+		return vm.opCheckLockTimeVerify()
+	default:
+		return scriptError(ErrCodeUnsupportedOpcode, fmt.Sprintf("不支持的操作码%s", OpcodeName(op)))
+	}
+	return nil
+}
+
+// verify 弹出栈顶元素，为假时返回指定错误码
+func (vm *Engine) verify(code ErrorCode, op byte) error {
+	ok, err := vm.dstack.popBool()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return scriptError(code, fmt.Sprintf("%s验证失败", OpcodeName(op)))
+	}
+	return nil
+}
+
+// opIf 处理OP_IF和OP_NOTIF
+//
+// 未执行的分支中不弹出栈元素，内层分支标记为condSkip。
+func (vm *Engine) opIf(negate bool) error {
+	cond := condSkip
+	if vm.isBranchExecuting() {
+		ok, err := vm.dstack.popBool()
+		if err != nil {
+			return err
+		}
+		cond = condFalse
+		if ok != negate {
+			cond = condTrue
+		}
+	}
+	vm.condStack = append(vm.condStack, cond)
+	return nil
+}
+
+// opElse 切换当前分支的执行状态
+func (vm *Engine) opElse() error {
+	if len(vm.condStack) == 0 {
+		return scriptError(ErrCodeUnbalancedConditional, "OP_ELSE没有对应的OP_IF")
+	}
+	switch top := &vm.condStack[len(vm.condStack)-1]; *top {
+	case condTrue:
+		*top = condFalse
+	case condFalse:
+		*top = condTrue
+	}
+	return nil
+}
+
+// opEndIf 结束当前条件分支
+func (vm *Engine) opEndIf() error {
+	if len(vm.condStack) == 0 {
+		return scriptError(ErrCodeUnbalancedConditional, "OP_ENDIF没有对应的OP_IF")
+	}
+	vm.condStack = vm.condStack[:len(vm.condStack)-1]
+	return nil
+}
+
+// opHash 用指定的哈希算法替换栈顶元素
+func (vm *Engine) opHash(op byte) error {
+	v, err := vm.dstack.pop()
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case OpRipemd160:
+		vm.dstack.push(utils.RIPEMD160Hash(v))
+	case OpSha256:
+		hash := sha256.Sum256(v)
+		vm.dstack.push(hash[:])
+	case OpHash160:
+		vm.dstack.push(utils.Hash160(v))
+	case OpHash256:
+		vm.dstack.push(utils.DoubleSHA256(v))
+	}
+	return nil
+}
+
+// opCheckSig 验证签名
+//
+// 栈顶依次为公钥和签名，验证结果以布尔值压栈。空签名视为验证失败，
+// 格式错误的签名或公钥则使脚本直接失败。
+func (vm *Engine) opCheckSig() error {
+	pubKeyBytes, err := vm.dstack.pop()
+	if err != nil {
+		return err
+	}
+	sigBytes, err := vm.dstack.pop()
+	if err != nil {
+		return err
+	}
+
+	valid, err := vm.checkSignature(sigBytes, pubKeyBytes)
+	if err != nil {
+		return err
+	}
+	vm.dstack.pushBool(valid)
+	return nil
+}
+
+// opCheckMultiSig 验证m-of-n多重签名
+//
+// 栈格式（从栈底到栈顶）：<dummy> <sig1>...<sigm> <m> <pubkey1>...<pubkeyn> <n>
+// 签名必须按公钥的顺序排列，每个公钥最多匹配一个签名。dummy元素是历史实现
+// 多弹出的一个元素，必须为空。
+func (vm *Engine) opCheckMultiSig() error {
+	numPubKeys, err := vm.dstack.popInt(defaultNumLen)
+	if err != nil {
+		return err
+	}
+	if numPubKeys < 0 || numPubKeys > MaxPubKeysPerMultiSig {
+		return scriptError(ErrCodeInvalidPubKeyCount,
+			fmt.Sprintf("公钥数%d超出范围[0, %d]", numPubKeys, MaxPubKeysPerMultiSig))
+	}
+	vm.numOps += int(numPubKeys)
+	if vm.numOps > MaxOpsPerScript {
+		return scriptError(ErrCodeTooManyOperations, fmt.Sprintf("脚本操作数超过上限%d", MaxOpsPerScript))
+	}
+	pubKeys, err := vm.dstack.popN(int(numPubKeys))
+	if err != nil {
+		return err
+	}
+
+	numSigs, err := vm.dstack.popInt(defaultNumLen)
+	if err != nil {
+		return err
+	}
+	if numSigs < 0 || numSigs > numPubKeys {
+		return scriptError(ErrCodeInvalidSignatureCount,
+			fmt.Sprintf("签名数%d超出范围[0, %d]", numSigs, numPubKeys))
+	}
+	sigs, err := vm.dstack.popN(int(numSigs))
+	if err != nil {
+		return err
+	}
+
+	dummy, err := vm.dstack.pop()
+	if err != nil {
+		return err
+	}
+	if len(dummy) != 0 {
+		return scriptError(ErrCodeSigNullDummy, fmt.Sprintf("OP_CHECKMULTISIG的额外元素不为空: %x", dummy))
+	}
+
+	success := true
+	for sigIdx, keyIdx := 0, 0; sigIdx < len(sigs); keyIdx++ {
+		// 剩余公钥不足以匹配剩余签名
+		if len(sigs)-sigIdx > len(pubKeys)-keyIdx {
+			success = false
+			break
+		}
+		valid, err := vm.checkSignature(sigs[sigIdx], pubKeys[keyIdx])
+		if err != nil {
+			return err
+		}
+		if valid {
+			sigIdx++
+		}
+	}
+
+	vm.dstack.pushBool(success)
+	return nil
+}
+
+// checkSignature 用公钥验证签名元素
+//
+// 空签名返回false；签名或公钥编码错误返回对应的错误码。
+func (vm *Engine) checkSignature(sigBytes, pubKeyBytes []byte) (bool, error) {
+	if len(sigBytes) == 0 {
+		return false, nil
+	}
+	sig, hashType, err := parseSigAndHashType(sigBytes)
+	if err != nil {
+		return false, err
+	}
+	pubKey, err := parsePubKey(pubKeyBytes)
+	if err != nil {
+		return false, err
+	}
+
+	hash, err := CalcSignatureHash(vm.rawScripts[vm.scriptIdx], hashType, vm.tx, vm.txIdx)
+	if err != nil {
+		return false, err
+	}
+	return sig.Verify(hash[:], pubKey), nil
+}
+
+// opCheckLockTimeVerify 检查交易锁定时间（BIP65）
+//
+// 栈顶元素为要求的最小锁定时间，执行后保留在栈中。要求的锁定时间与交易
+// 锁定时间必须同为区块高度或同为时间戳，且不大于交易锁定时间；
+// 输入序列号不能为MaxTxInSequenceNum，否则交易锁定时间不生效。
+func (vm *Engine) opCheckLockTimeVerify() error {
+	v, err := vm.dstack.peek()
+	if err != nil {
+		return err
+	}
+	lockTime, err := makeScriptNum(v, lockTimeNumLen)
+	if err != nil {
+		return err
+	}
+	if lockTime < 0 {
+		return scriptError(ErrCodeNegativeLockTime, fmt.Sprintf("锁定时间%d为负", lockTime))
+	}
+
+	txLockTime := int64(vm.tx.LockTime)
+	if (txLockTime < blockchain.LockTimeThreshold) != (int64(lockTime) < blockchain.LockTimeThreshold) {
+		return scriptError(ErrCodeUnsatisfiedLockTime,
+			fmt.Sprintf("锁定时间类型不一致: 要求%d, 交易%d", lockTime, txLockTime))
+	}
+	if int64(lockTime) > txLockTime {
+		return scriptError(ErrCodeUnsatisfiedLockTime,
+			fmt.Sprintf("交易锁定时间%d小于要求的%d", txLockTime, lockTime))
+	}
+	if vm.tx.TxIn[vm.txIdx].Sequence == blockchain.MaxTxInSequenceNum {
+		return scriptError(ErrCodeUnsatisfiedLockTime, "输入序列号为最大值, 交易锁定时间不生效")
+	}
+	return nil
+}
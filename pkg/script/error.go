@@ -0,0 +1,168 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+)
+
+// error.go - 脚本执行错误定义文件
+//
+// 脚本执行失败时返回Error，其中ErrorCode精确标识失败原因，
+// Description给出包含操作码和具体数值的说明。调用方可以用errors.As取得Error，
+// 或用IsErrorCode判断错误是否属于某个错误码。
+
+// ErrorCode 脚本错误码
+type ErrorCode int
+
+// 脚本错误码定义
+const (
+	// ErrCodeInvalidIndex 输入索引超出交易输入范围
+	ErrCodeInvalidIndex ErrorCode = iota
+
+	// ErrCodeScriptTooBig 脚本长度超过MaxScriptSize
+	ErrCodeScriptTooBig
+
+	// ErrCodeMalformedPush 数据推送操作码声明的长度超过脚本剩余长度
+	ErrCodeMalformedPush
+
+	// ErrCodeElementTooBig 推送的数据超过MaxScriptElementSize
+	ErrCodeElementTooBig
+
+	// ErrCodeTooManyOperations 单个脚本执行的非推送操作数超过MaxOpsPerScript
+	ErrCodeTooManyOperations
+
+	// ErrCodeStackOverflow 栈中元素数超过MaxStackSize
+	ErrCodeStackOverflow
+
+	// ErrCodeNotPushOnly 解锁脚本包含数据推送以外的操作码
+	ErrCodeNotPushOnly
+
+	// ErrCodeUnsupportedOpcode 执行了不支持的操作码
+	ErrCodeUnsupportedOpcode
+
+	// ErrCodeEarlyReturn 执行了OP_RETURN
+	ErrCodeEarlyReturn
+
+	// ErrCodeUnbalancedConditional OP_ELSE/OP_ENDIF没有对应的OP_IF，或脚本结束时条件分支未闭合
+	ErrCodeUnbalancedConditional
+
+	// ErrCodeInvalidStackOperation 操作需要的栈元素不足
+	ErrCodeInvalidStackOperation
+
+	// ErrCodeEmptyStack 脚本执行结束时栈为空
+	ErrCodeEmptyStack
+
+	// ErrCodeEvalFalse 脚本执行结束时栈顶元素为假
+	ErrCodeEvalFalse
+
+	// ErrCodeVerify OP_VERIFY的栈顶元素为假
+	ErrCodeVerify
+
+	// ErrCodeEqualVerify OP_EQUALVERIFY比较的两个元素不相等
+	ErrCodeEqualVerify
+
+	// ErrCodeCheckSigVerify OP_CHECKSIGVERIFY签名验证失败
+	ErrCodeCheckSigVerify
+
+	// ErrCodeCheckMultiSigVerify OP_CHECKMULTISIGVERIFY签名验证失败
+	ErrCodeCheckMultiSigVerify
+
+	// ErrCodeMinimalData 整数没有使用最短编码
+	ErrCodeMinimalData
+
+	// ErrCodeNumberTooBig 整数编码超过操作允许的字节数
+	ErrCodeNumberTooBig
+
+	// ErrCodeInvalidPubKeyCount 多重签名的公钥数为负或超过MaxPubKeysPerMultiSig
+	ErrCodeInvalidPubKeyCount
+
+	// ErrCodeInvalidSignatureCount 多重签名的签名数为负或超过公钥数
+	ErrCodeInvalidSignatureCount
+
+	// ErrCodeSigNullDummy OP_CHECKMULTISIG额外弹出的元素不为空
+	ErrCodeSigNullDummy
+
+	// ErrCodeNegativeLockTime OP_CHECKLOCKTIMEVERIFY的锁定时间为负
+	ErrCodeNegativeLockTime
+
+	// ErrCodeUnsatisfiedLockTime 交易的锁定时间不满足OP_CHECKLOCKTIMEVERIFY的要求
+	ErrCodeUnsatisfiedLockTime
+
+	// ErrCodeInvalidSigHashType 签名的哈希类型不受支持
+	ErrCodeInvalidSigHashType
+
+	// ErrCodeSigDER 签名不是严格的DER编码
+	ErrCodeSigDER
+
+	// ErrCodeSigHighS 签名的S值超过曲线阶的一半
+	ErrCodeSigHighS
+
+	// ErrCodePubKeyType 公钥不是有效的压缩或非压缩公钥
+	ErrCodePubKeyType
+)
+
+// errorCodeStrings 错误码名称映射，用于日志输出
+var errorCodeStrings = map[ErrorCode]string{
+	ErrCodeInvalidIndex:          "ErrCodeInvalidIndex",
+	ErrCodeScriptTooBig:          "ErrCodeScriptTooBig",
+	ErrCodeMalformedPush:         "ErrCodeMalformedPush",
+	ErrCodeElementTooBig:         "ErrCodeElementTooBig",
+	ErrCodeTooManyOperations:     "ErrCodeTooManyOperations",
+	ErrCodeStackOverflow:         "ErrCodeStackOverflow",
+	ErrCodeNotPushOnly:           "ErrCodeNotPushOnly",
+	ErrCodeUnsupportedOpcode:     "ErrCodeUnsupportedOpcode",
+	ErrCodeEarlyReturn:           "ErrCodeEarlyReturn",
+	ErrCodeUnbalancedConditional: "ErrCodeUnbalancedConditional",
+	ErrCodeInvalidStackOperation: "ErrCodeInvalidStackOperation",
+	ErrCodeEmptyStack:            "ErrCodeEmptyStack",
+	ErrCodeEvalFalse:             "ErrCodeEvalFalse",
+	ErrCodeVerify:                "ErrCodeVerify",
+	ErrCodeEqualVerify:           "ErrCodeEqualVerify",
+	ErrCodeCheckSigVerify:        "ErrCodeCheckSigVerify",
+	ErrCodeCheckMultiSigVerify:   "ErrCodeCheckMultiSigVerify",
+	ErrCodeMinimalData:           "ErrCodeMinimalData",
+	ErrCodeNumberTooBig:          "ErrCodeNumberTooBig",
+	ErrCodeInvalidPubKeyCount:    "ErrCodeInvalidPubKeyCount",
+	ErrCodeInvalidSignatureCount: "ErrCodeInvalidSignatureCount",
+	ErrCodeSigNullDummy:          "ErrCodeSigNullDummy",
+	ErrCodeNegativeLockTime:      "ErrCodeNegativeLockTime",
+	ErrCodeUnsatisfiedLockTime:   "ErrCodeUnsatisfiedLockTime",
+	ErrCodeInvalidSigHashType:    "ErrCodeInvalidSigHashType",
+	ErrCodeSigDER:                "ErrCodeSigDER",
+	ErrCodeSigHighS:              "ErrCodeSigHighS",
+	ErrCodePubKeyType:            "ErrCodePubKeyType",
+}
+
+// String 返回错误码名称
+func (e ErrorCode) String() string {
+	if s, ok := errorCodeStrings[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("未知错误码(%d)", int(e))
+}
+
+// Error 脚本执行错误
+//
+// 字段说明：
+// - ErrorCode: 失败原因
+// - Description: 错误的详细描述
+type Error struct {
+	ErrorCode   ErrorCode // 失败原因
+	Description string    // 错误详细描述
+}
+
+// Error 实现error接口
+func (e Error) Error() string {
+	return e.Description
+}
+
+// scriptError 创建脚本执行错误
+func scriptError(code ErrorCode, desc string) Error {
+	return Error{ErrorCode: code, Description: desc}
+}
+
+// IsErrorCode 判断错误链中是否包含指定错误码的脚本错误
+func IsErrorCode(err error, code ErrorCode) bool {
+	var serr Error
+	return errors.As(err, &serr) && serr.ErrorCode == code
+}
@@ -0,0 +1,199 @@
+package script
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// opcode.go - 操作码定义和脚本解析文件
+//
+// 操作码的取值与比特币一致，本包只实现标准锁定脚本和解锁脚本用到的子集，
+// 其他操作码被执行时返回ErrCodeUnsupportedOpcode。
+
+// 数据推送操作码
+const (
+	// Op0 推送空字节数组（数值0）
+	Op0 = 0x00
+
+	// OpFalse Op0的别名
+	OpFalse = Op0
+
+	// OpData1 推送随后的1字节数据，OpData1到OpData75依次推送1到75字节
+	OpData1 = 0x01
+
+	// OpData20 推送随后的20字节数据（公钥哈希或脚本哈希）
+	OpData20 = 0x14
+
+	// OpData33 推送随后的33字节数据（压缩公钥）
+	OpData33 = 0x21
+
+	// OpData65 推送随后的65字节数据（非压缩公钥）
+	OpData65 = 0x41
+
+	// OpData75 推送随后的75字节数据
+	OpData75 = 0x4b
+
+	// OpPushData1 随后1字节为数据长度
+	OpPushData1 = 0x4c
+
+	// OpPushData2 随后2字节（小端序）为数据长度
+	OpPushData2 = 0x4d
+
+	// OpPushData4 随后4字节（小端序）为数据长度
+	OpPushData4 = 0x4e
+
+	// Op1Negate 推送数值-1
+	Op1Negate = 0x4f
+
+	// Op1 推送数值1，Op1到Op16依次推送1到16
+	Op1 = 0x51
+
+	// OpTrue Op1的别名
+	OpTrue = Op1
+
+	// Op16 推送数值16
+	Op16 = 0x60
+)
+
+// 流程控制、栈操作、哈希和签名操作码
+const (
+	OpNop                 = 0x61 // 无操作
+	OpIf                  = 0x63 // 栈顶为真时执行随后的分支
+	OpNotIf               = 0x64 // 栈顶为假时执行随后的分支
+	OpElse                = 0x67 // 切换当前分支的执行状态
+	OpEndIf               = 0x68 // 结束条件分支
+	OpVerify              = 0x69 // 栈顶为假时脚本失败
+	OpReturn              = 0x6a // 脚本立即失败，用于标记不可花费的输出
+	OpDrop                = 0x75 // 移除栈顶元素
+	OpDup                 = 0x76 // 复制栈顶元素
+	OpEqual               = 0x87 // 比较栈顶两个元素是否相等
+	OpEqualVerify         = 0x88 // OP_EQUAL后执行OP_VERIFY
+	OpRipemd160           = 0xa6 // 计算栈顶元素的RIPEMD-160
+	OpSha256              = 0xa8 // 计算栈顶元素的SHA-256
+	OpHash160             = 0xa9 // 计算栈顶元素的SHA-256后再RIPEMD-160
+	OpHash256             = 0xaa // 计算栈顶元素的双重SHA-256
+	OpCheckSig            = 0xac // 验证签名
+	OpCheckSigVerify      = 0xad // OP_CHECKSIG后执行OP_VERIFY
+	OpCheckMultiSig       = 0xae // 验证m-of-n多重签名
+	OpCheckMultiSigVerify = 0xaf // OP_CHECKMULTISIG后执行OP_VERIFY
+	OpCheckLockTimeVerify = 0xb1 // 交易锁定时间未达到栈顶数值时脚本失败（BIP65）
+)
+
+// opcodeNames 操作码名称，用于错误描述
+var opcodeNames = map[byte]string{
+	Op0:                   "OP_0",
+	OpPushData1:           "OP_PUSHDATA1",
+	OpPushData2:           "OP_PUSHDATA2",
+	OpPushData4:           "OP_PUSHDATA4",
+	Op1Negate:             "OP_1NEGATE",
+	OpNop:                 "OP_NOP",
+	OpIf:                  "OP_IF",
+	OpNotIf:               "OP_NOTIF",
+	OpElse:                "OP_ELSE",
+	OpEndIf:               "OP_ENDIF",
+	OpVerify:              "OP_VERIFY",
+	OpReturn:              "OP_RETURN",
+	OpDrop:                "OP_DROP",
+	OpDup:                 "OP_DUP",
+	OpEqual:               "OP_EQUAL",
+	OpEqualVerify:         "OP_EQUALVERIFY",
+	OpRipemd160:           "OP_RIPEMD160",
+	OpSha256:              "OP_SHA256",
+	OpHash160:             "OP_HASH160",
+	OpHash256:             "OP_HASH256",
+	OpCheckSig:            "OP_CHECKSIG",
+	OpCheckSigVerify:      "OP_CHECKSIGVERIFY",
+	OpCheckMultiSig:       "OP_CHECKMULTISIG",
+	OpCheckMultiSigVerify: "OP_CHECKMULTISIGVERIFY",
+	OpCheckLockTimeVerify: "OP_CHECKLOCKTIMEVERIFY",
+}
+
+// OpcodeName 返回操作码的名称
+func OpcodeName(op byte) string {
+	switch {
+	case op >= OpData1 && op <= OpData75:
+		return fmt.Sprintf("OP_DATA_%d", op)
+	case op >= Op1 && op <= Op16:
+		return fmt.Sprintf("OP_%d", op-Op1+1)
+	}
+	if name, ok := opcodeNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("OP_UNKNOWN(0x%02x)", op)
+}
+
+// parsedOpcode 解析后的操作码及其推送的数据
+type parsedOpcode struct {
+	opcode byte
+	data   []byte
+}
+
+// isPush 判断操作码是否为数据推送操作
+//
+// Op0到Op16都视为推送操作，它们不计入MaxOpsPerScript。
+func (pop *parsedOpcode) isPush() bool {
+	return pop.opcode <= Op16
+}
+
+// isConditional 判断操作码是否为条件分支操作
+//
+// 条件分支操作在未执行的分支中也要处理，以维护分支嵌套关系。
+func (pop *parsedOpcode) isConditional() bool {
+	switch pop.opcode {
+	case OpIf, OpNotIf, OpElse, OpEndIf:
+		return true
+	}
+	return false
+}
+
+// parseScript 将脚本解析为操作码序列
+//
+// 数据推送声明的长度超过脚本剩余长度时返回ErrCodeMalformedPush。
+func parseScript(script []byte) ([]parsedOpcode, error) {
+	var ops []parsedOpcode
+	for i := 0; i < len(script); {
+		op := script[i]
+		i++
+
+		var dataLen int
+		switch {
+		case op >= OpData1 && op <= OpData75:
+			dataLen = int(op)
+		case op == OpPushData1:
+			if i+1 > len(script) {
+				return nil, malformedPush(op, i)
+			}
+			dataLen = int(script[i])
+			i++
+		case op == OpPushData2:
+			if i+2 > len(script) {
+				return nil, malformedPush(op, i)
+			}
+			dataLen = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		case op == OpPushData4:
+			if i+4 > len(script) {
+				return nil, malformedPush(op, i)
+			}
+			dataLen = int(binary.LittleEndian.Uint32(script[i:]))
+			i += 4
+		}
+
+		if dataLen < 0 || dataLen > len(script)-i {
+			return nil, malformedPush(op, i)
+		}
+		pop := parsedOpcode{opcode: op}
+		if op >= OpData1 && op <= OpPushData4 {
+			pop.data = script[i : i+dataLen]
+		}
+		ops = append(ops, pop)
+		i += dataLen
+	}
+	return ops, nil
+}
+
+// malformedPush 创建数据推送越界错误
+func malformedPush(op byte, offset int) error {
+	return scriptError(ErrCodeMalformedPush,
+		fmt.Sprintf("%s在偏移%d处的数据超出脚本末尾", OpcodeName(op), offset))
+}
@@ -0,0 +1,194 @@
+// Package script 实现了简化的比特币脚本解释器
+//
+// 每个交易输出的锁定脚本规定了花费该输出的条件，花费它的交易输入提供解锁脚本。
+// 验证时先执行解锁脚本，再以其留下的栈执行锁定脚本，结束时栈顶为真即验证通过。
+// 锁定脚本为P2SH格式时，解锁脚本推送的最后一个元素作为赎回脚本继续执行（BIP16）。
+//
+// 解释器支持数据推送、OP_DUP、OP_HASH160、OP_EQUAL/OP_EQUALVERIFY、
+// OP_CHECKSIG/OP_CHECKMULTISIG、OP_CHECKLOCKTIMEVERIFY、OP_RETURN和
+// OP_IF/OP_NOTIF/OP_ELSE/OP_ENDIF等标准脚本用到的操作码。为了限制恶意脚本
+// 消耗的资源，脚本长度、推送数据长度、每个脚本的操作数和栈深度都有上限，
+// 失败时返回带有精确错误码的Error。
+//
+// 签名覆盖的交易内容由签名哈希类型决定：SIGHASH_ALL、SIGHASH_NONE和SIGHASH_SINGLE
+// 都可以与SIGHASH_ANYONECANPAY组合，多个参与方因此可以各自只签名自己的输入。
+// 签名必须是严格DER编码的low-S ECDSA签名。
+package script
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+)
+
+// 脚本执行限制
+const (
+	// MaxScriptElementSize 单次推送数据的最大字节数
+	MaxScriptElementSize = 520
+
+	// MaxOpsPerScript 单个脚本执行的非推送操作数上限，
+	// OP_CHECKMULTISIG的每个公钥额外计1次
+	MaxOpsPerScript = 201
+
+	// MaxStackSize 栈中元素数上限
+	MaxStackSize = 1000
+
+	// MaxPubKeysPerMultiSig 多重签名的最大公钥数
+	MaxPubKeysPerMultiSig = 20
+)
+
+// IsPushOnly 判断脚本是否只包含数据推送操作
+//
+// 格式错误的脚本返回false。
+func IsPushOnly(script []byte) bool {
+	ops, err := parseScript(script)
+	if err != nil {
+		return false
+	}
+	for i := range ops {
+		if !ops[i].isPush() {
+			return false
+		}
+	}
+	return true
+}
+
+// CountSigOps 统计脚本中的签名操作数
+//
+// CHECKSIG类操作计1次，CHECKMULTISIG类操作按最大公钥数计算。
+// 遇到格式错误的数据推送时停止统计并返回已统计的数量。
+func CountSigOps(script []byte) int {
+	count := 0
+	for i := 0; i < len(script); {
+		op := script[i]
+		i++
+
+		var dataLen int
+		switch {
+		case op >= OpData1 && op <= OpData75:
+			dataLen = int(op)
+		case op == OpPushData1:
+			if i+1 > len(script) {
+				return count
+			}
+			dataLen = int(script[i])
+			i++
+		case op == OpPushData2:
+			if i+2 > len(script) {
+				return count
+			}
+			dataLen = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		case op == OpPushData4:
+			if i+4 > len(script) {
+				return count
+			}
+			dataLen = int(binary.LittleEndian.Uint32(script[i:]))
+			i += 4
+		case op == OpCheckSig || op == OpCheckSigVerify:
+			count++
+		case op == OpCheckMultiSig || op == OpCheckMultiSigVerify:
+			count += MaxPubKeysPerMultiSig
+		}
+
+		if dataLen < 0 || dataLen > len(script)-i {
+			return count
+		}
+		i += dataLen
+	}
+	return count
+}
+
+// CountTxSigOps 统计交易所有输入脚本和输出脚本中的签名操作数
+func CountTxSigOps(tx *blockchain.Transaction) int {
+	count := 0
+	for _, txIn := range tx.TxIn {
+		count += CountSigOps(txIn.SignatureScript)
+	}
+	for _, txOut := range tx.TxOut {
+		count += CountSigOps(txOut.PkScript)
+	}
+	return count
+}
+
+// IsPayToScriptHash 判断锁定脚本是否为P2SH格式
+//
+// 脚本格式：OP_HASH160 <20字节脚本哈希> OP_EQUAL
+func IsPayToScriptHash(script []byte) bool {
+	return len(script) == 23 && script[0] == OpHash160 && script[1] == OpData20 && script[22] == OpEqual
+}
+
+// Builder 脚本构造器
+//
+// 数据和整数总是使用最短的推送方式编码。构造过程中的错误被记录下来，
+// 由Script统一返回，因此可以链式调用。
+type Builder struct {
+	script []byte
+	err    error
+}
+
+// NewBuilder 创建脚本构造器
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// AddOp 追加操作码
+func (b *Builder) AddOp(op byte) *Builder {
+	b.script = append(b.script, op)
+	return b
+}
+
+// AddData 追加推送数据的操作
+//
+// 数据超过MaxScriptElementSize时Script返回ErrCodeElementTooBig。
+func (b *Builder) AddData(data []byte) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if len(data) > MaxScriptElementSize {
+		b.err = scriptError(ErrCodeElementTooBig,
+			fmt.Sprintf("推送数据%d字节超过上限%d", len(data), MaxScriptElementSize))
+		return b
+	}
+
+	switch n := len(data); {
+	case n == 0:
+		b.script = append(b.script, Op0)
+	case n == 1 && data[0] >= 1 && data[0] <= 16:
+		b.script = append(b.script, Op1+data[0]-1)
+	case n == 1 && data[0] == 0x81:
+		b.script = append(b.script, Op1Negate)
+	case n <= OpData75:
+		b.script = append(b.script, byte(n))
+		b.script = append(b.script, data...)
+	case n <= 0xff:
+		b.script = append(b.script, OpPushData1, byte(n))
+		b.script = append(b.script, data...)
+	default:
+		b.script = append(b.script, OpPushData2)
+		b.script = binary.LittleEndian.AppendUint16(b.script, uint16(n))
+		b.script = append(b.script, data...)
+	}
+	return b
+}
+
+// AddInt64 追加推送整数的操作
+func (b *Builder) AddInt64(n int64) *Builder {
+	if n == 0 {
+		return b.AddOp(Op0)
+	}
+	return b.AddData(scriptNum(n).Bytes())
+}
+
+// Script 返回构造的脚本
+func (b *Builder) Script() ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.script) > blockchain.MaxScriptSize {
+		return nil, scriptError(ErrCodeScriptTooBig,
+			fmt.Sprintf("脚本长度%d超过上限%d", len(b.script), blockchain.MaxScriptSize))
+	}
+	return b.script, nil
+}
@@ -0,0 +1,202 @@
+package script
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// SigHashType 签名哈希类型，决定签名覆盖交易的哪些部分
+//
+// 哈希类型附加在DER编码签名的末尾，占1字节。
+type SigHashType uint32
+
+// 签名哈希类型
+const (
+	// SigHashAll 签名覆盖全部输入和输出
+	SigHashAll SigHashType = 0x1
+
+	// SigHashNone 签名覆盖全部输入但不覆盖输出，其他输入的序列号可以修改
+	SigHashNone SigHashType = 0x2
+
+	// SigHashSingle 签名覆盖全部输入和与被签名输入索引相同的输出，
+	// 其他输出和其他输入的序列号可以修改
+	SigHashSingle SigHashType = 0x3
+
+	// SigHashAnyOneCanPay 与上面三种类型组合使用，签名只覆盖被签名的输入，
+	// 其他参与方可以继续添加输入
+	SigHashAnyOneCanPay SigHashType = 0x80
+
+	// sigHashMask 基本类型所在的位
+	sigHashMask = 0x1f
+)
+
+// String 返回哈希类型的名称
+func (t SigHashType) String() string {
+	var name string
+	switch t &^ SigHashAnyOneCanPay {
+	case SigHashAll:
+		name = "SIGHASH_ALL"
+	case SigHashNone:
+		name = "SIGHASH_NONE"
+	case SigHashSingle:
+		name = "SIGHASH_SINGLE"
+	default:
+		return fmt.Sprintf("SIGHASH_UNKNOWN(0x%x)", uint32(t))
+	}
+	if t&SigHashAnyOneCanPay != 0 {
+		name += "|SIGHASH_ANYONECANPAY"
+	}
+	return name
+}
+
+// checkHashType 检查哈希类型是否为三种基本类型之一，可以附加ANYONECANPAY
+func checkHashType(hashType SigHashType) error {
+	switch hashType &^ SigHashAnyOneCanPay {
+	case SigHashAll, SigHashNone, SigHashSingle:
+		return nil
+	}
+	return scriptError(ErrCodeInvalidSigHashType, fmt.Sprintf("不支持的签名哈希类型0x%x", uint32(hashType)))
+}
+
+// CalcSignatureHash 计算交易第idx个输入的签名哈希
+//
+// 交易副本中被签名输入的解锁脚本替换为subScript（正在执行的锁定脚本或赎回脚本），
+// 其他输入的解锁脚本清空，再按哈希类型裁剪：
+// - SIGHASH_NONE: 移除全部输出，其他输入的序列号置0
+// - SIGHASH_SINGLE: 只保留到idx为止的输出，idx之前的输出置为空输出（金额-1、空脚本），
+// 其他输入的序列号置0；idx没有对应的输出时返回ErrCodeInvalidIndex
+// - SIGHASH_ANYONECANPAY: 只保留被签名的输入
+//
+// 裁剪后的交易序列化后追加4字节哈希类型，再计算双重SHA-256。
+func CalcSignatureHash(subScript []byte, hashType SigHashType, tx *blockchain.Transaction, idx int) ([32]byte, error) {
+	var hash [32]byte
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return hash, scriptError(ErrCodeInvalidIndex,
+			fmt.Sprintf("输入索引%d超出范围, 交易共%d个输入", idx, len(tx.TxIn)))
+	}
+	if err := checkHashType(hashType); err != nil {
+		return hash, err
+	}
+
+	// 比特币在这种情况下签名常数1，任何人都可以重用该签名，这里直接拒绝
+	if hashType&sigHashMask == SigHashSingle && idx >= len(tx.TxOut) {
+		return hash, scriptError(ErrCodeInvalidIndex,
+			fmt.Sprintf("SIGHASH_SINGLE的输入索引%d没有对应的输出, 交易共%d个输出", idx, len(tx.TxOut)))
+	}
+
+	txCopy := *tx
+	txCopy.TxIn = make([]*blockchain.TxIn, len(tx.TxIn))
+	for i, txIn := range tx.TxIn {
+		in := *txIn
+		if i == idx {
+			in.SignatureScript = subScript
+		} else {
+			in.SignatureScript = nil
+		}
+		txCopy.TxIn[i] = &in
+	}
+
+	switch hashType & sigHashMask {
+	case SigHashNone:
+		txCopy.TxOut = nil
+		clearOtherSequences(&txCopy, idx)
+	case SigHashSingle:
+		txCopy.TxOut = make([]*blockchain.TxOut, idx+1)
+		for i := 0; i < idx; i++ {
+			txCopy.TxOut[i] = blockchain.NewTxOut(-1, nil)
+		}
+		txCopy.TxOut[idx] = tx.TxOut[idx]
+		clearOtherSequences(&txCopy, idx)
+	}
+	if hashType&SigHashAnyOneCanPay != 0 {
+		txCopy.TxIn = txCopy.TxIn[idx : idx+1]
+	}
+
+	data := binary.LittleEndian.AppendUint32(txCopy.Serialize(), uint32(hashType))
+	copy(hash[:], utils.DoubleSHA256(data))
+	return hash, nil
+}
+
+// clearOtherSequences 将被签名输入以外的输入序列号置0
+func clearOtherSequences(tx *blockchain.Transaction, idx int) {
+	for i, txIn := range tx.TxIn {
+		if i != idx {
+			txIn.Sequence = 0
+		}
+	}
+}
+
+// RawTxInSignature 对交易第idx个输入签名
+//
+// 使用RFC6979确定性随机数和low-S形式生成ECDSA签名，返回DER编码的签名并在末尾
+// 附加1字节哈希类型，可以直接放入解锁脚本。subScript为被花费输出的锁定脚本，
+// P2SH输出则为赎回脚本。
+func RawTxInSignature(tx *blockchain.Transaction, idx int, subScript []byte, hashType SigHashType,
+	key *secp256k1.PrivateKey) ([]byte, error) {
+	hash, err := CalcSignatureHash(subScript, hashType, tx, idx)
+	if err != nil {
+		return nil, err
+	}
+	sig := ecdsa.Sign(key, hash[:]).Serialize()
+	return append(sig, byte(hashType)), nil
+}
+
+// SignatureScript 生成花费P2PKH输出的解锁脚本
+//
+// 脚本格式：<签名> <公钥>，compress决定使用压缩还是非压缩公钥，
+// 必须与锁定脚本中公钥哈希对应的格式一致。
+func SignatureScript(tx *blockchain.Transaction, idx int, subScript []byte, hashType SigHashType,
+	key *secp256k1.PrivateKey, compress bool) ([]byte, error) {
+	sig, err := RawTxInSignature(tx, idx, subScript, hashType, key)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey := key.PubKey().SerializeUncompressed()
+	if compress {
+		pubKey = key.PubKey().SerializeCompressed()
+	}
+	return NewBuilder().AddData(sig).AddData(pubKey).Script()
+}
+
+// parseSigAndHashType 拆分栈中的签名元素，返回签名和哈希类型
+//
+// 签名必须是严格的DER编码（BIP66）且S值不超过曲线阶的一半（low-S），
+// 否则同一签名可以被第三方改写为另一个有效签名，从而改变交易哈希。
+func parseSigAndHashType(sig []byte) (*ecdsa.Signature, SigHashType, error) {
+	hashType := SigHashType(sig[len(sig)-1])
+	if err := checkHashType(hashType); err != nil {
+		return nil, 0, err
+	}
+
+	signature, err := ecdsa.ParseDERSignature(sig[:len(sig)-1])
+	if err != nil {
+		return nil, 0, scriptError(ErrCodeSigDER, fmt.Sprintf("签名编码错误: %v", err))
+	}
+	if s := signature.S(); s.IsOverHalfOrder() {
+		return nil, 0, scriptError(ErrCodeSigHighS, "签名的S值超过曲线阶的一半")
+	}
+	return signature, hashType, nil
+}
+
+// parsePubKey 解析栈中的公钥，只接受33字节压缩公钥和65字节非压缩公钥
+func parsePubKey(pubKey []byte) (*secp256k1.PublicKey, error) {
+	switch {
+	case len(pubKey) == secp256k1.PubKeyBytesLenCompressed && (pubKey[0] == 0x02 || pubKey[0] == 0x03):
+	case len(pubKey) == secp256k1.PubKeyBytesLenUncompressed && pubKey[0] == 0x04:
+	default:
+		return nil, scriptError(ErrCodePubKeyType, fmt.Sprintf("公钥格式错误: %x", pubKey))
+	}
+
+	key, err := secp256k1.ParsePubKey(pubKey)
+	if err != nil {
+		return nil, scriptError(ErrCodePubKeyType, fmt.Sprintf("无效的公钥: %v", err))
+	}
+	return key, nil
+}
@@ -0,0 +1,167 @@
+package script
+
+import "fmt"
+
+// stack.go - 脚本数据栈和整数编码
+
+// 整数编码的最大字节数
+const (
+	// defaultNumLen 算术操作数的最大字节数
+	defaultNumLen = 4
+
+	// lockTimeNumLen OP_CHECKLOCKTIMEVERIFY操作数的最大字节数，可以表示到2^39-1
+	lockTimeNumLen = 5
+)
+
+// scriptNum 脚本中的整数
+//
+// 整数以小端序存储，最高字节的最高位为符号位，0编码为空字节数组。
+type scriptNum int64
+
+// makeScriptNum 解析栈元素中的整数
+//
+// 元素超过maxLen字节时返回ErrCodeNumberTooBig，没有使用最短编码时
+// 返回ErrCodeMinimalData，避免同一数值有多种编码。
+func makeScriptNum(v []byte, maxLen int) (scriptNum, error) {
+	if len(v) > maxLen {
+		return 0, scriptError(ErrCodeNumberTooBig,
+			fmt.Sprintf("整数编码%d字节超过上限%d", len(v), maxLen))
+	}
+	if len(v) == 0 {
+		return 0, nil
+	}
+
+	// 最高字节除符号位外为0时，只有次高字节的最高位被占用才是最短编码
+	if v[len(v)-1]&0x7f == 0 && (len(v) == 1 || v[len(v)-2]&0x80 == 0) {
+		return 0, scriptError(ErrCodeMinimalData, fmt.Sprintf("整数%x不是最短编码", v))
+	}
+
+	var result int64
+	for i, b := range v {
+		result |= int64(b) << uint(8*i)
+	}
+	if v[len(v)-1]&0x80 != 0 {
+		result &^= int64(0x80) << uint(8*(len(v)-1))
+		return scriptNum(-result), nil
+	}
+	return scriptNum(result), nil
+}
+
+// Bytes 返回整数的最短编码
+func (n scriptNum) Bytes() []byte {
+	if n == 0 {
+		return nil
+	}
+
+	negative := n < 0
+	if negative {
+		n = -n
+	}
+	result := make([]byte, 0, 9)
+	for n > 0 {
+		result = append(result, byte(n&0xff))
+		n >>= 8
+	}
+
+	// 最高位已被占用时追加一个字节存放符号位
+	if result[len(result)-1]&0x80 != 0 {
+		if negative {
+			result = append(result, 0x80)
+		} else {
+			result = append(result, 0x00)
+		}
+	} else if negative {
+		result[len(result)-1] |= 0x80
+	}
+	return result
+}
+
+// asBool 将栈元素解释为布尔值
+//
+// 全零字节（包括负零0x80）为假，其他为真。
+func asBool(v []byte) bool {
+	for i, b := range v {
+		if b != 0 {
+			return i != len(v)-1 || b != 0x80
+		}
+	}
+	return false
+}
+
+// fromBool 将布尔值编码为栈元素
+func fromBool(v bool) []byte {
+	if v {
+		return []byte{1}
+	}
+	return nil
+}
+
+// stack 脚本数据栈，切片末尾为栈顶
+type stack [][]byte
+
+// depth 返回栈中元素数
+func (s *stack) depth() int {
+	return len(*s)
+}
+
+// push 压入元素
+func (s *stack) push(v []byte) {
+	*s = append(*s, v)
+}
+
+// pushBool 压入布尔值
+func (s *stack) pushBool(v bool) {
+	s.push(fromBool(v))
+}
+
+// pushInt 压入整数
+func (s *stack) pushInt(n scriptNum) {
+	s.push(n.Bytes())
+}
+
+// pop 弹出栈顶元素
+func (s *stack) pop() ([]byte, error) {
+	if len(*s) == 0 {
+		return nil, scriptError(ErrCodeInvalidStackOperation, "栈为空，无法弹出元素")
+	}
+	v := (*s)[len(*s)-1]
+	*s = (*s)[:len(*s)-1]
+	return v, nil
+}
+
+// popN 弹出栈顶n个元素，按压入顺序返回
+func (s *stack) popN(n int) ([][]byte, error) {
+	if n > len(*s) {
+		return nil, scriptError(ErrCodeInvalidStackOperation,
+			fmt.Sprintf("需要%d个栈元素, 栈中只有%d个", n, len(*s)))
+	}
+	items := append([][]byte(nil), (*s)[len(*s)-n:]...)
+	*s = (*s)[:len(*s)-n]
+	return items, nil
+}
+
+// popBool 弹出栈顶元素并解释为布尔值
+func (s *stack) popBool() (bool, error) {
+	v, err := s.pop()
+	if err != nil {
+		return false, err
+	}
+	return asBool(v), nil
+}
+
+// popInt 弹出栈顶元素并解析为整数
+func (s *stack) popInt(maxLen int) (scriptNum, error) {
+	v, err := s.pop()
+	if err != nil {
+		return 0, err
+	}
+	return makeScriptNum(v, maxLen)
+}
+
+// peek 返回栈顶元素但不弹出
+func (s *stack) peek() ([]byte, error) {
+	if len(*s) == 0 {
+		return nil, scriptError(ErrCodeInvalidStackOperation, "栈为空，无法读取栈顶元素")
+	}
+	return (*s)[len(*s)-1], nil
+}
@@ -0,0 +1,409 @@
+// Package storage 实现了基于BoltDB的区块链持久化存储
+//
+// 存储内容包括：
+// - 区块数据：以区块哈希为键保存完整序列化区块
+// - 区块头索引：以区块哈希为键保存区块头及其高度，支持仅有区块头的条目
+// - 高度索引：主链高度到区块哈希的映射
+// - 链状态：当前主链末端（tip）的哈希与高度
+//
+// 首次打开数据库时会自动写入创世区块并将其设为主链末端。
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// 数据库桶和键定义
+var (
+	blocksBucket      = []byte(utils.BlocksBucket)     // 区块哈希 -> 序列化区块
+	headersBucket     = []byte("headers")              // 区块哈希 -> 区块头(80) | 高度(4)
+	heightIndexBucket = []byte("heightindex")          // 高度(4，大端序) -> 区块哈希
+	chainStateBucket  = []byte(utils.ChainStateBucket) // 链状态
+	tipKey            = []byte("tip")                  // 主链末端的哈希和高度
+)
+
+// openTimeout 打开数据库时等待文件锁的最长时间
+const openTimeout = 3 * time.Second
+
+// 标准错误定义
+var (
+	// ErrBlockNotFound 请求的区块不存在
+	ErrBlockNotFound = errors.New("区块不存在")
+
+	// ErrHeaderNotFound 请求的区块头不存在
+	ErrHeaderNotFound = errors.New("区块头不存在")
+
+	// ErrHeightNotFound 请求的主链高度不存在
+	ErrHeightNotFound = errors.New("主链高度不存在")
+)
+
+// Store 区块链持久化存储
+type Store struct {
+	db        *bolt.DB
+	batchSize int
+	params    *blockchain.ChainParams
+}
+
+// Open 打开区块链存储
+//
+// 根据数据库配置打开BoltDB文件，不存在时自动创建。
+// 首次打开时写入网络参数中的创世区块。
+//
+// 参数：
+// - cfg: 数据库配置，Type必须为bolt
+// - params: 网络参数
+func Open(cfg utils.DatabaseConfig, params *blockchain.ChainParams) (*Store, error) {
+	if params == nil {
+		return nil, errors.New("缺少网络参数")
+	}
+	if cfg.Type != "bolt" {
+		return nil, fmt.Errorf("不支持的数据库类型: %s", cfg.Type)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("创建数据库目录失败: %v", err)
+	}
+
+	db, err := bolt.Open(cfg.Path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库%s失败: %v", cfg.Path, err)
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	store := &Store{
+		db:        db,
+		batchSize: batchSize,
+		params:    params,
+	}
+
+	if err := store.init(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// init 创建所需的桶并在首次打开时写入创世区块
+func (s *Store) init() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{blocksBucket, headersBucket, heightIndexBucket, chainStateBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("创建桶%s失败: %v", name, err)
+			}
+		}
+
+		if tx.Bucket(chainStateBucket).Get(tipKey) != nil {
+			return nil
+		}
+
+		genesis := s.params.GenesisBlock
+		if err := putBlock(tx, genesis, 0); err != nil {
+			return fmt.Errorf("写入创世区块失败: %v", err)
+		}
+		return setTip(tx, genesis.Hash(), 0)
+	})
+}
+
+// Close 关闭数据库
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// DB 返回底层BoltDB实例
+//
+// 供UTXO集合等需要与区块存储共享同一数据库文件的组件使用。
+func (s *Store) DB() *bolt.DB {
+	return s.db
+}
+
+// PutBlock 保存区块
+//
+// 同时写入区块数据和区块头索引，不修改主链状态。
+//
+// 参数：
+// - block: 待保存的区块
+// - height: 区块高度
+func (s *Store) PutBlock(block *blockchain.Block, height int32) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putBlock(tx, block, height)
+	})
+}
+
+// PutBlocks 批量保存一组连续区块
+//
+// 区块必须依次衔接，第一个区块的高度为startHeight。
+// 每BatchSize个区块提交一次事务，兼顾写入性能与单个事务的大小。
+//
+// 参数：
+// - blocks: 依次衔接的区块列表
+// - startHeight: 第一个区块的高度
+func (s *Store) PutBlocks(blocks []*blockchain.Block, startHeight int32) error {
+	for i := 1; i < len(blocks); i++ {
+		if blocks[i].Header.PrevBlockHash != blocks[i-1].Hash() {
+			return fmt.Errorf("批量写入的区块%d与前一个区块不衔接", i)
+		}
+	}
+
+	for start := 0; start < len(blocks); start += s.batchSize {
+		end := start + s.batchSize
+		if end > len(blocks) {
+			end = len(blocks)
+		}
+
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			for i := start; i < end; i++ {
+				if err := putBlock(tx, blocks[i], startHeight+int32(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("批量写入区块失败(第%d-%d个): %v", start, end-1, err)
+		}
+	}
+
+	return nil
+}
+
+// PutHeader 保存区块头
+//
+// 仅写入区块头索引，用于先同步区块头、后下载区块数据的场景。
+//
+// 参数：
+// - header: 区块头
+// - height: 区块高度
+func (s *Store) PutHeader(header *blockchain.BlockHeader, height int32) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putHeader(tx, header, height)
+	})
+}
+
+// GetBlock 根据哈希获取区块
+//
+// 参数：
+// - hash: 区块哈希
+func (s *Store) GetBlock(hash [32]byte) (*blockchain.Block, error) {
+	var block *blockchain.Block
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(blocksBucket).Get(hash[:])
+		if data == nil {
+			return fmt.Errorf("%w: %s", ErrBlockNotFound, utils.HashToString(hash[:]))
+		}
+
+		block = &blockchain.Block{}
+		return block.Deserialize(data)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// HasBlock 判断是否已保存指定区块的完整数据
+//
+// 参数：
+// - hash: 区块哈希
+func (s *Store) HasBlock(hash [32]byte) (bool, error) {
+	var exists bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(blocksBucket).Get(hash[:]) != nil
+		return nil
+	})
+
+	return exists, err
+}
+
+// GetHeader 根据哈希获取区块头及其高度
+//
+// 参数：
+// - hash: 区块哈希
+func (s *Store) GetHeader(hash [32]byte) (*blockchain.BlockHeader, int32, error) {
+	var header *blockchain.BlockHeader
+	var height int32
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(headersBucket).Get(hash[:])
+		if data == nil {
+			return fmt.Errorf("%w: %s", ErrHeaderNotFound, utils.HashToString(hash[:]))
+		}
+
+		var err error
+		header, height, err = decodeHeaderEntry(data)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return header, height, nil
+}
+
+// ForEachHeader 遍历所有已保存的区块头
+//
+// 遍历顺序按区块哈希排列，与高度无关。回调返回错误时停止遍历。
+//
+// 参数：
+// - fn: 回调函数，接收区块头及其高度
+func (s *Store) ForEachHeader(fn func(header *blockchain.BlockHeader, height int32) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(headersBucket).ForEach(func(_, v []byte) error {
+			header, height, err := decodeHeaderEntry(v)
+			if err != nil {
+				return err
+			}
+			return fn(header, height)
+		})
+	})
+}
+
+// SetMainChainTip 设置主链末端
+//
+// 更新高度索引中该高度对应的区块，删除所有更高的高度索引，
+// 并记录新的主链末端。连接区块时传入新区块，断开区块时传入其父区块。
+//
+// 参数：
+// - hash: 新的主链末端区块哈希
+// - height: 新的主链末端高度
+func (s *Store) SetMainChainTip(hash [32]byte, height int32) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return setTip(tx, hash, height)
+	})
+}
+
+// Tip 获取当前主链末端的哈希和高度
+func (s *Store) Tip() ([32]byte, int32, error) {
+	var hash [32]byte
+	var height int32
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(chainStateBucket).Get(tipKey)
+		if len(data) != 36 {
+			return fmt.Errorf("链状态记录损坏")
+		}
+
+		copy(hash[:], data[:32])
+		height = int32(binary.LittleEndian.Uint32(data[32:]))
+		return nil
+	})
+
+	return hash, height, err
+}
+
+// GetHashByHeight 获取主链指定高度的区块哈希
+//
+// 参数：
+// - height: 主链高度
+func (s *Store) GetHashByHeight(height int32) ([32]byte, error) {
+	var hash [32]byte
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(heightIndexBucket).Get(heightKey(height))
+		if data == nil {
+			return fmt.Errorf("%w: %d", ErrHeightNotFound, height)
+		}
+
+		copy(hash[:], data)
+		return nil
+	})
+
+	return hash, err
+}
+
+// GetBlockByHeight 获取主链指定高度的区块
+//
+// 参数：
+// - height: 主链高度
+func (s *Store) GetBlockByHeight(height int32) (*blockchain.Block, error) {
+	hash, err := s.GetHashByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetBlock(hash)
+}
+
+// putBlock 在事务中写入区块数据和区块头索引
+func putBlock(tx *bolt.Tx, block *blockchain.Block, height int32) error {
+	hash := block.Hash()
+	if err := tx.Bucket(blocksBucket).Put(hash[:], block.Serialize()); err != nil {
+		return err
+	}
+	return putHeader(tx, block.Header, height)
+}
+
+// putHeader 在事务中写入区块头索引
+func putHeader(tx *bolt.Tx, header *blockchain.BlockHeader, height int32) error {
+	hash := header.Hash()
+
+	data := make([]byte, blockchain.BlockHeaderSize+4)
+	copy(data, header.Serialize())
+	binary.LittleEndian.PutUint32(data[blockchain.BlockHeaderSize:], uint32(height))
+
+	return tx.Bucket(headersBucket).Put(hash[:], data)
+}
+
+// decodeHeaderEntry 解析区块头索引条目
+func decodeHeaderEntry(data []byte) (*blockchain.BlockHeader, int32, error) {
+	if len(data) != blockchain.BlockHeaderSize+4 {
+		return nil, 0, fmt.Errorf("区块头索引条目长度错误: %d", len(data))
+	}
+
+	header := &blockchain.BlockHeader{}
+	if err := header.Deserialize(data[:blockchain.BlockHeaderSize]); err != nil {
+		return nil, 0, err
+	}
+
+	height := int32(binary.LittleEndian.Uint32(data[blockchain.BlockHeaderSize:]))
+	return header, height, nil
+}
+
+// setTip 在事务中更新高度索引和主链末端记录
+func setTip(tx *bolt.Tx, hash [32]byte, height int32) error {
+	index := tx.Bucket(heightIndexBucket)
+
+	// 删除新末端之上的所有高度索引
+	var stale [][]byte
+	cursor := index.Cursor()
+	for k, _ := cursor.Seek(heightKey(height + 1)); k != nil; k, _ = cursor.Next() {
+		stale = append(stale, append([]byte(nil), k...))
+	}
+	for _, k := range stale {
+		if err := index.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	if err := index.Put(heightKey(height), hash[:]); err != nil {
+		return err
+	}
+
+	data := make([]byte, 36)
+	copy(data, hash[:])
+	binary.LittleEndian.PutUint32(data[32:], uint32(height))
+	return tx.Bucket(chainStateBucket).Put(tipKey, data)
+}
+
+// heightKey 生成高度索引的键
+//
+// 使用大端序编码，使键的字典序与高度顺序一致，便于范围遍历。
+func heightKey(height int32) []byte {
+	return utils.Uint32ToBigEndian(uint32(height))
+}
@@ -36,11 +36,14 @@ type NetworkConfig struct {
 	MaxConnections    int      `yaml:"max_connections"`
 	ConnectionTimeout int      `yaml:"connection_timeout"`
 	HeartbeatInterval int      `yaml:"heartbeat_interval"`
+	BanThreshold      uint32   `yaml:"ban_threshold"`
+	BanDuration       int      `yaml:"ban_duration"`
 	Seeds             []string `yaml:"seeds"`
 }
 
 // RPCConfig RPC配置
 type RPCConfig struct {
+	Host       string `yaml:"host"` // 监听的主机地址，为空时监听所有网络接口
 	Port       int    `yaml:"port"`
 	EnableCORS bool   `yaml:"enable_cors"`
 	RateLimit  int    `yaml:"rate_limit"`
@@ -58,11 +61,10 @@ type MiningConfig struct {
 
 // BlockchainConfig 区块链配置
 type BlockchainConfig struct {
-	DataDir                      string `yaml:"data_dir"`
-	MaxBlockSize                 int    `yaml:"max_block_size"`
-	GenesisDifficulty            uint32 `yaml:"genesis_difficulty"`
-	DifficultyAdjustmentInterval int    `yaml:"difficulty_adjustment_interval"`
-	MaxSupply                    int64  `yaml:"max_supply"`
+	Network      string `yaml:"network"`
+	DataDir      string `yaml:"data_dir"`
+	MaxBlockSize int    `yaml:"max_block_size"`
+	MaxSupply    int64  `yaml:"max_supply"`
 }
 
 // DatabaseConfig 数据库配置
@@ -119,9 +121,12 @@ func DefaultConfig() *Config {
 			MaxConnections:    50,
 			ConnectionTimeout: 30,
 			HeartbeatInterval: 60,
+			BanThreshold:      100,
+			BanDuration:       86400,
 			Seeds:             []string{},
 		},
 		RPC: RPCConfig{
+			Host:       "127.0.0.1",
 			Port:       8545,
 			EnableCORS: true,
 			RateLimit:  1000,
@@ -135,11 +140,10 @@ func DefaultConfig() *Config {
 			BlockTime:    10,
 		},
 		Blockchain: BlockchainConfig{
-			DataDir:                      "./data",
-			MaxBlockSize:                 1048576,
-			GenesisDifficulty:            0x1d00ffff,
-			DifficultyAdjustmentInterval: 2016,
-			MaxSupply:                    21000000,
+			Network:      "mainnet",
+			DataDir:      "./data",
+			MaxBlockSize: 1048576,
+			MaxSupply:    21000000,
 		},
 		Database: DatabaseConfig{
 			Type:      "bolt",
@@ -212,10 +216,18 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("无效的网络端口: %d", c.Network.Port)
 	}
 
+	if c.Network.BanDuration < 0 {
+		return fmt.Errorf("无效的封禁时长: %d", c.Network.BanDuration)
+	}
+
 	if c.RPC.Port <= 0 || c.RPC.Port > 65535 {
 		return fmt.Errorf("无效的RPC端口: %d", c.RPC.Port)
 	}
 
+	if c.Blockchain.MaxSupply <= 0 || c.Blockchain.MaxSupply > MaxSupply {
+		return fmt.Errorf("无效的最大供应量: %d", c.Blockchain.MaxSupply)
+	}
+
 	if c.Blockchain.MaxBlockSize <= 0 {
 		return fmt.Errorf("无效的最大区块大小: %d", c.Blockchain.MaxBlockSize)
 	}
@@ -257,6 +269,16 @@ func (c *Config) GetHeartbeatInterval() time.Duration {
 	return time.Duration(c.Network.HeartbeatInterval) * time.Second
 }
 
+// GetBanDuration 获取封禁时长
+func (c *Config) GetBanDuration() time.Duration {
+	return time.Duration(c.Network.BanDuration) * time.Second
+}
+
+// GetMaxSupply 获取以聪为单位的最大供应量
+func (c *Config) GetMaxSupply() int64 {
+	return c.Blockchain.MaxSupply * SatoshiPerBitcoin
+}
+
 // GetRequestTimeout 获取请求超时时间
 func (c *Config) GetRequestTimeout() time.Duration {
 	return time.Duration(c.Security.RequestTimeout) * time.Second
@@ -9,27 +9,13 @@ const (
 
 	// 协议版本
 	ProtocolVersion = 1
-
-	// 网络魔数
-	MainNetMagic = 0xD9B4BEF9
-	TestNetMagic = 0xDAB5BFFA
-	RegTestMagic = 0xFABFB5DA
 )
 
 // 区块链常量
 const (
-	// 创世区块时间戳
-	GenesisTimestamp = 1635724800 // 2021-11-01 00:00:00 UTC
-
 	// 最大区块大小
 	MaxBlockSize = 1 * 1024 * 1024 // 1MB
 
-	// 目标出块时间
-	TargetBlockTime = 10 * time.Minute
-
-	// 难度调整间隔
-	DifficultyAdjustmentInterval = 2016
-
 	// 最大供应量
 	MaxSupply = 21_000_000
 
@@ -38,6 +24,12 @@ const (
 
 	// 奖励减半间隔
 	HalvingInterval = 210_000
+
+	// 每个比特币对应的聪数
+	SatoshiPerBitcoin = 100_000_000
+
+	// 以聪为单位的最大供应量
+	MaxSatoshi = MaxSupply * SatoshiPerBitcoin
 )
 
 // 网络常量
@@ -93,10 +85,6 @@ const (
 
 // 地址常量
 const (
-	// 地址版本
-	MainNetAddressVersion = 0x00
-	TestNetAddressVersion = 0x6F
-
 	// 地址长度
 	AddressLength = 25
 
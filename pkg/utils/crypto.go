@@ -92,12 +92,8 @@ func BitsToTarget(bits uint32) *big.Int {
 	target := big.NewInt(int64(mantissa))
 	target.Lsh(target, uint(8*(exponent-3)))
 
-	// 确保不超过最大目标值
-	maxTarget := GetMaxTarget()
-	if target.Cmp(maxTarget) > 0 {
-		return maxTarget
-	}
-
+	// 不在此处截断到最大目标值，由调用方根据网络的工作量证明上限判断，
+	// 否则超出上限的难度位会被静默当作合法值
 	return target
 }
 
@@ -235,14 +231,19 @@ func CompareTargets(target1, target2 *big.Int) int {
 // targetTime: 目标出块时间（秒）
 // currentBits: 当前难度位
 func AdjustDifficulty(actualTime, targetTime int64, currentBits uint32) uint32 {
+	return AdjustDifficultyWithLimit(actualTime, targetTime, currentBits, GetMaxTarget())
+}
+
+// AdjustDifficultyWithLimit 根据实际出块时间调整难度，新目标值不超过powLimit
+// actualTime: 实际出块时间（秒）
+// targetTime: 目标出块时间（秒）
+// currentBits: 当前难度位
+// powLimit: 网络允许的最大目标值（最低难度）
+func AdjustDifficultyWithLimit(actualTime, targetTime int64, currentBits uint32, powLimit *big.Int) uint32 {
 	if actualTime <= 0 || targetTime <= 0 {
 		return currentBits
 	}
 
-	// 计算时间比率
-	timeRatio := new(big.Rat)
-	timeRatio.SetFrac(big.NewInt(actualTime), big.NewInt(targetTime))
-
 	// 获取当前目标值
 	currentTarget := BitsToTarget(currentBits)
 
@@ -252,7 +253,6 @@ func AdjustDifficulty(actualTime, targetTime int64, currentBits uint32) uint32 {
 	newTarget.Div(newTarget, big.NewInt(targetTime))
 
 	// 限制调整幅度（比特币限制为4倍）
-	maxTarget := GetMaxTarget()
 	minAdjustment := new(big.Int)
 	maxAdjustment := new(big.Int)
 
@@ -265,8 +265,8 @@ func AdjustDifficulty(actualTime, targetTime int64, currentBits uint32) uint32 {
 	if newTarget.Cmp(maxAdjustment) > 0 {
 		newTarget = maxAdjustment
 	}
-	if newTarget.Cmp(maxTarget) > 0 {
-		newTarget = maxTarget
+	if newTarget.Cmp(powLimit) > 0 {
+		newTarget = powLimit
 	}
 
 	return TargetToBits(newTarget)
@@ -0,0 +1,178 @@
+package utxo
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// entry.go - UTXO条目定义及其存储格式
+//
+// 每个未花费输出在数据库中以OutPoint为键存储一个Entry，格式为：
+//
+//	Value(8) | Height(4) | Flags(1) | ScriptLen(VarInt) | PkScript
+//
+// 其中Flags的最低位表示该输出是否来自Coinbase交易。
+
+// entryFlagCoinbase Coinbase输出标志位
+const entryFlagCoinbase = 0x01
+
+// Entry 未花费交易输出条目
+//
+// 字段说明：
+// - Value: 输出金额（聪）
+// - PkScript: 输出锁定脚本
+// - Height: 创建该输出的区块高度
+// - IsCoinbase: 是否为Coinbase交易的输出，用于成熟度检查
+type Entry struct {
+	Value      int64  // 输出金额（聪）
+	PkScript   []byte // 锁定脚本
+	Height     int32  // 所在区块高度
+	IsCoinbase bool   // 是否来自Coinbase交易
+}
+
+// NewEntry 根据交易输出创建UTXO条目
+//
+// 参数：
+// - txOut: 交易输出
+// - height: 所在区块高度
+// - isCoinbase: 是否来自Coinbase交易
+func NewEntry(txOut *blockchain.TxOut, height int32, isCoinbase bool) *Entry {
+	return &Entry{
+		Value:      txOut.Value,
+		PkScript:   txOut.PkScript,
+		Height:     height,
+		IsCoinbase: isCoinbase,
+	}
+}
+
+// IsMature 判断在给定高度花费时Coinbase输出是否已成熟
+//
+// 非Coinbase输出始终视为成熟。
+//
+// 参数：
+// - spendHeight: 花费交易所在的区块高度
+// - params: 网络参数，提供Coinbase成熟确认数
+func (e *Entry) IsMature(spendHeight int32, params *blockchain.ChainParams) bool {
+	if !e.IsCoinbase {
+		return true
+	}
+	return spendHeight-e.Height >= params.CoinbaseMaturity
+}
+
+// Serialize 序列化UTXO条目
+func (e *Entry) Serialize() []byte {
+	buf := make([]byte, 0, 13+utils.VarIntSize(uint64(len(e.PkScript)))+len(e.PkScript))
+
+	buf = append(buf, utils.Uint64ToLittleEndian(uint64(e.Value))...)
+	buf = append(buf, utils.Uint32ToLittleEndian(uint32(e.Height))...)
+
+	var flags byte
+	if e.IsCoinbase {
+		flags |= entryFlagCoinbase
+	}
+	buf = append(buf, flags)
+
+	buf = append(buf, utils.EncodeVarInt(uint64(len(e.PkScript)))...)
+	buf = append(buf, e.PkScript...)
+
+	return buf
+}
+
+// Deserialize 反序列化UTXO条目
+//
+// 返回本次解析消耗的字节数，以便从撤销数据中连续读取多个条目。
+//
+// 参数：
+// - data: 字节数组
+func (e *Entry) Deserialize(data []byte) (int, error) {
+	if len(data) < 13 {
+		return 0, fmt.Errorf("UTXO条目数据长度不足: %d字节", len(data))
+	}
+
+	e.Value = int64(binary.LittleEndian.Uint64(data[0:8]))
+	e.Height = int32(binary.LittleEndian.Uint32(data[8:12]))
+	e.IsCoinbase = data[12]&entryFlagCoinbase != 0
+	offset := 13
+
+	scriptLen, bytesRead, err := utils.DecodeVarInt(data[offset:])
+	if err != nil {
+		return 0, fmt.Errorf("解码脚本长度失败: %v", err)
+	}
+	offset += bytesRead
+
+	if uint64(len(data)-offset) < scriptLen {
+		return 0, fmt.Errorf("UTXO条目脚本数据不足: 需要%d字节", scriptLen)
+	}
+
+	e.PkScript = make([]byte, scriptLen)
+	copy(e.PkScript, data[offset:offset+int(scriptLen)])
+	offset += int(scriptLen)
+
+	return offset, nil
+}
+
+// outPointKey 生成OutPoint在数据库中的键
+//
+// 键格式为 Hash(32) | Index(4，小端序)。
+func outPointKey(op blockchain.OutPoint) []byte {
+	key := make([]byte, 36)
+	copy(key, op.Hash[:])
+	binary.LittleEndian.PutUint32(key[32:], op.Index)
+	return key
+}
+
+// spentOutput 区块撤销数据中记录的单个被花费输出
+type spentOutput struct {
+	outPoint blockchain.OutPoint
+	entry    *Entry
+}
+
+// serializeUndo 序列化区块撤销数据
+//
+// 格式为 Count(VarInt) | [OutPointKey(36) | Entry]...
+func serializeUndo(spent []spentOutput) []byte {
+	buf := utils.EncodeVarInt(uint64(len(spent)))
+	for _, s := range spent {
+		buf = append(buf, outPointKey(s.outPoint)...)
+		buf = append(buf, s.entry.Serialize()...)
+	}
+	return buf
+}
+
+// deserializeUndo 反序列化区块撤销数据
+func deserializeUndo(data []byte) ([]spentOutput, error) {
+	count, offset, err := utils.DecodeVarInt(data)
+	if err != nil {
+		return nil, fmt.Errorf("解码撤销条目数量失败: %v", err)
+	}
+
+	if count > uint64(len(data)/(36+13)) {
+		return nil, fmt.Errorf("撤销条目数量与数据长度不符: %d", count)
+	}
+
+	spent := make([]spentOutput, 0, count)
+	for i := uint64(0); i < count; i++ {
+		if len(data)-offset < 36 {
+			return nil, fmt.Errorf("撤销数据长度不足，无法读取条目%d", i)
+		}
+
+		var op blockchain.OutPoint
+		copy(op.Hash[:], data[offset:offset+32])
+		op.Index = binary.LittleEndian.Uint32(data[offset+32:])
+		offset += 36
+
+		entry := &Entry{}
+		n, err := entry.Deserialize(data[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("解析撤销条目%d失败: %v", i, err)
+		}
+		offset += n
+
+		spent = append(spent, spentOutput{outPoint: op, entry: entry})
+	}
+
+	return spent, nil
+}
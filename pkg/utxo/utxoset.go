@@ -0,0 +1,361 @@
+// Package utxo 实现了基于BoltDB的未花费交易输出（UTXO）集合管理
+//
+// UTXO集合以OutPoint为键索引所有尚未被花费的交易输出。
+// 连接区块时花费其输入引用的输出并添加新输出，同时记录撤销数据；
+// 断开区块时利用撤销数据恢复被花费的输出，从而支持链重组时的回滚。
+package utxo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// 数据库桶和键定义
+var (
+	utxoBucket  = []byte(utils.UTXOBucket) // OutPoint -> Entry
+	undoBucket  = []byte("utxoundo")       // 区块哈希 -> 撤销数据
+	stateBucket = []byte("utxostate")      // UTXO集合状态
+	bestKey     = []byte("best")           // 最新连接区块的哈希和高度
+)
+
+// 标准错误定义
+var (
+	// ErrMissingInput 交易输入引用的输出不存在或已被花费
+	ErrMissingInput = errors.New("引用的输出不存在或已被花费")
+
+	// ErrOutputExists 新增输出与尚未花费的输出重复
+	ErrOutputExists = errors.New("输出已存在于UTXO集合中")
+
+	// ErrNotBestBlock 连接或断开的区块与UTXO集合当前状态不衔接
+	ErrNotBestBlock = errors.New("区块与UTXO集合当前状态不衔接")
+
+	// ErrMissingUndo 断开区块时找不到撤销数据
+	ErrMissingUndo = errors.New("缺少区块撤销数据")
+)
+
+// Set UTXO集合
+//
+// 所有修改操作都在单个BoltDB事务中完成，保证区块连接和断开的原子性。
+type Set struct {
+	db *bolt.DB
+}
+
+// NewSet 创建UTXO集合
+//
+// 在给定的数据库中创建所需的桶。数据库可以与区块存储共享。
+//
+// 参数：
+// - db: 已打开的BoltDB实例
+func NewSet(db *bolt.DB) (*Set, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{utxoBucket, undoBucket, stateBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("创建桶%s失败: %v", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Set{db: db}, nil
+}
+
+// BestBlock 获取UTXO集合最新连接的区块
+//
+// 集合为空（尚未连接任何区块）时返回零哈希和高度-1。
+func (s *Set) BestBlock() ([32]byte, int32, error) {
+	var hash [32]byte
+	height := int32(-1)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		hash, height = readBest(tx)
+		return nil
+	})
+
+	return hash, height, err
+}
+
+// ConnectBlock 将区块连接到UTXO集合
+//
+// 按交易顺序处理：先花费交易输入引用的输出，再添加交易的新输出，
+// 因此同一区块内后面的交易可以花费前面交易的输出。
+// 被花费的输出作为撤销数据按区块哈希保存，用于DisconnectBlock。
+//
+// 参数：
+// - block: 待连接的区块
+// - height: 区块高度，必须为当前最新高度加一
+func (s *Set) ConnectBlock(block *blockchain.Block, height int32) error {
+	blockHash := block.Hash()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bestHash, bestHeight := readBest(tx)
+		if bestHeight >= 0 && (block.Header.PrevBlockHash != bestHash || height != bestHeight+1) {
+			return fmt.Errorf("%w: 区块%s(高度%d)的前块不是%s(高度%d)", ErrNotBestBlock,
+				utils.HashToString(blockHash[:]), height, utils.HashToString(bestHash[:]), bestHeight)
+		}
+
+		utxos := tx.Bucket(utxoBucket)
+		var spent []spentOutput
+
+		for txIndex, t := range block.Transactions {
+			isCoinbase := txIndex == 0 && t.IsCoinbase()
+
+			// 花费输入引用的输出
+			if !isCoinbase {
+				for _, txIn := range t.TxIn {
+					op := txIn.PreviousOutPoint
+					key := outPointKey(op)
+
+					data := utxos.Get(key)
+					if data == nil {
+						return fmt.Errorf("%w: %s", ErrMissingInput, op)
+					}
+
+					entry := &Entry{}
+					if _, err := entry.Deserialize(data); err != nil {
+						return fmt.Errorf("解析UTXO条目%s失败: %v", op, err)
+					}
+
+					if err := utxos.Delete(key); err != nil {
+						return err
+					}
+					spent = append(spent, spentOutput{outPoint: op, entry: entry})
+				}
+			}
+
+			// 添加交易的新输出
+			txHash := t.Hash()
+			for i, txOut := range t.TxOut {
+				op := blockchain.OutPoint{Hash: txHash, Index: uint32(i)}
+				key := outPointKey(op)
+				if utxos.Get(key) != nil {
+					return fmt.Errorf("%w: %s", ErrOutputExists, op)
+				}
+
+				entry := NewEntry(txOut, height, isCoinbase)
+				if err := utxos.Put(key, entry.Serialize()); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := tx.Bucket(undoBucket).Put(blockHash[:], serializeUndo(spent)); err != nil {
+			return err
+		}
+
+		return writeBest(tx, blockHash, height)
+	})
+}
+
+// DisconnectBlock 从UTXO集合中断开区块
+//
+// 只能断开当前最新连接的区块。按逆序处理交易：
+// 删除交易创建的输出，并利用撤销数据恢复其花费的输出。
+//
+// 参数：
+// - block: 待断开的区块，必须是UTXO集合最新连接的区块
+func (s *Set) DisconnectBlock(block *blockchain.Block) error {
+	blockHash := block.Hash()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bestHash, bestHeight := readBest(tx)
+		if bestHeight < 0 || bestHash != blockHash {
+			return fmt.Errorf("%w: 区块%s不是最新连接的区块", ErrNotBestBlock, utils.HashToString(blockHash[:]))
+		}
+
+		undo := tx.Bucket(undoBucket)
+		undoData := undo.Get(blockHash[:])
+		if undoData == nil {
+			return fmt.Errorf("%w: %s", ErrMissingUndo, utils.HashToString(blockHash[:]))
+		}
+
+		spent, err := deserializeUndo(undoData)
+		if err != nil {
+			return err
+		}
+
+		utxos := tx.Bucket(utxoBucket)
+		spentIndex := len(spent)
+
+		for txIndex := len(block.Transactions) - 1; txIndex >= 0; txIndex-- {
+			t := block.Transactions[txIndex]
+
+			// 删除交易创建的输出
+			txHash := t.Hash()
+			for i := range t.TxOut {
+				key := outPointKey(blockchain.OutPoint{Hash: txHash, Index: uint32(i)})
+				if err := utxos.Delete(key); err != nil {
+					return err
+				}
+			}
+
+			if txIndex == 0 && t.IsCoinbase() {
+				continue
+			}
+
+			// 逆序恢复交易花费的输出
+			for i := len(t.TxIn) - 1; i >= 0; i-- {
+				spentIndex--
+				if spentIndex < 0 {
+					return fmt.Errorf("撤销数据与区块交易不匹配: %s", utils.HashToString(blockHash[:]))
+				}
+
+				restored := spent[spentIndex]
+				if restored.outPoint != t.TxIn[i].PreviousOutPoint {
+					return fmt.Errorf("撤销数据与区块交易不匹配: %s", restored.outPoint)
+				}
+				if err := utxos.Put(outPointKey(restored.outPoint), restored.entry.Serialize()); err != nil {
+					return err
+				}
+			}
+		}
+
+		if spentIndex != 0 {
+			return fmt.Errorf("撤销数据与区块交易不匹配: 剩余%d个条目", spentIndex)
+		}
+
+		if err := undo.Delete(blockHash[:]); err != nil {
+			return err
+		}
+
+		return writeBest(tx, block.Header.PrevBlockHash, bestHeight-1)
+	})
+}
+
+// FetchEntry 查询指定输出的UTXO条目
+//
+// 输出不存在或已被花费时返回nil。
+//
+// 参数：
+// - op: 待查询的输出引用
+func (s *Set) FetchEntry(op blockchain.OutPoint) (*Entry, error) {
+	var entry *Entry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(utxoBucket).Get(outPointKey(op))
+		if data == nil {
+			return nil
+		}
+
+		entry = &Entry{}
+		_, err := entry.Deserialize(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// IsSpendable 判断指定输出在给定高度是否可被花费
+//
+// 输出必须存在于UTXO集合中，若来自Coinbase交易还必须已达到成熟确认数。
+//
+// 参数：
+// - op: 待检查的输出引用
+// - spendHeight: 花费交易所在的区块高度
+// - params: 网络参数
+func (s *Set) IsSpendable(op blockchain.OutPoint, spendHeight int32, params *blockchain.ChainParams) (bool, error) {
+	entry, err := s.FetchEntry(op)
+	if err != nil {
+		return false, err
+	}
+	if entry == nil {
+		return false, nil
+	}
+
+	return entry.IsMature(spendHeight, params), nil
+}
+
+// Balance 计算锁定到指定脚本的所有未花费输出总额
+//
+// 需要遍历整个UTXO集合，适用于钱包余额查询等非高频场景。
+//
+// 参数：
+// - pkScript: 输出锁定脚本
+func (s *Set) Balance(pkScript []byte) (int64, error) {
+	var balance int64
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(utxoBucket).ForEach(func(_, v []byte) error {
+			entry := &Entry{}
+			if _, err := entry.Deserialize(v); err != nil {
+				return err
+			}
+			if bytes.Equal(entry.PkScript, pkScript) {
+				balance += entry.Value
+			}
+			return nil
+		})
+	})
+
+	return balance, err
+}
+
+// TotalValue 计算UTXO集合中所有未花费输出的总金额
+func (s *Set) TotalValue() (int64, error) {
+	var total int64
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(utxoBucket).ForEach(func(_, v []byte) error {
+			entry := &Entry{}
+			if _, err := entry.Deserialize(v); err != nil {
+				return err
+			}
+			total += entry.Value
+			return nil
+		})
+	})
+
+	return total, err
+}
+
+// Count 获取UTXO集合中的条目总数
+func (s *Set) Count() (int, error) {
+	var count int
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(utxoBucket).Stats().KeyN
+		return nil
+	})
+
+	return count, err
+}
+
+// readBest 读取UTXO集合最新连接的区块
+func readBest(tx *bolt.Tx) ([32]byte, int32) {
+	var hash [32]byte
+
+	data := tx.Bucket(stateBucket).Get(bestKey)
+	if len(data) != 36 {
+		return hash, -1
+	}
+
+	copy(hash[:], data[:32])
+	return hash, int32(binary.LittleEndian.Uint32(data[32:]))
+}
+
+// writeBest 写入UTXO集合最新连接的区块
+//
+// 高度小于0表示集合已回到空状态，此时删除状态记录。
+func writeBest(tx *bolt.Tx, hash [32]byte, height int32) error {
+	state := tx.Bucket(stateBucket)
+	if height < 0 {
+		return state.Delete(bestKey)
+	}
+
+	data := make([]byte, 36)
+	copy(data, hash[:])
+	binary.LittleEndian.PutUint32(data[32:], uint32(height))
+	return state.Put(bestKey, data)
+}
@@ -0,0 +1,332 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// BIP32参数
+const (
+	// HardenedKeyStart 强化子密钥的起始索引
+	HardenedKeyStart = 0x80000000
+
+	// MinSeedSize 主密钥种子的最小长度
+	MinSeedSize = 16
+
+	// MaxSeedSize 主密钥种子的最大长度
+	MaxSeedSize = 64
+
+	// serializedKeySize 扩展密钥序列化后的长度（不含校验和）
+	//
+	// 版本(4) | 深度(1) | 父密钥指纹(4) | 子密钥索引(4) | 链码(32) | 密钥(33)
+	serializedKeySize = 4 + 1 + 4 + 4 + 32 + 33
+)
+
+// masterKey 由种子生成主密钥的HMAC密钥
+var masterKey = []byte("Bitcoin seed")
+
+var (
+	// ErrInvalidSeed 种子长度超出范围
+	ErrInvalidSeed = errors.New("无效的种子长度")
+
+	// ErrInvalidChild 推导出的子密钥无效，应使用下一个索引
+	ErrInvalidChild = errors.New("推导出的子密钥无效")
+
+	// ErrDeriveHardenedFromPublic 扩展公钥不能推导强化子密钥
+	ErrDeriveHardenedFromPublic = errors.New("不能从扩展公钥推导强化子密钥")
+
+	// ErrDepthExceeded 推导深度超过255
+	ErrDepthExceeded = errors.New("推导深度超过上限")
+
+	// ErrNotPrivate 扩展密钥不包含私钥
+	ErrNotPrivate = errors.New("扩展密钥不包含私钥")
+
+	// ErrInvalidPath 推导路径格式错误
+	ErrInvalidPath = errors.New("无效的推导路径")
+
+	// ErrInvalidExtendedKey 扩展密钥编码错误
+	ErrInvalidExtendedKey = errors.New("无效的扩展密钥")
+
+	// ErrWrongNetwork 扩展密钥的版本前缀不属于当前网络
+	ErrWrongNetwork = errors.New("扩展密钥不属于当前网络")
+)
+
+// ExtendedKey BIP32扩展密钥
+//
+// 扩展私钥可以推导普通和强化子密钥，扩展公钥只能推导普通子公钥。
+type ExtendedKey struct {
+	params    *blockchain.ChainParams
+	depth     uint8
+	parentFP  [4]byte
+	childNum  uint32
+	chainCode [32]byte
+	key       []byte // 私钥32字节或压缩公钥33字节
+	isPrivate bool
+}
+
+// NewMaster 由种子生成主扩展私钥
+func NewMaster(seed []byte, params *blockchain.ChainParams) (*ExtendedKey, error) {
+	if len(seed) < MinSeedSize || len(seed) > MaxSeedSize {
+		return nil, fmt.Errorf("%w: %d字节", ErrInvalidSeed, len(seed))
+	}
+
+	mac := hmac.New(sha512.New, masterKey)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	var scalar secp256k1.ModNScalar
+	if overflow := scalar.SetByteSlice(sum[:32]); overflow || scalar.IsZero() {
+		return nil, ErrInvalidSeed
+	}
+
+	key := &ExtendedKey{
+		params:    params,
+		key:       sum[:32],
+		isPrivate: true,
+	}
+	copy(key.chainCode[:], sum[32:])
+	return key, nil
+}
+
+// Derive 推导索引为i的子密钥
+//
+// 索引不小于HardenedKeyStart时推导强化子密钥。返回ErrInvalidChild时
+// 调用方应跳过该索引。
+func (k *ExtendedKey) Derive(i uint32) (*ExtendedKey, error) {
+	if k.depth == 255 {
+		return nil, ErrDepthExceeded
+	}
+	hardened := i >= HardenedKeyStart
+	if hardened && !k.isPrivate {
+		return nil, ErrDeriveHardenedFromPublic
+	}
+
+	// 强化推导：HMAC-SHA512(链码, 0x00 || 私钥 || 索引)
+	// 普通推导：HMAC-SHA512(链码, 公钥 || 索引)
+	pubKey := k.pubKeyBytes()
+	data := make([]byte, 0, 37)
+	if hardened {
+		data = append(data, 0x00)
+		data = append(data, k.key...)
+	} else {
+		data = append(data, pubKey...)
+	}
+	data = binary.BigEndian.AppendUint32(data, i)
+
+	mac := hmac.New(sha512.New, k.chainCode[:])
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	var tweak secp256k1.ModNScalar
+	if overflow := tweak.SetByteSlice(sum[:32]); overflow {
+		return nil, ErrInvalidChild
+	}
+
+	var childKey []byte
+	if k.isPrivate {
+		// 子私钥 = tweak + 父私钥 (mod n)
+		var parent secp256k1.ModNScalar
+		parent.SetByteSlice(k.key)
+		tweak.Add(&parent)
+		if tweak.IsZero() {
+			return nil, ErrInvalidChild
+		}
+		childBytes := tweak.Bytes()
+		childKey = childBytes[:]
+	} else {
+		// 子公钥 = tweak*G + 父公钥
+		parent, err := secp256k1.ParsePubKey(k.key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExtendedKey, err)
+		}
+		var tweakPoint, parentPoint, result secp256k1.JacobianPoint
+		secp256k1.ScalarBaseMultNonConst(&tweak, &tweakPoint)
+		parent.AsJacobian(&parentPoint)
+		secp256k1.AddNonConst(&tweakPoint, &parentPoint, &result)
+		if (result.X.IsZero() && result.Y.IsZero()) || result.Z.IsZero() {
+			return nil, ErrInvalidChild
+		}
+		result.ToAffine()
+		childKey = secp256k1.NewPublicKey(&result.X, &result.Y).SerializeCompressed()
+	}
+
+	child := &ExtendedKey{
+		params:    k.params,
+		depth:     k.depth + 1,
+		childNum:  i,
+		key:       childKey,
+		isPrivate: k.isPrivate,
+	}
+	copy(child.parentFP[:], utils.Hash160(pubKey)[:4])
+	copy(child.chainCode[:], sum[32:])
+	return child, nil
+}
+
+// DerivePath 按路径推导子密钥，例如 m/44'/1'/0'/0/5
+//
+// 强化索引用'或h后缀表示。路径必须以m开头，推导从当前密钥开始。
+func (k *ExtendedKey) DerivePath(path string) (*ExtendedKey, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := k
+	for _, i := range indexes {
+		if key, err = key.Derive(i); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// ParsePath 解析推导路径，返回各级子密钥索引
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("%w: %q必须以m开头", ErrInvalidPath, path)
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		var offset uint32
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") {
+			part = part[:len(part)-1]
+			offset = HardenedKeyStart
+		}
+		i, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || i >= HardenedKeyStart {
+			return nil, fmt.Errorf("%w: %q中的索引%q无效", ErrInvalidPath, path, part)
+		}
+		indexes = append(indexes, uint32(i)+offset)
+	}
+	return indexes, nil
+}
+
+// Neuter 返回对应的扩展公钥
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	if !k.isPrivate {
+		return k
+	}
+	pub := *k
+	pub.key = k.pubKeyBytes()
+	pub.isPrivate = false
+	return &pub
+}
+
+// IsPrivate 判断是否为扩展私钥
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.isPrivate
+}
+
+// Depth 返回推导深度，主密钥为0
+func (k *ExtendedKey) Depth() uint8 {
+	return k.depth
+}
+
+// ChildIndex 返回子密钥索引
+func (k *ExtendedKey) ChildIndex() uint32 {
+	return k.childNum
+}
+
+// KeyPair 返回扩展私钥对应的密钥对
+func (k *ExtendedKey) KeyPair() (*KeyPair, error) {
+	if !k.isPrivate {
+		return nil, ErrNotPrivate
+	}
+	return NewKeyPair(k.key)
+}
+
+// SerializePubKey 返回33字节压缩公钥
+func (k *ExtendedKey) SerializePubKey() []byte {
+	return k.pubKeyBytes()
+}
+
+// String 返回扩展密钥的Base58Check编码
+func (k *ExtendedKey) String() string {
+	data := make([]byte, 0, serializedKeySize+4)
+	if k.isPrivate {
+		data = append(data, k.params.HDPrivateKeyID[:]...)
+	} else {
+		data = append(data, k.params.HDPublicKeyID[:]...)
+	}
+	data = append(data, k.depth)
+	data = append(data, k.parentFP[:]...)
+	data = binary.BigEndian.AppendUint32(data, k.childNum)
+	data = append(data, k.chainCode[:]...)
+	if k.isPrivate {
+		data = append(data, 0x00)
+	}
+	data = append(data, k.key...)
+	data = append(data, utils.Checksum(data)...)
+	return utils.Base58Encode(data)
+}
+
+// ParseExtendedKey 解析扩展密钥，版本前缀必须属于当前网络
+func ParseExtendedKey(s string, params *blockchain.ChainParams) (*ExtendedKey, error) {
+	data, err := utils.Base58Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExtendedKey, err)
+	}
+	if len(data) != serializedKeySize+4 {
+		return nil, fmt.Errorf("%w: 长度%d", ErrInvalidExtendedKey, len(data))
+	}
+	payload, checksum := data[:serializedKeySize], data[serializedKeySize:]
+	if !bytes.Equal(utils.Checksum(payload), checksum) {
+		return nil, fmt.Errorf("%w: 校验和不匹配", ErrInvalidExtendedKey)
+	}
+
+	var version [4]byte
+	copy(version[:], payload[0:4])
+	key := &ExtendedKey{
+		params:   params,
+		depth:    payload[4],
+		childNum: binary.BigEndian.Uint32(payload[9:13]),
+	}
+	copy(key.parentFP[:], payload[5:9])
+	copy(key.chainCode[:], payload[13:45])
+	keyData := payload[45:]
+
+	switch version {
+	case params.HDPrivateKeyID:
+		if keyData[0] != 0x00 {
+			return nil, fmt.Errorf("%w: 私钥前缀错误", ErrInvalidExtendedKey)
+		}
+		if _, err := NewKeyPair(keyData[1:]); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExtendedKey, err)
+		}
+		key.key = append([]byte(nil), keyData[1:]...)
+		key.isPrivate = true
+	case params.HDPublicKeyID:
+		if _, err := secp256k1.ParsePubKey(keyData); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExtendedKey, err)
+		}
+		key.key = append([]byte(nil), keyData...)
+	default:
+		return nil, fmt.Errorf("%w: 版本前缀%x不属于%s网络", ErrWrongNetwork, version, params.Name)
+	}
+
+	if key.depth == 0 && (key.childNum != 0 || key.parentFP != [4]byte{}) {
+		return nil, fmt.Errorf("%w: 主密钥的父密钥指纹或索引不为零", ErrInvalidExtendedKey)
+	}
+	return key, nil
+}
+
+// pubKeyBytes 返回33字节压缩公钥
+func (k *ExtendedKey) pubKeyBytes() []byte {
+	if !k.isPrivate {
+		return k.key
+	}
+	return secp256k1.PrivKeyFromBytes(k.key).PubKey().SerializeCompressed()
+}
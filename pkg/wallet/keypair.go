@@ -0,0 +1,117 @@
+// Package wallet 实现了密钥管理和分层确定性（HD）钱包
+//
+// 密钥使用secp256k1曲线。钱包由BIP39助记词生成种子，按BIP32从种子推导主密钥，
+// 再按BIP44路径 m/44'/币种'/账户'/链/索引 推导接收和找零密钥，相同的助记词
+// 总是推导出相同的密钥。助记词用口令加密（scrypt派生密钥，AES-256-GCM加密）
+// 后保存在数据库的wallets桶中，每个节点可以保存多个以名称区分的钱包。
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// 密钥长度
+const (
+	// PrivateKeySize 私钥长度
+	PrivateKeySize = 32
+
+	// PubKeySize 压缩公钥长度
+	PubKeySize = 33
+)
+
+var (
+	// ErrInvalidPrivateKey 私钥长度错误或不在曲线阶范围内
+	ErrInvalidPrivateKey = errors.New("无效的私钥")
+
+	// ErrInvalidWIF WIF私钥格式错误
+	ErrInvalidWIF = errors.New("无效的WIF私钥")
+)
+
+// KeyPair secp256k1密钥对
+type KeyPair struct {
+	privKey *secp256k1.PrivateKey
+}
+
+// GenerateKeyPair 使用安全随机数生成新的密钥对
+func GenerateKeyPair() (*KeyPair, error) {
+	privKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("生成私钥失败: %v", err)
+	}
+	return &KeyPair{privKey: privKey}, nil
+}
+
+// NewKeyPair 从32字节私钥创建密钥对
+//
+// 私钥必须在1到曲线阶减1之间。
+func NewKeyPair(privKey []byte) (*KeyPair, error) {
+	if len(privKey) != PrivateKeySize {
+		return nil, fmt.Errorf("%w: 长度%d", ErrInvalidPrivateKey, len(privKey))
+	}
+
+	var scalar secp256k1.ModNScalar
+	if overflow := scalar.SetByteSlice(privKey); overflow || scalar.IsZero() {
+		return nil, fmt.Errorf("%w: 超出曲线阶范围", ErrInvalidPrivateKey)
+	}
+	return &KeyPair{privKey: secp256k1.NewPrivateKey(&scalar)}, nil
+}
+
+// PrivateKey 返回secp256k1私钥
+func (kp *KeyPair) PrivateKey() *secp256k1.PrivateKey {
+	return kp.privKey
+}
+
+// PublicKey 返回secp256k1公钥
+func (kp *KeyPair) PublicKey() *secp256k1.PublicKey {
+	return kp.privKey.PubKey()
+}
+
+// SerializePrivateKey 返回32字节私钥
+func (kp *KeyPair) SerializePrivateKey() []byte {
+	return kp.privKey.Serialize()
+}
+
+// SerializePubKey 返回33字节压缩公钥
+func (kp *KeyPair) SerializePubKey() []byte {
+	return kp.privKey.PubKey().SerializeCompressed()
+}
+
+// PubKeyHash 返回压缩公钥的Hash160，用于P2PKH锁定脚本
+func (kp *KeyPair) PubKeyHash() []byte {
+	return utils.Hash160(kp.SerializePubKey())
+}
+
+// WIF 返回压缩公钥格式的WIF私钥编码
+//
+// 格式：Base58Check(版本 + 私钥 + 0x01)，版本为网络参数的PrivateKeyID。
+func (kp *KeyPair) WIF(params *blockchain.ChainParams) string {
+	payload := append(kp.SerializePrivateKey(), 0x01)
+	return utils.Base58CheckEncode(payload, params.PrivateKeyID)
+}
+
+// DecodeWIF 解码WIF私钥，版本字节必须与网络参数一致
+//
+// 同时接受压缩和非压缩公钥格式的编码，密钥对总是使用压缩公钥。
+func DecodeWIF(wif string, params *blockchain.ChainParams) (*KeyPair, error) {
+	payload, version, err := utils.Base58CheckDecode(wif)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWIF, err)
+	}
+	if version != params.PrivateKeyID {
+		return nil, fmt.Errorf("%w: 版本0x%02x不属于%s网络", ErrInvalidWIF, version, params.Name)
+	}
+
+	switch {
+	case len(payload) == PrivateKeySize+1 && payload[PrivateKeySize] == 0x01:
+		payload = payload[:PrivateKeySize]
+	case len(payload) != PrivateKeySize:
+		return nil, fmt.Errorf("%w: 长度%d", ErrInvalidWIF, len(payload))
+	}
+	return NewKeyPair(payload)
+}
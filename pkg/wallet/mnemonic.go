@@ -0,0 +1,168 @@
+package wallet
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// BIP39参数
+const (
+	// MinEntropyBits 助记词熵的最小位数（12个单词）
+	MinEntropyBits = 128
+
+	// MaxEntropyBits 助记词熵的最大位数（24个单词）
+	MaxEntropyBits = 256
+
+	// DefaultEntropyBits 新钱包助记词熵的位数
+	DefaultEntropyBits = 256
+
+	// seedIterations 由助记词生成种子的PBKDF2迭代次数
+	seedIterations = 2048
+
+	// SeedSize 由助记词生成的种子长度
+	SeedSize = 64
+)
+
+var (
+	// ErrInvalidEntropy 熵的位数不是32的倍数或超出范围
+	ErrInvalidEntropy = errors.New("无效的熵长度")
+
+	// ErrInvalidMnemonic 助记词单词数错误或包含词表外的单词
+	ErrInvalidMnemonic = errors.New("无效的助记词")
+
+	// ErrMnemonicChecksum 助记词校验和错误
+	ErrMnemonicChecksum = errors.New("助记词校验和错误")
+)
+
+// englishWordList BIP39英文词表
+//
+//go:embed wordlist_english.txt
+var englishWordList string
+
+var (
+	// wordList 按索引排列的词表
+	wordList = strings.Fields(englishWordList)
+
+	// wordIndex 单词到索引的映射
+	wordIndex = func() map[string]int {
+		index := make(map[string]int, len(wordList))
+		for i, word := range wordList {
+			index[word] = i
+		}
+		return index
+	}()
+)
+
+// NewEntropy 生成指定位数的随机熵
+func NewEntropy(bits int) ([]byte, error) {
+	if err := checkEntropyBits(bits); err != nil {
+		return nil, err
+	}
+	return utils.GenerateRandomBytes(bits / 8)
+}
+
+// NewMnemonic 将熵编码为BIP39助记词
+//
+// 熵的SHA-256哈希的前(位数/32)位作为校验和附加在熵之后，
+// 每11位对应词表中的一个单词。
+func NewMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if err := checkEntropyBits(bits); err != nil {
+		return "", err
+	}
+
+	checksum := sha256.Sum256(entropy)
+	data := append(append([]byte(nil), entropy...), checksum[0])
+	totalBits := bits + bits/32
+
+	words := make([]string, totalBits/11)
+	for i := range words {
+		words[i] = wordList[readBits(data, i*11, 11)]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy 解码助记词并验证校验和，返回熵
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	totalBits := len(words) * 11
+	bits := totalBits * 32 / 33
+	if len(words)%3 != 0 || checkEntropyBits(bits) != nil {
+		return nil, fmt.Errorf("%w: 单词数%d", ErrInvalidMnemonic, len(words))
+	}
+
+	data := make([]byte, (totalBits+7)/8)
+	for i, word := range words {
+		index, ok := wordIndex[word]
+		if !ok {
+			return nil, fmt.Errorf("%w: 第%d个单词%q不在词表中", ErrInvalidMnemonic, i+1, word)
+		}
+		writeBits(data, i*11, 11, index)
+	}
+
+	entropy := data[:bits/8]
+	checksumBits := bits / 32
+	checksum := sha256.Sum256(entropy)
+	if readBits(data, bits, checksumBits) != readBits(checksum[:], 0, checksumBits) {
+		return nil, ErrMnemonicChecksum
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic 验证助记词的单词和校验和
+func ValidateMnemonic(mnemonic string) error {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err
+}
+
+// NewSeed 由助记词和可选的密码生成64字节种子
+//
+// 种子为PBKDF2-HMAC-SHA512(助记词, "mnemonic"+密码, 2048次)。单词之间的空白
+// 被规范化为单个空格；助记词和密码不做Unicode NFKD规范化，
+// 只有ASCII输入与BIP39的结果一致。
+func NewSeed(mnemonic, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key(sha512.New, normalized, []byte("mnemonic"+passphrase), seedIterations, SeedSize)
+}
+
+// checkEntropyBits 检查熵的位数
+func checkEntropyBits(bits int) error {
+	if bits < MinEntropyBits || bits > MaxEntropyBits || bits%32 != 0 {
+		return fmt.Errorf("%w: %d位", ErrInvalidEntropy, bits)
+	}
+	return nil
+}
+
+// readBits 从data的第offset位开始按大端序读取count位
+func readBits(data []byte, offset, count int) int {
+	value := 0
+	for i := 0; i < count; i++ {
+		bit := offset + i
+		value <<= 1
+		if data[bit/8]&(0x80>>(bit%8)) != 0 {
+			value |= 1
+		}
+	}
+	return value
+}
+
+// writeBits 从data的第offset位开始按大端序写入value的低count位
+func writeBits(data []byte, offset, count, value int) {
+	for i := 0; i < count; i++ {
+		bit := offset + i
+		if value&(1<<(count-1-i)) != 0 {
+			data[bit/8] |= 0x80 >> (bit % 8)
+		}
+	}
+}
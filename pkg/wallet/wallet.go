@@ -0,0 +1,459 @@
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/boltdb/bolt"
+	"golang.org/x/crypto/scrypt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// walletsBucket 钱包记录桶：钱包名称 -> 钱包记录
+var walletsBucket = []byte(utils.WalletsBucket)
+
+// 钱包记录格式
+//
+//	版本(1) | 创建时间(8) | 下一个接收索引(4) | 下一个找零索引(4) | 盐(16) | 随机数(12) | 密文
+//
+// 密文为AES-256-GCM加密的助记词，附加数据为钱包名称，防止记录被挪用到其他名称下。
+// 加密密钥由口令和盐经scrypt派生。整数均为小端序。
+const (
+	// recordVersion 当前钱包记录格式版本
+	recordVersion = 1
+
+	saltSize   = 16
+	nonceSize  = 12
+	headerSize = 1 + 8 + 4 + 4
+	cipherOff  = headerSize + saltSize + nonceSize
+
+	// scrypt参数
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+
+	// MaxNameLen 钱包名称的最大长度
+	MaxNameLen = 64
+)
+
+// 密钥链
+const (
+	// ExternalBranch 接收地址链
+	ExternalBranch uint32 = 0
+
+	// InternalBranch 找零地址链
+	InternalBranch uint32 = 1
+)
+
+var (
+	// ErrWalletExists 同名钱包已存在
+	ErrWalletExists = errors.New("钱包已存在")
+
+	// ErrWalletNotFound 钱包不存在
+	ErrWalletNotFound = errors.New("钱包不存在")
+
+	// ErrWrongPassphrase 口令错误
+	ErrWrongPassphrase = errors.New("钱包口令错误")
+
+	// ErrEmptyPassphrase 口令为空
+	ErrEmptyPassphrase = errors.New("钱包口令不能为空")
+
+	// ErrInvalidName 钱包名称为空、过长或包含空白和控制字符
+	ErrInvalidName = errors.New("无效的钱包名称")
+
+	// ErrInvalidBranch 密钥链不是接收链或找零链
+	ErrInvalidBranch = errors.New("无效的密钥链")
+
+	// ErrCorruptedRecord 钱包记录损坏或版本不受支持
+	ErrCorruptedRecord = errors.New("钱包记录已损坏")
+)
+
+// Manager 管理节点上的多个钱包
+//
+// 所有方法都可以并发调用。
+type Manager struct {
+	db     *bolt.DB
+	params *blockchain.ChainParams
+	mu     sync.Mutex
+}
+
+// New 创建钱包管理器
+//
+// 参数：
+// - db: 已打开的BoltDB实例，可以与区块存储共享
+// - params: 网络参数，决定扩展密钥前缀和BIP44币种编号
+func New(db *bolt.DB, params *blockchain.ChainParams) (*Manager, error) {
+	if db == nil {
+		return nil, errors.New("钱包管理器缺少数据库")
+	}
+	if params == nil {
+		return nil, errors.New("钱包管理器缺少网络参数")
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(walletsBucket); err != nil {
+			return fmt.Errorf("创建桶%s失败: %v", walletsBucket, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{db: db, params: params}, nil
+}
+
+// Create 创建新钱包，返回钱包和24个单词的助记词
+//
+// 助记词是恢复钱包的唯一凭据，调用方应提示用户妥善保存。
+func (m *Manager) Create(name, passphrase string) (*Wallet, string, error) {
+	entropy, err := NewEntropy(DefaultEntropyBits)
+	if err != nil {
+		return nil, "", err
+	}
+	mnemonic, err := NewMnemonic(entropy)
+	if err != nil {
+		return nil, "", err
+	}
+
+	w, err := m.Restore(name, mnemonic, passphrase)
+	if err != nil {
+		return nil, "", err
+	}
+	return w, mnemonic, nil
+}
+
+// Restore 由助记词恢复钱包并以口令加密保存
+//
+// 种子由助记词和空的BIP39密码生成，相同的助记词在同一网络上总是推导出相同的密钥。
+func (m *Manager) Restore(name, mnemonic, passphrase string) (*Wallet, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+
+	w, err := m.newWallet(name, mnemonic, time.Unix(time.Now().Unix(), 0), [2]uint32{})
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := encryptRecord(name, passphrase, w)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err = m.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(walletsBucket)
+		if bucket.Get([]byte(name)) != nil {
+			return fmt.Errorf("%w: %s", ErrWalletExists, name)
+		}
+		return bucket.Put([]byte(name), record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Open 用口令解密并打开钱包
+func (m *Manager) Open(name, passphrase string) (*Wallet, error) {
+	var record []byte
+	err := m.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(walletsBucket).Get([]byte(name))
+		if v == nil {
+			return fmt.Errorf("%w: %s", ErrWalletNotFound, name)
+		}
+		record = append([]byte(nil), v...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mnemonic, createdAt, next, err := decryptRecord(name, passphrase, record)
+	if err != nil {
+		return nil, err
+	}
+	return m.newWallet(name, mnemonic, createdAt, next)
+}
+
+// List 返回所有钱包名称，按名称排序
+func (m *Manager) List() ([]string, error) {
+	var names []string
+	err := m.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(walletsBucket).ForEach(func(k, _ []byte) error {
+			names = append(names, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete 删除钱包
+//
+// 删除后只能通过助记词恢复。
+func (m *Manager) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(walletsBucket)
+		if bucket.Get([]byte(name)) == nil {
+			return fmt.Errorf("%w: %s", ErrWalletNotFound, name)
+		}
+		return bucket.Delete([]byte(name))
+	})
+}
+
+// newWallet 由助记词推导账户密钥并创建钱包
+func (m *Manager) newWallet(name, mnemonic string, createdAt time.Time, next [2]uint32) (*Wallet, error) {
+	seed, err := NewSeed(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+	master, err := NewMaster(seed, m.params)
+	if err != nil {
+		return nil, err
+	}
+	account, err := master.DerivePath(fmt.Sprintf("m/44'/%d'/0'", m.params.HDCoinType))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Wallet{
+		manager:   m,
+		name:      name,
+		mnemonic:  mnemonic,
+		createdAt: createdAt,
+		account:   account,
+		next:      next,
+	}, nil
+}
+
+// allocateIndex 在一个事务中分配密钥链上下一个可用的索引并保存
+//
+// 从保存的索引与调用方缓存的索引中较大的一个开始，跳过derive返回
+// ErrInvalidChild的索引。同一钱包的多个句柄因此不会分配到相同的索引，
+// 保存的索引也不会回退。
+func (m *Manager) allocateIndex(name string, branch, cached uint32,
+	derive func(index uint32) (*KeyPair, error)) (*KeyPair, uint32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var kp *KeyPair
+	var allocated uint32
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(walletsBucket)
+		v := bucket.Get([]byte(name))
+		if v == nil {
+			return fmt.Errorf("%w: %s", ErrWalletNotFound, name)
+		}
+		if len(v) < headerSize {
+			return fmt.Errorf("%w: %s", ErrCorruptedRecord, name)
+		}
+
+		start := binary.LittleEndian.Uint32(v[9+4*branch:])
+		if cached > start {
+			start = cached
+		}
+		for index := start; index < HardenedKeyStart; index++ {
+			key, err := derive(index)
+			if errors.Is(err, ErrInvalidChild) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			record := append([]byte(nil), v...)
+			binary.LittleEndian.PutUint32(record[9+4*branch:], index+1)
+			kp, allocated = key, index
+			return bucket.Put([]byte(name), record)
+		}
+		return fmt.Errorf("%w: 密钥链%d的索引已用尽", ErrInvalidPath, branch)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return kp, allocated, nil
+}
+
+// Wallet 已解密的HD钱包
+//
+// 密钥按BIP44路径 m/44'/币种'/0'/链/索引 推导。所有方法都可以并发调用。
+type Wallet struct {
+	manager   *Manager
+	name      string
+	mnemonic  string
+	createdAt time.Time
+	account   *ExtendedKey
+
+	mu   sync.Mutex
+	next [2]uint32 // 各密钥链下一个未使用的索引
+}
+
+// Name 返回钱包名称
+func (w *Wallet) Name() string {
+	return w.name
+}
+
+// Mnemonic 返回钱包的助记词
+func (w *Wallet) Mnemonic() string {
+	return w.mnemonic
+}
+
+// CreatedAt 返回钱包创建时间
+func (w *Wallet) CreatedAt() time.Time {
+	return w.createdAt
+}
+
+// AccountKey 返回账户扩展私钥 m/44'/币种'/0'
+func (w *Wallet) AccountKey() *ExtendedKey {
+	return w.account
+}
+
+// DeriveKey 推导指定密钥链和索引的密钥对
+func (w *Wallet) DeriveKey(branch, index uint32) (*KeyPair, error) {
+	if branch != ExternalBranch && branch != InternalBranch {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidBranch, branch)
+	}
+	if index >= HardenedKeyStart {
+		return nil, fmt.Errorf("%w: 索引%d超出范围", ErrInvalidPath, index)
+	}
+
+	branchKey, err := w.account.Derive(branch)
+	if err != nil {
+		return nil, err
+	}
+	child, err := branchKey.Derive(index)
+	if err != nil {
+		return nil, err
+	}
+	return child.KeyPair()
+}
+
+// NextKey 返回密钥链上下一个未使用的密钥对及其索引，并保存新的索引
+//
+// 索引以数据库中保存的值为准，同一钱包的其他句柄分配过的索引不会被重复使用。
+func (w *Wallet) NextKey(branch uint32) (*KeyPair, uint32, error) {
+	if branch != ExternalBranch && branch != InternalBranch {
+		return nil, 0, fmt.Errorf("%w: %d", ErrInvalidBranch, branch)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	kp, index, err := w.manager.allocateIndex(w.name, branch, w.next[branch], func(index uint32) (*KeyPair, error) {
+		return w.DeriveKey(branch, index)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("分配钱包索引失败: %w", err)
+	}
+	w.next[branch] = index + 1
+	return kp, index, nil
+}
+
+// NextIndex 返回密钥链上下一个未使用的索引
+func (w *Wallet) NextIndex(branch uint32) uint32 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if branch > InternalBranch {
+		return 0
+	}
+	return w.next[branch]
+}
+
+// validateName 检查钱包名称
+func validateName(name string) error {
+	if name == "" || len(name) > MaxNameLen {
+		return fmt.Errorf("%w: 长度必须在1到%d字节之间", ErrInvalidName, MaxNameLen)
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return fmt.Errorf("%w: %q包含空白或控制字符", ErrInvalidName, name)
+		}
+	}
+	return nil
+}
+
+// encryptRecord 加密助记词并生成钱包记录
+func encryptRecord(name, passphrase string, w *Wallet) ([]byte, error) {
+	salt, err := utils.GenerateRandomBytes(saltSize)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := utils.GenerateRandomBytes(nonceSize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	record := make([]byte, 0, cipherOff+len(w.mnemonic)+aead.Overhead())
+	record = append(record, recordVersion)
+	record = binary.LittleEndian.AppendUint64(record, uint64(w.createdAt.Unix()))
+	record = binary.LittleEndian.AppendUint32(record, w.next[ExternalBranch])
+	record = binary.LittleEndian.AppendUint32(record, w.next[InternalBranch])
+	record = append(record, salt...)
+	record = append(record, nonce...)
+	return aead.Seal(record, nonce, []byte(w.mnemonic), []byte(name)), nil
+}
+
+// decryptRecord 解密钱包记录，返回助记词、创建时间和各密钥链的下一个索引
+func decryptRecord(name, passphrase string, record []byte) (string, time.Time, [2]uint32, error) {
+	var next [2]uint32
+	if len(record) < cipherOff || record[0] != recordVersion {
+		return "", time.Time{}, next, fmt.Errorf("%w: %s", ErrCorruptedRecord, name)
+	}
+
+	createdAt := time.Unix(int64(binary.LittleEndian.Uint64(record[1:9])), 0)
+	next[ExternalBranch] = binary.LittleEndian.Uint32(record[9:13])
+	next[InternalBranch] = binary.LittleEndian.Uint32(record[13:17])
+	salt := record[headerSize : headerSize+saltSize]
+	nonce := record[headerSize+saltSize : cipherOff]
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return "", time.Time{}, next, err
+	}
+	plaintext, err := aead.Open(nil, nonce, record[cipherOff:], []byte(name))
+	if err != nil {
+		return "", time.Time{}, next, fmt.Errorf("%w: %s", ErrWrongPassphrase, name)
+	}
+	return string(plaintext), createdAt, next, nil
+}
+
+// newAEAD 由口令和盐派生AES-256-GCM加密器
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("派生加密密钥失败: %v", err)
+	}
+	defer utils.ZeroBytes(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
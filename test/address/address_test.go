@@ -0,0 +1,134 @@
+package address_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/address"
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// generatorPubKey secp256k1生成元G的压缩公钥（私钥为1）
+const generatorPubKey = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+// TestPubKeyHashAddress 测试P2PKH地址与公钥、锁定脚本和字符串之间的转换
+func TestPubKeyHashAddress(t *testing.T) {
+	pubKey, _ := hex.DecodeString(generatorPubKey)
+	addr, err := address.NewAddressPubKey(pubKey, blockchain.MainNetParams)
+	if err != nil {
+		t.Fatalf("创建地址失败: %v", err)
+	}
+	if addr.String() != "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH" {
+		t.Errorf("地址编码错误: %s", addr)
+	}
+	if hex.EncodeToString(addr.ScriptAddress()) != "751e76e8199196d454941c45d1b3a323f1433bd6" {
+		t.Errorf("公钥哈希错误: %x", addr.ScriptAddress())
+	}
+
+	pkScript := addr.PkScript()
+	if hex.EncodeToString(pkScript) != "76a914751e76e8199196d454941c45d1b3a323f1433bd688ac" {
+		t.Errorf("锁定脚本错误: %x", pkScript)
+	}
+
+	decoded, err := address.Decode(addr.String(), blockchain.MainNetParams)
+	if err != nil {
+		t.Fatalf("解析地址失败: %v", err)
+	}
+	fromScript, err := address.FromPkScript(pkScript, blockchain.MainNetParams)
+	if err != nil {
+		t.Fatalf("从锁定脚本提取地址失败: %v", err)
+	}
+	for _, a := range []address.Address{decoded, fromScript} {
+		if _, ok := a.(*address.AddressPubKeyHash); !ok || a.String() != addr.String() {
+			t.Errorf("往返转换结果错误: %T %s", a, a)
+		}
+	}
+
+	if _, err := address.NewAddressPubKey(pubKey[:32], blockchain.MainNetParams); !errors.Is(err, address.ErrInvalidPubKey) {
+		t.Errorf("期望ErrInvalidPubKey, 实际: %v", err)
+	}
+}
+
+// TestScriptHashAddress 测试P2SH地址与赎回脚本、锁定脚本和字符串之间的转换
+func TestScriptHashAddress(t *testing.T) {
+	redeemScript := []byte{0x51} // OP_TRUE
+	for _, params := range []*blockchain.ChainParams{blockchain.MainNetParams, blockchain.TestNetParams} {
+		addr, err := address.NewAddressScriptHash(redeemScript, params)
+		if err != nil {
+			t.Fatalf("创建地址失败: %v", err)
+		}
+		if !bytes.Equal(addr.ScriptAddress(), utils.Hash160(redeemScript)) {
+			t.Errorf("%s脚本哈希错误: %x", params.Name, addr.ScriptAddress())
+		}
+
+		decoded, err := address.Decode(addr.String(), params)
+		if err != nil {
+			t.Fatalf("解析地址失败: %v", err)
+		}
+		if _, ok := decoded.(*address.AddressScriptHash); !ok || !decoded.IsForNet(params) {
+			t.Errorf("%s解析结果错误: %T", params.Name, decoded)
+		}
+
+		fromScript, err := address.FromPkScript(addr.PkScript(), params)
+		if err != nil {
+			t.Fatalf("从锁定脚本提取地址失败: %v", err)
+		}
+		if fromScript.String() != addr.String() {
+			t.Errorf("%s锁定脚本往返转换错误: %s", params.Name, fromScript)
+		}
+	}
+
+	mainnet, _ := address.NewAddressScriptHash(redeemScript, blockchain.MainNetParams)
+	if !strings.HasPrefix(mainnet.String(), "3") {
+		t.Errorf("主网P2SH地址应以3开头: %s", mainnet)
+	}
+}
+
+// TestDecodeRejectsOtherNetworks 测试拒绝属于其他网络的地址
+func TestDecodeRejectsOtherNetworks(t *testing.T) {
+	hash := make([]byte, address.HashSize)
+	mainnet, _ := address.NewAddressPubKeyHash(hash, blockchain.MainNetParams)
+	testnet, _ := address.NewAddressScriptHashFromHash(hash, blockchain.TestNetParams)
+
+	if _, err := address.Decode(mainnet.String(), blockchain.TestNetParams); !errors.Is(err, address.ErrWrongNetwork) {
+		t.Errorf("期望ErrWrongNetwork, 实际: %v", err)
+	}
+	if _, err := address.Decode(testnet.String(), blockchain.SimNetParams); !errors.Is(err, address.ErrWrongNetwork) {
+		t.Errorf("期望ErrWrongNetwork, 实际: %v", err)
+	}
+	if mainnet.IsForNet(blockchain.TestNetParams) || !testnet.IsForNet(blockchain.RegTestParams) {
+		t.Error("IsForNet结果错误")
+	}
+
+	// 测试网和回归测试网共用版本字节
+	if _, err := address.Decode(testnet.String(), blockchain.RegTestParams); err != nil {
+		t.Errorf("回归测试网应接受测试网地址: %v", err)
+	}
+}
+
+// TestDecodeInvalid 测试无效地址的检测
+func TestDecodeInvalid(t *testing.T) {
+	valid := "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH"
+	tests := []string{
+		"",
+		"1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMh", // 校验和错误
+		"1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAM0", // 无效字符
+		utils.Base58CheckEncode(make([]byte, 19), 0x00), // 哈希长度错误
+	}
+	for _, s := range tests {
+		if _, err := address.Decode(s, blockchain.MainNetParams); !errors.Is(err, address.ErrInvalidAddress) {
+			t.Errorf("地址%q: 期望ErrInvalidAddress, 实际: %v", s, err)
+		}
+	}
+	if _, err := address.Decode(valid, blockchain.MainNetParams); err != nil {
+		t.Errorf("有效地址被拒绝: %v", err)
+	}
+
+	if _, err := address.FromPkScript([]byte{0x6a, 0x01, 0x00}, blockchain.MainNetParams); !errors.Is(err, address.ErrUnknownScript) {
+		t.Errorf("期望ErrUnknownScript, 实际: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package address_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/address"
+	"simplied-bitcoin-network-go/pkg/bech32"
+	"simplied-bitcoin-network-go/pkg/blockchain"
+)
+
+// TestWitnessAddress 测试BIP173和BIP350的隔离见证地址测试向量
+func TestWitnessAddress(t *testing.T) {
+	tests := []struct {
+		addr     string
+		params   *blockchain.ChainParams
+		pkScript string
+		version  byte
+	}{
+		{
+			addr:     "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4",
+			params:   blockchain.MainNetParams,
+			pkScript: "0014751e76e8199196d454941c45d1b3a323f1433bd6",
+			version:  0,
+		},
+		{
+			addr:     "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7",
+			params:   blockchain.TestNetParams,
+			pkScript: "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262",
+			version:  0,
+		},
+		{
+			addr:     "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+			params:   blockchain.MainNetParams,
+			pkScript: "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			version:  1,
+		},
+		{
+			addr:     "BC1SW50QGDZ25J",
+			params:   blockchain.MainNetParams,
+			pkScript: "6002751e",
+			version:  16,
+		},
+	}
+
+	for _, tt := range tests {
+		decoded, err := address.Decode(tt.addr, tt.params)
+		if err != nil {
+			t.Errorf("%s: 解析失败: %v", tt.addr, err)
+			continue
+		}
+		witness, ok := decoded.(*address.AddressWitness)
+		if !ok || witness.WitnessVersion() != tt.version {
+			t.Errorf("%s: 解析结果错误: %T", tt.addr, decoded)
+			continue
+		}
+		if hex.EncodeToString(decoded.PkScript()) != tt.pkScript {
+			t.Errorf("%s: 锁定脚本错误: %x", tt.addr, decoded.PkScript())
+		}
+		if decoded.String() != strings.ToLower(tt.addr) {
+			t.Errorf("%s: 重新编码结果错误: %s", tt.addr, decoded)
+		}
+
+		pkScript, _ := hex.DecodeString(tt.pkScript)
+		fromScript, err := address.FromPkScript(pkScript, tt.params)
+		if err != nil || fromScript.String() != decoded.String() {
+			t.Errorf("%s: 从锁定脚本提取地址错误: %v %v", tt.addr, fromScript, err)
+		}
+	}
+}
+
+// TestWitnessPubKeyAddress 测试由压缩公钥创建P2WPKH地址
+func TestWitnessPubKeyAddress(t *testing.T) {
+	pubKey, _ := hex.DecodeString(generatorPubKey)
+	addr, err := address.NewAddressWitnessPubKey(pubKey, blockchain.MainNetParams)
+	if err != nil {
+		t.Fatalf("创建地址失败: %v", err)
+	}
+	if addr.String() != "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4" {
+		t.Errorf("地址编码错误: %s", addr)
+	}
+
+	regtest, err := address.NewAddressWitnessScript([]byte{0x51}, blockchain.RegTestParams)
+	if err != nil {
+		t.Fatalf("创建地址失败: %v", err)
+	}
+	if !strings.HasPrefix(regtest.String(), "bcrt1q") || !regtest.IsForNet(blockchain.RegTestParams) {
+		t.Errorf("回归测试网地址前缀错误: %s", regtest)
+	}
+	if _, err := address.Decode(regtest.String(), blockchain.TestNetParams); !errors.Is(err, address.ErrWrongNetwork) {
+		t.Errorf("期望ErrWrongNetwork, 实际: %v", err)
+	}
+}
+
+// TestInvalidWitnessAddress 测试无效隔离见证地址的检测
+func TestInvalidWitnessAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		addr string
+		err  error
+	}{
+		{"未知前缀", "tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut", address.ErrWrongNetwork},
+		{"版本1使用Bech32校验和", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", address.ErrInvalidAddress},
+		{"版本0使用Bech32m校验和", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", address.ErrInvalidAddress},
+		{"见证版本超过16", "BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R", address.ErrInvalidAddress},
+		{"见证程序过短", "bc1pw5dgrnzv", address.ErrInvalidAddress},
+		{"见证程序过长", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav", address.ErrInvalidAddress},
+		{"填充位非零", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v07qwwzcrf", address.ErrInvalidAddress},
+		{"测试网地址", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", address.ErrWrongNetwork},
+	}
+
+	for _, tt := range tests {
+		if _, err := address.Decode(tt.addr, blockchain.MainNetParams); !errors.Is(err, tt.err) {
+			t.Errorf("%s: 期望%v, 实际: %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+// TestWitnessAddressTypoPosition 测试地址解析错误中包含输错字符的位置
+func TestWitnessAddressTypoPosition(t *testing.T) {
+	typo := []byte("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4")
+	typo[17] = 'x'
+
+	_, err := address.Decode(string(typo), blockchain.MainNetParams)
+	if !errors.Is(err, address.ErrInvalidAddress) || !errors.Is(err, bech32.ErrInvalidChecksum) {
+		t.Fatalf("期望校验和错误, 实际: %v", err)
+	}
+	var bechErr *bech32.Error
+	if !errors.As(err, &bechErr) || !reflect.DeepEqual(bechErr.Positions, []int{17}) {
+		t.Errorf("出错位置错误: %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package addrmgr_test
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"simplied-bitcoin-network-go/pkg/addrmgr"
+	"simplied-bitcoin-network-go/pkg/network"
+)
+
+// openDB 在临时目录中创建数据库
+func openDB(t *testing.T) *bolt.DB {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "peers.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newAddrManager 创建地址簿
+func newAddrManager(t *testing.T, db *bolt.DB) *addrmgr.AddrManager {
+	t.Helper()
+
+	a, err := addrmgr.New(db)
+	if err != nil {
+		t.Fatalf("创建地址簿失败: %v", err)
+	}
+	return a
+}
+
+// addr 创建公网地址a.b.c.d:8333
+func addr(a, b, c, d byte) *network.NetAddress {
+	return network.NewNetAddress(net.IPv4(a, b, c, d), 8333, network.SFNodeNetwork)
+}
+
+// TestAddAndGood 测试添加地址并在连接成功后移入已验证表
+func TestAddAndGood(t *testing.T) {
+	a := newAddrManager(t, openDB(t))
+	if a.GetAddress() != nil {
+		t.Fatal("空地址簿不应返回地址")
+	}
+
+	src := addr(1, 2, 3, 4)
+	a.AddAddresses([]*network.NetAddress{addr(5, 6, 7, 8), addr(9, 10, 11, 12)}, src)
+	a.AddAddress(addr(5, 6, 7, 8), src)
+	a.AddAddress(network.NewNetAddress(net.IPv4zero, 8333, 0), src)
+	a.AddAddress(network.NewNetAddress(net.IPv4(5, 6, 7, 9), 0, 0), src)
+	if n := a.NumAddresses(); n != 2 {
+		t.Fatalf("应有2个地址（重复和不可用地址被忽略）, 实际%d个", n)
+	}
+
+	got := a.GetAddress()
+	if got == nil || (got.String() != "5.6.7.8:8333" && got.String() != "9.10.11.12:8333") {
+		t.Fatalf("返回了未知地址: %v", got)
+	}
+
+	a.Attempt(addr(5, 6, 7, 8))
+	a.Good(addr(5, 6, 7, 8))
+	a.Good(addr(13, 14, 15, 16)) // 未知地址被忽略
+	if a.NumTried() != 1 || a.NumAddresses() != 2 {
+		t.Errorf("应有1个已验证地址, 实际%d个, 共%d个", a.NumTried(), a.NumAddresses())
+	}
+}
+
+// TestSourceCannotFlood 测试单个来源无法占满新地址表
+//
+// 同一来源网段的地址最多分布到16个桶中，诚实来源只有与攻击者重合的桶受影响。
+func TestSourceCannotFlood(t *testing.T) {
+	a := newAddrManager(t, openDB(t))
+
+	attacker := addr(66, 66, 66, 66)
+	attackerBuckets := make(map[int]bool)
+	var flood []*network.NetAddress
+	for i := 0; i < 20000; i++ {
+		na := addr(byte(i>>8)+1, byte(i), 1, 1)
+		flood = append(flood, na)
+		attackerBuckets[a.NewBucketIndex(na, attacker)] = true
+	}
+	if len(attackerBuckets) > 16 {
+		t.Fatalf("单个来源的地址应只分布到16个桶, 实际%d个", len(attackerBuckets))
+	}
+	a.AddAddresses(flood, attacker)
+
+	flooded := a.NumAddresses()
+	if flooded > len(attackerBuckets)*64 {
+		t.Fatalf("单个来源的地址应只占用自己的桶, 实际%d个", flooded)
+	}
+
+	// 其他网段的来源只在与攻击者重合的桶中与其竞争
+	honest := addr(99, 1, 1, 1)
+	honestBuckets := make(map[int]bool)
+	unaffected := 0
+	for i := 0; i < 100; i++ {
+		na := addr(200, byte(i), 1, 1)
+		bucket := a.NewBucketIndex(na, honest)
+		honestBuckets[bucket] = true
+		if !attackerBuckets[bucket] {
+			unaffected++
+		}
+		a.AddAddress(na, honest)
+	}
+	if len(honestBuckets) > 16 {
+		t.Errorf("诚实来源的地址应只分布到16个桶, 实际%d个", len(honestBuckets))
+	}
+	if got := a.NumAddresses() - flooded; got < unaffected {
+		t.Errorf("不与攻击者共享桶的%d个地址应全部保留, 实际新增%d个", unaffected, got)
+	}
+}
+
+// TestGetAddressPrefersTried 测试优先选择成功连接过的地址
+func TestGetAddressPrefersTried(t *testing.T) {
+	a := newAddrManager(t, openDB(t))
+
+	src := addr(1, 2, 3, 4)
+	for i := 0; i < 100; i++ {
+		a.AddAddress(addr(10+byte(i), 1, 1, 1), src)
+	}
+	good := addr(10, 1, 1, 1)
+	a.Good(good)
+
+	tried := 0
+	for i := 0; i < 1000; i++ {
+		if a.GetAddress().String() == good.String() {
+			tried++
+		}
+	}
+	if tried < 500 {
+		t.Errorf("已验证地址应被优先选择, 1000次中只选中%d次", tried)
+	}
+}
+
+// TestPersistence 测试地址簿保存后重新加载
+func TestPersistence(t *testing.T) {
+	db := openDB(t)
+	a := newAddrManager(t, db)
+	a.Start()
+
+	src := addr(1, 2, 3, 4)
+	for i := 0; i < 50; i++ {
+		a.AddAddress(addr(20, byte(i), 1, 1), src)
+	}
+	a.Good(addr(20, 7, 1, 1))
+
+	// 超过30天未活跃的地址在保存时被淘汰
+	stale := addr(30, 1, 1, 1)
+	stale.Timestamp = time.Now().Add(-40 * 24 * time.Hour)
+	a.AddAddress(stale, src)
+
+	if err := a.Stop(); err != nil {
+		t.Fatalf("保存地址簿失败: %v", err)
+	}
+
+	reloaded := newAddrManager(t, db)
+	if n := reloaded.NumAddresses(); n != 50 {
+		t.Errorf("重新加载后应有50个地址, 实际%d个", n)
+	}
+	if n := reloaded.NumTried(); n != 1 {
+		t.Errorf("重新加载后应有1个已验证地址, 实际%d个", n)
+	}
+	for _, na := range reloaded.AddressCache() {
+		if na.String() == stale.String() {
+			t.Error("失效地址不应被保存")
+		}
+	}
+}
+
+// TestAddressCache 测试通告地址的数量
+func TestAddressCache(t *testing.T) {
+	a := newAddrManager(t, openDB(t))
+
+	if n := len(a.AddressCache()); n != 0 {
+		t.Fatalf("空地址簿应返回0个地址, 实际%d个", n)
+	}
+
+	for i := 0; i < 30; i++ {
+		a.AddAddress(addr(40, byte(i), 1, 1), addr(byte(50+i), 1, 1, 1))
+	}
+	if n := len(a.AddressCache()); n != 30 {
+		t.Errorf("地址较少时应全部返回, 实际%d个", n)
+	}
+
+	for i := 0; i < 3000; i++ {
+		a.AddAddress(addr(byte(i>>8)+60, byte(i), 1, 1), addr(byte(i), byte(i>>8), 1, 1))
+	}
+	total := a.NumAddresses()
+	cache := a.AddressCache()
+	want := total * 23 / 100
+	if want > network.MaxAddrPerMsg {
+		want = network.MaxAddrPerMsg
+	}
+	if len(cache) != want {
+		t.Errorf("应返回%d个地址, 实际%d个", want, len(cache))
+	}
+
+	seen := make(map[string]bool)
+	for _, na := range cache {
+		if seen[na.String()] {
+			t.Fatalf("重复的地址: %v", na)
+		}
+		seen[na.String()] = true
+	}
+}
@@ -0,0 +1,113 @@
+package banman_test
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"simplied-bitcoin-network-go/pkg/banman"
+)
+
+// openDB 在临时目录中创建数据库
+func openDB(t *testing.T) *bolt.DB {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "peers.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newBanManager 创建封禁列表
+func newBanManager(t *testing.T, db *bolt.DB) *banman.BanManager {
+	t.Helper()
+
+	b, err := banman.New(db)
+	if err != nil {
+		t.Fatalf("创建封禁列表失败: %v", err)
+	}
+	return b
+}
+
+// TestBanAndUnban 测试封禁、查询和解除封禁
+func TestBanAndUnban(t *testing.T) {
+	b := newBanManager(t, openDB(t))
+	ip := net.ParseIP("10.0.0.1")
+
+	if b.IsBanned(ip) {
+		t.Fatal("未封禁的IP不应处于封禁期")
+	}
+	if err := b.Ban(ip, 0, "无效时长"); !errors.Is(err, banman.ErrInvalidDuration) {
+		t.Errorf("期望ErrInvalidDuration, 实际: %v", err)
+	}
+
+	if err := b.Ban(ip, time.Hour, "发送无效区块"); err != nil {
+		t.Fatalf("封禁失败: %v", err)
+	}
+	// IPv4映射的IPv6形式视为同一IP
+	if !b.IsBanned(ip.To4()) || !b.IsBanned(ip.To16()) {
+		t.Error("IP应处于封禁期")
+	}
+	entry := b.Lookup(ip)
+	if entry == nil || entry.Reason != "发送无效区块" || entry.Until.Sub(entry.CreatedAt) != time.Hour {
+		t.Fatalf("封禁记录错误: %+v", entry)
+	}
+
+	if err := b.Unban(ip); err != nil {
+		t.Fatalf("解除封禁失败: %v", err)
+	}
+	if b.IsBanned(ip) {
+		t.Error("解除封禁后IP不应处于封禁期")
+	}
+	if err := b.Unban(ip); !errors.Is(err, banman.ErrNotBanned) {
+		t.Errorf("期望ErrNotBanned, 实际: %v", err)
+	}
+}
+
+// TestBanListPersistence 测试封禁列表在重新打开后仍然有效
+func TestBanListPersistence(t *testing.T) {
+	db := openDB(t)
+	b := newBanManager(t, db)
+
+	b.Ban(net.ParseIP("10.0.0.1"), time.Hour, "a")
+	b.Ban(net.ParseIP("10.0.0.2"), 2*time.Hour, "b")
+	b.Ban(net.ParseIP("2001:db8::1"), 30*time.Minute, "c")
+	b.Ban(net.ParseIP("10.0.0.3"), time.Second, "即将到期")
+
+	reopened := newBanManager(t, db)
+	list := reopened.List()
+	if len(list) < 3 || len(list) > 4 {
+		t.Fatalf("重新打开后应有3-4条封禁记录, 实际%d条", len(list))
+	}
+	if list[0].IP.String() == "10.0.0.3" {
+		list = list[1:]
+	}
+	want := []string{"2001:db8::1", "10.0.0.1", "10.0.0.2"}
+	for i, entry := range list {
+		if entry.IP.String() != want[i] {
+			t.Errorf("第%d条记录应为%s, 实际%s", i, want[i], entry.IP)
+		}
+	}
+
+	// 到期的记录不再生效，重新加载时被清除
+	time.Sleep(1100 * time.Millisecond)
+	if reopened.IsBanned(net.ParseIP("10.0.0.3")) {
+		t.Error("到期的封禁不应生效")
+	}
+	if n := len(newBanManager(t, db).List()); n != 3 {
+		t.Errorf("到期记录应被清除, 实际%d条", n)
+	}
+
+	if err := reopened.Clear(); err != nil {
+		t.Fatalf("清空封禁列表失败: %v", err)
+	}
+	if n := len(newBanManager(t, db).List()); n != 0 {
+		t.Errorf("清空后不应有封禁记录, 实际%d条", n)
+	}
+}
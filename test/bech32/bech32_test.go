@@ -0,0 +1,143 @@
+package bech32_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/bech32"
+)
+
+// TestValidStrings 测试BIP173和BIP350的有效测试向量
+func TestValidStrings(t *testing.T) {
+	tests := []struct {
+		str     string
+		version bech32.Version
+	}{
+		{"A12UEL5L", bech32.Bech32},
+		{"a12uel5l", bech32.Bech32},
+		{"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs", bech32.Bech32},
+		{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", bech32.Bech32},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", bech32.Bech32},
+		{"A1LQFN3A", bech32.Bech32m},
+		{"a1lqfn3a", bech32.Bech32m},
+		{"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx", bech32.Bech32m},
+		{"11llllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllludsr8", bech32.Bech32m},
+		{"?1v759aa", bech32.Bech32m},
+	}
+
+	for _, tt := range tests {
+		hrp, data, version, err := bech32.Decode(tt.str)
+		if err != nil {
+			t.Errorf("%s: 解码失败: %v", tt.str, err)
+			continue
+		}
+		if version != tt.version {
+			t.Errorf("%s: 期望%v, 实际: %v", tt.str, tt.version, version)
+		}
+
+		encoded, err := bech32.Encode(hrp, data, version)
+		if err != nil {
+			t.Errorf("%s: 编码失败: %v", tt.str, err)
+			continue
+		}
+		if encoded != strings.ToLower(tt.str) {
+			t.Errorf("重新编码结果错误: %s != %s", encoded, tt.str)
+		}
+	}
+}
+
+// TestInvalidStrings 测试无效字符串的错误类别
+func TestInvalidStrings(t *testing.T) {
+	tests := []struct {
+		str string
+		err error
+	}{
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e2w", bech32.ErrInvalidChecksum},
+		{"s lit1checkupstagehandshakeupstreamerranterredcaperredp8hs2p", bech32.ErrInvalidCharacter},
+		{"spl\x7ft1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", bech32.ErrInvalidCharacter},
+		{"split1cheo2y9e2w", bech32.ErrInvalidCharacter},
+		{"split1a2y9w", bech32.ErrInvalidLength},
+		{"1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", bech32.ErrMissingSeparator},
+		{"11qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqsqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqc8247j", bech32.ErrInvalidLength},
+		{"pzry9x0s0muk", bech32.ErrMissingSeparator},
+		{"x1b4n0q5v", bech32.ErrInvalidCharacter},
+		{"de1lg7wt\xff", bech32.ErrInvalidCharacter},
+		{"A1G7SGD8", bech32.ErrInvalidChecksum},
+		{"10a06t8", bech32.ErrInvalidLength},
+		{"a12UEL5L", bech32.ErrMixedCase},
+	}
+
+	for _, tt := range tests {
+		_, _, _, err := bech32.Decode(tt.str)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: 期望%v, 实际: %v", tt.str, tt.err, err)
+		}
+		var bechErr *bech32.Error
+		if !errors.As(err, &bechErr) {
+			t.Errorf("%q: 错误类型应为*bech32.Error: %T", tt.str, err)
+		}
+	}
+}
+
+// TestLocateErrors 测试定位输错的字符
+func TestLocateErrors(t *testing.T) {
+	const valid = "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+
+	tests := []struct {
+		name      string
+		str       string
+		positions []int
+	}{
+		{"校验和字符", replaceAt(valid, 41, 'q'), []int{41}},
+		{"数据字符", replaceAt(valid, 10, 'p'), []int{10}},
+		{"两个字符", replaceAt(replaceAt(valid, 5, 'z'), 30, 'x'), []int{5, 30}},
+		{"Bech32m", replaceAt("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", 20, 'q'), []int{20}},
+		{"无效字符", replaceAt(valid, 8, 'b'), []int{8}},
+		{"大小写混用", replaceAt(valid, 12, 'E'), []int{12}},
+	}
+
+	for _, tt := range tests {
+		_, _, _, err := bech32.Decode(tt.str)
+		var bechErr *bech32.Error
+		if !errors.As(err, &bechErr) {
+			t.Errorf("%s: 期望*bech32.Error, 实际: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(bechErr.Positions, tt.positions) {
+			t.Errorf("%s: 期望位置%v, 实际: %v", tt.name, tt.positions, bechErr.Positions)
+		}
+	}
+}
+
+// TestConvertBits 测试字节与5位分组之间的转换
+func TestConvertBits(t *testing.T) {
+	data := []byte{0x75, 0x1e, 0x76, 0xe8, 0x19, 0x91}
+	converted, err := bech32.ConvertBits(data, 8, 5, true)
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	back, err := bech32.ConvertBits(converted, 5, 8, false)
+	if err != nil {
+		t.Fatalf("反向转换失败: %v", err)
+	}
+	if !reflect.DeepEqual(back, data) {
+		t.Errorf("往返转换结果错误: %x", back)
+	}
+
+	// 填充位非零
+	if _, err := bech32.ConvertBits([]byte{0x1f}, 5, 8, false); !errors.Is(err, bech32.ErrInvalidData) {
+		t.Errorf("期望ErrInvalidData, 实际: %v", err)
+	}
+	if _, err := bech32.Encode("bc", []byte{32}, bech32.Bech32); !errors.Is(err, bech32.ErrInvalidData) {
+		t.Errorf("期望ErrInvalidData, 实际: %v", err)
+	}
+}
+
+// replaceAt 替换字符串中指定位置的字符
+func replaceAt(s string, i int, c byte) string {
+	b := []byte(s)
+	b[i] = c
+	return string(b)
+}
@@ -153,14 +153,15 @@ func TestBlockHeaderValidation(t *testing.T) {
 // TestBlockSerialization 测试完整区块序列化
 func TestBlockSerialization(t *testing.T) {
 	// 创建测试交易
-	tx1 := blockchain.NewTransaction([]byte("transaction 1 data"))
-	tx2 := blockchain.NewTransaction([]byte("transaction 2 data"))
+	tx1 := newTestTransaction("transaction 1 data")
+	tx2 := newTestTransaction("transaction 2 data")
 	transactions := []*blockchain.Transaction{tx1, tx2}
 
 	// 计算Merkle根
 	txHashes := make([][]byte, len(transactions))
 	for i, tx := range transactions {
-		txHashes[i] = tx.Hash[:]
+		txHash := tx.Hash()
+		txHashes[i] = txHash[:]
 	}
 	merkleRoot := utils.MerkleRoot(txHashes)
 	var merkleRootArray [32]byte
@@ -210,15 +211,16 @@ func TestBlockSerialization(t *testing.T) {
 // TestBlockSizeCalculation 测试区块大小计算
 func TestBlockSizeCalculation(t *testing.T) {
 	// 创建不同大小的交易
-	smallTx := blockchain.NewTransaction([]byte("small"))
-	largeTx := blockchain.NewTransaction(make([]byte, 1000))
+	smallTx := newTestTransaction("small")
+	largeTx := newTestTransaction(string(make([]byte, 1000)))
 
 	transactions := []*blockchain.Transaction{smallTx, largeTx}
 
 	// 计算Merkle根
 	txHashes := make([][]byte, len(transactions))
 	for i, tx := range transactions {
-		txHashes[i] = tx.Hash[:]
+		txHash := tx.Hash()
+		txHashes[i] = txHash[:]
 	}
 	merkleRoot := utils.MerkleRoot(txHashes)
 	var merkleRootArray [32]byte
@@ -245,8 +247,9 @@ func TestBlockSizeCalculation(t *testing.T) {
 // TestBlockValidation 测试区块验证
 func TestBlockValidation(t *testing.T) {
 	// 创建有效区块
-	tx := blockchain.NewTransaction([]byte("test transaction"))
-	txHashes := [][]byte{tx.Hash[:]}
+	tx := newTestTransaction("test transaction")
+	txHash := tx.Hash()
+	txHashes := [][]byte{txHash[:]}
 	merkleRoot := utils.MerkleRoot(txHashes)
 	var merkleRootArray [32]byte
 	copy(merkleRootArray[:], merkleRoot)
@@ -280,16 +283,17 @@ func TestBlockValidation(t *testing.T) {
 // TestBlockMerkleRoot 测试Merkle根计算
 func TestBlockMerkleRoot(t *testing.T) {
 	// 创建测试交易
-	tx1 := blockchain.NewTransaction([]byte("tx1"))
-	tx2 := blockchain.NewTransaction([]byte("tx2"))
-	tx3 := blockchain.NewTransaction([]byte("tx3"))
+	tx1 := newTestTransaction("tx1")
+	tx2 := newTestTransaction("tx2")
+	tx3 := newTestTransaction("tx3")
 
 	transactions := []*blockchain.Transaction{tx1, tx2, tx3}
 
 	// 使用utils包计算期望的Merkle根
 	txHashes := make([][]byte, len(transactions))
 	for i, tx := range transactions {
-		txHashes[i] = tx.Hash[:]
+		txHash := tx.Hash()
+		txHashes[i] = txHash[:]
 	}
 	expectedMerkleRoot := utils.MerkleRoot(txHashes)
 
@@ -311,16 +315,17 @@ func TestBlockMerkleRoot(t *testing.T) {
 // TestBlockTransactionOperations 测试区块交易操作
 func TestBlockTransactionOperations(t *testing.T) {
 	// 创建测试交易
-	tx1 := blockchain.NewTransaction([]byte("tx1"))
-	tx2 := blockchain.NewTransaction([]byte("tx2"))
-	tx3 := blockchain.NewTransaction([]byte("tx3"))
+	tx1 := newTestTransaction("tx1")
+	tx2 := newTestTransaction("tx2")
+	tx3 := newTestTransaction("tx3")
 
 	transactions := []*blockchain.Transaction{tx1, tx2, tx3}
 
 	// 创建区块
 	txHashes := make([][]byte, len(transactions))
 	for i, tx := range transactions {
-		txHashes[i] = tx.Hash[:]
+		txHash := tx.Hash()
+		txHashes[i] = txHash[:]
 	}
 	merkleRoot := utils.MerkleRoot(txHashes)
 	var merkleRootArray [32]byte
@@ -330,22 +335,22 @@ func TestBlockTransactionOperations(t *testing.T) {
 	block := blockchain.NewBlock(header, transactions)
 
 	// 测试HasTransaction
-	if !block.HasTransaction(tx1.Hash) {
+	if !block.HasTransaction(tx1.Hash()) {
 		t.Error("区块应该包含tx1")
 	}
 
 	// 测试不存在的交易
-	nonExistentTx := blockchain.NewTransaction([]byte("non-existent"))
-	if block.HasTransaction(nonExistentTx.Hash) {
+	nonExistentTx := newTestTransaction("non-existent")
+	if block.HasTransaction(nonExistentTx.Hash()) {
 		t.Error("区块不应该包含不存在的交易")
 	}
 
 	// 测试GetTransaction
-	retrievedTx := block.GetTransaction(tx2.Hash)
+	retrievedTx := block.GetTransaction(tx2.Hash())
 	if retrievedTx == nil {
-		t.Error("应该能够获取tx2")
+		t.Fatal("应该能够获取tx2")
 	}
-	if !bytes.Equal(retrievedTx.Hash[:], tx2.Hash[:]) {
+	if retrievedTx.Hash() != tx2.Hash() {
 		t.Error("获取的交易哈希不匹配")
 	}
 
@@ -373,8 +378,9 @@ func TestBlockHeaderDifficulty(t *testing.T) {
 
 // TestBlockString 测试字符串表示
 func TestBlockString(t *testing.T) {
-	tx := blockchain.NewTransaction([]byte("test"))
-	txHashes := [][]byte{tx.Hash[:]}
+	tx := newTestTransaction("test")
+	txHash := tx.Hash()
+	txHashes := [][]byte{txHash[:]}
 	merkleRoot := utils.MerkleRoot(txHashes)
 	var merkleRootArray [32]byte
 	copy(merkleRootArray[:], merkleRoot)
@@ -395,23 +401,50 @@ func TestBlockString(t *testing.T) {
 	}
 }
 
-// TestNewTransaction 测试交易创建
-func TestNewTransaction(t *testing.T) {
-	data := []byte("test transaction data")
-	tx := blockchain.NewTransaction(data)
+// TestBlockSerializationVariableSizes 测试不同大小交易的区块序列化往返
+func TestBlockSerializationVariableSizes(t *testing.T) {
+	// 构造大小差异明显的交易，旧的平均分配算法会将其解析错位
+	transactions := []*blockchain.Transaction{
+		newTestTransaction("a"),
+		newTestSpendTransaction(string(make([]byte, 700))),
+		newTestSpendTransaction("medium sized transaction payload"),
+	}
 
-	if tx == nil {
-		t.Fatal("交易创建失败")
+	txHashes := make([][]byte, len(transactions))
+	for i, tx := range transactions {
+		txHash := tx.Hash()
+		txHashes[i] = txHash[:]
 	}
+	var merkleRootArray [32]byte
+	copy(merkleRootArray[:], utils.MerkleRoot(txHashes))
+
+	header := blockchain.NewBlockHeader(1, [32]byte{}, merkleRootArray, uint32(time.Now().Unix()), 0x1d00ffff, 12345)
+	original := blockchain.NewBlock(header, transactions)
 
-	if !bytes.Equal(tx.Data, data) {
-		t.Error("交易数据不匹配")
+	data := original.Serialize()
+	if len(data) != original.Size() {
+		t.Errorf("序列化长度与Size()不一致: 期望%d, 实际%d", original.Size(), len(data))
 	}
 
-	// 验证哈希计算
-	expectedHash := utils.DoubleSHA256(data)
-	if !bytes.Equal(tx.Hash[:], expectedHash) {
-		t.Error("交易哈希计算错误")
+	deserialized := &blockchain.Block{}
+	if err := deserialized.Deserialize(data); err != nil {
+		t.Fatalf("反序列化失败: %v", err)
+	}
+
+	// 逐笔验证交易哈希
+	for i, tx := range transactions {
+		if deserialized.Transactions[i].Hash() != tx.Hash() {
+			t.Errorf("交易%d哈希不匹配", i)
+		}
+	}
+
+	// 再次序列化应得到完全相同的字节
+	if !bytes.Equal(deserialized.Serialize(), data) {
+		t.Error("区块往返序列化结果不一致")
+	}
+
+	if err := deserialized.Validate(); err != nil {
+		t.Errorf("反序列化后的区块验证失败: %v", err)
 	}
 }
 
@@ -433,6 +466,10 @@ func TestBlockDeserializationErrors(t *testing.T) {
 			name: "无效VarInt",
 			data: append(make([]byte, blockchain.BlockHeaderSize), 0xff, 0xff),
 		},
+		{
+			name: "交易数据截断",
+			data: append(make([]byte, blockchain.BlockHeaderSize), 0x01, 0x01, 0x00, 0x00, 0x00, 0x01),
+		},
 	}
 
 	for _, tt := range tests {
@@ -452,3 +489,18 @@ func TestBlockDeserializationErrors(t *testing.T) {
 		t.Error("区块头反序列化应该返回错误")
 	}
 }
+
+// newTestTransaction 创建测试用交易
+//
+// 将给定数据放入Coinbase输入脚本，保证不同数据产生不同的交易哈希。
+func newTestTransaction(data string) *blockchain.Transaction {
+	return blockchain.NewCoinbaseTransaction([]byte(data), 5000000000, []byte{})
+}
+
+// newTestSpendTransaction 创建测试用的普通（非Coinbase）交易
+func newTestSpendTransaction(data string) *blockchain.Transaction {
+	prevOut := blockchain.NewOutPoint([32]byte{1}, 0)
+	return blockchain.NewTransaction(blockchain.TxVersion,
+		[]*blockchain.TxIn{blockchain.NewTxIn(prevOut, []byte(data))},
+		[]*blockchain.TxOut{blockchain.NewTxOut(1000, []byte{0x51})}, 0)
+}
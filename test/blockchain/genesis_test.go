@@ -106,7 +106,7 @@ func TestIsGenesisBlock(t *testing.T) {
 	}
 
 	// 测试普通区块
-	tx := blockchain.NewTransaction([]byte("test"))
+	tx := newTestTransaction("test")
 	header := blockchain.NewBlockHeader(1, [32]byte{}, [32]byte{}, uint32(time.Now().Unix()), 0x1d00ffff, 12345)
 	normalBlock := blockchain.NewBlock(header, []*blockchain.Transaction{tx})
 
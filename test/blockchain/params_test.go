@@ -0,0 +1,96 @@
+package blockchain_test
+
+import (
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// allParams 所有内置网络的参数
+var allParams = []*blockchain.ChainParams{
+	blockchain.MainNetParams,
+	blockchain.TestNetParams,
+	blockchain.RegTestParams,
+	blockchain.SimNetParams,
+}
+
+// TestChainParamsUnique 测试各网络的魔数、创世区块和端口互不相同
+func TestChainParamsUnique(t *testing.T) {
+	magics := make(map[uint32]string)
+	genesis := make(map[[32]byte]string)
+	ports := make(map[int]string)
+
+	for _, params := range allParams {
+		if other, ok := magics[params.Net]; ok {
+			t.Errorf("%s与%s的魔数相同", params.Name, other)
+		}
+		magics[params.Net] = params.Name
+
+		if other, ok := genesis[params.GenesisHash]; ok {
+			t.Errorf("%s与%s的创世区块相同", params.Name, other)
+		}
+		genesis[params.GenesisHash] = params.Name
+
+		for _, port := range []int{params.DefaultPort, params.RPCPort} {
+			if other, ok := ports[port]; ok {
+				t.Errorf("%s与%s的端口%d冲突", params.Name, other, port)
+			}
+			ports[port] = params.Name
+		}
+	}
+}
+
+// TestChainParamsGenesis 测试创世区块与网络参数一致
+func TestChainParamsGenesis(t *testing.T) {
+	for _, params := range allParams {
+		block := params.GenesisBlock
+		if block.Hash() != params.GenesisHash || !params.IsGenesisHash(block.Hash()) {
+			t.Errorf("%s创世区块哈希缓存错误", params.Name)
+		}
+		if block.Header.Bits != params.PowLimitBits {
+			t.Errorf("%s创世区块难度位应为工作量证明上限: %08x", params.Name, block.Header.Bits)
+		}
+		if params.PowLimit.Cmp(utils.BitsToTarget(params.PowLimitBits)) != 0 {
+			t.Errorf("%s工作量证明上限与难度位不一致", params.Name)
+		}
+		if block.GetMerkleRoot() != block.Header.MerkleRoot {
+			t.Errorf("%s创世区块Merkle根错误", params.Name)
+		}
+		if !block.Header.MeetsTarget() {
+			t.Errorf("%s创世区块不满足难度目标: %x", params.Name, block.Hash())
+		}
+	}
+
+	// 主网创世区块与全局创世区块一致
+	if blockchain.MainNetParams.GenesisHash != blockchain.GetGenesisBlockHash() {
+		t.Error("主网参数的创世区块应为全局创世区块")
+	}
+}
+
+// TestChainParamsRetarget 测试难度调整参数
+func TestChainParamsRetarget(t *testing.T) {
+	if got := blockchain.MainNetParams.TargetTimespan(); got != 2016*blockchain.TargetBlockTime {
+		t.Errorf("主网目标时间跨度错误: %v", got)
+	}
+	if !blockchain.RegTestParams.NoRetargeting || blockchain.SimNetParams.NoRetargeting {
+		t.Error("只有回归测试网络应禁用难度调整")
+	}
+}
+
+// TestParamsForNetwork 测试按名称查找网络参数
+func TestParamsForNetwork(t *testing.T) {
+	for _, params := range allParams {
+		got, err := blockchain.ParamsForNetwork(params.Name)
+		if err != nil || got != params {
+			t.Errorf("查找%s失败: %v", params.Name, err)
+		}
+	}
+
+	if got, err := blockchain.ParamsForNetwork(""); err != nil || got != blockchain.MainNetParams {
+		t.Error("空名称应返回主网参数")
+	}
+	if _, err := blockchain.ParamsForNetwork("signet"); err == nil {
+		t.Error("未知网络应返回错误")
+	}
+}
@@ -0,0 +1,75 @@
+package blockchain_test
+
+import (
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// TestCalcBlockSubsidy 测试区块奖励减半计划
+func TestCalcBlockSubsidy(t *testing.T) {
+	tests := []struct {
+		height   int32
+		expected int64
+	}{
+		{-1, 0},
+		{0, 5000000000},
+		{209999, 5000000000},
+		{210000, 2500000000},
+		{420000, 1250000000},
+		{630000, 625000000},
+		{210000 * 33, 0},
+		{210000 * 32, 1},
+		{210000 * 64, 0},
+	}
+
+	for _, tt := range tests {
+		if got := blockchain.CalcBlockSubsidy(tt.height, blockchain.MainNetParams); got != tt.expected {
+			t.Errorf("CalcBlockSubsidy(%d) = %d, 期望%d", tt.height, got, tt.expected)
+		}
+	}
+}
+
+// TestCalcTotalSubsidy 测试累计奖励不超过最大供应量
+func TestCalcTotalSubsidy(t *testing.T) {
+	if got := blockchain.CalcTotalSubsidy(0, blockchain.MainNetParams); got != 5000000000 {
+		t.Errorf("创世区块累计奖励错误: %d", got)
+	}
+	if got := blockchain.CalcTotalSubsidy(210000, blockchain.MainNetParams); got != 210000*5000000000+2500000000 {
+		t.Errorf("跨越第一次减半的累计奖励错误: %d", got)
+	}
+
+	// 逐块累加与分段求和一致
+	var sum int64
+	for h := int32(0); h < 1000; h++ {
+		sum += blockchain.CalcBlockSubsidy(h, blockchain.MainNetParams)
+	}
+	if got := blockchain.CalcTotalSubsidy(999, blockchain.MainNetParams); got != sum {
+		t.Errorf("分段求和%d与逐块累加%d不一致", got, sum)
+	}
+
+	// 全部奖励发放完毕后的总量
+	total := blockchain.CalcTotalSubsidy(1<<30, blockchain.MainNetParams)
+	if total != 2099999997690000 {
+		t.Errorf("全部奖励总额错误: %d", total)
+	}
+	if total > utils.MaxSatoshi {
+		t.Errorf("全部奖励总额%d超过最大供应量%d", total, int64(utils.MaxSatoshi))
+	}
+}
+
+// TestCalcBlockSubsidyRegTest 测试回归测试网络的奖励减半周期
+func TestCalcBlockSubsidyRegTest(t *testing.T) {
+	params := blockchain.RegTestParams
+
+	if got := blockchain.CalcBlockSubsidy(149, params); got != 5000000000 {
+		t.Errorf("第一个减半周期的奖励错误: %d", got)
+	}
+	if got := blockchain.CalcBlockSubsidy(150, params); got != 2500000000 {
+		t.Errorf("第一次减半后的奖励错误: %d", got)
+	}
+	if got := blockchain.CalcTotalSubsidy(150, params); got != 150*5000000000+2500000000 {
+		t.Errorf("跨越第一次减半的累计奖励错误: %d", got)
+	}
+}
@@ -0,0 +1,145 @@
+package blockchain_test
+
+import (
+	"bytes"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// TestNewTransaction 测试交易创建
+func TestNewTransaction(t *testing.T) {
+	prevHash := [32]byte{1, 2, 3}
+	txIn := blockchain.NewTxIn(blockchain.NewOutPoint(prevHash, 7), []byte{0x51})
+	txOut := blockchain.NewTxOut(1000, []byte{0x76, 0xa9})
+
+	tx := blockchain.NewTransaction(blockchain.TxVersion, []*blockchain.TxIn{txIn}, []*blockchain.TxOut{txOut}, 42)
+	if tx == nil {
+		t.Fatal("交易创建失败")
+	}
+
+	if tx.Version != blockchain.TxVersion || tx.LockTime != 42 {
+		t.Error("交易版本或锁定时间不匹配")
+	}
+	if tx.TxIn[0].Sequence != blockchain.MaxTxInSequenceNum {
+		t.Errorf("默认序列号错误: %x", tx.TxIn[0].Sequence)
+	}
+	if tx.TxIn[0].PreviousOutPoint.Index != 7 || tx.TxIn[0].PreviousOutPoint.Hash != prevHash {
+		t.Error("前置输出引用不匹配")
+	}
+	if tx.IsCoinbase() {
+		t.Error("普通交易不应被识别为Coinbase交易")
+	}
+
+	// 验证哈希计算
+	expectedHash := utils.DoubleSHA256(tx.Serialize())
+	txHash := tx.Hash()
+	if !bytes.Equal(txHash[:], expectedHash) {
+		t.Error("交易哈希计算错误")
+	}
+}
+
+// TestTransactionSerialization 测试交易序列化往返
+func TestTransactionSerialization(t *testing.T) {
+	tx := blockchain.NewTransaction(2, []*blockchain.TxIn{
+		blockchain.NewTxIn(blockchain.NewOutPoint([32]byte{0xaa}, 0), []byte("sig script 1")),
+		blockchain.NewTxIn(blockchain.NewOutPoint([32]byte{0xbb}, 3), make([]byte, 300)),
+	}, []*blockchain.TxOut{
+		blockchain.NewTxOut(5000, []byte{0x51}),
+		blockchain.NewTxOut(0, []byte{}),
+		blockchain.NewTxOut(2100000000000000, make([]byte, 0x1000)),
+	}, 500000)
+	tx.TxIn[1].Sequence = 0xfffffffd
+
+	data := tx.Serialize()
+	if len(data) != tx.SerializeSize() {
+		t.Errorf("SerializeSize()错误: 期望%d, 实际%d", len(data), tx.SerializeSize())
+	}
+
+	decoded := &blockchain.Transaction{}
+	if err := decoded.Deserialize(data); err != nil {
+		t.Fatalf("反序列化失败: %v", err)
+	}
+
+	if !bytes.Equal(decoded.Serialize(), data) {
+		t.Error("往返序列化结果不一致")
+	}
+	if decoded.Hash() != tx.Hash() {
+		t.Error("往返后交易哈希不一致")
+	}
+	if decoded.TxIn[1].Sequence != 0xfffffffd {
+		t.Error("序列号不匹配")
+	}
+	if decoded.TxOut[2].Value != 2100000000000000 {
+		t.Error("输出金额不匹配")
+	}
+
+	// 多余字节应被拒绝
+	if err := decoded.Deserialize(append(data, 0x00)); err == nil {
+		t.Error("带多余字节的数据应该反序列化失败")
+	}
+
+	// 截断数据应被拒绝
+	for _, n := range []int{0, 3, 5, 40, len(data) - 1} {
+		if err := decoded.Deserialize(data[:n]); err == nil {
+			t.Errorf("截断到%d字节的数据应该反序列化失败", n)
+		}
+	}
+}
+
+// TestGenesisCoinbaseLayout 测试创世Coinbase交易与手工布局一致
+func TestGenesisCoinbaseLayout(t *testing.T) {
+	genesis := blockchain.GetGenesisBlock()
+	coinbase := genesis.Transactions[0]
+
+	if !coinbase.IsCoinbase() {
+		t.Fatal("创世区块第一笔交易应为Coinbase交易")
+	}
+
+	// 按照比特币交易格式手工拼接期望的字节
+	script := []byte(blockchain.GenesisCoinbaseMessage)
+	var expected []byte
+	expected = append(expected, utils.Uint32ToLittleEndian(1)...)
+	expected = append(expected, utils.EncodeVarInt(1)...)
+	expected = append(expected, make([]byte, 32)...)
+	expected = append(expected, 0xFF, 0xFF, 0xFF, 0xFF)
+	expected = append(expected, utils.EncodeVarInt(uint64(len(script)))...)
+	expected = append(expected, script...)
+	expected = append(expected, utils.Uint32ToLittleEndian(0xFFFFFFFF)...)
+	expected = append(expected, utils.EncodeVarInt(1)...)
+	expected = append(expected, utils.Uint64ToLittleEndian(5000000000)...)
+	expected = append(expected, utils.EncodeVarInt(0)...)
+	expected = append(expected, utils.Uint32ToLittleEndian(0)...)
+
+	if !bytes.Equal(coinbase.Serialize(), expected) {
+		t.Error("创世Coinbase交易序列化布局不一致")
+	}
+	if coinbase.TotalOutputValue() != 5000000000 {
+		t.Errorf("创世Coinbase输出金额错误: %d", coinbase.TotalOutputValue())
+	}
+}
+
+// TestDeserializeTransactionStream 测试从连续数据中逐笔解析交易
+func TestDeserializeTransactionStream(t *testing.T) {
+	tx1 := newTestTransaction("first")
+	tx2 := newTestTransaction("second transaction with longer script")
+
+	stream := append(tx1.Serialize(), tx2.Serialize()...)
+
+	decoded1, n1, err := blockchain.DeserializeTransaction(stream)
+	if err != nil {
+		t.Fatalf("解析第一笔交易失败: %v", err)
+	}
+	decoded2, n2, err := blockchain.DeserializeTransaction(stream[n1:])
+	if err != nil {
+		t.Fatalf("解析第二笔交易失败: %v", err)
+	}
+
+	if n1+n2 != len(stream) {
+		t.Errorf("消耗字节数错误: %d + %d != %d", n1, n2, len(stream))
+	}
+	if decoded1.Hash() != tx1.Hash() || decoded2.Hash() != tx2.Hash() {
+		t.Error("逐笔解析的交易哈希不匹配")
+	}
+}
@@ -0,0 +1,417 @@
+package chain_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/events"
+	"simplied-bitcoin-network-go/pkg/storage"
+	"simplied-bitcoin-network-go/pkg/utils"
+	"simplied-bitcoin-network-go/pkg/utxo"
+)
+
+// easyBits 测试使用的最低难度，约一半的哈希满足目标
+const easyBits = 0x207fffff
+
+// genesisTime 测试创世区块的时间戳
+const genesisTime = 1600000000
+
+// newTestGenesis 创建低难度的测试创世区块
+func newTestGenesis() *blockchain.Block {
+	coinbase := blockchain.NewCoinbaseTransaction([]byte("chain test genesis"), 5000000000, []byte{0x51})
+	header := blockchain.NewBlockHeader(1, [32]byte{}, [32]byte{}, genesisTime, easyBits, 0)
+	block := blockchain.NewBlock(header, []*blockchain.Transaction{coinbase})
+	header.MerkleRoot = block.GetMerkleRoot()
+	solve(header)
+	return block
+}
+
+// testParams 测试使用的网络参数
+//
+// 基于模拟网络参数（最低难度且启用难度调整），创世区块替换为newTestGenesis。
+var testParams = newTestParams()
+
+// newTestParams 创建以测试创世区块为起点的网络参数
+//
+// Coinbase输出在下一个区块即可花费，便于构造花费交易。
+func newTestParams() *blockchain.ChainParams {
+	params := *blockchain.SimNetParams
+	params.GenesisBlock = newTestGenesis()
+	params.GenesisHash = params.GenesisBlock.Hash()
+	params.CoinbaseMaturity = 1
+	return &params
+}
+
+// solve 递增Nonce直到区块头满足难度目标
+func solve(header *blockchain.BlockHeader) {
+	for !header.MeetsTarget() {
+		header.Nonce++
+	}
+}
+
+// newBlock 在parent之上构造一个已求解的区块
+//
+// tag用于区分同一父区块下的不同区块。
+func newBlock(parent *blockchain.Block, timestamp uint32, tag byte) *blockchain.Block {
+	coinbase := blockchain.NewCoinbaseTransaction([]byte{tag, byte(timestamp)}, 5000000000, []byte{0x51})
+	header := blockchain.NewBlockHeader(1, parent.Hash(), [32]byte{}, timestamp, easyBits, 0)
+	block := blockchain.NewBlock(header, []*blockchain.Transaction{coinbase})
+	header.MerkleRoot = block.GetMerkleRoot()
+	solve(header)
+	return block
+}
+
+// extend 在parent之上依次构造count个区块，每个区块时间戳递增600秒
+func extend(parent *blockchain.Block, count int, tag byte) []*blockchain.Block {
+	blocks := make([]*blockchain.Block, 0, count)
+	for i := 0; i < count; i++ {
+		block := newBlock(parent, parent.Header.Timestamp+600, tag)
+		blocks = append(blocks, block)
+		parent = block
+	}
+	return blocks
+}
+
+// harness 区块链引擎测试环境
+type harness struct {
+	chain *chain.Chain
+	store *storage.Store
+	utxos *utxo.Set
+	bus   *events.Bus
+	cfg   utils.DatabaseConfig
+}
+
+// setupChain 使用测试网络参数创建区块链引擎
+//
+// opts用于在创建引擎前调整引擎配置。
+func setupChain(t *testing.T, opts ...func(*chain.Config)) *harness {
+	t.Helper()
+
+	cfg := utils.DatabaseConfig{
+		Type: "bolt",
+		Path: filepath.Join(t.TempDir(), "chain.db"),
+	}
+	return openChain(t, cfg, opts...)
+}
+
+// openChain 打开存储并创建区块链引擎
+func openChain(t *testing.T, cfg utils.DatabaseConfig, opts ...func(*chain.Config)) *harness {
+	t.Helper()
+
+	store, err := storage.Open(cfg, testParams)
+	if err != nil {
+		t.Fatalf("打开存储失败: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	utxos, err := utxo.NewSet(store.DB())
+	if err != nil {
+		t.Fatalf("创建UTXO集合失败: %v", err)
+	}
+
+	bus := events.NewBus()
+	chainCfg := &chain.Config{Store: store, UTXOSet: utxos, Events: bus, Params: testParams}
+	for _, opt := range opts {
+		opt(chainCfg)
+	}
+	c, err := chain.New(chainCfg)
+	if err != nil {
+		t.Fatalf("创建区块链引擎失败: %v", err)
+	}
+	return &harness{chain: c, store: store, utxos: utxos, bus: bus, cfg: cfg}
+}
+
+// processAll 依次处理区块并返回最后一个区块是否成为主链末端
+func processAll(t *testing.T, c *chain.Chain, blocks []*blockchain.Block) bool {
+	t.Helper()
+
+	var isMain bool
+	for i, block := range blocks {
+		var err error
+		isMain, err = c.ProcessBlock(block)
+		if err != nil {
+			t.Fatalf("处理第%d个区块失败: %v", i, err)
+		}
+	}
+	return isMain
+}
+
+// TestProcessBlockExtendsMainChain 测试顺序接收区块时主链延伸
+func TestProcessBlockExtendsMainChain(t *testing.T) {
+	c := setupChain(t).chain
+
+	blocks := extend(testParams.GenesisBlock, 5, 'a')
+	if !processAll(t, c, blocks) {
+		t.Error("延伸主链的区块应成为主链末端")
+	}
+
+	best := c.BestSnapshot()
+	if best.Height != 5 || best.Hash != blocks[4].Hash() {
+		t.Errorf("主链末端错误: 高度%d", best.Height)
+	}
+	if best.Bits != easyBits {
+		t.Errorf("主链末端难度位错误: %08x", best.Bits)
+	}
+
+	for i, block := range blocks {
+		got, err := c.BlockByHeight(int32(i + 1))
+		if err != nil {
+			t.Fatalf("按高度读取区块失败: %v", err)
+		}
+		if got.Hash() != block.Hash() {
+			t.Errorf("高度%d的区块不匹配", i+1)
+		}
+		if !c.MainChainHasBlock(block.Hash()) {
+			t.Errorf("高度%d的区块应在主链上", i+1)
+		}
+	}
+}
+
+// TestBestChainSelection 测试按累计工作量选择主链
+func TestBestChainSelection(t *testing.T) {
+	h := setupChain(t)
+	c := h.chain
+	genesis := testParams.GenesisBlock
+
+	mainBranch := extend(genesis, 3, 'a')
+	processAll(t, c, mainBranch)
+
+	// 工作量较小的侧链不影响主链
+	sideBranch := extend(genesis, 4, 'b')
+	if processAll(t, c, sideBranch[:2]) {
+		t.Error("工作量较小的侧链区块不应成为主链末端")
+	}
+	if !c.HaveBlock(sideBranch[1].Hash()) || c.MainChainHasBlock(sideBranch[1].Hash()) {
+		t.Error("侧链区块应被保存但不在主链上")
+	}
+
+	tips := c.ChainTips()
+	if len(tips) != 2 {
+		t.Fatalf("应有2个链末端, 实际%d", len(tips))
+	}
+	if !tips[0].Active || tips[0].Hash != mainBranch[2].Hash() || tips[0].BranchLen != 0 {
+		t.Error("第一个链末端应为主链末端")
+	}
+	if tips[1].Active || tips[1].Height != 2 || tips[1].BranchLen != 2 {
+		t.Errorf("侧链末端信息错误: %+v", tips[1])
+	}
+
+	// 工作量相同时保留先收到的分支
+	if processAll(t, c, sideBranch[2:3]) {
+		t.Error("工作量相同的侧链不应替换主链")
+	}
+	if c.BestSnapshot().Hash != mainBranch[2].Hash() {
+		t.Error("工作量相同时主链末端不应改变")
+	}
+
+	// 工作量更大时切换主链
+	if !processAll(t, c, sideBranch[3:]) {
+		t.Error("工作量更大的侧链应成为主链")
+	}
+	best := c.BestSnapshot()
+	if best.Hash != sideBranch[3].Hash() || best.Height != 4 {
+		t.Errorf("切换后的主链末端错误: 高度%d", best.Height)
+	}
+	if c.MainChainHasBlock(mainBranch[0].Hash()) {
+		t.Error("原主链区块不应再位于主链上")
+	}
+	for i, block := range sideBranch {
+		got, err := c.BlockByHeight(int32(i + 1))
+		if err != nil || got.Hash() != block.Hash() {
+			t.Errorf("切换后高度%d的区块不匹配: %v", i+1, err)
+		}
+	}
+
+	// 重新打开后恢复索引和主链
+	h.store.Close()
+	reopened := openChain(t, h.cfg).chain
+	if reopened.BestSnapshot().Hash != best.Hash {
+		t.Error("重新打开后主链末端不一致")
+	}
+	if best.WorkSum.Cmp(reopened.BestSnapshot().WorkSum) != 0 {
+		t.Error("重新打开后累计工作量不一致")
+	}
+	if len(reopened.ChainTips()) != 2 {
+		t.Error("重新打开后应恢复侧链")
+	}
+}
+
+// TestMedianTimeRule 测试时间戳必须大于前11个区块的中位时间
+func TestMedianTimeRule(t *testing.T) {
+	c := setupChain(t).chain
+
+	blocks := extend(testParams.GenesisBlock, 12, 'a')
+	processAll(t, c, blocks)
+
+	tip := blocks[len(blocks)-1]
+	medianTime := c.BestSnapshot().MedianTime
+	if medianTime != blocks[6].Header.Timestamp {
+		t.Fatalf("中位时间错误: 期望%d, 实际%d", blocks[6].Header.Timestamp, medianTime)
+	}
+
+	// 时间戳等于中位时间的区块被拒绝
+	_, err := c.ProcessBlock(newBlock(tip, medianTime, 'x'))
+	assertRuleError(t, err, blockchain.ErrCodeInvalidTimestamp)
+
+	// 时间戳早于父区块但大于中位时间的区块被接受
+	if _, err := c.ProcessBlock(newBlock(tip, medianTime+1, 'y')); err != nil {
+		t.Errorf("时间戳大于中位时间的区块应被接受: %v", err)
+	}
+}
+
+// TestProcessBlockRuleErrors 测试拒绝区块时报告被违反的规则
+func TestProcessBlockRuleErrors(t *testing.T) {
+	c := setupChain(t).chain
+	genesis := testParams.GenesisBlock
+
+	valid := newBlock(genesis, genesisTime+600, 'v')
+	processAll(t, c, []*blockchain.Block{valid})
+
+	nextTime := uint32(genesisTime + 1200)
+
+	// resolve 修改区块后重新计算Merkle根并求解
+	resolve := func(block *blockchain.Block) *blockchain.Block {
+		block.Header.MerkleRoot = block.GetMerkleRoot()
+		solve(block.Header)
+		return block
+	}
+
+	tests := []struct {
+		name  string
+		block func() *blockchain.Block
+		code  blockchain.ErrorCode
+	}{
+		{
+			name:  "重复区块",
+			block: func() *blockchain.Block { return valid },
+			code:  blockchain.ErrCodeDuplicateBlock,
+		},
+		{
+			name: "未知前块",
+			block: func() *blockchain.Block {
+				return newBlock(newBlock(valid, nextTime, 'o'), nextTime+600, 'p')
+			},
+			code: blockchain.ErrCodeInvalidPrevBlockHash,
+		},
+		{
+			name: "难度超过上限",
+			block: func() *blockchain.Block {
+				block := newBlock(valid, nextTime, 'd')
+				block.Header.Bits = 0x2100ffff
+				return resolve(block)
+			},
+			code: blockchain.ErrCodeInvalidDifficulty,
+		},
+		{
+			name: "哈希不满足难度",
+			block: func() *blockchain.Block {
+				block := newBlock(valid, nextTime, 'h')
+				block.Header.Bits = 0x1d00ffff
+				return block
+			},
+			code: blockchain.ErrCodeInvalidBlockHash,
+		},
+		{
+			name: "未来时间戳",
+			block: func() *blockchain.Block {
+				return newBlock(valid, uint32(time.Now().Add(3*time.Hour).Unix()), 'f')
+			},
+			code: blockchain.ErrCodeTimeTooNew,
+		},
+		{
+			name: "Merkle根不匹配",
+			block: func() *blockchain.Block {
+				block := newBlock(valid, nextTime, 'm')
+				block.Header.MerkleRoot = [32]byte{1}
+				solve(block.Header)
+				return block
+			},
+			code: blockchain.ErrCodeInvalidMerkleRoot,
+		},
+		{
+			name: "多个Coinbase",
+			block: func() *blockchain.Block {
+				block := newBlock(valid, nextTime, 'c')
+				extra := blockchain.NewCoinbaseTransaction([]byte("extra"), 1, []byte{0x51})
+				block.Transactions = append(block.Transactions, extra)
+				return resolve(block)
+			},
+			code: blockchain.ErrCodeInvalidCoinbase,
+		},
+		{
+			name: "首笔交易不是Coinbase",
+			block: func() *blockchain.Block {
+				block := newBlock(valid, nextTime, 'n')
+				prevOut := blockchain.NewOutPoint(valid.Transactions[0].Hash(), 0)
+				spend := blockchain.NewTransaction(blockchain.TxVersion,
+					[]*blockchain.TxIn{blockchain.NewTxIn(prevOut, nil)},
+					[]*blockchain.TxOut{blockchain.NewTxOut(1000, []byte{0x51})}, 0)
+				block.Transactions = []*blockchain.Transaction{spend}
+				return resolve(block)
+			},
+			code: blockchain.ErrCodeInvalidCoinbase,
+		},
+		{
+			name: "空区块",
+			block: func() *blockchain.Block {
+				block := newBlock(valid, nextTime, 'e')
+				block.Transactions = nil
+				return block
+			},
+			code: blockchain.ErrCodeEmptyBlock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.ProcessBlock(tt.block())
+			assertRuleError(t, err, tt.code)
+		})
+	}
+
+	if c.BestSnapshot().Hash != valid.Hash() {
+		t.Error("被拒绝的区块不应改变主链末端")
+	}
+}
+
+// assertRuleError 检查错误为指定错误码的RuleError
+func assertRuleError(t *testing.T, err error, code blockchain.ErrorCode) {
+	t.Helper()
+
+	var ruleErr blockchain.RuleError
+	if !errors.As(err, &ruleErr) {
+		t.Fatalf("期望RuleError(%v), 实际: %v", code, err)
+	}
+	if ruleErr.ErrorCode != code {
+		t.Errorf("错误码不匹配: 期望%v, 实际%v (%v)", code, ruleErr.ErrorCode, ruleErr)
+	}
+}
+
+// TestNewRejectsForeignGenesis 测试存储中的创世区块与网络参数不一致时拒绝启动
+func TestNewRejectsForeignGenesis(t *testing.T) {
+	h := setupChain(t)
+	h.store.Close()
+
+	store, err := storage.Open(h.cfg, blockchain.RegTestParams)
+	if err != nil {
+		t.Fatalf("打开存储失败: %v", err)
+	}
+	defer store.Close()
+
+	utxos, err := utxo.NewSet(store.DB())
+	if err != nil {
+		t.Fatalf("创建UTXO集合失败: %v", err)
+	}
+
+	_, err = chain.New(&chain.Config{Store: store, UTXOSet: utxos, Params: blockchain.RegTestParams})
+	if err == nil {
+		t.Error("创世区块与网络参数不一致时应返回错误")
+	}
+	if _, err := chain.New(&chain.Config{Store: store, UTXOSet: utxos}); err == nil {
+		t.Error("缺少网络参数时应返回错误")
+	}
+}
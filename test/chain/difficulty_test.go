@@ -0,0 +1,128 @@
+package chain_test
+
+import (
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// testInterval 测试使用的难度调整间隔
+const testInterval = 10
+
+// withParams 返回在网络参数副本上应用modify的配置选项，不影响共享的testParams
+func withParams(modify func(*blockchain.ChainParams)) func(*chain.Config) {
+	return func(cfg *chain.Config) {
+		params := *cfg.Params
+		modify(&params)
+		cfg.Params = &params
+	}
+}
+
+// withInterval 设置难度调整间隔
+var withInterval = withParams(func(params *blockchain.ChainParams) {
+	params.RetargetInterval = testInterval
+})
+
+// newBlockWithBits 在parent之上构造使用指定难度位的已求解区块
+func newBlockWithBits(parent *blockchain.Block, timestamp uint32, bits uint32, tag byte) *blockchain.Block {
+	coinbase := blockchain.NewCoinbaseTransaction([]byte{tag, byte(timestamp), byte(timestamp >> 8)}, 5000000000, []byte{0x51})
+	header := blockchain.NewBlockHeader(1, parent.Hash(), [32]byte{}, timestamp, bits, 0)
+	block := blockchain.NewBlock(header, []*blockchain.Transaction{coinbase})
+	header.MerkleRoot = block.GetMerkleRoot()
+	solve(header)
+	return block
+}
+
+// mineWindow 以固定间隔挖出区块直到下一个区块需要调整难度
+func mineWindow(t *testing.T, c *chain.Chain, spacing uint32) *blockchain.Block {
+	t.Helper()
+
+	parent := testParams.GenesisBlock
+	for height := 1; height < testInterval; height++ {
+		block := newBlockWithBits(parent, parent.Header.Timestamp+spacing, easyBits, 'w')
+		processAll(t, c, []*blockchain.Block{block})
+		parent = block
+	}
+	return parent
+}
+
+// TestDifficultyRetarget 测试按调整窗口的实际时间跨度调整难度
+func TestDifficultyRetarget(t *testing.T) {
+	c := setupChain(t, withInterval).chain
+	powLimit := utils.BitsToTarget(easyBits)
+
+	// 出块速度为目标的一半，难度应提高
+	const spacing = 300
+	parent := mineWindow(t, c, spacing)
+
+	targetTimespan := int64(testInterval * blockchain.TargetBlockTime.Seconds())
+	actualTimespan := int64(parent.Header.Timestamp - genesisTime)
+	expected := utils.AdjustDifficultyWithLimit(actualTimespan, targetTimespan, easyBits, powLimit)
+	if expected == easyBits {
+		t.Fatal("测试数据应导致难度变化")
+	}
+	if got := c.CalcNextRequiredDifficulty(); got != expected {
+		t.Fatalf("调整后的难度位错误: 期望%08x, 实际%08x", expected, got)
+	}
+	newTarget := utils.BitsToTarget(expected)
+	if newTarget.Cmp(powLimit) >= 0 {
+		t.Error("出块过快时目标值应降低")
+	}
+
+	// 沿用旧难度的区块被拒绝
+	_, err := c.ProcessBlock(newBlockWithBits(parent, parent.Header.Timestamp+spacing, easyBits, 'x'))
+	assertRuleError(t, err, blockchain.ErrCodeInvalidDifficulty)
+
+	retarget := newBlockWithBits(parent, parent.Header.Timestamp+spacing, expected, 'y')
+	if _, err := c.ProcessBlock(retarget); err != nil {
+		t.Fatalf("使用调整后难度的区块应被接受: %v", err)
+	}
+
+	// 非调整高度沿用父区块的难度
+	if got := c.CalcNextRequiredDifficulty(); got != expected {
+		t.Errorf("非调整高度的难度位应沿用父区块: %08x", got)
+	}
+	_, err = c.ProcessBlock(newBlockWithBits(retarget, retarget.Header.Timestamp+spacing, easyBits, 'z'))
+	assertRuleError(t, err, blockchain.ErrCodeInvalidDifficulty)
+}
+
+// TestDifficultyRetargetClamp 测试调整幅度限制和工作量证明上限
+func TestDifficultyRetargetClamp(t *testing.T) {
+	powLimit := utils.BitsToTarget(easyBits)
+	targetTimespan := int64(testInterval * blockchain.TargetBlockTime.Seconds())
+
+	t.Run("出块过慢不超过上限", func(t *testing.T) {
+		c := setupChain(t, withInterval).chain
+		mineWindow(t, c, 6000)
+
+		if got := c.CalcNextRequiredDifficulty(); got != easyBits {
+			t.Errorf("目标值应被限制在工作量证明上限: %08x", got)
+		}
+	})
+
+	t.Run("出块过快最多提高4倍", func(t *testing.T) {
+		c := setupChain(t, withInterval).chain
+		mineWindow(t, c, 10)
+
+		expected := utils.AdjustDifficultyWithLimit(targetTimespan/4, targetTimespan, easyBits, powLimit)
+		if got := c.CalcNextRequiredDifficulty(); got != expected {
+			t.Errorf("时间跨度应被限制为目标的1/4: 期望%08x, 实际%08x", expected, got)
+		}
+	})
+
+	t.Run("禁用难度调整", func(t *testing.T) {
+		c := setupChain(t, withInterval, withParams(func(params *blockchain.ChainParams) {
+			params.NoRetargeting = true
+		})).chain
+		parent := mineWindow(t, c, 10)
+
+		if got := c.CalcNextRequiredDifficulty(); got != easyBits {
+			t.Errorf("禁用难度调整时应沿用原难度: %08x", got)
+		}
+		if _, err := c.ProcessBlock(newBlockWithBits(parent, parent.Header.Timestamp+10, easyBits, 'n')); err != nil {
+			t.Errorf("禁用难度调整时原难度的区块应被接受: %v", err)
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package chain_test
+
+import (
+	"errors"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+)
+
+// processHeaders 依次处理区块的区块头
+func processHeaders(t *testing.T, c *chain.Chain, blocks []*blockchain.Block) {
+	t.Helper()
+
+	for i, block := range blocks {
+		if err := c.ProcessBlockHeader(block.Header); err != nil {
+			t.Fatalf("处理第%d个区块头失败: %v", i, err)
+		}
+	}
+}
+
+// TestProcessBlockHeader 测试只有区块头时不改变主链
+func TestProcessBlockHeader(t *testing.T) {
+	h := setupChain(t)
+	c := h.chain
+
+	blocks := extend(testParams.GenesisBlock, 5, 'a')
+	processHeaders(t, c, blocks)
+
+	hash, height := c.BestHeader()
+	if hash != blocks[4].Hash() || height != 5 {
+		t.Errorf("最佳区块头错误: 高度%d", height)
+	}
+	if best := c.BestSnapshot(); best.Height != 0 {
+		t.Errorf("只有区块头时主链不应延伸, 实际高度%d", best.Height)
+	}
+	if !c.HaveHeader(blocks[2].Hash()) || c.HaveBlock(blocks[2].Hash()) {
+		t.Error("应只有区块头而没有区块数据")
+	}
+
+	// 重复处理同一区块头不报错
+	if err := c.ProcessBlockHeader(blocks[0].Header); err != nil {
+		t.Errorf("重复的区块头不应报错: %v", err)
+	}
+
+	// 重新打开后区块头仍在索引中
+	h.store.Close()
+	reopened := openChain(t, h.cfg).chain
+	if _, height := reopened.BestHeader(); height != 5 {
+		t.Errorf("重新打开后最佳区块头高度应为5, 实际%d", height)
+	}
+}
+
+// TestProcessBlockHeaderRejects 测试拒绝无效的区块头
+func TestProcessBlockHeaderRejects(t *testing.T) {
+	c := setupChain(t).chain
+
+	orphan := extend(testParams.GenesisBlock, 2, 'a')[1]
+	badPoW := extend(testParams.GenesisBlock, 1, 'b')[0]
+	for badPoW.Header.MeetsTarget() {
+		badPoW.Header.Nonce++
+	}
+	badBits := newBlock(testParams.GenesisBlock, genesisTime+600, 'c')
+	badBits.Header.Bits = 0x1f7fffff
+	solve(badBits.Header)
+
+	tests := []struct {
+		name   string
+		header *blockchain.BlockHeader
+		code   blockchain.ErrorCode
+	}{
+		{"未知前块", orphan.Header, blockchain.ErrCodeInvalidPrevBlockHash},
+		{"工作量不足", badPoW.Header, blockchain.ErrCodeInvalidBlockHash},
+		{"难度位错误", badBits.Header, blockchain.ErrCodeInvalidDifficulty},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.ProcessBlockHeader(tt.header)
+			var ruleErr blockchain.RuleError
+			if !errors.As(err, &ruleErr) || ruleErr.ErrorCode != tt.code {
+				t.Errorf("期望%v, 实际: %v", tt.code, err)
+			}
+		})
+	}
+
+	if _, height := c.BestHeader(); height != 0 {
+		t.Errorf("无效区块头不应改变最佳区块头, 实际高度%d", height)
+	}
+}
+
+// TestProcessBlockAfterHeaders 测试先同步区块头后按顺序提交区块
+func TestProcessBlockAfterHeaders(t *testing.T) {
+	c := setupChain(t).chain
+
+	blocks := extend(testParams.GenesisBlock, 6, 'a')
+	processHeaders(t, c, blocks)
+
+	if got := c.BlocksToDownload(100); len(got) != 6 || got[0] != blocks[0].Hash() || got[5] != blocks[5].Hash() {
+		t.Fatalf("待下载区块错误: %d个", len(got))
+	}
+	if got := c.BlocksToDownload(2); len(got) != 2 || got[1] != blocks[1].Hash() {
+		t.Fatal("待下载区块应从分叉点之后开始")
+	}
+
+	// 父区块没有数据时返回ErrMissingParentData
+	if _, err := c.ProcessBlock(blocks[2]); !errors.Is(err, chain.ErrMissingParentData) {
+		t.Fatalf("期望ErrMissingParentData, 实际: %v", err)
+	}
+
+	processAll(t, c, blocks[:3])
+	if got := c.BlocksToDownload(100); len(got) != 3 || got[0] != blocks[3].Hash() {
+		t.Errorf("下载窗口应随主链前移: %d个", len(got))
+	}
+
+	processAll(t, c, blocks[3:])
+	if best := c.BestSnapshot(); best.Height != 6 || best.Hash != blocks[5].Hash() {
+		t.Errorf("主链末端错误: 高度%d", best.Height)
+	}
+	if got := c.BlocksToDownload(100); len(got) != 0 {
+		t.Errorf("同步完成后不应有待下载区块: %d个", len(got))
+	}
+}
+
+// TestBlockLocator 测试区块定位器的步长
+func TestBlockLocator(t *testing.T) {
+	c := setupChain(t).chain
+
+	blocks := extend(testParams.GenesisBlock, 30, 'a')
+	processAll(t, c, blocks)
+
+	locator := c.BlockLocator(blocks[29].Hash())
+	heights := make([]int32, len(locator))
+	for i, hash := range locator {
+		height, err := c.BlockHeightByHash(hash)
+		if err != nil {
+			t.Fatalf("定位器包含未知区块: %v", err)
+		}
+		heights[i] = height
+	}
+
+	want := []int32{30, 29, 28, 27, 26, 25, 24, 23, 22, 21, 20, 18, 14, 6, 0}
+	if len(heights) != len(want) {
+		t.Fatalf("定位器高度错误: %v", heights)
+	}
+	for i := range want {
+		if heights[i] != want[i] {
+			t.Fatalf("定位器高度错误: %v", heights)
+		}
+	}
+
+	if c.BlockLocator([32]byte{1}) != nil {
+		t.Error("未知区块的定位器应为nil")
+	}
+}
+
+// TestLocateHeaders 测试根据定位器返回后续区块头
+func TestLocateHeaders(t *testing.T) {
+	c := setupChain(t).chain
+
+	main := extend(testParams.GenesisBlock, 10, 'a')
+	processAll(t, c, main)
+	side := extend(main[3], 2, 'b')
+	processAll(t, c, side)
+
+	// 定位器以侧链区块开头时，从其后第一个主链区块之后开始
+	headers := c.LocateHeaders([][32]byte{side[1].Hash(), main[3].Hash()}, [32]byte{}, 100)
+	if len(headers) != 6 || headers[0].Hash() != main[4].Hash() {
+		t.Fatalf("应返回高度5-10的6个区块头, 实际%d个", len(headers))
+	}
+
+	if headers := c.LocateHeaders(nil, [32]byte{}, 3); len(headers) != 3 || headers[0].Hash() != main[0].Hash() {
+		t.Error("空定位器应从创世区块之后开始并受数量上限限制")
+	}
+
+	headers = c.LocateHeaders([][32]byte{testParams.GenesisHash}, main[4].Hash(), 100)
+	if len(headers) != 5 || headers[4].Hash() != main[4].Hash() {
+		t.Errorf("应在hashStop处停止, 实际%d个", len(headers))
+	}
+}
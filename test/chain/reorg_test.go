@@ -0,0 +1,181 @@
+package chain_test
+
+import (
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/events"
+)
+
+// recorder 记录事件总线上发布的事件
+type recorder struct {
+	events []events.Event
+}
+
+// subscribeAll 订阅所有区块链事件
+func (r *recorder) subscribeAll(bus *events.Bus) {
+	for _, typ := range []events.Type{events.BlockConnected, events.BlockDisconnected, events.ChainReorganized} {
+		bus.Subscribe(typ, func(e events.Event) { r.events = append(r.events, e) })
+	}
+}
+
+// coinbaseOutPoint 返回区块Coinbase交易第一个输出的引用
+func coinbaseOutPoint(block *blockchain.Block) blockchain.OutPoint {
+	return *blockchain.NewOutPoint(block.Transactions[0].Hash(), 0)
+}
+
+// TestReorganization 测试侧链超过主链时的重组、UTXO更新和事件通知
+func TestReorganization(t *testing.T) {
+	h := setupChain(t)
+	genesis := testParams.GenesisBlock
+
+	rec := &recorder{}
+	rec.subscribeAll(h.bus)
+
+	mainBranch := extend(genesis, 2, 'a')
+	processAll(t, h.chain, mainBranch)
+
+	// 延伸主链只发布连接事件
+	if len(rec.events) != 2 || rec.events[0].Type != events.BlockConnected {
+		t.Fatalf("延伸主链应发布2个连接事件, 实际%d个", len(rec.events))
+	}
+	rec.events = nil
+
+	sideBranch := extend(genesis, 3, 'b')
+	processAll(t, h.chain, sideBranch[:2])
+	if len(rec.events) != 0 {
+		t.Fatalf("侧链区块不应发布事件, 实际%d个", len(rec.events))
+	}
+
+	if !processAll(t, h.chain, sideBranch[2:]) {
+		t.Fatal("工作量更大的侧链应成为主链")
+	}
+
+	// 事件顺序：从旧末端断开，从分叉点连接，最后发布重组事件
+	expected := []struct {
+		typ  events.Type
+		hash [32]byte
+	}{
+		{events.BlockDisconnected, mainBranch[1].Hash()},
+		{events.BlockDisconnected, mainBranch[0].Hash()},
+		{events.BlockConnected, sideBranch[0].Hash()},
+		{events.BlockConnected, sideBranch[1].Hash()},
+		{events.BlockConnected, sideBranch[2].Hash()},
+	}
+	if len(rec.events) != len(expected)+1 {
+		t.Fatalf("事件数量错误: 期望%d, 实际%d", len(expected)+1, len(rec.events))
+	}
+	for i, exp := range expected {
+		e := rec.events[i]
+		data := e.Data.(*events.BlockEvent)
+		if e.Type != exp.typ || data.Block.Hash() != exp.hash {
+			t.Errorf("第%d个事件错误: %v %x", i, e.Type, data.Block.Hash())
+		}
+	}
+
+	last := rec.events[len(rec.events)-1]
+	if last.Type != events.ChainReorganized {
+		t.Fatalf("最后一个事件应为重组事件, 实际%v", last.Type)
+	}
+	reorg := last.Data.(*events.ReorgEvent)
+	if reorg.ForkHash != genesis.Hash() || reorg.ForkHeight != 0 {
+		t.Error("重组事件的分叉点错误")
+	}
+	if len(reorg.Removed) != 2 || reorg.Removed[0] != mainBranch[1].Hash() {
+		t.Error("重组事件的移除列表错误")
+	}
+	if len(reorg.Added) != 3 || reorg.Added[2] != sideBranch[2].Hash() {
+		t.Error("重组事件的新增列表错误")
+	}
+
+	// UTXO集合与新主链一致
+	bestHash, bestHeight, err := h.utxos.BestBlock()
+	if err != nil || bestHash != sideBranch[2].Hash() || bestHeight != 3 {
+		t.Errorf("UTXO集合最新区块错误: 高度%d, %v", bestHeight, err)
+	}
+	for _, block := range mainBranch {
+		if entry, _ := h.utxos.FetchEntry(coinbaseOutPoint(block)); entry != nil {
+			t.Error("旧主链的Coinbase输出应被移除")
+		}
+	}
+	for _, block := range sideBranch {
+		if entry, _ := h.utxos.FetchEntry(coinbaseOutPoint(block)); entry == nil {
+			t.Error("新主链的Coinbase输出应存在")
+		}
+	}
+}
+
+// TestReorganizationInvalidBranch 测试新分支包含无效区块时恢复原主链
+func TestReorganizationInvalidBranch(t *testing.T) {
+	h := setupChain(t)
+	genesis := testParams.GenesisBlock
+
+	mainBranch := extend(genesis, 2, 'a')
+	processAll(t, h.chain, mainBranch)
+
+	// 侧链第二个区块花费不存在的输出，只有在连接时才能发现
+	b1 := newBlock(genesis, genesis.Header.Timestamp+600, 'b')
+	b2 := newBlock(b1, b1.Header.Timestamp+600, 'b')
+	missing := blockchain.NewOutPoint([32]byte{0xde, 0xad}, 0)
+	spend := blockchain.NewTransaction(blockchain.TxVersion,
+		[]*blockchain.TxIn{blockchain.NewTxIn(missing, nil)},
+		[]*blockchain.TxOut{blockchain.NewTxOut(1000, []byte{0x51})}, 0)
+	b2.Transactions = append(b2.Transactions, spend)
+	b2.Header.MerkleRoot = b2.GetMerkleRoot()
+	solve(b2.Header)
+	b3 := newBlock(b2, b2.Header.Timestamp+600, 'b')
+
+	processAll(t, h.chain, []*blockchain.Block{b1, b2})
+
+	rec := &recorder{}
+	rec.subscribeAll(h.bus)
+
+	_, err := h.chain.ProcessBlock(b3)
+	assertRuleError(t, err, blockchain.ErrCodeMissingTxOut)
+
+	if len(rec.events) != 0 {
+		t.Errorf("重组失败时不应发布事件, 实际%d个", len(rec.events))
+	}
+
+	best := h.chain.BestSnapshot()
+	if best.Hash != mainBranch[1].Hash() {
+		t.Fatalf("重组失败后应恢复原主链, 实际高度%d", best.Height)
+	}
+	bestHash, _, _ := h.utxos.BestBlock()
+	if bestHash != mainBranch[1].Hash() {
+		t.Error("重组失败后UTXO集合应恢复到原主链末端")
+	}
+	for i, block := range mainBranch {
+		got, err := h.chain.BlockByHeight(int32(i + 1))
+		if err != nil || got.Hash() != block.Hash() {
+			t.Errorf("重组失败后高度%d的区块不匹配", i+1)
+		}
+	}
+
+	// 无效区块的后代被拒绝
+	_, err = h.chain.ProcessBlock(newBlock(b3, b3.Header.Timestamp+600, 'b'))
+	assertRuleError(t, err, blockchain.ErrCodeInvalidAncestor)
+}
+
+// TestUTXOSetSyncOnOpen 测试打开引擎时将落后的UTXO集合同步到主链末端
+func TestUTXOSetSyncOnOpen(t *testing.T) {
+	h := setupChain(t)
+
+	blocks := extend(testParams.GenesisBlock, 3, 'a')
+	processAll(t, h.chain, blocks)
+
+	// 模拟节点在更新UTXO集合前退出
+	if err := h.utxos.DisconnectBlock(blocks[2]); err != nil {
+		t.Fatalf("断开区块失败: %v", err)
+	}
+	h.store.Close()
+
+	reopened := openChain(t, h.cfg)
+	bestHash, bestHeight, err := reopened.utxos.BestBlock()
+	if err != nil || bestHash != blocks[2].Hash() || bestHeight != 3 {
+		t.Errorf("UTXO集合应同步到主链末端, 实际高度%d, %v", bestHeight, err)
+	}
+	if entry, _ := reopened.utxos.FetchEntry(coinbaseOutPoint(blocks[2])); entry == nil {
+		t.Error("同步后主链末端的Coinbase输出应存在")
+	}
+}
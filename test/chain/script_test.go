@@ -0,0 +1,84 @@
+package chain_test
+
+import (
+	"bytes"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/script"
+)
+
+// TestScriptValidationRule 测试区块中交易输入的脚本和锁定时间检查
+func TestScriptValidationRule(t *testing.T) {
+	h := setupChain(t)
+	genesis := testParams.GenesisBlock
+	subsidy := blockchain.CalcBlockSubsidy(1, testParams)
+
+	funding := newBlockWithTxs(genesis, 'f', subsidy)
+	processAll(t, h.chain, []*blockchain.Block{funding})
+	prev := funding.Transactions[0]
+
+	// 花费锁定脚本为OP_0的输出
+	unspendable := spendOutput(prev, 0, subsidy)
+	unspendable.TxOut[0].PkScript = []byte{script.Op0}
+	_, err := h.chain.ProcessBlock(newBlockWithTxs(funding, 'a', subsidy, unspendable, spendOutput(unspendable, 0, subsidy)))
+	assertRuleError(t, err, blockchain.ErrCodeScriptValidation)
+
+	// 解锁脚本包含非推送操作
+	notPush := spendOutput(prev, 0, subsidy)
+	notPush.TxIn[0].SignatureScript = []byte{script.OpNop}
+	_, err = h.chain.ProcessBlock(newBlockWithTxs(funding, 'b', subsidy, notPush))
+	assertRuleError(t, err, blockchain.ErrCodeScriptValidation)
+
+	// 锁定时间晚于区块高度
+	locked := spendOutput(prev, 0, subsidy)
+	locked.LockTime = 2
+	locked.TxIn[0].Sequence = 0
+	_, err = h.chain.ProcessBlock(newBlockWithTxs(funding, 'c', subsidy, locked))
+	assertRuleError(t, err, blockchain.ErrCodeUnfinalizedTx)
+
+	// OP_CHECKLOCKTIMEVERIFY要求的锁定时间已到达，输出可以被花费
+	cltvScript, err := script.NewBuilder().AddInt64(1).AddOp(script.OpCheckLockTimeVerify).Script()
+	if err != nil {
+		t.Fatalf("构造脚本失败: %v", err)
+	}
+	cltv := spendOutput(prev, 0, subsidy)
+	cltv.TxOut[0].PkScript = cltvScript
+	redeem := spendOutput(cltv, 0, subsidy)
+	redeem.LockTime = 1
+	redeem.TxIn[0].Sequence = 0
+
+	ok := newBlockWithTxs(funding, 'o', subsidy, cltv, redeem)
+	if _, err := h.chain.ProcessBlock(ok); err != nil {
+		t.Fatalf("脚本有效的区块应被接受: %v", err)
+	}
+	if h.chain.BestSnapshot().Hash != ok.Hash() {
+		t.Error("有效区块应成为主链末端")
+	}
+}
+
+// TestBlockSigOpsRule 测试区块签名操作总数不能超过MaxBlockSigOps
+func TestBlockSigOpsRule(t *testing.T) {
+	h := setupChain(t)
+	genesis := testParams.GenesisBlock
+	subsidy := blockchain.CalcBlockSubsidy(1, testParams)
+
+	funding := newBlockWithTxs(genesis, 'f', subsidy)
+	processAll(t, h.chain, []*blockchain.Block{funding})
+	prev := funding.Transactions[0]
+
+	// 每个输出按最大公钥数计算，共计MaxBlockSigOps/2个签名操作
+	multiSig := bytes.Repeat([]byte{script.OpCheckMultiSig}, blockchain.MaxBlockSigOps/2/script.MaxPubKeysPerMultiSig)
+
+	over := spendOutput(prev, 0, subsidy/2, subsidy/2)
+	over.TxOut[0].PkScript = multiSig
+	over.TxOut[1].PkScript = append(append([]byte{}, multiSig...), script.OpCheckSig)
+	_, err := h.chain.ProcessBlock(newBlockWithTxs(funding, 'a', subsidy, over))
+	assertRuleError(t, err, blockchain.ErrCodeTooManySigOps)
+
+	// 恰好达到上限的区块可以连接
+	atLimit := spendOutput(prev, 0, subsidy/2, subsidy/2)
+	atLimit.TxOut[0].PkScript = multiSig
+	atLimit.TxOut[1].PkScript = multiSig
+	processAll(t, h.chain, []*blockchain.Block{newBlockWithTxs(funding, 'b', subsidy, atLimit)})
+}
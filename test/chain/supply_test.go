@@ -0,0 +1,208 @@
+package chain_test
+
+import (
+	"strings"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// newBlockWithTxs 在parent之上构造包含指定交易的区块，Coinbase输出金额为coinbaseValue
+func newBlockWithTxs(parent *blockchain.Block, tag byte, coinbaseValue int64, txs ...*blockchain.Transaction) *blockchain.Block {
+	timestamp := parent.Header.Timestamp + 600
+	coinbase := blockchain.NewCoinbaseTransaction([]byte{tag, byte(timestamp)}, coinbaseValue, []byte{0x51})
+	header := blockchain.NewBlockHeader(1, parent.Hash(), [32]byte{}, timestamp, easyBits, 0)
+	block := blockchain.NewBlock(header, append([]*blockchain.Transaction{coinbase}, txs...))
+	header.MerkleRoot = block.GetMerkleRoot()
+	solve(header)
+	return block
+}
+
+// spendOutput 创建花费prev第index个输出的交易
+func spendOutput(prev *blockchain.Transaction, index uint32, values ...int64) *blockchain.Transaction {
+	prevOut := blockchain.NewOutPoint(prev.Hash(), index)
+	var outputs []*blockchain.TxOut
+	for _, value := range values {
+		outputs = append(outputs, blockchain.NewTxOut(value, []byte{0x51}))
+	}
+	return blockchain.NewTransaction(blockchain.TxVersion, []*blockchain.TxIn{blockchain.NewTxIn(prevOut, nil)}, outputs, 0)
+}
+
+// TestCoinbaseValueRule 测试Coinbase输出不能超过奖励加手续费
+func TestCoinbaseValueRule(t *testing.T) {
+	h := setupChain(t)
+	genesis := testParams.GenesisBlock
+	subsidy := blockchain.CalcBlockSubsidy(1, testParams)
+
+	funding := newBlockWithTxs(genesis, 'f', subsidy)
+	processAll(t, h.chain, []*blockchain.Block{funding})
+	prev := funding.Transactions[0]
+
+	// 手续费为3000
+	spend := spendOutput(prev, 0, subsidy-5000, 2000)
+	const fees = 3000
+
+	_, err := h.chain.ProcessBlock(newBlockWithTxs(funding, 'x', subsidy+fees+1, spend))
+	assertRuleError(t, err, blockchain.ErrCodeBadCoinbaseValue)
+
+	_, err = h.chain.ProcessBlock(newBlockWithTxs(funding, 'y', subsidy, spendOutput(prev, 0, subsidy+1)))
+	assertRuleError(t, err, blockchain.ErrCodeSpendTooHigh)
+
+	_, err = h.chain.ProcessBlock(newBlockWithTxs(funding, 'z', subsidy, spendOutput(prev, 0, -1)))
+	assertRuleError(t, err, blockchain.ErrCodeInvalidTxOutValue)
+
+	// 同一区块内重复花费
+	_, err = h.chain.ProcessBlock(newBlockWithTxs(funding, 'd', subsidy,
+		spendOutput(prev, 0, 1000), spendOutput(prev, 0, 2000)))
+	assertRuleError(t, err, blockchain.ErrCodeMissingTxOut)
+
+	// 恰好领取奖励加手续费的区块被接受，区块内后面的交易可以花费前面交易的输出
+	child := spendOutput(spend, 1, 1500)
+	ok := newBlockWithTxs(funding, 'o', subsidy+fees+500, spend, child)
+	if _, err := h.chain.ProcessBlock(ok); err != nil {
+		t.Fatalf("Coinbase金额等于奖励加手续费的区块应被接受: %v", err)
+	}
+	if h.chain.BestSnapshot().Hash != ok.Hash() {
+		t.Error("有效区块应成为主链末端")
+	}
+}
+
+// TestAuditSupply 测试供应量审计
+func TestAuditSupply(t *testing.T) {
+	h := setupChain(t)
+	genesis := testParams.GenesisBlock
+
+	b1 := newBlockWithTxs(genesis, 'a', blockchain.CalcBlockSubsidy(1, testParams))
+	spend := spendOutput(b1.Transactions[0], 0, blockchain.CalcBlockSubsidy(1, testParams)-10000)
+	// 矿工少领取1000聪，这部分不会被发行
+	b2 := newBlockWithTxs(b1, 'a', blockchain.CalcBlockSubsidy(2, testParams)+10000-1000, spend)
+	processAll(t, h.chain, []*blockchain.Block{b1, b2})
+
+	audit, err := h.chain.AuditSupply(utils.MaxSatoshi)
+	if err != nil {
+		t.Fatalf("审计失败: %v", err)
+	}
+
+	scheduled := blockchain.CalcTotalSubsidy(2, testParams)
+	if audit.Height != 2 || audit.Scheduled != scheduled {
+		t.Errorf("审计高度或计划发行量错误: %+v", audit)
+	}
+	if audit.Issued != scheduled-1000 {
+		t.Errorf("实际发行量错误: 期望%d, 实际%d", scheduled-1000, audit.Issued)
+	}
+	if audit.UTXOTotal != audit.Issued {
+		t.Errorf("UTXO总额%d与发行量%d不一致", audit.UTXOTotal, audit.Issued)
+	}
+
+	// 供应量上限低于实际发行量时审计失败
+	if _, err := h.chain.AuditSupply(audit.Issued - 1); err == nil || !strings.Contains(err.Error(), "最大供应量") {
+		t.Errorf("超过最大供应量时应审计失败: %v", err)
+	}
+}
+
+// TestAuditSupplyDuringProcessing 测试区块处理过程中审计结果保持一致
+func TestAuditSupplyDuringProcessing(t *testing.T) {
+	h := setupChain(t)
+	subsidy := blockchain.CalcBlockSubsidy(1, testParams)
+
+	// 每个区块花费上一个区块的Coinbase输出，使UTXO集合在连接区块时变化
+	blocks := []*blockchain.Block{newBlockWithTxs(testParams.GenesisBlock, 'a', subsidy)}
+	for i := 1; i < 30; i++ {
+		prev := blocks[i-1]
+		spend := spendOutput(prev.Transactions[0], 0, prev.Transactions[0].TxOut[0].Value-1000)
+		blocks = append(blocks, newBlockWithTxs(prev, 'a', subsidy+1000, spend))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, block := range blocks {
+			if _, err := h.chain.ProcessBlock(block); err != nil {
+				t.Errorf("处理区块失败: %v", err)
+				return
+			}
+		}
+	}()
+
+	for {
+		if _, err := h.chain.AuditSupply(utils.MaxSatoshi); err != nil {
+			t.Fatalf("并发审计失败: %v", err)
+		}
+		select {
+		case <-done:
+			return
+		default:
+		}
+	}
+}
+
+// TestCoinbaseMaturityRule 测试区块不能花费未成熟的Coinbase输出
+func TestCoinbaseMaturityRule(t *testing.T) {
+	params := *testParams
+	params.CoinbaseMaturity = 3
+	h := setupChain(t, func(cfg *chain.Config) { cfg.Params = &params })
+	subsidy := blockchain.CalcBlockSubsidy(1, &params)
+
+	funding := newBlockWithTxs(testParams.GenesisBlock, 'f', subsidy)
+	blocks := append([]*blockchain.Block{funding}, extend(funding, 1, 'e')...)
+	processAll(t, h.chain, blocks)
+	prev := funding.Transactions[0]
+
+	// 高度3的区块花费高度1的Coinbase输出，只有2个确认
+	_, err := h.chain.ProcessBlock(newBlockWithTxs(blocks[1], 'x', subsidy, spendOutput(prev, 0, subsidy)))
+	assertRuleError(t, err, blockchain.ErrCodeImmatureSpend)
+
+	// 同一区块内花费本区块的Coinbase输出
+	tip := blocks[1]
+	coinbase := blockchain.NewCoinbaseTransaction([]byte{'s'}, subsidy, []byte{0x51})
+	header := blockchain.NewBlockHeader(1, tip.Hash(), [32]byte{}, tip.Header.Timestamp+600, easyBits, 0)
+	same := blockchain.NewBlock(header, []*blockchain.Transaction{coinbase, spendOutput(coinbase, 0, subsidy)})
+	header.MerkleRoot = same.GetMerkleRoot()
+	solve(header)
+	_, err = h.chain.ProcessBlock(same)
+	assertRuleError(t, err, blockchain.ErrCodeImmatureSpend)
+
+	// 高度4时达到3个确认，输出可以被花费
+	blocks = append(blocks, extend(blocks[1], 1, 'g')...)
+	processAll(t, h.chain, blocks[2:])
+	ok := newBlockWithTxs(blocks[2], 'o', subsidy, spendOutput(prev, 0, subsidy))
+	if _, err := h.chain.ProcessBlock(ok); err != nil {
+		t.Fatalf("花费已成熟Coinbase输出的区块应被接受: %v", err)
+	}
+	if h.chain.BestSnapshot().Hash != ok.Hash() {
+		t.Error("有效区块应成为主链末端")
+	}
+}
+
+// TestMutatedBlockRejected 测试重复末尾交易的区块被拒绝且不影响真实区块
+//
+// 交易数为奇数时重复最后一笔交易不改变Merkle根，篡改后的区块与真实区块的哈希相同。
+func TestMutatedBlockRejected(t *testing.T) {
+	h := setupChain(t)
+	subsidy := blockchain.CalcBlockSubsidy(1, testParams)
+
+	funding := newBlockWithTxs(testParams.GenesisBlock, 'f', subsidy)
+	processAll(t, h.chain, []*blockchain.Block{funding})
+
+	tx1 := spendOutput(funding.Transactions[0], 0, subsidy)
+	tx2 := spendOutput(tx1, 0, subsidy)
+	valid := newBlockWithTxs(funding, 'v', subsidy, tx1, tx2)
+
+	mutated := blockchain.NewBlock(valid.Header, append(append([]*blockchain.Transaction{}, valid.Transactions...), tx2))
+	if mutated.GetMerkleRoot() != valid.Header.MerkleRoot || mutated.Hash() != valid.Hash() {
+		t.Fatal("篡改后的区块应与真实区块具有相同的Merkle根和哈希")
+	}
+	_, err := h.chain.ProcessBlock(mutated)
+	assertRuleError(t, err, blockchain.ErrCodeDuplicateTx)
+
+	// 真实区块仍然可以被接受，其后代也不受影响
+	next := newBlockWithTxs(valid, 'n', subsidy)
+	if !processAll(t, h.chain, []*blockchain.Block{valid, next}) {
+		t.Error("真实区块的后代应成为主链末端")
+	}
+	if h.chain.BestSnapshot().Hash != next.Hash() {
+		t.Error("主链末端应为真实区块的后代")
+	}
+}
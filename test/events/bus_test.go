@@ -0,0 +1,31 @@
+package events_test
+
+import (
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/events"
+)
+
+// TestBusSubscribePublish 测试按类型分发事件和取消订阅
+func TestBusSubscribePublish(t *testing.T) {
+	bus := events.NewBus()
+
+	var order []string
+	unsubscribe := bus.Subscribe(events.BlockConnected, func(e events.Event) { order = append(order, "first") })
+	bus.Subscribe(events.BlockConnected, func(e events.Event) { order = append(order, "second") })
+	bus.Subscribe(events.BlockDisconnected, func(e events.Event) { order = append(order, "other") })
+
+	bus.Publish(events.Event{Type: events.BlockConnected})
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Fatalf("事件分发顺序错误: %v", order)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	order = nil
+
+	bus.Publish(events.Event{Type: events.BlockConnected})
+	if len(order) != 1 || order[0] != "second" {
+		t.Errorf("取消订阅后仍收到事件: %v", order)
+	}
+}
@@ -0,0 +1,345 @@
+package mempool_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/events"
+	"simplied-bitcoin-network-go/pkg/mempool"
+	"simplied-bitcoin-network-go/pkg/mining"
+	"simplied-bitcoin-network-go/pkg/script"
+	"simplied-bitcoin-network-go/pkg/storage"
+	"simplied-bitcoin-network-go/pkg/utils"
+	"simplied-bitcoin-network-go/pkg/utxo"
+)
+
+// subsidy 测试期间的区块奖励
+const subsidy = 50 * 100_000_000
+
+// payout 测试使用的奖励锁定脚本（OP_TRUE）
+var payout = []byte{0x51}
+
+// params 测试网络参数，缩短Coinbase成熟期以减少需要挖出的区块
+var params = func() *blockchain.ChainParams {
+	p := *blockchain.RegTestParams
+	p.CoinbaseMaturity = 2
+	return &p
+}()
+
+// staticSource 返回固定候选交易的交易来源
+type staticSource []*mining.TxDesc
+
+func (s staticSource) MiningDescs() []*mining.TxDesc { return s }
+
+// harness 测试用的区块链和内存池
+type harness struct {
+	chain   *chain.Chain
+	utxoSet *utxo.Set
+	pool    *mempool.TxPool
+	bus     *events.Bus
+}
+
+// newHarness 创建区块链和内存池
+func newHarness(t *testing.T, maxSize int) *harness {
+	return newHarnessWithConfig(t, &mempool.Config{MaxSize: maxSize})
+}
+
+// newHarnessWithConfig 使用指定的内存池配置创建区块链和内存池
+func newHarnessWithConfig(t *testing.T, cfg *mempool.Config) *harness {
+	t.Helper()
+
+	store, err := storage.Open(utils.DatabaseConfig{Type: "bolt", Path: filepath.Join(t.TempDir(), "chain.db")}, params)
+	if err != nil {
+		t.Fatalf("打开存储失败: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	utxoSet, err := utxo.NewSet(store.DB())
+	if err != nil {
+		t.Fatalf("创建UTXO集合失败: %v", err)
+	}
+
+	bus := events.NewBus()
+	c, err := chain.New(&chain.Config{Store: store, UTXOSet: utxoSet, Events: bus, Params: params})
+	if err != nil {
+		t.Fatalf("创建区块链引擎失败: %v", err)
+	}
+
+	cfg.Chain, cfg.UTXOSet, cfg.Events = c, utxoSet, bus
+	pool, err := mempool.New(cfg)
+	if err != nil {
+		t.Fatalf("创建内存池失败: %v", err)
+	}
+	return &harness{chain: c, utxoSet: utxoSet, pool: pool, bus: bus}
+}
+
+// mine 用给定交易来源挖出一个区块并提交给区块链
+func (h *harness) mine(t *testing.T, source mining.TxSource) *blockchain.Block {
+	t.Helper()
+
+	template, err := mining.NewBlockTemplate(h.chain, source, payout)
+	if err != nil {
+		t.Fatalf("构建区块模板失败: %v", err)
+	}
+	for !template.Block.Header.MeetsTarget() {
+		template.Block.Header.Nonce++
+	}
+	if _, err := h.chain.ProcessBlock(template.Block); err != nil {
+		t.Fatalf("区块被拒绝: %v", err)
+	}
+	return template.Block
+}
+
+// mineCoinbases 挖出count个区块以及使其全部成熟所需的区块，返回前count个Coinbase交易
+func (h *harness) mineCoinbases(t *testing.T, count int) []*blockchain.Transaction {
+	t.Helper()
+
+	var coinbases []*blockchain.Transaction
+	for i := 0; i < count+int(params.CoinbaseMaturity); i++ {
+		coinbases = append(coinbases, h.mine(t, nil).Transactions[0])
+	}
+	return coinbases[:count]
+}
+
+// spend 创建花费指定输出的交易
+func spend(prev *blockchain.Transaction, index uint32, value int64) *blockchain.Transaction {
+	prevOut := blockchain.NewOutPoint(prev.Hash(), index)
+	return blockchain.NewTransaction(blockchain.TxVersion,
+		[]*blockchain.TxIn{blockchain.NewTxIn(prevOut, nil)},
+		[]*blockchain.TxOut{blockchain.NewTxOut(value, payout)}, 0)
+}
+
+// TestAcceptTransaction 测试交易验证规则
+func TestAcceptTransaction(t *testing.T) {
+	h := newHarness(t, 0)
+	coinbases := h.mineCoinbases(t, 2)
+
+	tx := spend(coinbases[0], 0, subsidy-5000)
+	if err := h.pool.ProcessTransaction(tx); err != nil {
+		t.Fatalf("有效交易被拒绝: %v", err)
+	}
+	if !h.pool.HaveTransaction(tx.Hash()) || h.pool.Count() != 1 || h.pool.Size() != tx.SerializeSize() {
+		t.Fatal("交易应在内存池中")
+	}
+	if got, err := h.pool.FetchTransaction(tx.Hash()); err != nil || got != tx {
+		t.Errorf("读取交易失败: %v", err)
+	}
+	if _, err := h.pool.FetchTransaction([32]byte{1}); !errors.Is(err, mempool.ErrTxNotFound) {
+		t.Errorf("读取不存在的交易应返回ErrTxNotFound, 实际: %v", err)
+	}
+
+	immature := h.mine(t, nil).Transactions[0]
+	overflow := spend(coinbases[1], 0, subsidy)
+	overflow.TxOut[0].Value = subsidy + 1
+	duplicateInput := spend(coinbases[1], 0, 1)
+	duplicateInput.TxIn = append(duplicateInput.TxIn, duplicateInput.TxIn[0])
+	nonFinal := spend(coinbases[1], 0, subsidy-5000)
+	nonFinal.LockTime = uint32(h.chain.BestSnapshot().Height + 2)
+	nonFinal.TxIn[0].Sequence = 0
+	badScript := spend(coinbases[1], 0, subsidy-5000)
+	badScript.TxIn[0].SignatureScript = []byte{script.OpReturn}
+
+	tests := []struct {
+		name string
+		tx   *blockchain.Transaction
+		err  error
+	}{
+		{"重复交易", tx, mempool.ErrDuplicateTx},
+		{"Coinbase交易", immature, mempool.ErrCoinbase},
+		{"重复花费", spend(coinbases[0], 0, subsidy-10000), mempool.ErrDoubleSpend},
+		{"输出不存在", spend(coinbases[1], 1, 1), mempool.ErrMissingInputs},
+		{"未成熟Coinbase", spend(immature, 0, subsidy-5000), mempool.ErrImmatureSpend},
+		{"手续费不足", spend(coinbases[1], 0, subsidy-utils.MinTransactionFee+1), mempool.ErrInsufficientFee},
+		{"输出超过输入", overflow, mempool.ErrInvalidTx},
+		{"交易内重复输入", duplicateInput, mempool.ErrInvalidTx},
+		{"未达到锁定时间", nonFinal, mempool.ErrNonFinal},
+		{"脚本验证失败", badScript, mempool.ErrScriptValidation},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := h.pool.ProcessTransaction(test.tx); !errors.Is(err, test.err) {
+				t.Errorf("应返回%v, 实际: %v", test.err, err)
+			}
+		})
+	}
+	if h.pool.Count() != 1 {
+		t.Errorf("被拒绝的交易不应加入内存池, 交易数%d", h.pool.Count())
+	}
+}
+
+// TestFeeRateEviction 测试内存池按手续费率排序并在超过上限时移除最低的交易
+func TestFeeRateEviction(t *testing.T) {
+	txSize := spend(blockchain.NewCoinbaseTransaction(nil, 0, payout), 0, 0).SerializeSize()
+	h := newHarness(t, 2*txSize)
+	coinbases := h.mineCoinbases(t, 4)
+
+	low := spend(coinbases[0], 0, subsidy-2000)
+	mid := spend(coinbases[1], 0, subsidy-5000)
+	high := spend(coinbases[2], 0, subsidy-9000)
+	lowest := spend(coinbases[3], 0, subsidy-1000)
+
+	for _, tx := range []*blockchain.Transaction{low, mid, high} {
+		if err := h.pool.ProcessTransaction(tx); err != nil {
+			t.Fatalf("有效交易被拒绝: %v", err)
+		}
+	}
+	if h.pool.HaveTransaction(low.Hash()) {
+		t.Error("超过上限时应移除手续费率最低的交易")
+	}
+	if err := h.pool.ProcessTransaction(lowest); !errors.Is(err, mempool.ErrMempoolFull) {
+		t.Errorf("手续费率最低的交易应返回ErrMempoolFull, 实际: %v", err)
+	}
+
+	descs := h.pool.TxDescs()
+	if len(descs) != 2 || descs[0].Tx.Hash() != high.Hash() || descs[1].Tx.Hash() != mid.Hash() {
+		t.Fatal("交易应按手续费率从高到低排列")
+	}
+	if descs[0].Fee != 9000 || descs[0].FeeRate != 9000/float64(high.SerializeSize()) {
+		t.Errorf("手续费信息错误: %d %.2f", descs[0].Fee, descs[0].FeeRate)
+	}
+	if h.pool.Size() > h.pool.MaxSize() {
+		t.Errorf("内存池大小%d超过上限%d", h.pool.Size(), h.pool.MaxSize())
+	}
+}
+
+// TestBlockConnectedRemovesTransactions 测试区块连接后移除已确认和冲突的交易
+func TestBlockConnectedRemovesTransactions(t *testing.T) {
+	h := newHarness(t, 0)
+	coinbases := h.mineCoinbases(t, 3)
+
+	confirmed := spend(coinbases[0], 0, subsidy-5000)
+	conflicted := spend(coinbases[1], 0, subsidy-5000)
+	remaining := spend(coinbases[2], 0, subsidy-5000)
+	for _, tx := range []*blockchain.Transaction{confirmed, conflicted, remaining} {
+		if err := h.pool.ProcessTransaction(tx); err != nil {
+			t.Fatalf("有效交易被拒绝: %v", err)
+		}
+	}
+
+	// 区块包含confirmed以及与conflicted花费同一输出的另一笔交易
+	doubleSpend := spend(coinbases[1], 0, subsidy-20000)
+	h.mine(t, staticSource{
+		{Tx: confirmed, Fee: 5000},
+		{Tx: doubleSpend, Fee: 20000},
+	})
+
+	if h.pool.HaveTransaction(confirmed.Hash()) {
+		t.Error("已确认的交易应被移除")
+	}
+	if h.pool.HaveTransaction(conflicted.Hash()) {
+		t.Error("与区块交易冲突的交易应被移除")
+	}
+	if !h.pool.HaveTransaction(remaining.Hash()) || h.pool.Count() != 1 {
+		t.Fatal("未确认的交易应保留")
+	}
+
+	// 以内存池为交易来源挖矿，打包剩余交易
+	block := h.mine(t, h.pool)
+	if !block.HasTransaction(remaining.Hash()) {
+		t.Error("区块应打包内存池中的交易")
+	}
+	if h.pool.Count() != 0 || h.pool.Size() != 0 {
+		t.Errorf("打包后内存池应为空, 交易数%d 大小%d", h.pool.Count(), h.pool.Size())
+	}
+}
+
+// TestTransactionChains 测试未确认交易链的祖先和后代统计以及长度限制
+func TestTransactionChains(t *testing.T) {
+	h := newHarness(t, 0)
+	coinbases := h.mineCoinbases(t, 1)
+
+	// 依次花费上一笔交易的输出，每笔交易支付5000聪手续费
+	chain := []*blockchain.Transaction{spend(coinbases[0], 0, subsidy-5000)}
+	for i := 1; i < mempool.DefaultMaxAncestors; i++ {
+		prev := chain[i-1]
+		chain = append(chain, spend(prev, 0, prev.TxOut[0].Value-5000))
+	}
+	for _, tx := range chain {
+		if err := h.pool.ProcessTransaction(tx); err != nil {
+			t.Fatalf("有效交易被拒绝: %v", err)
+		}
+	}
+
+	last := chain[len(chain)-1]
+	overflow := spend(last, 0, last.TxOut[0].Value-5000)
+	if err := h.pool.ProcessTransaction(overflow); !errors.Is(err, mempool.ErrTooLongMempoolChain) {
+		t.Fatalf("超过祖先数量上限应返回ErrTooLongMempoolChain, 实际: %v", err)
+	}
+
+	descs := make(map[[32]byte]*mempool.TxDesc)
+	for _, desc := range h.pool.TxDescs() {
+		descs[desc.Tx.Hash()] = desc
+	}
+	first, tail := descs[chain[0].Hash()], descs[last.Hash()]
+	if first.AncestorCount != 1 || first.DescendantCount != len(chain) {
+		t.Errorf("首笔交易统计错误: 祖先%d 后代%d", first.AncestorCount, first.DescendantCount)
+	}
+	if tail.AncestorCount != len(chain) || tail.AncestorFees != int64(len(chain))*5000 {
+		t.Errorf("末笔交易统计错误: 祖先%d 手续费%d", tail.AncestorCount, tail.AncestorFees)
+	}
+	if tail.DescendantCount != 1 || tail.AncestorSize != h.pool.Size() {
+		t.Errorf("末笔交易统计错误: 后代%d 祖先大小%d", tail.DescendantCount, tail.AncestorSize)
+	}
+
+	// 首笔交易被确认后，其后代交易保留在内存池中
+	h.mine(t, staticSource{{Tx: chain[0], Fee: 5000}})
+	if h.pool.Count() != len(chain)-1 {
+		t.Fatalf("确认交易的后代交易应保留, 交易数%d", h.pool.Count())
+	}
+	if err := h.pool.ProcessTransaction(overflow); err != nil {
+		t.Errorf("确认后交易链缩短，交易应被接受: %v", err)
+	}
+	for _, desc := range h.pool.TxDescs() {
+		if desc.Tx.Hash() == chain[1].Hash() && desc.AncestorCount != 1 {
+			t.Errorf("父交易确认后祖先数应为1, 实际%d", desc.AncestorCount)
+		}
+	}
+}
+
+// TestEvictionRemovesDescendants 测试移除交易时一并移除后代交易，以及子交易保护父交易
+func TestEvictionRemovesDescendants(t *testing.T) {
+	txSize := spend(blockchain.NewCoinbaseTransaction(nil, 0, payout), 0, 0).SerializeSize()
+	h := newHarness(t, 3*txSize)
+	coinbases := h.mineCoinbases(t, 5)
+
+	parent := spend(coinbases[0], 0, subsidy-1000)
+	child := spend(parent, 0, parent.TxOut[0].Value-2000)
+	mid := spend(coinbases[1], 0, subsidy-5000)
+	high := spend(coinbases[2], 0, subsidy-9000)
+	for _, tx := range []*blockchain.Transaction{parent, child, mid, high} {
+		if err := h.pool.ProcessTransaction(tx); err != nil {
+			t.Fatalf("有效交易被拒绝: %v", err)
+		}
+	}
+	if h.pool.HaveTransaction(parent.Hash()) || h.pool.HaveTransaction(child.Hash()) {
+		t.Fatal("父交易被移除时其子交易应一并移除")
+	}
+	if h.pool.Count() != 2 {
+		t.Fatalf("交易数错误: %d", h.pool.Count())
+	}
+
+	// 高手续费子交易使父交易的交易包手续费率高于mid
+	cpfpParent := spend(coinbases[3], 0, subsidy-1000)
+	cpfpChild := spend(cpfpParent, 0, cpfpParent.TxOut[0].Value-50000)
+	for _, tx := range []*blockchain.Transaction{cpfpParent, cpfpChild} {
+		if err := h.pool.ProcessTransaction(tx); err != nil {
+			t.Fatalf("有效交易被拒绝: %v", err)
+		}
+	}
+	if !h.pool.HaveTransaction(cpfpParent.Hash()) || h.pool.HaveTransaction(mid.Hash()) {
+		t.Error("应移除交易包手续费率最低的mid而保留被子交易带动的父交易")
+	}
+
+	// 与区块交易冲突的父交易连同子交易一起移除
+	doubleSpend := spend(coinbases[3], 0, subsidy-20000)
+	h.mine(t, staticSource{{Tx: doubleSpend, Fee: 20000}})
+	if h.pool.HaveTransaction(cpfpParent.Hash()) || h.pool.HaveTransaction(cpfpChild.Hash()) {
+		t.Error("冲突交易及其后代交易应被移除")
+	}
+	if !h.pool.HaveTransaction(high.Hash()) || h.pool.Count() != 1 || h.pool.Size() != txSize {
+		t.Errorf("无关交易应保留, 交易数%d 大小%d", h.pool.Count(), h.pool.Size())
+	}
+}
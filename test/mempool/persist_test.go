@@ -0,0 +1,117 @@
+package mempool_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/mempool"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// newPool 在同一条区块链上创建新的内存池，模拟节点重启
+func (h *harness) newPool(t *testing.T, expiry time.Duration) *mempool.TxPool {
+	t.Helper()
+
+	pool, err := mempool.New(&mempool.Config{Chain: h.chain, UTXOSet: h.utxoSet, Expiry: expiry})
+	if err != nil {
+		t.Fatalf("创建内存池失败: %v", err)
+	}
+	return pool
+}
+
+// TestDumpAndLoad 测试保存内存池并在重新加载时验证交易
+func TestDumpAndLoad(t *testing.T) {
+	h := newHarness(t, 0)
+	coinbases := h.mineCoinbases(t, 3)
+
+	parent := spend(coinbases[0], 0, subsidy-5000)
+	child := spend(parent, 0, parent.TxOut[0].Value-5000)
+	other := spend(coinbases[1], 0, subsidy-5000)
+	invalidated := spend(coinbases[2], 0, subsidy-5000)
+	for _, tx := range []*blockchain.Transaction{parent, child, other, invalidated} {
+		if err := h.pool.ProcessTransaction(tx); err != nil {
+			t.Fatalf("有效交易被拒绝: %v", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "regtest", mempool.DumpFileName)
+	if err := h.pool.Dump(path); err != nil {
+		t.Fatalf("保存内存池失败: %v", err)
+	}
+	added := make(map[[32]byte]time.Time)
+	for _, desc := range h.pool.TxDescs() {
+		added[desc.Tx.Hash()] = desc.Added
+	}
+
+	// 节点停止期间，invalidated的输入被区块中的另一笔交易花费
+	h.mine(t, staticSource{{Tx: spend(coinbases[2], 0, subsidy-20000), Fee: 20000}})
+
+	restarted := h.newPool(t, 0)
+	stats, err := restarted.Load(path)
+	if err != nil {
+		t.Fatalf("加载内存池失败: %v", err)
+	}
+	if stats != (mempool.LoadStats{Accepted: 3, Failed: 1}) {
+		t.Errorf("加载统计错误: %+v", stats)
+	}
+	if restarted.HaveTransaction(invalidated.Hash()) {
+		t.Error("不再有效的交易应被丢弃")
+	}
+	for _, desc := range restarted.TxDescs() {
+		if !desc.Added.Equal(added[desc.Tx.Hash()].Truncate(time.Second)) {
+			t.Errorf("交易加入时间未保留: %v", desc.Added)
+		}
+		if desc.Tx.Hash() == child.Hash() && desc.AncestorCount != 2 {
+			t.Errorf("子交易的祖先关系未恢复: %d", desc.AncestorCount)
+		}
+	}
+
+	expiring := h.newPool(t, time.Nanosecond)
+	if stats, err := expiring.Load(path); err != nil || stats.Expired != 4 || expiring.Count() != 0 {
+		t.Errorf("过期交易应被丢弃: %+v %v", stats, err)
+	}
+}
+
+// TestLoadInvalidDump 测试加载缺失、损坏和版本不支持的文件
+func TestLoadInvalidDump(t *testing.T) {
+	h := newHarness(t, 0)
+	coinbases := h.mineCoinbases(t, 1)
+	if err := h.pool.ProcessTransaction(spend(coinbases[0], 0, subsidy-5000)); err != nil {
+		t.Fatalf("有效交易被拒绝: %v", err)
+	}
+
+	dir := t.TempDir()
+	if stats, err := h.newPool(t, 0).Load(filepath.Join(dir, "missing.dat")); err != nil || stats != (mempool.LoadStats{}) {
+		t.Errorf("文件不存在时应不做任何操作: %+v %v", stats, err)
+	}
+
+	path := filepath.Join(dir, mempool.DumpFileName)
+	if err := h.pool.Dump(path); err != nil {
+		t.Fatalf("保存内存池失败: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取文件失败: %v", err)
+	}
+
+	corrupted := append([]byte(nil), data...)
+	corrupted[20] ^= 0xff
+	os.WriteFile(path, corrupted, 0644)
+	if _, err := h.newPool(t, 0).Load(path); !errors.Is(err, mempool.ErrDumpCorrupted) {
+		t.Errorf("损坏的文件应返回ErrDumpCorrupted, 实际: %v", err)
+	}
+
+	// 修改版本并重新计算校验和
+	future := append([]byte(nil), data[:len(data)-4]...)
+	binary.LittleEndian.PutUint32(future[0:4], mempool.DumpVersion+1)
+	future = append(future, utils.DoubleSHA256(future)[:4]...)
+	os.WriteFile(path, future, 0644)
+	if _, err := h.newPool(t, 0).Load(path); !errors.Is(err, mempool.ErrDumpVersion) {
+		t.Errorf("不支持的版本应返回ErrDumpVersion, 实际: %v", err)
+	}
+}
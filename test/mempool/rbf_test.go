@@ -0,0 +1,135 @@
+package mempool_test
+
+import (
+	"errors"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/events"
+	"simplied-bitcoin-network-go/pkg/mempool"
+)
+
+// replaceable 创建声明允许替换的花费交易
+func replaceable(prev *blockchain.Transaction, index uint32, value int64) *blockchain.Transaction {
+	tx := spend(prev, index, value)
+	tx.TxIn[0].Sequence = mempool.MaxRBFSequence
+	return tx
+}
+
+// TestReplaceByFee 测试替换规则以及替换事件
+func TestReplaceByFee(t *testing.T) {
+	h := newHarness(t, 0)
+	coinbases := h.mineCoinbases(t, 2)
+
+	var replacedEvents []*events.TxReplacedEvent
+	h.bus.Subscribe(events.TransactionReplaced, func(e events.Event) {
+		replacedEvents = append(replacedEvents, e.Data.(*events.TxReplacedEvent))
+	})
+
+	original := replaceable(coinbases[0], 0, subsidy-5000)
+	child := spend(original, 0, original.TxOut[0].Value-5000)
+	final := spend(coinbases[1], 0, subsidy-5000)
+	for _, tx := range []*blockchain.Transaction{original, child, final} {
+		if err := h.pool.ProcessTransaction(tx); err != nil {
+			t.Fatalf("有效交易被拒绝: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		tx   *blockchain.Transaction
+		err  error
+	}{
+		{"冲突交易不允许替换", spend(coinbases[1], 0, subsidy-50000), mempool.ErrDoubleSpend},
+		{"手续费率不高于被替换交易", spend(coinbases[0], 0, subsidy-5000), mempool.ErrReplacement},
+		{"手续费增加不足", spend(coinbases[0], 0, subsidy-10500), mempool.ErrReplacement},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := h.pool.ProcessTransaction(test.tx); !errors.Is(err, test.err) {
+				t.Errorf("应返回%v, 实际: %v", test.err, err)
+			}
+		})
+	}
+	if h.pool.Count() != 3 || len(replacedEvents) != 0 {
+		t.Fatalf("被拒绝的替换不应修改内存池, 交易数%d", h.pool.Count())
+	}
+
+	replacement := spend(coinbases[0], 0, subsidy-20000)
+	if err := h.pool.ProcessTransaction(replacement); err != nil {
+		t.Fatalf("替换交易被拒绝: %v", err)
+	}
+	if h.pool.HaveTransaction(original.Hash()) || h.pool.HaveTransaction(child.Hash()) {
+		t.Error("被替换的交易及其后代交易应被移除")
+	}
+	if !h.pool.HaveTransaction(replacement.Hash()) || h.pool.Count() != 2 {
+		t.Errorf("替换后交易数错误: %d", h.pool.Count())
+	}
+
+	if len(replacedEvents) != 1 {
+		t.Fatalf("应发布一次替换事件, 实际%d次", len(replacedEvents))
+	}
+	event := replacedEvents[0]
+	if event.Replacement.Hash() != replacement.Hash() || len(event.Replaced) != 2 {
+		t.Fatalf("替换事件内容错误: %d笔被替换交易", len(event.Replaced))
+	}
+	replaced := map[[32]byte]bool{event.Replaced[0].Hash(): true, event.Replaced[1].Hash(): true}
+	if !replaced[original.Hash()] || !replaced[child.Hash()] {
+		t.Error("替换事件应包含冲突交易及其后代交易")
+	}
+}
+
+// TestReplacementInheritsSignal 测试祖先交易声明允许替换时后代交易也可以被替换
+func TestReplacementInheritsSignal(t *testing.T) {
+	h := newHarness(t, 0)
+	coinbases := h.mineCoinbases(t, 1)
+
+	parent := replaceable(coinbases[0], 0, subsidy-5000)
+	child := spend(parent, 0, parent.TxOut[0].Value-5000)
+	for _, tx := range []*blockchain.Transaction{parent, child} {
+		if err := h.pool.ProcessTransaction(tx); err != nil {
+			t.Fatalf("有效交易被拒绝: %v", err)
+		}
+	}
+
+	// 花费parent的输出且与child冲突，手续费高于child
+	replacement := spend(parent, 0, parent.TxOut[0].Value-20000)
+	if err := h.pool.ProcessTransaction(replacement); err != nil {
+		t.Fatalf("替换交易被拒绝: %v", err)
+	}
+	if h.pool.HaveTransaction(child.Hash()) || !h.pool.HaveTransaction(parent.Hash()) {
+		t.Error("应只替换冲突的子交易")
+	}
+
+	// 替换交易不能花费将被替换的交易的输出
+	conflict := spend(coinbases[0], 0, subsidy-90000)
+	conflict.TxIn = append(conflict.TxIn, blockchain.NewTxIn(blockchain.NewOutPoint(replacement.Hash(), 0), nil))
+	if err := h.pool.ProcessTransaction(conflict); !errors.Is(err, mempool.ErrReplacement) {
+		t.Errorf("花费被替换交易输出的交易应返回ErrReplacement, 实际: %v", err)
+	}
+}
+
+// TestReplacementEvictionLimit 测试替换交易移除的交易数上限
+func TestReplacementEvictionLimit(t *testing.T) {
+	h := newHarnessWithConfig(t, &mempool.Config{MaxReplacementEvictions: 2})
+	coinbases := h.mineCoinbases(t, 1)
+
+	chain := []*blockchain.Transaction{replaceable(coinbases[0], 0, subsidy-5000)}
+	for i := 1; i < 3; i++ {
+		prev := chain[i-1]
+		chain = append(chain, spend(prev, 0, prev.TxOut[0].Value-5000))
+	}
+	for _, tx := range chain {
+		if err := h.pool.ProcessTransaction(tx); err != nil {
+			t.Fatalf("有效交易被拒绝: %v", err)
+		}
+	}
+
+	replacement := spend(coinbases[0], 0, subsidy-100000)
+	if err := h.pool.ProcessTransaction(replacement); !errors.Is(err, mempool.ErrReplacement) {
+		t.Fatalf("超过移除上限应返回ErrReplacement, 实际: %v", err)
+	}
+	if h.pool.Count() != len(chain) {
+		t.Errorf("被拒绝的替换不应修改内存池, 交易数%d", h.pool.Count())
+	}
+}
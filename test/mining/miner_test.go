@@ -0,0 +1,151 @@
+package mining_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/events"
+	"simplied-bitcoin-network-go/pkg/mining"
+	"simplied-bitcoin-network-go/pkg/storage"
+	"simplied-bitcoin-network-go/pkg/utils"
+	"simplied-bitcoin-network-go/pkg/utxo"
+)
+
+// payout 测试使用的奖励锁定脚本（OP_TRUE）
+var payout = []byte{0x51}
+
+// setupChain 使用指定网络参数创建区块链引擎
+func setupChain(t *testing.T, params *blockchain.ChainParams) (*chain.Chain, *events.Bus) {
+	t.Helper()
+
+	store, err := storage.Open(utils.DatabaseConfig{Type: "bolt", Path: filepath.Join(t.TempDir(), "chain.db")}, params)
+	if err != nil {
+		t.Fatalf("打开存储失败: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	utxoSet, err := utxo.NewSet(store.DB())
+	if err != nil {
+		t.Fatalf("创建UTXO集合失败: %v", err)
+	}
+
+	bus := events.NewBus()
+	c, err := chain.New(&chain.Config{Store: store, UTXOSet: utxoSet, Events: bus, Params: params})
+	if err != nil {
+		t.Fatalf("创建区块链引擎失败: %v", err)
+	}
+	return c, bus
+}
+
+// TestMinerMinesBlocks 测试矿工持续挖出衔接主链的区块
+func TestMinerMinesBlocks(t *testing.T) {
+	c, bus := setupChain(t, blockchain.RegTestParams)
+
+	miner, err := mining.New(&mining.Config{Chain: c, Events: bus, PayoutScript: payout, Threads: 4})
+	if err != nil {
+		t.Fatalf("创建矿工失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	const target = 5
+	bus.Subscribe(events.BlockConnected, func(e events.Event) {
+		if e.Data.(*events.BlockEvent).Height >= target {
+			cancel()
+		}
+	})
+
+	if err := miner.Run(ctx); err != nil {
+		t.Fatalf("挖矿失败: %v", err)
+	}
+
+	best := c.BestSnapshot()
+	if best.Height < target {
+		t.Fatalf("主链高度应至少为%d, 实际%d", target, best.Height)
+	}
+	if miner.BlocksFound() < target || miner.Hashes() == 0 {
+		t.Errorf("统计信息错误: 区块%d 哈希%d", miner.BlocksFound(), miner.Hashes())
+	}
+
+	for height := int32(1); height <= best.Height; height++ {
+		block, err := c.BlockByHeight(height)
+		if err != nil {
+			t.Fatalf("读取高度%d的区块失败: %v", height, err)
+		}
+
+		coinbase := block.Transactions[0]
+		if !bytes.Equal(coinbase.TxOut[0].PkScript, payout) {
+			t.Errorf("高度%d的Coinbase未支付到矿工脚本", height)
+		}
+
+		// Coinbase脚本以区块高度开头
+		script := coinbase.TxIn[0].SignatureScript
+		if script[0] != 1 || script[1] != byte(height) {
+			t.Errorf("高度%d的Coinbase脚本未编码区块高度: %x", height, script)
+		}
+		if !bytes.HasSuffix(script, []byte(mining.CoinbaseFlags)) {
+			t.Errorf("高度%d的Coinbase脚本缺少矿工标识", height)
+		}
+	}
+}
+
+// TestMinerStopsOnCancel 测试取消上下文后矿工退出
+func TestMinerStopsOnCancel(t *testing.T) {
+	// 主网难度下测试期间不可能挖出区块
+	c, bus := setupChain(t, blockchain.MainNetParams)
+
+	miner, err := mining.New(&mining.Config{Chain: c, Events: bus, PayoutScript: payout, Threads: 2})
+	if err != nil {
+		t.Fatalf("创建矿工失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- miner.Run(ctx) }()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("取消后应返回nil, 实际: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("取消上下文后矿工未退出")
+	}
+
+	if miner.Hashes() == 0 {
+		t.Error("矿工应已计算哈希")
+	}
+	if miner.BlocksFound() != 0 || c.BestSnapshot().Height != 0 {
+		t.Error("主网难度下不应挖出区块")
+	}
+}
+
+// TestNewMinerConfig 测试矿工配置检查和默认线程数
+func TestNewMinerConfig(t *testing.T) {
+	c, _ := setupChain(t, blockchain.MainNetParams)
+
+	miner, err := mining.New(&mining.Config{Chain: c, PayoutScript: payout})
+	if err != nil {
+		t.Fatalf("创建矿工失败: %v", err)
+	}
+	if miner.Threads() != runtime.NumCPU() {
+		t.Errorf("线程数为0时应使用全部CPU核心, 实际%d", miner.Threads())
+	}
+
+	if _, err := mining.New(&mining.Config{Chain: c}); err == nil {
+		t.Error("缺少奖励脚本时应返回错误")
+	}
+	if _, err := mining.New(&mining.Config{PayoutScript: payout}); err == nil {
+		t.Error("缺少区块链引擎时应返回错误")
+	}
+}
@@ -0,0 +1,200 @@
+package mining_test
+
+import (
+	"bytes"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/mining"
+)
+
+// subsidy 测试期间的区块奖励
+const subsidy = 50 * 100_000_000
+
+// spendableParams 回归测试网络参数，Coinbase输出在下一个区块即可花费
+var spendableParams = func() *blockchain.ChainParams {
+	params := *blockchain.RegTestParams
+	params.CoinbaseMaturity = 1
+	return &params
+}()
+
+// staticSource 返回固定候选交易的交易来源
+type staticSource []*mining.TxDesc
+
+func (s staticSource) MiningDescs() []*mining.TxDesc { return s }
+
+// mineTemplate 构建模板、求解并提交给区块链
+func mineTemplate(t *testing.T, c *chain.Chain, source mining.TxSource) *mining.BlockTemplate {
+	t.Helper()
+
+	template, err := mining.NewBlockTemplate(c, source, payout)
+	if err != nil {
+		t.Fatalf("构建区块模板失败: %v", err)
+	}
+	for !template.Block.Header.MeetsTarget() {
+		template.Block.Header.Nonce++
+	}
+	if _, err := c.ProcessBlock(template.Block); err != nil {
+		t.Fatalf("模板区块被拒绝: %v", err)
+	}
+	return template
+}
+
+// spend 创建花费指定输出的交易
+func spend(prev *blockchain.Transaction, index uint32, value int64, pkScript []byte) *blockchain.Transaction {
+	prevOut := blockchain.NewOutPoint(prev.Hash(), index)
+	return blockchain.NewTransaction(blockchain.TxVersion,
+		[]*blockchain.TxIn{blockchain.NewTxIn(prevOut, nil)},
+		[]*blockchain.TxOut{blockchain.NewTxOut(value, pkScript)}, 0)
+}
+
+// TestBlockTemplateFeeOrdering 测试按手续费率挑选交易并保持依赖顺序
+func TestBlockTemplateFeeOrdering(t *testing.T) {
+	c, _ := setupChain(t, spendableParams)
+
+	var coinbases []*blockchain.Transaction
+	for i := 0; i < 2; i++ {
+		coinbases = append(coinbases, mineTemplate(t, c, nil).Block.Transactions[0])
+	}
+
+	// low手续费率最低，child依赖low且手续费率最高，high居中
+	low := spend(coinbases[0], 0, subsidy-1000, payout)
+	high := spend(coinbases[1], 0, subsidy-50000, payout)
+	child := spend(low, 0, subsidy-1000-90000, payout)
+	source := staticSource{
+		{Tx: child, Fee: 90000},
+		{Tx: low, Fee: 1000},
+		{Tx: high, Fee: 50000},
+	}
+
+	template := mineTemplate(t, c, source)
+	block := template.Block
+
+	expected := []*blockchain.Transaction{high, low, child}
+	if len(block.Transactions) != len(expected)+1 {
+		t.Fatalf("交易数量错误: %d", len(block.Transactions))
+	}
+	for i, tx := range expected {
+		if block.Transactions[i+1].Hash() != tx.Hash() {
+			t.Errorf("第%d笔交易顺序错误", i+1)
+		}
+	}
+
+	if template.Fees != 141000 {
+		t.Errorf("手续费总额错误: %d", template.Fees)
+	}
+	if got := block.Transactions[0].TotalOutputValue(); got != subsidy+template.Fees {
+		t.Errorf("Coinbase金额应为奖励加手续费, 实际%d", got)
+	}
+	if block.Header.MerkleRoot != block.GetMerkleRoot() {
+		t.Error("模板Merkle根错误")
+	}
+	if block.Header.Bits != c.CalcNextRequiredDifficulty() {
+		t.Errorf("模板难度位错误: %08x", block.Header.Bits)
+	}
+
+	script := block.Transactions[0].TxIn[0].SignatureScript
+	if !bytes.HasPrefix(script, []byte{0x01, byte(template.Height)}) {
+		t.Errorf("Coinbase脚本未编码区块高度: %x", script)
+	}
+	if c.BestSnapshot().Height != template.Height {
+		t.Error("模板区块应成为主链末端")
+	}
+}
+
+// TestBlockTemplateAncestorPackages 测试按交易包手续费率挑选交易（子交易为父交易支付手续费）
+func TestBlockTemplateAncestorPackages(t *testing.T) {
+	c, _ := setupChain(t, spendableParams)
+
+	var coinbases []*blockchain.Transaction
+	for i := 0; i < 2; i++ {
+		coinbases = append(coinbases, mineTemplate(t, c, nil).Block.Transactions[0])
+	}
+
+	// parent单独的手续费率最低，但与child组成的交易包手续费率高于other
+	parent := spend(coinbases[0], 0, subsidy-1000, payout)
+	child := spend(parent, 0, subsidy-1000-200000, payout)
+	other := spend(coinbases[1], 0, subsidy-50000, payout)
+	source := staticSource{
+		{Tx: other, Fee: 50000},
+		{Tx: child, Fee: 200000},
+		{Tx: parent, Fee: 1000},
+	}
+
+	block := mineTemplate(t, c, source).Block
+	expected := []*blockchain.Transaction{parent, child, other}
+	if len(block.Transactions) != len(expected)+1 {
+		t.Fatalf("交易数量错误: %d", len(block.Transactions))
+	}
+	for i, tx := range expected {
+		if block.Transactions[i+1].Hash() != tx.Hash() {
+			t.Errorf("第%d笔交易顺序错误", i+1)
+		}
+	}
+}
+
+// TestBlockTemplateLimits 测试挑选交易时遵守区块限制
+func TestBlockTemplateLimits(t *testing.T) {
+	c, _ := setupChain(t, blockchain.RegTestParams)
+	prev := blockchain.NewCoinbaseTransaction([]byte("unused"), subsidy, payout)
+
+	t.Run("签名操作数", func(t *testing.T) {
+		heavy := spend(prev, 0, 1, bytes.Repeat([]byte{0xae}, blockchain.MaxBlockSigOps/20))
+		overflow := spend(prev, 1, 1, []byte{0xac})
+		plain := spend(prev, 2, 1, payout)
+		source := staticSource{
+			{Tx: heavy, Fee: 100000},
+			{Tx: overflow, Fee: 100},
+			{Tx: plain, Fee: 50},
+		}
+
+		template, err := mining.NewBlockTemplate(c, source, payout)
+		if err != nil {
+			t.Fatalf("构建区块模板失败: %v", err)
+		}
+		if template.SigOps != blockchain.MaxBlockSigOps {
+			t.Errorf("签名操作数错误: %d", template.SigOps)
+		}
+		if len(template.Block.Transactions) != 3 || template.Block.HasTransaction(overflow.Hash()) {
+			t.Error("超出签名操作限制的交易不应被打包")
+		}
+	})
+
+	t.Run("区块大小", func(t *testing.T) {
+		var source staticSource
+		for i := 0; i < 110; i++ {
+			tx := spend(prev, uint32(i), 1, bytes.Repeat([]byte{0x51}, blockchain.MaxScriptSize))
+			source = append(source, &mining.TxDesc{Tx: tx, Fee: 10000})
+		}
+
+		template, err := mining.NewBlockTemplate(c, source, payout)
+		if err != nil {
+			t.Fatalf("构建区块模板失败: %v", err)
+		}
+		if size := template.Block.Size(); size > blockchain.MaxBlockSize {
+			t.Errorf("区块大小超过限制: %d", size)
+		}
+		if len(template.Block.Transactions) >= len(source)+1 {
+			t.Error("超出区块大小的交易不应被打包")
+		}
+		if err := template.Block.Validate(); err != nil {
+			t.Errorf("模板区块应通过验证: %v", err)
+		}
+	})
+
+	t.Run("交易数量", func(t *testing.T) {
+		var source staticSource
+		for i := 0; i < blockchain.MaxTransactionsPerBlock+10; i++ {
+			source = append(source, &mining.TxDesc{Tx: spend(prev, uint32(i), 1, payout), Fee: 1000})
+		}
+
+		template, err := mining.NewBlockTemplate(c, source, payout)
+		if err != nil {
+			t.Fatalf("构建区块模板失败: %v", err)
+		}
+		if len(template.Block.Transactions) != blockchain.MaxTransactionsPerBlock {
+			t.Errorf("交易数量应达到上限, 实际%d", len(template.Block.Transactions))
+		}
+	})
+}
@@ -0,0 +1,252 @@
+package netsync_test
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/netsync"
+	"simplied-bitcoin-network-go/pkg/network"
+)
+
+// mockPool 记录交易的简单交易池
+type mockPool struct {
+	mu        sync.Mutex
+	txs       map[[32]byte]*blockchain.Transaction
+	processed int // 通过ProcessTransaction接受的交易数
+}
+
+// newMockPool 创建空交易池
+func newMockPool() *mockPool {
+	return &mockPool{txs: make(map[[32]byte]*blockchain.Transaction)}
+}
+
+func (p *mockPool) HaveTransaction(hash [32]byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.txs[hash]
+	return ok
+}
+
+func (p *mockPool) FetchTransaction(hash [32]byte) (*blockchain.Transaction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if tx, ok := p.txs[hash]; ok {
+		return tx, nil
+	}
+	return nil, errors.New("交易不存在")
+}
+
+func (p *mockPool) ProcessTransaction(tx *blockchain.Transaction) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.txs[tx.Hash()] = tx
+	p.processed++
+	return nil
+}
+
+// add 直接加入交易，模拟本地提交的交易
+func (p *mockPool) add(tx *blockchain.Transaction) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.txs[tx.Hash()] = tx
+}
+
+// stats 返回是否拥有交易以及接受的交易数
+func (p *mockPool) stats(hash [32]byte) (bool, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.txs[hash]
+	return ok, p.processed
+}
+
+// withPool 为节点配置交易池
+func withPool(pool *mockPool) func(*netsync.Config) {
+	return func(cfg *netsync.Config) { cfg.TxPool = pool }
+}
+
+// newLine 创建依次相连的节点：nodes[i+1]主动连接nodes[i]
+func newLine(t *testing.T, count int, opts ...func(i int) func(*netsync.Config)) []*testNode {
+	t.Helper()
+
+	nodes := make([]*testNode, count)
+	for i := range nodes {
+		var nodeOpts []func(*netsync.Config)
+		for _, opt := range opts {
+			nodeOpts = append(nodeOpts, opt(i))
+		}
+		nodes[i] = newNode(t, nil, nodeOpts...)
+		if i > 0 {
+			nodes[i].connect(t, nodes[i-1].peers.Addr())
+		}
+	}
+	return nodes
+}
+
+// TestBlockRelay 测试新区块经过中间节点传播到整个网络
+func TestBlockRelay(t *testing.T) {
+	nodes := newLine(t, 3)
+	blocks := buildChain(params.GenesisBlock, 0, 4, 'a')
+
+	// 第一个节点挖出新区块
+	if _, err := nodes[0].chain.ProcessBlock(blocks[0]); err != nil {
+		t.Fatalf("处理区块失败: %v", err)
+	}
+	nodes[2].waitForHeight(t, blocks[0], 1)
+
+	// 最后一个节点连续挖出区块，反向传播
+	for _, block := range blocks[1:] {
+		if _, err := nodes[2].chain.ProcessBlock(block); err != nil {
+			t.Fatalf("处理区块失败: %v", err)
+		}
+	}
+	nodes[0].waitForHeight(t, blocks[3], 4)
+	nodes[1].waitForHeight(t, blocks[3], 4)
+}
+
+// TestTransactionRelay 测试交易经过中间节点传播且不回传给来源
+func TestTransactionRelay(t *testing.T) {
+	pools := []*mockPool{newMockPool(), newMockPool(), newMockPool()}
+	nodes := newLine(t, 3, func(i int) func(*netsync.Config) { return withPool(pools[i]) })
+
+	tx := blockchain.NewCoinbaseTransaction([]byte("relay"), 1000, []byte{0x51})
+	hash := tx.Hash()
+	pools[0].add(tx)
+	nodes[0].sync.AnnounceInventory(network.NewInvVect(network.InvTypeTx, hash))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if have, _ := pools[2].stats(hash); have {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("交易未传播到最后一个节点")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	for i, pool := range pools {
+		want := 1
+		if i == 0 {
+			want = 0
+		}
+		if _, processed := pool.stats(hash); processed != want {
+			t.Errorf("节点%d应接受%d次交易, 实际%d次", i, want, processed)
+		}
+	}
+}
+
+// TestRelaySkipsKnownInventory 测试不向已通告过区块的节点回传该区块
+func TestRelaySkipsKnownInventory(t *testing.T) {
+	blocks := buildChain(params.GenesisBlock, 0, 1, 'a')
+	block := blocks[0]
+
+	node := newNode(t, nil)
+	rp := newRawPeer(t, 0)
+	node.connect(t, rp.listener.Addr())
+	conn := rp.accept(t)
+
+	inv := &network.MsgInv{}
+	inv.AddInvVect(network.NewInvVect(network.InvTypeBlock, block.Hash()))
+	network.WriteMessage(conn, inv, params.Net)
+
+	expect(t, conn, network.CmdGetHeaders)
+	headers := &network.MsgHeaders{}
+	headers.AddBlockHeader(block.Header)
+	network.WriteMessage(conn, headers, params.Net)
+
+	expect(t, conn, network.CmdGetData)
+	network.WriteMessage(conn, &network.MsgBlock{Block: block}, params.Net)
+	node.waitForHeight(t, block, 1)
+
+	// 区块连接后会被通告，但对端已知该区块，不应收到inv
+	conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	for {
+		msg, err := network.ReadMessage(conn, params.Net)
+		if err != nil {
+			break
+		}
+		if m, ok := msg.(*network.MsgInv); ok {
+			t.Fatalf("不应向来源节点回传区块通告: %v", m.InvList[0].Hash)
+		}
+	}
+}
+
+// TestTransactionRequestRetry 测试交易请求失败或超时后改向其他通告节点请求
+func TestTransactionRequestRetry(t *testing.T) {
+	pool := newMockPool()
+	node := newNode(t, nil, withPool(pool), func(cfg *netsync.Config) { cfg.StallTimeout = 300 * time.Millisecond })
+
+	rpA, rpB := newRawPeer(t, 0), newRawPeer(t, 0)
+	node.connect(t, rpA.listener.Addr())
+	connA := rpA.accept(t)
+	node.connect(t, rpB.listener.Addr())
+	connB := rpB.accept(t)
+
+	tx := blockchain.NewCoinbaseTransaction([]byte("retry"), 1000, []byte{0x51})
+	iv := network.NewInvVect(network.InvTypeTx, tx.Hash())
+	inv := &network.MsgInv{}
+	inv.AddInvVect(iv)
+
+	// 只向第一个通告节点请求
+	network.WriteMessage(connA, inv, params.Net)
+	expectTxRequest(t, connA, iv)
+	network.WriteMessage(connB, inv, params.Net)
+
+	// A没有该交易，改向B请求
+	notFound := &network.MsgNotFound{}
+	notFound.AddInvVect(iv)
+	network.WriteMessage(connA, notFound, params.Net)
+	expectTxRequest(t, connB, iv)
+
+	// B不响应，请求超时后A再次通告时重新请求
+	time.Sleep(time.Second)
+	network.WriteMessage(connA, inv, params.Net)
+	expectTxRequest(t, connA, iv)
+	network.WriteMessage(connA, &network.MsgTx{Tx: tx}, params.Net)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if have, _ := pool.stats(tx.Hash()); have {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("重新请求的交易未被接受")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// expectTxRequest 等待请求指定交易的getdata消息
+func expectTxRequest(t *testing.T, conn net.Conn, iv *network.InvVect) {
+	t.Helper()
+
+	msg := expect(t, conn, network.CmdGetData).(*network.MsgGetData)
+	if len(msg.InvList) != 1 || *msg.InvList[0] != *iv {
+		t.Fatalf("getdata请求了错误的清单项: %v", msg.InvList)
+	}
+}
+
+// TestGetDataNotFound 测试请求本节点没有的对象时回复notfound
+func TestGetDataNotFound(t *testing.T) {
+	node := newNode(t, nil, withPool(newMockPool()))
+	rp := newRawPeer(t, 0)
+	node.connect(t, rp.listener.Addr())
+	conn := rp.accept(t)
+
+	getData := &network.MsgGetData{}
+	getData.AddInvVect(network.NewInvVect(network.InvTypeTx, [32]byte{1}))
+	getData.AddInvVect(network.NewInvVect(network.InvTypeBlock, params.GenesisHash))
+	getData.AddInvVect(network.NewInvVect(network.InvTypeBlock, [32]byte{2}))
+	network.WriteMessage(conn, getData, params.Net)
+
+	expect(t, conn, network.CmdBlock)
+	msg := expect(t, conn, network.CmdNotFound).(*network.MsgNotFound)
+	if len(msg.InvList) != 2 || *msg.InvList[0] != *getData.InvList[0] || *msg.InvList[1] != *getData.InvList[2] {
+		t.Errorf("notfound清单项错误: %v", msg.InvList)
+	}
+}
@@ -0,0 +1,383 @@
+package netsync_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/chain"
+	"simplied-bitcoin-network-go/pkg/events"
+	"simplied-bitcoin-network-go/pkg/netsync"
+	"simplied-bitcoin-network-go/pkg/network"
+	"simplied-bitcoin-network-go/pkg/storage"
+	"simplied-bitcoin-network-go/pkg/utils"
+	"simplied-bitcoin-network-go/pkg/utxo"
+)
+
+// params 测试使用的网络参数
+var params = blockchain.RegTestParams
+
+// buildChain 在parent之上构造count个有效区块
+//
+// Coinbase脚本包含高度和tag，保证不同区块的Coinbase交易哈希不同。
+func buildChain(parent *blockchain.Block, parentHeight int32, count int, tag byte) []*blockchain.Block {
+	blocks := make([]*blockchain.Block, 0, count)
+	for i := 0; i < count; i++ {
+		height := parentHeight + int32(i) + 1
+		script := binary.LittleEndian.AppendUint32([]byte{tag}, uint32(height))
+		coinbase := blockchain.NewCoinbaseTransaction(script, blockchain.CalcBlockSubsidy(height, params), []byte{0x51})
+
+		header := blockchain.NewBlockHeader(1, parent.Hash(), [32]byte{}, parent.Header.Timestamp+600, params.PowLimitBits, 0)
+		block := blockchain.NewBlock(header, []*blockchain.Transaction{coinbase})
+		header.MerkleRoot = block.GetMerkleRoot()
+		for !header.MeetsTarget() {
+			header.Nonce++
+		}
+
+		blocks = append(blocks, block)
+		parent = block
+	}
+	return blocks
+}
+
+// testNode 由区块链引擎、同步管理器和连接管理器组成的测试节点
+type testNode struct {
+	chain       *chain.Chain
+	sync        *netsync.SyncManager
+	peers       *network.PeerManager
+	disconnects chan error // 已断开连接的断开原因
+}
+
+// newNode 创建监听本地回环地址的测试节点，并预先处理blocks
+func newNode(t *testing.T, blocks []*blockchain.Block, opts ...func(*netsync.Config)) *testNode {
+	t.Helper()
+
+	store, err := storage.Open(utils.DatabaseConfig{Type: "bolt", Path: filepath.Join(t.TempDir(), "chain.db")}, params)
+	if err != nil {
+		t.Fatalf("打开存储失败: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	utxoSet, err := utxo.NewSet(store.DB())
+	if err != nil {
+		t.Fatalf("创建UTXO集合失败: %v", err)
+	}
+	bus := events.NewBus()
+	c, err := chain.New(&chain.Config{Store: store, UTXOSet: utxoSet, Events: bus, Params: params})
+	if err != nil {
+		t.Fatalf("创建区块链引擎失败: %v", err)
+	}
+	for i, block := range blocks {
+		if _, err := c.ProcessBlock(block); err != nil {
+			t.Fatalf("处理第%d个区块失败: %v", i, err)
+		}
+	}
+
+	syncCfg := &netsync.Config{Chain: c, Events: bus}
+	for _, opt := range opts {
+		opt(syncCfg)
+	}
+	sm, err := netsync.New(syncCfg)
+	if err != nil {
+		t.Fatalf("创建同步管理器失败: %v", err)
+	}
+	sm.Start()
+	t.Cleanup(sm.Stop)
+
+	node := &testNode{chain: c, sync: sm, disconnects: make(chan error, 16)}
+	node.peers, err = network.NewPeerManager(network.ManagerConfig{
+		Params:            params,
+		ListenAddr:        "127.0.0.1:0",
+		MaxInbound:        8,
+		MaxOutbound:       8,
+		ConnectionTimeout: 2 * time.Second,
+		HeartbeatInterval: time.Minute,
+		BestHeight:        func() int32 { return c.BestSnapshot().Height },
+		OnPeerConnected:   sm.NewPeer,
+		OnPeerDisconnected: func(p *network.Peer) {
+			sm.DonePeer(p)
+			node.disconnects <- p.DisconnectReason()
+		},
+		OnMessage: sm.HandleMessage,
+	})
+	if err != nil {
+		t.Fatalf("创建连接管理器失败: %v", err)
+	}
+	if err := node.peers.Start(); err != nil {
+		t.Fatalf("启动连接管理器失败: %v", err)
+	}
+	t.Cleanup(node.peers.Stop)
+	return node
+}
+
+// connect 主动连接另一个测试节点
+func (n *testNode) connect(t *testing.T, addr net.Addr) {
+	t.Helper()
+
+	if _, err := n.peers.Connect(addr.String()); err != nil {
+		t.Fatalf("连接%s失败: %v", addr, err)
+	}
+}
+
+// waitForHeight 等待主链到达指定区块
+func (n *testNode) waitForHeight(t *testing.T, tip *blockchain.Block, height int32) {
+	t.Helper()
+
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		best := n.chain.BestSnapshot()
+		if best.Height == height && best.Hash == tip.Hash() {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("同步超时: 进度%+v", n.sync.Progress())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// waitForDisconnect 等待一个连接断开并返回断开原因
+func (n *testNode) waitForDisconnect(t *testing.T) error {
+	t.Helper()
+
+	select {
+	case reason := <-n.disconnects:
+		return reason
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待连接断开超时")
+		return nil
+	}
+}
+
+// TestInitialBlockDownload 测试从单个节点完成区块头和区块同步
+func TestInitialBlockDownload(t *testing.T) {
+	blocks := buildChain(params.GenesisBlock, 0, 120, 'a')
+	source := newNode(t, blocks)
+	fresh := newNode(t, nil, func(cfg *netsync.Config) {
+		cfg.MaxBlocksInFlight = 4
+		cfg.BlockDownloadWindow = 16
+	})
+
+	fresh.connect(t, source.peers.Addr())
+	fresh.waitForHeight(t, blocks[119], 120)
+
+	progress := fresh.sync.Progress()
+	if progress.HeadersHeight != 120 || progress.BlocksHeight != 120 || !progress.IsCurrent() {
+		t.Errorf("同步进度错误: %+v", progress)
+	}
+	if progress.BlocksInFlight != 0 || progress.BlocksPending != 0 {
+		t.Errorf("同步完成后不应有未完成的下载: %+v", progress)
+	}
+}
+
+// TestInitialBlockDownloadMultiplePeers 测试从多个节点并行下载
+//
+// 区块头超过一个headers消息的上限，需要多轮getheaders。
+func TestInitialBlockDownloadMultiplePeers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("跳过长链同步测试")
+	}
+
+	count := network.MaxBlockHeadersPerMsg + 50
+	blocks := buildChain(params.GenesisBlock, 0, count, 'a')
+	first := newNode(t, blocks)
+	second := newNode(t, blocks)
+	fresh := newNode(t, nil)
+
+	fresh.connect(t, first.peers.Addr())
+	fresh.connect(t, second.peers.Addr())
+	fresh.waitForHeight(t, blocks[count-1], int32(count))
+
+	if progress := fresh.sync.Progress(); progress.Peers != 2 || progress.HeadersHeight != int32(count) {
+		t.Errorf("同步进度错误: %+v", progress)
+	}
+}
+
+// TestSyncFollowsMostWork 测试同步到累计工作量最大的分支
+func TestSyncFollowsMostWork(t *testing.T) {
+	common := buildChain(params.GenesisBlock, 0, 10, 'a')
+	short := append(common[:10:10], buildChain(common[9], 10, 5, 'b')...)
+	long := append(common[:10:10], buildChain(common[9], 10, 8, 'c')...)
+
+	fresh := newNode(t, short)
+	source := newNode(t, long)
+
+	fresh.connect(t, source.peers.Addr())
+	fresh.waitForHeight(t, long[17], 18)
+}
+
+// rawPeer 手工控制消息的对端，用于模拟异常节点
+type rawPeer struct {
+	listener net.Listener
+	conns    chan net.Conn
+}
+
+// newRawPeer 创建监听本地回环地址的原始对端
+//
+// 每个接入的连接都以height作为起始高度完成握手。
+func newRawPeer(t *testing.T, height int32) *rawPeer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	rp := &rawPeer{listener: listener, conns: make(chan net.Conn, 1)}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		if _, err := network.ReadMessage(conn, params.Net); err != nil {
+			return
+		}
+		addr := network.NewNetAddress(net.IPv4(127, 0, 0, 1), 0, 0)
+		network.WriteMessage(conn, network.NewMsgVersion(addr, addr, 12345, height), params.Net)
+		network.WriteMessage(conn, &network.MsgVerAck{}, params.Net)
+		if _, err := network.ReadMessage(conn, params.Net); err != nil {
+			return
+		}
+		rp.conns <- conn
+	}()
+	return rp
+}
+
+// accept 返回完成握手的连接
+func (rp *rawPeer) accept(t *testing.T) net.Conn {
+	t.Helper()
+
+	select {
+	case conn := <-rp.conns:
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待握手超时")
+		return nil
+	}
+}
+
+// expect 读取消息直到收到指定命令
+func expect(t *testing.T, conn net.Conn, command string) network.Message {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		msg, err := network.ReadMessage(conn, params.Net)
+		if err != nil {
+			t.Fatalf("等待%s消息失败: %v", command, err)
+		}
+		if msg.Command() == command {
+			return msg
+		}
+	}
+}
+
+// TestInvalidHeaderDisconnects 测试发送无效区块头的节点被断开
+func TestInvalidHeaderDisconnects(t *testing.T) {
+	blocks := buildChain(params.GenesisBlock, 0, 5, 'a')
+	// 第4个区块头的工作量证明无效
+	bad := *blocks[3].Header
+	for bad.MeetsTarget() {
+		bad.Nonce++
+	}
+
+	fresh := newNode(t, nil)
+	rp := newRawPeer(t, 5)
+	fresh.connect(t, rp.listener.Addr())
+	conn := rp.accept(t)
+
+	expect(t, conn, network.CmdGetHeaders)
+	headers := &network.MsgHeaders{}
+	for _, block := range blocks[:3] {
+		headers.AddBlockHeader(block.Header)
+	}
+	headers.AddBlockHeader(&bad)
+	if err := network.WriteMessage(conn, headers, params.Net); err != nil {
+		t.Fatalf("发送区块头失败: %v", err)
+	}
+
+	if reason := fresh.waitForDisconnect(t); !errors.Is(reason, netsync.ErrInvalidHeader) {
+		t.Errorf("断开原因应为ErrInvalidHeader, 实际: %v", reason)
+	}
+
+	// 无效区块头之前的区块头已被接受
+	if _, height := fresh.chain.BestHeader(); height != 3 {
+		t.Errorf("最佳区块头高度应为3, 实际%d", height)
+	}
+}
+
+// TestStalledPeerReplaced 测试不响应区块请求的节点被断开并由其他节点完成下载
+func TestStalledPeerReplaced(t *testing.T) {
+	blocks := buildChain(params.GenesisBlock, 0, 20, 'a')
+	fresh := newNode(t, nil, func(cfg *netsync.Config) { cfg.StallTimeout = 300 * time.Millisecond })
+
+	rp := newRawPeer(t, 20)
+	fresh.connect(t, rp.listener.Addr())
+	conn := rp.accept(t)
+
+	// 提供区块头但不响应getdata
+	expect(t, conn, network.CmdGetHeaders)
+	headers := &network.MsgHeaders{}
+	for _, block := range blocks {
+		headers.AddBlockHeader(block.Header)
+	}
+	network.WriteMessage(conn, headers, params.Net)
+	expect(t, conn, network.CmdGetData)
+
+	if reason := fresh.waitForDisconnect(t); !errors.Is(reason, netsync.ErrStalled) {
+		t.Errorf("断开原因应为ErrStalled, 实际: %v", reason)
+	}
+	if progress := fresh.sync.Progress(); progress.HeadersHeight != 20 || progress.BlocksHeight != 0 {
+		t.Fatalf("同步进度错误: %+v", progress)
+	}
+
+	source := newNode(t, blocks)
+	fresh.connect(t, source.peers.Addr())
+	fresh.waitForHeight(t, blocks[19], 20)
+}
+
+// TestInvalidBlockDropsDescendants 测试无效区块的待连接后代被丢弃
+func TestInvalidBlockDropsDescendants(t *testing.T) {
+	// 第2个区块的Coinbase多领取1聪，区块头有效但区块无法连接
+	valid := buildChain(params.GenesisBlock, 0, 1, 'a')
+	coinbase := blockchain.NewCoinbaseTransaction([]byte{'b', 2}, blockchain.CalcBlockSubsidy(2, params)+1, []byte{0x51})
+	header := blockchain.NewBlockHeader(1, valid[0].Hash(), [32]byte{}, valid[0].Header.Timestamp+600, params.PowLimitBits, 0)
+	bad := blockchain.NewBlock(header, []*blockchain.Transaction{coinbase})
+	header.MerkleRoot = bad.GetMerkleRoot()
+	for !header.MeetsTarget() {
+		header.Nonce++
+	}
+	blocks := append(append(valid, bad), buildChain(bad, 2, 3, 'a')...)
+
+	fresh := newNode(t, nil)
+	rp := newRawPeer(t, 5)
+	fresh.connect(t, rp.listener.Addr())
+	conn := rp.accept(t)
+
+	expect(t, conn, network.CmdGetHeaders)
+	headers := &network.MsgHeaders{}
+	for _, block := range blocks {
+		headers.AddBlockHeader(block.Header)
+	}
+	network.WriteMessage(conn, headers, params.Net)
+	expect(t, conn, network.CmdGetData)
+
+	// 后代先于无效的父区块到达
+	for _, i := range []int{0, 4, 3, 2, 1} {
+		network.WriteMessage(conn, &network.MsgBlock{Block: blocks[i]}, params.Net)
+	}
+
+	if reason := fresh.waitForDisconnect(t); !errors.Is(reason, netsync.ErrInvalidBlock) {
+		t.Errorf("断开原因应为ErrInvalidBlock, 实际: %v", reason)
+	}
+	if progress := fresh.sync.Progress(); progress.BlocksHeight != 1 || progress.BlocksPending != 0 {
+		t.Errorf("无效区块的后代应被丢弃: %+v", progress)
+	}
+}
@@ -0,0 +1,59 @@
+package network_test
+
+import (
+	"bytes"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/network"
+)
+
+// FuzzReadMessage 对完整消息帧进行模糊测试
+//
+// 任意输入都不能导致panic；成功解码的消息重新编码后必须能再次解码为相同负载。
+func FuzzReadMessage(f *testing.F) {
+	for _, msg := range testMessages() {
+		frame, err := network.EncodeMessage(msg, testMagic)
+		if err != nil {
+			f.Fatalf("编码种子失败: %v", err)
+		}
+		f.Add(frame)
+	}
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		msg, err := network.ReadMessage(bytes.NewReader(frame), testMagic)
+		if err != nil {
+			return
+		}
+		checkReencode(t, msg)
+	})
+}
+
+// FuzzDecodePayload 对各消息类型的负载编解码进行模糊测试，不涉及消息头
+func FuzzDecodePayload(f *testing.F) {
+	for _, msg := range testMessages() {
+		f.Add(msg.Command(), msg.Serialize())
+	}
+
+	f.Fuzz(func(t *testing.T, command string, payload []byte) {
+		msg, err := network.DecodePayload(command, payload)
+		if err != nil {
+			return
+		}
+		checkReencode(t, msg)
+	})
+}
+
+// checkReencode 检查消息重新编码后可以解码为相同的负载
+func checkReencode(t *testing.T, msg network.Message) {
+	t.Helper()
+
+	payload := msg.Serialize()
+	again, err := network.DecodePayload(msg.Command(), payload)
+	if err != nil {
+		t.Fatalf("重新编码的%s消息无法解码: %v", msg.Command(), err)
+	}
+	if !bytes.Equal(again.Serialize(), payload) {
+		t.Fatalf("%s消息编解码不稳定", msg.Command())
+	}
+}
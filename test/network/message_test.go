@@ -0,0 +1,269 @@
+package network_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/network"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// testMagic 测试使用的网络魔数
+const testMagic = blockchain.RegTestMagic
+
+// testAddress 创建测试网络地址
+func testAddress(ip string, port uint16) *network.NetAddress {
+	return &network.NetAddress{
+		Timestamp: time.Unix(1700000000, 0),
+		Services:  network.SFNodeNetwork,
+		IP:        net.ParseIP(ip).To16(),
+		Port:      port,
+	}
+}
+
+// testMessages 返回每种消息类型的示例
+func testMessages() []network.Message {
+	genesis := blockchain.RegTestParams.GenesisBlock
+	hash := genesis.Hash()
+
+	version := network.NewMsgVersion(testAddress("127.0.0.1", 8081), testAddress("10.0.0.2", 8082), 0x1122334455667788, 42)
+	version.Timestamp = time.Unix(1700000000, 0)
+	version.UserAgent = "/simplied:1.0.0/"
+	version.AddrRecv.Timestamp = time.Time{}
+	version.AddrFrom.Timestamp = time.Time{}
+
+	inv := &network.MsgInv{}
+	inv.AddInvVect(network.NewInvVect(network.InvTypeBlock, hash))
+	inv.AddInvVect(network.NewInvVect(network.InvTypeTx, genesis.Transactions[0].Hash()))
+
+	getData := &network.MsgGetData{}
+	getData.AddInvVect(network.NewInvVect(network.InvTypeBlock, hash))
+
+	notFound := &network.MsgNotFound{}
+	notFound.AddInvVect(network.NewInvVect(network.InvTypeTx, hash))
+
+	getHeaders := &network.MsgGetHeaders{ProtocolVersion: network.ProtocolVersion}
+	getHeaders.AddBlockLocatorHash(hash)
+
+	headers := &network.MsgHeaders{}
+	headers.AddBlockHeader(genesis.Header)
+
+	addr := &network.MsgAddr{}
+	addr.AddAddress(testAddress("192.168.1.10", 8080))
+	addr.AddAddress(testAddress("2001:db8::1", 18080))
+
+	reject := network.NewMsgReject(network.CmdBlock, network.RejectInvalid, "bad merkle root")
+	reject.Hash = hash
+
+	return []network.Message{
+		version,
+		&network.MsgVerAck{},
+		&network.MsgPing{Nonce: 7},
+		&network.MsgPong{Nonce: 7},
+		inv,
+		getData,
+		notFound,
+		getHeaders,
+		headers,
+		&network.MsgBlock{Block: genesis},
+		&network.MsgTx{Tx: genesis.Transactions[0]},
+		addr,
+		reject,
+		network.NewMsgReject(network.CmdVersion, network.RejectObsolete, "protocol too old"),
+	}
+}
+
+// TestMessageRoundTrip 测试所有消息类型的编解码往返
+func TestMessageRoundTrip(t *testing.T) {
+	for _, msg := range testMessages() {
+		t.Run(msg.Command(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := network.WriteMessage(&buf, msg, testMagic); err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			frame := append([]byte(nil), buf.Bytes()...)
+
+			got, err := network.ReadMessage(&buf, testMagic)
+			if err != nil {
+				t.Fatalf("解码失败: %v", err)
+			}
+			if got.Command() != msg.Command() {
+				t.Fatalf("命令不一致: %s", got.Command())
+			}
+			if !bytes.Equal(got.Serialize(), msg.Serialize()) {
+				t.Error("重新编码的负载与原负载不一致")
+			}
+			if buf.Len() != 0 {
+				t.Errorf("读取后剩余%d字节", buf.Len())
+			}
+
+			if _, err := network.DecodeMessage(frame, testMagic); err != nil {
+				t.Errorf("DecodeMessage失败: %v", err)
+			}
+		})
+	}
+}
+
+// TestMessageFields 测试解码后的字段值
+func TestMessageFields(t *testing.T) {
+	me := testAddress("127.0.0.1", 8081)
+	you := testAddress("10.0.0.2", 8082)
+	version := network.NewMsgVersion(me, you, 99, 123)
+	version.UserAgent = "/test/"
+
+	frame, err := network.EncodeMessage(version, testMagic)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	msg, err := network.DecodeMessage(frame, testMagic)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+
+	got := msg.(*network.MsgVersion)
+	if got.ProtocolVersion != network.ProtocolVersion || got.Nonce != 99 || got.StartHeight != 123 ||
+		got.UserAgent != "/test/" || got.Services != network.SFNodeNetwork {
+		t.Errorf("version字段错误: %+v", got)
+	}
+	if got.AddrFrom.String() != "127.0.0.1:8081" || got.AddrRecv.String() != "10.0.0.2:8082" {
+		t.Errorf("地址错误: %s %s", got.AddrFrom.String(), got.AddrRecv.String())
+	}
+	if !got.Timestamp.Equal(version.Timestamp) {
+		t.Errorf("时间戳错误: %v", got.Timestamp)
+	}
+
+	addr := &network.MsgAddr{}
+	addr.AddAddress(testAddress("192.168.1.10", 8080))
+	frame, _ = network.EncodeMessage(addr, testMagic)
+	msg, err = network.DecodeMessage(frame, testMagic)
+	if err != nil {
+		t.Fatalf("解码addr失败: %v", err)
+	}
+	gotAddr := msg.(*network.MsgAddr).AddrList[0]
+	if !reflect.DeepEqual(gotAddr, addr.AddrList[0]) {
+		t.Errorf("addr地址不一致: %+v", gotAddr)
+	}
+}
+
+// TestMessageHeaderLayout 测试消息头格式
+func TestMessageHeaderLayout(t *testing.T) {
+	frame, err := network.EncodeMessage(&network.MsgPing{Nonce: 1}, testMagic)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+
+	if len(frame) != network.MessageHeaderSize+8 {
+		t.Fatalf("帧长度错误: %d", len(frame))
+	}
+	if binary.LittleEndian.Uint32(frame[0:4]) != testMagic {
+		t.Error("魔数位置错误")
+	}
+	if !bytes.Equal(frame[4:16], []byte("ping\x00\x00\x00\x00\x00\x00\x00\x00")) {
+		t.Errorf("命令字段错误: %q", frame[4:16])
+	}
+	if binary.LittleEndian.Uint32(frame[16:20]) != 8 {
+		t.Error("长度字段错误")
+	}
+}
+
+// TestReadMessageRejects 测试拒绝无效的消息帧
+func TestReadMessageRejects(t *testing.T) {
+	valid, err := network.EncodeMessage(&network.MsgPing{Nonce: 1}, testMagic)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+
+	// mutate 复制有效帧并修改
+	mutate := func(modify func(frame []byte) []byte) []byte {
+		return modify(append([]byte(nil), valid...))
+	}
+
+	tests := []struct {
+		name  string
+		frame []byte
+		want  error
+	}{
+		{"错误的网络", mutate(func(f []byte) []byte {
+			binary.LittleEndian.PutUint32(f[0:4], blockchain.MainNetMagic)
+			return f
+		}), network.ErrWrongNetwork},
+		{"校验和错误", mutate(func(f []byte) []byte {
+			f[len(f)-1] ^= 0xff
+			return f
+		}), network.ErrChecksumMismatch},
+		{"超过全局长度上限", mutate(func(f []byte) []byte {
+			binary.LittleEndian.PutUint32(f[16:20], network.MaxMessagePayload+1)
+			return f
+		}), network.ErrPayloadTooLarge},
+		{"超过消息类型长度上限", mutate(func(f []byte) []byte {
+			binary.LittleEndian.PutUint32(f[16:20], 9)
+			return append(f, 0)
+		}), network.ErrPayloadTooLarge},
+		{"命令包含非法字符", mutate(func(f []byte) []byte {
+			f[5] = 0x01
+			return f
+		}), network.ErrMalformedHeader},
+		{"命令填充字节非0", mutate(func(f []byte) []byte {
+			f[15] = 'x'
+			return f
+		}), network.ErrMalformedHeader},
+		{"未知命令", mutate(func(f []byte) []byte {
+			copy(f[4:16], "unknown\x00\x00\x00\x00\x00")
+			return f
+		}), network.ErrUnknownCommand},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := network.ReadMessage(bytes.NewReader(tt.frame), testMagic)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("期望%v, 实际: %v", tt.want, err)
+			}
+		})
+	}
+
+	// 负载格式错误：pong负载长度不足但校验和正确
+	short := &network.MessageHeader{Magic: testMagic, Command: network.CmdPong, Length: 4}
+	payload := []byte{1, 2, 3, 4}
+	copy(short.Checksum[:], utils.Checksum(payload))
+	frame := append(short.Serialize(), payload...)
+	if _, err := network.DecodeMessage(frame, testMagic); !errors.Is(err, network.ErrMalformedMessage) {
+		t.Errorf("负载不足应返回ErrMalformedMessage, 实际: %v", err)
+	}
+
+	// 截断的帧
+	if _, err := network.ReadMessage(bytes.NewReader(valid[:len(valid)-1]), testMagic); err == nil {
+		t.Error("截断的帧应返回错误")
+	}
+}
+
+// TestMessageListLimits 测试列表长度限制
+func TestMessageListLimits(t *testing.T) {
+	inv := &network.MsgInv{}
+	for i := 0; i < network.MaxInvPerMsg; i++ {
+		if err := inv.AddInvVect(network.NewInvVect(network.InvTypeTx, [32]byte{})); err != nil {
+			t.Fatalf("添加第%d个清单项失败: %v", i, err)
+		}
+	}
+	if err := inv.AddInvVect(network.NewInvVect(network.InvTypeTx, [32]byte{})); err == nil {
+		t.Error("超过清单项上限应返回错误")
+	}
+
+	// 手工构造声明超过上限的headers负载
+	payload := []byte{0xfd, 0xd1, 0x07} // 2001
+	if _, err := network.DecodePayload(network.CmdHeaders, payload); !errors.Is(err, network.ErrMalformedMessage) {
+		t.Errorf("区块头数量超过上限应返回错误, 实际: %v", err)
+	}
+
+	// 声明的数量与数据长度不符
+	payload = []byte{0x05, 0x01}
+	if _, err := network.DecodePayload(network.CmdInv, payload); err == nil {
+		t.Error("清单项数量与数据长度不符应返回错误")
+	}
+}
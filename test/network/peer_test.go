@@ -0,0 +1,585 @@
+package network_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"simplied-bitcoin-network-go/pkg/addrmgr"
+	"simplied-bitcoin-network-go/pkg/banman"
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/network"
+)
+
+// newManager 创建监听本地回环地址的连接管理器
+//
+// opts用于在创建前调整配置，管理器在测试结束时停止。
+func newManager(t *testing.T, opts ...func(*network.ManagerConfig)) *network.PeerManager {
+	t.Helper()
+
+	cfg := network.ManagerConfig{
+		Params:            blockchain.RegTestParams,
+		ListenAddr:        "127.0.0.1:0",
+		MaxInbound:        8,
+		MaxOutbound:       8,
+		ConnectionTimeout: 2 * time.Second,
+		HeartbeatInterval: time.Minute,
+		Services:          network.SFNodeNetwork,
+		UserAgent:         "/test/",
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	m, err := network.NewPeerManager(cfg)
+	if err != nil {
+		t.Fatalf("创建连接管理器失败: %v", err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatalf("启动连接管理器失败: %v", err)
+	}
+	t.Cleanup(m.Stop)
+	return m
+}
+
+// waitFor 轮询直到条件成立或超时
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// rawHandshake 使用原始连接以指定版本消息完成握手
+func rawHandshake(t *testing.T, conn net.Conn, version *network.MsgVersion) {
+	t.Helper()
+
+	if err := network.WriteMessage(conn, version, blockchain.RegTestMagic); err != nil {
+		t.Fatalf("发送version失败: %v", err)
+	}
+	for _, want := range []string{network.CmdVersion, network.CmdVerAck} {
+		msg, err := network.ReadMessage(conn, blockchain.RegTestMagic)
+		if err != nil {
+			t.Fatalf("读取%s失败: %v", want, err)
+		}
+		if msg.Command() != want {
+			t.Fatalf("期望%s, 实际%s", want, msg.Command())
+		}
+	}
+	if err := network.WriteMessage(conn, &network.MsgVerAck{}, blockchain.RegTestMagic); err != nil {
+		t.Fatalf("发送verack失败: %v", err)
+	}
+}
+
+// testVersion 创建原始连接使用的version消息
+func testVersion(nonce uint64) *network.MsgVersion {
+	addr := network.NewNetAddress(net.IPv4(127, 0, 0, 1), 0, 0)
+	return network.NewMsgVersion(addr, addr, nonce, 0)
+}
+
+// TestHandshake 测试两个节点完成版本握手
+func TestHandshake(t *testing.T) {
+	var connected atomic.Int32
+	a := newManager(t, func(cfg *network.ManagerConfig) {
+		cfg.BestHeight = func() int32 { return 7 }
+		cfg.OnPeerConnected = func(*network.Peer) { connected.Add(1) }
+	})
+	b := newManager(t)
+
+	p, err := b.Connect(a.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	if p.Inbound() || p.StartingHeight() != 7 || p.UserAgent() != "/test/" ||
+		p.ProtocolVersion() != network.ProtocolVersion || p.Services() != network.SFNodeNetwork {
+		t.Errorf("对端信息错误: 高度%d 代理%s", p.StartingHeight(), p.UserAgent())
+	}
+
+	waitFor(t, "入站连接登记", func() bool { return a.ConnectedCount() == 1 && connected.Load() == 1 })
+	if !a.Peers()[0].Inbound() {
+		t.Error("被连接方应记录为入站连接")
+	}
+
+	if _, err := b.Connect(a.Addr().String()); !errors.Is(err, network.ErrAlreadyConnected) {
+		t.Errorf("重复连接应返回ErrAlreadyConnected, 实际: %v", err)
+	}
+}
+
+// TestHandshakeRejectsWrongNetwork 测试拒绝其他网络的节点
+func TestHandshakeRejectsWrongNetwork(t *testing.T) {
+	a := newManager(t)
+	b := newManager(t, func(cfg *network.ManagerConfig) { cfg.Params = blockchain.TestNetParams })
+
+	// 接收方读到其他网络的魔数后关闭连接，发起方因此握手失败
+	if _, err := b.Connect(a.Addr().String()); err == nil {
+		t.Fatal("不同网络的节点不应完成握手")
+	}
+	if a.ConnectedCount() != 0 || b.ConnectedCount() != 0 {
+		t.Error("握手失败后不应登记连接")
+	}
+}
+
+// TestHandshakeRejectsObsoleteVersion 测试拒绝协议版本过低的节点
+func TestHandshakeRejectsObsoleteVersion(t *testing.T) {
+	a := newManager(t)
+
+	conn, err := net.Dial("tcp", a.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	version := testVersion(1)
+	version.ProtocolVersion = 0
+	if err := network.WriteMessage(conn, version, blockchain.RegTestMagic); err != nil {
+		t.Fatalf("发送version失败: %v", err)
+	}
+
+	msg, err := network.ReadMessage(conn, blockchain.RegTestMagic)
+	if err != nil {
+		t.Fatalf("应收到reject消息: %v", err)
+	}
+	reject, ok := msg.(*network.MsgReject)
+	if !ok || reject.Code != network.RejectObsolete || reject.Cmd != network.CmdVersion {
+		t.Fatalf("期望版本过旧的reject, 实际: %v", msg)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := network.ReadMessage(conn, blockchain.RegTestMagic); err == nil {
+		t.Error("发送reject后连接应被关闭")
+	}
+}
+
+// TestSelfConnection 测试检测到连接自身时断开
+func TestSelfConnection(t *testing.T) {
+	a := newManager(t)
+
+	// 入站一侧发现Nonce相同后断开，出站一侧因连接关闭而握手失败
+	if _, err := a.Connect(a.Addr().String()); err == nil {
+		t.Fatal("连接自身应失败")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if a.ConnectedCount() != 0 {
+		t.Errorf("自连接不应被登记, 实际%d个连接", a.ConnectedCount())
+	}
+}
+
+// TestInboundLimit 测试入站连接数限制
+func TestInboundLimit(t *testing.T) {
+	a := newManager(t, func(cfg *network.ManagerConfig) { cfg.MaxInbound = 1 })
+
+	first, err := net.Dial("tcp", a.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer first.Close()
+	rawHandshake(t, first, testVersion(1))
+	waitFor(t, "第一个入站连接", func() bool { return a.ConnectedCount() == 1 })
+
+	second, err := net.Dial("tcp", a.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer second.Close()
+	network.WriteMessage(second, testVersion(2), blockchain.RegTestMagic)
+
+	second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := network.ReadMessage(second, blockchain.RegTestMagic); err == nil {
+		t.Error("超过入站上限的连接应被关闭")
+	}
+	if a.ConnectedCount() != 1 {
+		t.Errorf("连接数应保持为1, 实际%d", a.ConnectedCount())
+	}
+
+	// 第一个连接断开后名额被释放
+	first.Close()
+	waitFor(t, "名额释放", func() bool { return a.ConnectedCount() == 0 })
+
+	third, err := net.Dial("tcp", a.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer third.Close()
+	rawHandshake(t, third, testVersion(3))
+	waitFor(t, "新入站连接", func() bool { return a.ConnectedCount() == 1 })
+}
+
+// TestOutboundLimit 测试出站连接数限制
+func TestOutboundLimit(t *testing.T) {
+	a := newManager(t)
+	b := newManager(t)
+	c := newManager(t, func(cfg *network.ManagerConfig) { cfg.MaxOutbound = 1 })
+
+	if _, err := c.Connect(a.Addr().String()); err != nil {
+		t.Fatalf("第一个出站连接失败: %v", err)
+	}
+	if _, err := c.Connect(b.Addr().String()); !errors.Is(err, network.ErrOutboundSlotsFull) {
+		t.Errorf("超过出站上限应返回ErrOutboundSlotsFull, 实际: %v", err)
+	}
+}
+
+// TestHeartbeat 测试心跳ping和空闲连接断开
+func TestHeartbeat(t *testing.T) {
+	fast := func(cfg *network.ManagerConfig) { cfg.HeartbeatInterval = 50 * time.Millisecond }
+	a := newManager(t, fast)
+	b := newManager(t, fast)
+
+	p, err := b.Connect(a.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	waitFor(t, "ping往返时间", func() bool { return p.LastPingRTT() > 0 })
+
+	// 活跃的连接在多个空闲超时周期后仍然保持
+	time.Sleep(300 * time.Millisecond)
+	if b.ConnectedCount() != 1 {
+		t.Fatal("互相ping的连接不应因空闲被断开")
+	}
+
+	// 握手后不再发送任何消息的原始连接被断开
+	silent, err := net.Dial("tcp", a.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer silent.Close()
+	rawHandshake(t, silent, testVersion(99))
+	waitFor(t, "静默连接登记", func() bool { return a.ConnectedCount() == 2 })
+
+	var silentPeer *network.Peer
+	for _, peer := range a.Peers() {
+		if peer.Addr() == silent.LocalAddr().String() {
+			silentPeer = peer
+		}
+	}
+	if silentPeer == nil {
+		t.Fatal("未找到静默连接")
+	}
+
+	select {
+	case <-silentPeer.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("静默连接应在空闲超时后断开")
+	}
+	if !errors.Is(silentPeer.DisconnectReason(), network.ErrPeerIdle) {
+		t.Errorf("断开原因应为ErrPeerIdle, 实际: %v", silentPeer.DisconnectReason())
+	}
+}
+
+// TestOnMessage 测试业务消息分发和广播
+func TestOnMessage(t *testing.T) {
+	received := make(chan network.Message, 1)
+	a := newManager(t, func(cfg *network.ManagerConfig) {
+		cfg.OnMessage = func(p *network.Peer, msg network.Message) { received <- msg }
+	})
+	b := newManager(t)
+
+	if _, err := b.Connect(a.Addr().String()); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+
+	inv := &network.MsgInv{}
+	inv.AddInvVect(network.NewInvVect(network.InvTypeBlock, blockchain.RegTestParams.GenesisHash))
+	b.Broadcast(inv, nil)
+
+	select {
+	case msg := <-received:
+		got, ok := msg.(*network.MsgInv)
+		if !ok || len(got.InvList) != 1 || got.InvList[0].Hash != blockchain.RegTestParams.GenesisHash {
+			t.Errorf("收到的消息错误: %v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("未收到广播的消息")
+	}
+}
+
+// TestSeeds 测试自动连接种子节点并在断开后重连
+func TestSeeds(t *testing.T) {
+	a := newManager(t)
+	b := newManager(t, func(cfg *network.ManagerConfig) {
+		cfg.Seeds = []string{a.Addr().String()}
+		cfg.HeartbeatInterval = 100 * time.Millisecond
+	})
+
+	waitFor(t, "连接种子节点", func() bool { return b.ConnectedCount() == 1 })
+
+	first := b.Peers()[0]
+	first.Disconnect()
+	waitFor(t, "重连种子节点", func() bool {
+		peers := b.Peers()
+		return len(peers) == 1 && peers[0] != first
+	})
+}
+
+// openDB 在临时目录中创建数据库
+func openDB(t *testing.T) *bolt.DB {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "peers.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// withAddrBook 为管理器配置使用临时数据库的地址簿
+func withAddrBook(t *testing.T, book **addrmgr.AddrManager) func(*network.ManagerConfig) {
+	t.Helper()
+
+	var err error
+	if *book, err = addrmgr.New(openDB(t)); err != nil {
+		t.Fatalf("创建地址簿失败: %v", err)
+	}
+	return func(cfg *network.ManagerConfig) {
+		cfg.AddrBook = *book
+		cfg.HeartbeatInterval = 100 * time.Millisecond
+	}
+}
+
+// TestAddressDiscovery 测试通过addr消息发现并连接种子以外的节点
+func TestAddressDiscovery(t *testing.T) {
+	var bookA, bookB, bookC *addrmgr.AddrManager
+	a := newManager(t, withAddrBook(t, &bookA))
+	b := newManager(t, withAddrBook(t, &bookB), func(cfg *network.ManagerConfig) {
+		cfg.Seeds = []string{a.Addr().String()}
+	})
+	waitFor(t, "连接种子节点", func() bool { return a.ConnectedCount() == 1 })
+
+	// c只知道b，从b通告的地址中得知a并主动连接
+	c := newManager(t, withAddrBook(t, &bookC), func(cfg *network.ManagerConfig) {
+		cfg.Seeds = []string{b.Addr().String()}
+	})
+	waitFor(t, "连接通告的节点", func() bool {
+		for _, p := range c.Peers() {
+			if !p.Inbound() && p.Addr() == a.Addr().String() {
+				return true
+			}
+		}
+		return false
+	})
+
+	waitFor(t, "成功连接的地址移入已验证表", func() bool { return bookC.NumTried() == 2 })
+	if n := bookA.NumAddresses(); n < 2 {
+		t.Errorf("a应从入站节点的通告中得知其监听地址, 实际%d个地址", n)
+	}
+}
+
+// TestBanScore 测试格式错误的消息累计不当行为分数，达到阈值后断开并封禁IP
+func TestBanScore(t *testing.T) {
+	bans, err := banman.New(openDB(t))
+	if err != nil {
+		t.Fatalf("创建封禁列表失败: %v", err)
+	}
+	m := newManager(t, func(cfg *network.ManagerConfig) { cfg.BanList = bans })
+
+	conn, err := net.Dial("tcp", m.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer conn.Close()
+	rawHandshake(t, conn, testVersion(1))
+	waitFor(t, "完成握手", func() bool { return m.ConnectedCount() == 1 })
+	p := m.Peers()[0]
+
+	// 校验和错误的消息
+	var buf bytes.Buffer
+	network.WriteMessage(&buf, &network.MsgPing{Nonce: 1}, blockchain.RegTestMagic)
+	frame := buf.Bytes()
+	frame[network.MessageHeaderSize-1] ^= 0xff
+
+	for i := 0; i < 4; i++ {
+		conn.Write(frame)
+	}
+	waitFor(t, "累计不当行为分数", func() bool { return p.BanScore() == 4*network.BanScoreMalformedMessage })
+	if m.ConnectedCount() != 1 {
+		t.Fatal("未达到封禁阈值时不应断开连接")
+	}
+
+	conn.Write(frame)
+	p.WaitForDisconnect()
+	if reason := p.DisconnectReason(); !errors.Is(reason, network.ErrBanScoreExceeded) || !errors.Is(reason, network.ErrChecksumMismatch) {
+		t.Errorf("断开原因错误: %v", reason)
+	}
+	waitFor(t, "封禁IP", func() bool { return bans.IsBanned(net.ParseIP("127.0.0.1")) })
+
+	// 封禁期内的入站连接被直接关闭
+	banned, err := net.Dial("tcp", m.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer banned.Close()
+	banned.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := banned.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("已封禁地址的连接应被关闭, 实际: %v", err)
+	}
+
+	// 也不主动连接已封禁的地址
+	if _, err := m.Connect(newManager(t).Addr().String()); !errors.Is(err, network.ErrBanned) {
+		t.Errorf("期望ErrBanned, 实际: %v", err)
+	}
+}
+
+// TestBanThreshold 测试封禁阈值可配置，增加阈值大小的分数时立即断开
+func TestBanThreshold(t *testing.T) {
+	if p := connectedPeer(t, newManager(t)); p.BanThreshold() != network.DefaultBanThreshold {
+		t.Errorf("未配置时应使用默认阈值, 实际%d", p.BanThreshold())
+	}
+
+	m := newManager(t, func(cfg *network.ManagerConfig) { cfg.BanThreshold = 2 * network.DefaultBanThreshold })
+	p := connectedPeer(t, m)
+	if p.BanThreshold() != 2*network.DefaultBanThreshold {
+		t.Fatalf("阈值错误: %d", p.BanThreshold())
+	}
+	if p.AddBanScore(network.DefaultBanThreshold, errors.New("测试")) {
+		t.Fatal("未达到配置的阈值时不应断开")
+	}
+	if !p.AddBanScore(p.BanThreshold(), errors.New("测试")) {
+		t.Error("增加阈值大小的分数时应断开")
+	}
+	p.WaitForDisconnect()
+}
+
+// connectedPeer 以原始连接接入m并返回完成握手的节点
+func connectedPeer(t *testing.T, m *network.PeerManager) *network.Peer {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", m.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	rawHandshake(t, conn, testVersion(1))
+	waitFor(t, "完成握手", func() bool { return m.ConnectedCount() == 1 })
+	return m.Peers()[0]
+}
+
+// TestMalformedHeaderDisconnect 测试消息头格式错误时直接断开连接
+//
+// 消息头错误时负载没有被读取，继续读取会把负载当作下一个消息头解析。
+func TestMalformedHeaderDisconnect(t *testing.T) {
+	m := newManager(t)
+
+	conn, err := net.Dial("tcp", m.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer conn.Close()
+	rawHandshake(t, conn, testVersion(1))
+	waitFor(t, "完成握手", func() bool { return m.ConnectedCount() == 1 })
+	p := m.Peers()[0]
+
+	var buf bytes.Buffer
+	network.WriteMessage(&buf, &network.MsgPing{Nonce: 1}, blockchain.RegTestMagic)
+	frame := buf.Bytes()
+	frame[5] = 0x01
+	conn.Write(frame)
+
+	p.WaitForDisconnect()
+	if reason := p.DisconnectReason(); !errors.Is(reason, network.ErrMalformedHeader) {
+		t.Errorf("断开原因错误: %v", reason)
+	}
+}
+
+// TestSendQueueFull 测试不阻塞的发送在队列已满时断开连接
+func TestSendQueueFull(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+
+	// 未启动的节点不会取出队列中的消息
+	p := network.NewInboundPeer(local, network.PeerConfig{Magic: blockchain.RegTestMagic})
+	queued := 0
+	for p.TryQueueMessage(&network.MsgPing{Nonce: uint64(queued)}) {
+		queued++
+		if queued > 10000 {
+			t.Fatal("发送队列没有上限")
+		}
+	}
+	if queued == 0 {
+		t.Fatal("队列未满时应能加入消息")
+	}
+
+	if p.SendMessage(&network.MsgPing{}) {
+		t.Fatal("队列已满时不应加入消息")
+	}
+	if reason := p.DisconnectReason(); !errors.Is(reason, network.ErrSendQueueFull) {
+		t.Errorf("断开原因错误: %v", reason)
+	}
+	if p.TryQueueMessage(&network.MsgPing{}) {
+		t.Error("连接断开后不应加入消息")
+	}
+}
+
+// TestWriteTimeout 测试对端停止读取时写入超时并断开连接
+func TestWriteTimeout(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+
+	p := network.NewInboundPeer(local, network.PeerConfig{
+		Magic:        blockchain.RegTestMagic,
+		Nonce:        1,
+		WriteTimeout: 100 * time.Millisecond,
+	})
+	started := make(chan error, 1)
+	go func() { started <- p.Start() }()
+	rawHandshake(t, remote, testVersion(2))
+	if err := <-started; err != nil {
+		t.Fatalf("握手失败: %v", err)
+	}
+
+	// 对端不再读取，同步管道上的写入一直阻塞直到超时
+	p.SendMessage(&network.MsgPing{Nonce: 1})
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("写入超时后应断开连接")
+	}
+	var netErr net.Error
+	if reason := p.DisconnectReason(); !errors.As(reason, &netErr) || !netErr.Timeout() {
+		t.Errorf("断开原因应为写入超时: %v", reason)
+	}
+}
+
+// TestKnownInventory 测试已知清单项的滚动淘汰
+func TestKnownInventory(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	p := network.NewInboundPeer(local, network.PeerConfig{Magic: blockchain.RegTestMagic})
+
+	item := func(i int) *network.InvVect {
+		return network.NewInvVect(network.InvTypeTx, [32]byte{byte(i), byte(i >> 8)})
+	}
+
+	first := item(0)
+	p.AddKnownInventory(first)
+	if !p.IsKnownInventory(first) || p.IsKnownInventory(network.NewInvVect(network.InvTypeBlock, first.Hash)) {
+		t.Fatal("清单项应按类型和哈希区分")
+	}
+
+	// 重新加入的清单项移到最新位置，之后最早的清单项被淘汰
+	for i := 1; i < 1000; i++ {
+		p.AddKnownInventory(item(i))
+	}
+	p.AddKnownInventory(first)
+	p.AddKnownInventory(item(1000))
+	if !p.IsKnownInventory(first) {
+		t.Error("最近使用的清单项不应被淘汰")
+	}
+	if p.IsKnownInventory(item(1)) {
+		t.Error("超过容量后最早的清单项应被淘汰")
+	}
+}
@@ -0,0 +1,207 @@
+package rpc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"simplied-bitcoin-network-go/pkg/banman"
+	"simplied-bitcoin-network-go/pkg/rpc"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// testServer 测试用RPC服务
+type testServer struct {
+	bans    *banman.BanManager
+	baseURL string
+	authKey string
+}
+
+// newServer 启动监听本地回环地址的RPC服务，服务在测试结束时停止
+//
+// cfg中的监听地址和封禁列表由newServer设置。
+func newServer(t *testing.T, cfg rpc.Config) *testServer {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "rpc.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	bans, err := banman.New(db)
+	if err != nil {
+		t.Fatalf("创建封禁列表失败: %v", err)
+	}
+
+	cfg.ListenAddr = "127.0.0.1:0"
+	cfg.BanList = bans
+	server, err := rpc.New(&cfg)
+	if err != nil {
+		t.Fatalf("创建RPC服务失败: %v", err)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动RPC服务失败: %v", err)
+	}
+	t.Cleanup(func() { server.Stop() })
+
+	return &testServer{
+		bans:    bans,
+		baseURL: "http://" + server.Addr().String() + utils.APIBasePath,
+		authKey: cfg.AuthKey,
+	}
+}
+
+// do 发送请求并在out非空时解析响应，返回状态码
+func (s *testServer) do(t *testing.T, method, path string, body, out interface{}) int {
+	t.Helper()
+
+	var reader bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader.Reset(data)
+	}
+	req, err := http.NewRequest(method, s.baseURL+path, &reader)
+	if err != nil {
+		t.Fatalf("创建请求失败: %v", err)
+	}
+	if s.authKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.authKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s失败: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// TestBanEndpoints 测试封禁列表的增删查
+func TestBanEndpoints(t *testing.T) {
+	s := newServer(t, rpc.Config{AuthKey: "secret", BanDuration: 2 * time.Hour})
+
+	var info rpc.BanInfo
+	status := s.do(t, http.MethodPost, "/bans", rpc.BanRequest{IP: "10.0.0.1", Duration: 3600, Reason: "测试"}, &info)
+	if status != http.StatusCreated || info.IP != "10.0.0.1" || info.Until.Sub(info.CreatedAt) != time.Hour {
+		t.Fatalf("添加封禁失败: %d %+v", status, info)
+	}
+	s.do(t, http.MethodPost, "/bans", rpc.BanRequest{IP: "10.0.0.2"}, &info)
+	if info.Reason == "" || info.Until.Sub(info.CreatedAt) != 2*time.Hour {
+		t.Errorf("未指定时长和原因时应使用配置的默认值: %+v", info)
+	}
+	if !s.bans.IsBanned(net.ParseIP("10.0.0.1")) {
+		t.Error("封禁应写入封禁列表")
+	}
+
+	var list []rpc.BanInfo
+	if status := s.do(t, http.MethodGet, "/bans", nil, &list); status != http.StatusOK || len(list) != 2 {
+		t.Fatalf("应列出2条封禁记录: %d %+v", status, list)
+	}
+
+	if status := s.do(t, http.MethodDelete, "/bans/10.0.0.1", nil, nil); status != http.StatusNoContent {
+		t.Errorf("解除封禁应返回204, 实际%d", status)
+	}
+	var errResp rpc.ErrorResponse
+	if status := s.do(t, http.MethodDelete, "/bans/10.0.0.1", nil, &errResp); status != http.StatusNotFound || errResp.Error == "" {
+		t.Errorf("解除未封禁的IP应返回404, 实际%d", status)
+	}
+
+	if status := s.do(t, http.MethodDelete, "/bans", nil, nil); status != http.StatusNoContent {
+		t.Errorf("清空封禁列表应返回204, 实际%d", status)
+	}
+	s.do(t, http.MethodGet, "/bans", nil, &list)
+	if len(list) != 0 {
+		t.Errorf("清空后不应有封禁记录: %+v", list)
+	}
+}
+
+// TestBanEndpointRejects 测试拒绝无效的封禁请求
+func TestBanEndpointRejects(t *testing.T) {
+	s := newServer(t, rpc.Config{AuthKey: "secret"})
+
+	tests := []struct {
+		name string
+		req  rpc.BanRequest
+	}{
+		{"无效IP", rpc.BanRequest{IP: "not-an-ip"}},
+		{"负数时长", rpc.BanRequest{IP: "10.0.0.1", Duration: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errResp rpc.ErrorResponse
+			if status := s.do(t, http.MethodPost, "/bans", tt.req, &errResp); status != http.StatusBadRequest {
+				t.Errorf("期望400, 实际%d: %s", status, errResp.Error)
+			}
+		})
+	}
+	if len(s.bans.List()) != 0 {
+		t.Error("无效请求不应添加封禁")
+	}
+}
+
+// TestAuth 测试配置认证密钥后拒绝未认证的请求
+func TestAuth(t *testing.T) {
+	s := newServer(t, rpc.Config{AuthKey: "secret"})
+
+	var list []rpc.BanInfo
+	if status := s.do(t, http.MethodGet, "/bans", nil, &list); status != http.StatusOK {
+		t.Fatalf("携带正确密钥的请求应成功, 实际%d", status)
+	}
+
+	s.authKey = "wrong"
+	var errResp rpc.ErrorResponse
+	if status := s.do(t, http.MethodGet, "/bans", nil, &errResp); status != http.StatusUnauthorized {
+		t.Errorf("密钥错误应返回401, 实际%d", status)
+	}
+}
+
+// TestBanEndpointsWithoutAuth 测试未配置认证密钥时拒绝修改封禁列表
+func TestBanEndpointsWithoutAuth(t *testing.T) {
+	s := newServer(t, rpc.Config{EnableCORS: true})
+	if err := s.bans.Ban(net.ParseIP("10.0.0.1"), time.Hour, "测试"); err != nil {
+		t.Fatalf("封禁失败: %v", err)
+	}
+
+	var errResp rpc.ErrorResponse
+	if status := s.do(t, http.MethodPost, "/bans", rpc.BanRequest{IP: "10.0.0.2"}, &errResp); status != http.StatusForbidden {
+		t.Errorf("添加封禁应返回403, 实际%d", status)
+	}
+	if status := s.do(t, http.MethodDelete, "/bans/10.0.0.1", nil, nil); status != http.StatusForbidden {
+		t.Errorf("解除封禁应返回403, 实际%d", status)
+	}
+	if status := s.do(t, http.MethodDelete, "/bans", nil, nil); status != http.StatusForbidden {
+		t.Errorf("清空封禁列表应返回403, 实际%d", status)
+	}
+	if !s.bans.IsBanned(net.ParseIP("10.0.0.1")) || s.bans.IsBanned(net.ParseIP("10.0.0.2")) {
+		t.Error("封禁列表不应被修改")
+	}
+
+	var list []rpc.BanInfo
+	if status := s.do(t, http.MethodGet, "/bans", nil, &list); status != http.StatusOK || len(list) != 1 {
+		t.Errorf("只读接口应可用: %d %+v", status, list)
+	}
+
+	// 跨域预检不允许修改方法
+	req, _ := http.NewRequest(http.MethodOptions, s.baseURL+"/bans", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("预检请求失败: %v", err)
+	}
+	resp.Body.Close()
+	if methods := resp.Header.Get("Access-Control-Allow-Methods"); methods != "GET, OPTIONS" {
+		t.Errorf("跨域允许的方法错误: %q", methods)
+	}
+}
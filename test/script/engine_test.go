@@ -0,0 +1,329 @@
+package script_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/script"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// spendingTx 创建花费一个输出的交易
+func spendingTx(sigScript []byte) *blockchain.Transaction {
+	prevOut := blockchain.NewOutPoint([32]byte{0x01}, 0)
+	return blockchain.NewTransaction(blockchain.TxVersion,
+		[]*blockchain.TxIn{blockchain.NewTxIn(prevOut, sigScript)},
+		[]*blockchain.TxOut{blockchain.NewTxOut(1000, []byte{script.OpTrue})}, 0)
+}
+
+// execute 以pkScript验证tx的第idx个输入
+func execute(pkScript []byte, tx *blockchain.Transaction, idx int) error {
+	vm, err := script.NewEngine(pkScript, tx, idx)
+	if err != nil {
+		return err
+	}
+	return vm.Execute()
+}
+
+// assertScriptError 检查错误为指定错误码的脚本错误，code为负数时期望成功
+func assertScriptError(t *testing.T, err error, code script.ErrorCode) {
+	t.Helper()
+
+	if code < 0 {
+		if err != nil {
+			t.Errorf("期望执行成功, 实际: %v", err)
+		}
+		return
+	}
+	if !script.IsErrorCode(err, code) {
+		t.Errorf("期望%v, 实际: %v", code, err)
+	}
+}
+
+// sign 对交易第idx个输入签名，返回附加哈希类型的签名
+func sign(t *testing.T, key *secp256k1.PrivateKey, subScript []byte, tx *blockchain.Transaction, idx int) []byte {
+	t.Helper()
+
+	sig, err := script.RawTxInSignature(tx, idx, subScript, script.SigHashAll, key)
+	if err != nil {
+		t.Fatalf("签名失败: %v", err)
+	}
+	return sig
+}
+
+// newKey 生成测试私钥
+func newKey(t *testing.T) *secp256k1.PrivateKey {
+	t.Helper()
+
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("生成私钥失败: %v", err)
+	}
+	return key
+}
+
+// mustScript 构造脚本，失败时终止测试
+func mustScript(t *testing.T, b *script.Builder) []byte {
+	t.Helper()
+
+	s, err := b.Script()
+	if err != nil {
+		t.Fatalf("构造脚本失败: %v", err)
+	}
+	return s
+}
+
+// repeat 返回重复n次操作码的脚本
+func repeat(op byte, n int) []byte {
+	return bytes.Repeat([]byte{op}, n)
+}
+
+// TestOpcodes 测试操作码语义和执行限制
+func TestOpcodes(t *testing.T) {
+	const ok script.ErrorCode = -1
+	const opCat = 0x7e
+
+	hash := utils.Hash160([]byte("data"))
+	bigPush := append([]byte{script.OpPushData2, 0x09, 0x02}, make([]byte, script.MaxScriptElementSize+1)...)
+
+	tests := []struct {
+		name      string
+		sigScript []byte
+		pkScript  []byte
+		code      script.ErrorCode
+	}{
+		{"OP_TRUE", nil, []byte{script.OpTrue}, ok},
+		{"空脚本", nil, nil, script.ErrCodeEmptyStack},
+		{"栈顶为假", []byte{script.Op0}, nil, script.ErrCodeEvalFalse},
+		{"负零为假", []byte{script.OpData1, 0x80}, nil, script.ErrCodeEvalFalse},
+		{"OP_IF真分支", []byte{script.Op1}, []byte{script.OpIf, script.Op1, script.OpElse, script.Op0, script.OpEndIf}, ok},
+		{"OP_IF假分支", []byte{script.Op0}, []byte{script.OpIf, script.Op1, script.OpElse, script.Op0, script.OpEndIf}, script.ErrCodeEvalFalse},
+		{"OP_NOTIF", []byte{script.Op0}, []byte{script.OpNotIf, script.Op1, script.OpEndIf}, ok},
+		{"未执行的嵌套分支", []byte{script.Op0},
+			[]byte{script.OpIf, script.OpIf, script.OpReturn, script.OpElse, script.OpReturn, script.OpEndIf, script.OpElse, script.Op1, script.OpEndIf}, ok},
+		{"未执行分支中的不支持操作码", []byte{script.Op0}, []byte{script.OpIf, opCat, script.OpEndIf, script.Op1}, ok},
+		{"OP_ENDIF不匹配", nil, []byte{script.Op1, script.OpEndIf}, script.ErrCodeUnbalancedConditional},
+		{"OP_ELSE不匹配", nil, []byte{script.Op1, script.OpElse}, script.ErrCodeUnbalancedConditional},
+		{"分支未闭合", []byte{script.Op1}, []byte{script.OpIf, script.Op1}, script.ErrCodeUnbalancedConditional},
+		{"OP_IF栈为空", nil, []byte{script.OpIf, script.OpEndIf}, script.ErrCodeInvalidStackOperation},
+		{"OP_RETURN", []byte{script.Op1}, []byte{script.OpReturn}, script.ErrCodeEarlyReturn},
+		{"OP_VERIFY", []byte{script.Op0}, []byte{script.OpVerify, script.Op1}, script.ErrCodeVerify},
+		{"OP_DUP栈为空", nil, []byte{script.OpDup}, script.ErrCodeInvalidStackOperation},
+		{"OP_DROP", []byte{script.Op1, script.Op0}, []byte{script.OpDrop}, ok},
+		{"OP_HASH160", mustScript(t, script.NewBuilder().AddData([]byte("data"))),
+			mustScript(t, script.NewBuilder().AddOp(script.OpHash160).AddData(hash).AddOp(script.OpEqualVerify).AddOp(script.Op1)), ok},
+		{"OP_EQUALVERIFY", []byte{script.Op1, script.Op1 + 1},
+			[]byte{script.OpEqualVerify, script.Op1}, script.ErrCodeEqualVerify},
+		{"OP_1NEGATE", []byte{script.Op1Negate}, []byte{script.OpData1, 0x81, script.OpEqual}, ok},
+		{"不支持的操作码", []byte{script.Op1}, []byte{opCat}, script.ErrCodeUnsupportedOpcode},
+		{"解锁脚本包含非推送操作", []byte{script.Op1, script.OpDup}, []byte{script.OpEqual}, script.ErrCodeNotPushOnly},
+		{"推送越界", nil, []byte{script.OpData20, 0x01}, script.ErrCodeMalformedPush},
+		{"OP_PUSHDATA2长度不完整", nil, []byte{script.OpPushData2, 0x01}, script.ErrCodeMalformedPush},
+		{"推送数据过长", nil, bigPush, script.ErrCodeElementTooBig},
+		{"操作数达到上限", nil, append(repeat(script.OpNop, script.MaxOpsPerScript), script.Op1), ok},
+		{"操作数超过上限", nil, append(repeat(script.OpNop, script.MaxOpsPerScript+1), script.Op1), script.ErrCodeTooManyOperations},
+		{"栈溢出", nil, repeat(script.Op1, script.MaxStackSize+1), script.ErrCodeStackOverflow},
+		{"脚本过长", nil, make([]byte, blockchain.MaxScriptSize+1), script.ErrCodeScriptTooBig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertScriptError(t, execute(tt.pkScript, spendingTx(tt.sigScript), 0), tt.code)
+		})
+	}
+}
+
+// TestCheckLockTimeVerify 测试OP_CHECKLOCKTIMEVERIFY的锁定时间规则
+func TestCheckLockTimeVerify(t *testing.T) {
+	const ok script.ErrorCode = -1
+
+	tests := []struct {
+		name     string
+		operand  []byte
+		lockTime uint32
+		sequence uint32
+		code     script.ErrorCode
+	}{
+		{"高度已到达", []byte{0x64}, 100, 0, ok},
+		{"高度未到达", []byte{0x64}, 99, 0, script.ErrCodeUnsatisfiedLockTime},
+		{"时间戳已到达", []byte{0x00, 0x65, 0xcd, 0x1d}, blockchain.LockTimeThreshold, 0, ok},
+		{"类型不一致", []byte{0x64}, blockchain.LockTimeThreshold, 0, script.ErrCodeUnsatisfiedLockTime},
+		{"输入序列号为最大值", []byte{0x64}, 100, blockchain.MaxTxInSequenceNum, script.ErrCodeUnsatisfiedLockTime},
+		{"负数", []byte{0x81}, 100, 0, script.ErrCodeNegativeLockTime},
+		{"非最短编码", []byte{0x64, 0x00}, 100, 0, script.ErrCodeMinimalData},
+		{"超过5字节", []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x01}, 100, 0, script.ErrCodeNumberTooBig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkScript := mustScript(t, script.NewBuilder().AddData(tt.operand).AddOp(script.OpCheckLockTimeVerify))
+			tx := spendingTx(nil)
+			tx.LockTime = tt.lockTime
+			tx.TxIn[0].Sequence = tt.sequence
+			assertScriptError(t, execute(pkScript, tx, 0), tt.code)
+		})
+	}
+}
+
+// TestPayToPubKeyHash 测试P2PKH输出的签名验证
+func TestPayToPubKeyHash(t *testing.T) {
+	key := newKey(t)
+	pubKey := key.PubKey().SerializeCompressed()
+	pkScript := mustScript(t, script.NewBuilder().AddOp(script.OpDup).AddOp(script.OpHash160).
+		AddData(utils.Hash160(pubKey)).AddOp(script.OpEqualVerify).AddOp(script.OpCheckSig))
+
+	tx := spendingTx(nil)
+	sig := sign(t, key, pkScript, tx, 0)
+	tx.TxIn[0].SignatureScript = mustScript(t, script.NewBuilder().AddData(sig).AddData(pubKey))
+	if err := execute(pkScript, tx, 0); err != nil {
+		t.Fatalf("有效签名验证失败: %v", err)
+	}
+
+	// 其他私钥的公钥哈希不匹配
+	other := newKey(t)
+	otherPub := other.PubKey().SerializeCompressed()
+	forged := spendingTx(nil)
+	forged.TxIn[0].SignatureScript = mustScript(t, script.NewBuilder().
+		AddData(sign(t, other, pkScript, forged, 0)).AddData(otherPub))
+	assertScriptError(t, execute(pkScript, forged, 0), script.ErrCodeEqualVerify)
+
+	// 签名后修改输出
+	tx.TxOut[0].Value++
+	assertScriptError(t, execute(pkScript, tx, 0), script.ErrCodeEvalFalse)
+	tx.TxOut[0].Value--
+
+	tests := []struct {
+		name string
+		sig  []byte
+		pub  []byte
+		code script.ErrorCode
+	}{
+		{"空签名", nil, pubKey, script.ErrCodeEvalFalse},
+		{"不支持的哈希类型", append(append([]byte(nil), sig[:len(sig)-1]...), 0x05), pubKey, script.ErrCodeInvalidSigHashType},
+		{"签名不是DER编码", []byte{0x30, 0x01, 0x01, byte(script.SigHashAll)}, pubKey, script.ErrCodeSigDER},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spend := spendingTx(mustScript(t, script.NewBuilder().AddData(tt.sig).AddData(tt.pub)))
+			assertScriptError(t, execute(pkScript, spend, 0), tt.code)
+		})
+	}
+
+	// 直接对公钥签名的脚本会检查公钥格式
+	p2pk := mustScript(t, script.NewBuilder().AddData(append([]byte{0x05}, pubKey[1:]...)).AddOp(script.OpCheckSig))
+	spend := spendingTx(nil)
+	spend.TxIn[0].SignatureScript = mustScript(t, script.NewBuilder().AddData(sign(t, key, p2pk, spend, 0)))
+	assertScriptError(t, execute(p2pk, spend, 0), script.ErrCodePubKeyType)
+}
+
+// TestPayToScriptHashMultiSig 测试P2SH包装的2-of-3多重签名
+func TestPayToScriptHashMultiSig(t *testing.T) {
+	keys := []*secp256k1.PrivateKey{newKey(t), newKey(t), newKey(t)}
+	builder := script.NewBuilder().AddInt64(2)
+	for _, key := range keys {
+		builder.AddData(key.PubKey().SerializeCompressed())
+	}
+	redeemScript := mustScript(t, builder.AddInt64(3).AddOp(script.OpCheckMultiSig))
+	pkScript := mustScript(t, script.NewBuilder().AddOp(script.OpHash160).
+		AddData(utils.Hash160(redeemScript)).AddOp(script.OpEqual))
+	if !script.IsPayToScriptHash(pkScript) {
+		t.Fatal("应识别为P2SH锁定脚本")
+	}
+
+	tests := []struct {
+		name  string
+		dummy []byte
+		signs []int
+		code  script.ErrorCode
+	}{
+		{"第1和第3个私钥", nil, []int{0, 2}, -1},
+		{"第2和第3个私钥", nil, []int{1, 2}, -1},
+		{"签名顺序错误", nil, []int{2, 0}, script.ErrCodeEvalFalse},
+		{"签名不足", nil, []int{0}, script.ErrCodeInvalidStackOperation},
+		{"额外元素不为空", []byte{0x01}, []int{0, 1}, script.ErrCodeSigNullDummy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := spendingTx(nil)
+			sigScript := script.NewBuilder().AddData(tt.dummy)
+			for _, i := range tt.signs {
+				sigScript.AddData(sign(t, keys[i], redeemScript, tx, 0))
+			}
+			tx.TxIn[0].SignatureScript = mustScript(t, sigScript.AddData(redeemScript))
+			assertScriptError(t, execute(pkScript, tx, 0), tt.code)
+		})
+	}
+
+	// 赎回脚本哈希不匹配
+	tx := spendingTx(mustScript(t, script.NewBuilder().AddOp(script.Op1).AddData([]byte{script.Op1})))
+	assertScriptError(t, execute(pkScript, tx, 0), script.ErrCodeEvalFalse)
+}
+
+// TestBuilder 测试脚本构造器使用最短的推送方式
+func TestBuilder(t *testing.T) {
+	tests := []struct {
+		name   string
+		build  func(b *script.Builder)
+		script []byte
+	}{
+		{"整数0", func(b *script.Builder) { b.AddInt64(0) }, []byte{script.Op0}},
+		{"整数-1", func(b *script.Builder) { b.AddInt64(-1) }, []byte{script.Op1Negate}},
+		{"整数16", func(b *script.Builder) { b.AddInt64(16) }, []byte{script.Op16}},
+		{"整数17", func(b *script.Builder) { b.AddInt64(17) }, []byte{script.OpData1, 0x11}},
+		{"整数128", func(b *script.Builder) { b.AddInt64(128) }, []byte{0x02, 0x80, 0x00}},
+		{"整数-128", func(b *script.Builder) { b.AddInt64(-128) }, []byte{0x02, 0x80, 0x80}},
+		{"空数据", func(b *script.Builder) { b.AddData(nil) }, []byte{script.Op0}},
+		{"单字节5", func(b *script.Builder) { b.AddData([]byte{5}) }, []byte{script.Op1 + 4}},
+		{"76字节", func(b *script.Builder) { b.AddData(make([]byte, 76)) },
+			append([]byte{script.OpPushData1, 76}, make([]byte, 76)...)},
+		{"256字节", func(b *script.Builder) { b.AddData(make([]byte, 256)) },
+			append([]byte{script.OpPushData2, 0x00, 0x01}, make([]byte, 256)...)},
+	}
+
+	for _, tt := range tests {
+		b := script.NewBuilder()
+		tt.build(b)
+		got, err := b.Script()
+		if err != nil || !bytes.Equal(got, tt.script) {
+			t.Errorf("%s: 期望%x, 实际: %x %v", tt.name, tt.script, got, err)
+		}
+	}
+
+	_, err := script.NewBuilder().AddData(make([]byte, script.MaxScriptElementSize+1)).Script()
+	assertScriptError(t, err, script.ErrCodeElementTooBig)
+
+	if !script.IsPushOnly([]byte{script.Op0, script.OpData1, 0x01, script.Op16}) || script.IsPushOnly([]byte{script.OpDup}) {
+		t.Error("IsPushOnly判断错误")
+	}
+}
+
+// TestCountSigOps 测试脚本签名操作数统计
+func TestCountSigOps(t *testing.T) {
+	tests := []struct {
+		name   string
+		script []byte
+		count  int
+	}{
+		{"空脚本", nil, 0},
+		{"CHECKSIG类", []byte{script.OpCheckSig, script.OpCheckSigVerify}, 2},
+		{"CHECKMULTISIG类", []byte{script.OpCheckMultiSig, script.OpCheckMultiSigVerify}, 2 * script.MaxPubKeysPerMultiSig},
+		{"推送数据中的操作码不计入", []byte{script.OpData1 + 1, script.OpCheckSig, script.OpCheckSig, script.OpCheckSig}, 1},
+		{"格式错误的推送之后停止统计", []byte{script.OpCheckSig, script.OpPushData1}, 1},
+	}
+
+	for _, tt := range tests {
+		if got := script.CountSigOps(tt.script); got != tt.count {
+			t.Errorf("%s: 期望%d, 实际%d", tt.name, tt.count, got)
+		}
+	}
+
+	tx := spendingTx([]byte{script.OpCheckSig})
+	tx.TxOut[0].PkScript = []byte{script.OpCheckMultiSig}
+	if got := script.CountTxSigOps(tx); got != 1+script.MaxPubKeysPerMultiSig {
+		t.Errorf("交易签名操作数错误: %d", got)
+	}
+}
@@ -0,0 +1,227 @@
+package script_test
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/script"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// payToPubKeyHash 返回向私钥对应压缩公钥支付的P2PKH锁定脚本
+func payToPubKeyHash(t *testing.T, key *secp256k1.PrivateKey) []byte {
+	t.Helper()
+	return mustScript(t, script.NewBuilder().AddOp(script.OpDup).AddOp(script.OpHash160).
+		AddData(utils.Hash160(key.PubKey().SerializeCompressed())).AddOp(script.OpEqualVerify).AddOp(script.OpCheckSig))
+}
+
+// signInput 用SignatureScript设置交易第idx个输入的解锁脚本
+func signInput(t *testing.T, tx *blockchain.Transaction, idx int, pkScript []byte, hashType script.SigHashType,
+	key *secp256k1.PrivateKey) {
+	t.Helper()
+
+	sigScript, err := script.SignatureScript(tx, idx, pkScript, hashType, key, true)
+	if err != nil {
+		t.Fatalf("签名输入%d失败: %v", idx, err)
+	}
+	tx.TxIn[idx].SignatureScript = sigScript
+}
+
+// TestAnyoneCanPayCollaboration 测试多个参与方各自签名自己的输入
+func TestAnyoneCanPayCollaboration(t *testing.T) {
+	alice, bob := newKey(t), newKey(t)
+	alicePk, bobPk := payToPubKeyHash(t, alice), payToPubKeyHash(t, bob)
+
+	// Alice先签名自己的输入，签名只覆盖该输入和全部输出
+	tx := blockchain.NewTransaction(blockchain.TxVersion,
+		[]*blockchain.TxIn{blockchain.NewTxIn(blockchain.NewOutPoint([32]byte{0xa1}, 0), nil)},
+		[]*blockchain.TxOut{blockchain.NewTxOut(5000, []byte{script.OpTrue})}, 0)
+	signInput(t, tx, 0, alicePk, script.SigHashAll|script.SigHashAnyOneCanPay, alice)
+
+	// Bob随后添加并签名自己的输入
+	tx.TxIn = append(tx.TxIn, blockchain.NewTxIn(blockchain.NewOutPoint([32]byte{0xb0}, 1), nil))
+	signInput(t, tx, 1, bobPk, script.SigHashAll, bob)
+
+	if err := execute(alicePk, tx, 0); err != nil {
+		t.Errorf("Alice的输入验证失败: %v", err)
+	}
+	if err := execute(bobPk, tx, 1); err != nil {
+		t.Errorf("Bob的输入验证失败: %v", err)
+	}
+
+	// 输出被修改后两个签名都失效
+	tx.TxOut[0].Value = 6000
+	assertScriptError(t, execute(alicePk, tx, 0), script.ErrCodeEvalFalse)
+	assertScriptError(t, execute(bobPk, tx, 1), script.ErrCodeEvalFalse)
+
+	// 没有ANYONECANPAY的签名在添加输入后失效
+	solo := blockchain.NewTransaction(blockchain.TxVersion,
+		[]*blockchain.TxIn{blockchain.NewTxIn(blockchain.NewOutPoint([32]byte{0xa2}, 0), nil)},
+		[]*blockchain.TxOut{blockchain.NewTxOut(5000, []byte{script.OpTrue})}, 0)
+	signInput(t, solo, 0, alicePk, script.SigHashAll, alice)
+	solo.TxIn = append(solo.TxIn, blockchain.NewTxIn(blockchain.NewOutPoint([32]byte{0xb1}, 0), nil))
+	assertScriptError(t, execute(alicePk, solo, 0), script.ErrCodeEvalFalse)
+}
+
+// TestSigHashTypes 测试各哈希类型签名覆盖的交易内容
+//
+// 对两输入两输出交易的第2个输入签名，再逐项修改交易，检查签名是否仍然有效。
+func TestSigHashTypes(t *testing.T) {
+	mutations := []struct {
+		name   string
+		mutate func(tx *blockchain.Transaction)
+	}{
+		{"修改输出0", func(tx *blockchain.Transaction) { tx.TxOut[0].Value++ }},
+		{"修改输出1", func(tx *blockchain.Transaction) { tx.TxOut[1].Value++ }},
+		{"修改输入0序列号", func(tx *blockchain.Transaction) { tx.TxIn[0].Sequence = 7 }},
+		{"添加输入", func(tx *blockchain.Transaction) {
+			tx.TxIn = append(tx.TxIn, blockchain.NewTxIn(blockchain.NewOutPoint([32]byte{0x03}, 0), nil))
+		}},
+		{"添加输出", func(tx *blockchain.Transaction) {
+			tx.TxOut = append(tx.TxOut, blockchain.NewTxOut(1, []byte{script.OpTrue}))
+		}},
+	}
+
+	// valid依次对应mutations中的修改后签名是否仍然有效
+	tests := []struct {
+		hashType script.SigHashType
+		valid    [5]bool
+	}{
+		{script.SigHashAll, [5]bool{false, false, false, false, false}},
+		{script.SigHashNone, [5]bool{true, true, true, false, true}},
+		{script.SigHashSingle, [5]bool{true, false, true, false, true}},
+		{script.SigHashAll | script.SigHashAnyOneCanPay, [5]bool{false, false, true, true, false}},
+		{script.SigHashNone | script.SigHashAnyOneCanPay, [5]bool{true, true, true, true, true}},
+		{script.SigHashSingle | script.SigHashAnyOneCanPay, [5]bool{true, false, true, true, true}},
+	}
+
+	key := newKey(t)
+	pkScript := payToPubKeyHash(t, key)
+	for _, tt := range tests {
+		for i, m := range mutations {
+			t.Run(tt.hashType.String()+"/"+m.name, func(t *testing.T) {
+				tx := blockchain.NewTransaction(blockchain.TxVersion,
+					[]*blockchain.TxIn{
+						blockchain.NewTxIn(blockchain.NewOutPoint([32]byte{0x01}, 0), nil),
+						blockchain.NewTxIn(blockchain.NewOutPoint([32]byte{0x02}, 0), nil),
+					},
+					[]*blockchain.TxOut{
+						blockchain.NewTxOut(1000, []byte{script.OpTrue}),
+						blockchain.NewTxOut(2000, []byte{script.OpTrue}),
+					}, 0)
+				signInput(t, tx, 1, pkScript, tt.hashType, key)
+				if err := execute(pkScript, tx, 1); err != nil {
+					t.Fatalf("签名验证失败: %v", err)
+				}
+
+				m.mutate(tx)
+				err := execute(pkScript, tx, 1)
+				if tt.valid[i] && err != nil {
+					t.Errorf("修改未覆盖的内容后签名应仍然有效: %v", err)
+				}
+				if !tt.valid[i] {
+					assertScriptError(t, err, script.ErrCodeEvalFalse)
+				}
+			})
+		}
+	}
+}
+
+// TestSigHashSingleWithoutOutput 测试SIGHASH_SINGLE的输入没有对应输出时被拒绝
+func TestSigHashSingleWithoutOutput(t *testing.T) {
+	key := newKey(t)
+	pkScript := payToPubKeyHash(t, key)
+	tx := blockchain.NewTransaction(blockchain.TxVersion,
+		[]*blockchain.TxIn{
+			blockchain.NewTxIn(blockchain.NewOutPoint([32]byte{0x01}, 0), nil),
+			blockchain.NewTxIn(blockchain.NewOutPoint([32]byte{0x02}, 0), nil),
+		},
+		[]*blockchain.TxOut{blockchain.NewTxOut(1000, []byte{script.OpTrue})}, 0)
+
+	_, err := script.RawTxInSignature(tx, 1, pkScript, script.SigHashSingle, key)
+	assertScriptError(t, err, script.ErrCodeInvalidIndex)
+
+	// 验证时同样拒绝
+	sig, err := script.RawTxInSignature(tx, 0, pkScript, script.SigHashSingle, key)
+	if err != nil {
+		t.Fatalf("签名失败: %v", err)
+	}
+	tx.TxIn[1].SignatureScript = mustScript(t, script.NewBuilder().AddData(sig).AddData(key.PubKey().SerializeCompressed()))
+	assertScriptError(t, execute(pkScript, tx, 1), script.ErrCodeInvalidIndex)
+}
+
+// derEncode 将R和S编码为DER签名，不做low-S规范化
+func derEncode(r, s *secp256k1.ModNScalar) []byte {
+	encodeInt := func(v *secp256k1.ModNScalar) []byte {
+		b := v.Bytes()
+		i := 0
+		for i < len(b)-1 && b[i] == 0 {
+			i++
+		}
+		out := b[i:]
+		if out[0]&0x80 != 0 {
+			out = append([]byte{0x00}, out...)
+		}
+		return append([]byte{0x02, byte(len(out))}, out...)
+	}
+	body := append(encodeInt(r), encodeInt(s)...)
+	return append([]byte{0x30, byte(len(body))}, body...)
+}
+
+// TestStrictSignatureEncoding 测试签名编码的严格检查
+func TestStrictSignatureEncoding(t *testing.T) {
+	key := newKey(t)
+	pubKey := key.PubKey().SerializeCompressed()
+	pkScript := payToPubKeyHash(t, key)
+
+	tx := spendingTx(nil)
+	sig, err := script.RawTxInSignature(tx, 0, pkScript, script.SigHashAll, key)
+	if err != nil {
+		t.Fatalf("签名失败: %v", err)
+	}
+	parsed, err := ecdsa.ParseDERSignature(sig[:len(sig)-1])
+	if err != nil {
+		t.Fatalf("签名不是DER编码: %v", err)
+	}
+	r, s := parsed.R(), parsed.S()
+	if s.IsOverHalfOrder() {
+		t.Fatal("生成的签名应为low-S形式")
+	}
+
+	// 同一签名的high-S形式在数学上同样有效
+	highS := derEncode(&r, new(secp256k1.ModNScalar).NegateVal(&s))
+	// R值带有多余的前导零
+	padded := append([]byte{0x30, sig[1] + 1, 0x02, sig[3] + 1, 0x00}, sig[4:len(sig)-1]...)
+
+	withType := func(der []byte, hashType byte) []byte {
+		return append(append([]byte(nil), der...), hashType)
+	}
+	tests := []struct {
+		name string
+		sig  []byte
+		code script.ErrorCode
+	}{
+		{"low-S签名", sig, -1},
+		{"high-S签名", withType(highS, byte(script.SigHashAll)), script.ErrCodeSigHighS},
+		{"R值多余填充", withType(padded, byte(script.SigHashAll)), script.ErrCodeSigDER},
+		{"哈希类型0", withType(sig[:len(sig)-1], 0x00), script.ErrCodeInvalidSigHashType},
+		{"哈希类型4", withType(sig[:len(sig)-1], 0x04), script.ErrCodeInvalidSigHashType},
+		{"哈希类型0x41", withType(sig[:len(sig)-1], 0x41), script.ErrCodeInvalidSigHashType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spend := spendingTx(mustScript(t, script.NewBuilder().AddData(tt.sig).AddData(pubKey)))
+			assertScriptError(t, execute(pkScript, spend, 0), tt.code)
+		})
+	}
+
+	if _, err := script.CalcSignatureHash(pkScript, script.SigHashAll|0x40, tx, 0); !script.IsErrorCode(err, script.ErrCodeInvalidSigHashType) {
+		t.Errorf("期望ErrCodeInvalidSigHashType, 实际: %v", err)
+	}
+	if name := (script.SigHashSingle | script.SigHashAnyOneCanPay).String(); name != "SIGHASH_SINGLE|SIGHASH_ANYONECANPAY" {
+		t.Errorf("哈希类型名称错误: %s", name)
+	}
+}
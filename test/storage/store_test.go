@@ -0,0 +1,183 @@
+package storage_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/storage"
+	"simplied-bitcoin-network-go/pkg/utils"
+)
+
+// testConfig 返回指向临时目录的数据库配置
+func testConfig(t *testing.T, batchSize int) utils.DatabaseConfig {
+	t.Helper()
+	return utils.DatabaseConfig{
+		Type:      "bolt",
+		Path:      filepath.Join(t.TempDir(), "data", "chain.db"),
+		CacheSize: 1,
+		BatchSize: batchSize,
+	}
+}
+
+// buildChain 以创世区块为起点构造count个依次衔接的区块
+func buildChain(count int) []*blockchain.Block {
+	blocks := make([]*blockchain.Block, 0, count)
+	prev := blockchain.GetGenesisBlock()
+	for i := 0; i < count; i++ {
+		coinbase := blockchain.NewCoinbaseTransaction([]byte{byte(i + 1)}, 5000000000, []byte{0x51})
+		header := blockchain.NewBlockHeader(1, prev.Hash(), [32]byte{}, prev.Header.Timestamp+600, 0x1d00ffff, 0)
+		block := blockchain.NewBlock(header, []*blockchain.Transaction{coinbase})
+		header.MerkleRoot = block.GetMerkleRoot()
+
+		blocks = append(blocks, block)
+		prev = block
+	}
+	return blocks
+}
+
+// TestOpenInitialisesGenesis 测试首次打开时写入创世区块
+func TestOpenInitialisesGenesis(t *testing.T) {
+	store, err := storage.Open(testConfig(t, 10), blockchain.MainNetParams)
+	if err != nil {
+		t.Fatalf("打开存储失败: %v", err)
+	}
+	defer store.Close()
+
+	hash, height, err := store.Tip()
+	if err != nil {
+		t.Fatalf("读取主链末端失败: %v", err)
+	}
+	if hash != blockchain.GetGenesisBlockHash() || height != 0 {
+		t.Errorf("初始主链末端应为创世区块, 实际高度%d", height)
+	}
+
+	genesis, err := store.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("读取创世区块失败: %v", err)
+	}
+	if !blockchain.IsGenesisBlock(genesis) {
+		t.Error("高度0的区块应为创世区块")
+	}
+}
+
+// TestOpenUnsupportedType 测试不支持的数据库类型
+func TestOpenUnsupportedType(t *testing.T) {
+	cfg := testConfig(t, 10)
+	cfg.Type = "leveldb"
+
+	if _, err := storage.Open(cfg, blockchain.MainNetParams); err == nil {
+		t.Error("不支持的数据库类型应返回错误")
+	}
+}
+
+// TestPutBlocksAndHeightIndex 测试批量写入与高度索引
+func TestPutBlocksAndHeightIndex(t *testing.T) {
+	cfg := testConfig(t, 2)
+	store, err := storage.Open(cfg, blockchain.MainNetParams)
+	if err != nil {
+		t.Fatalf("打开存储失败: %v", err)
+	}
+
+	blocks := buildChain(5)
+	if err := store.PutBlocks(blocks, 1); err != nil {
+		t.Fatalf("批量写入失败: %v", err)
+	}
+
+	for i, block := range blocks {
+		if err := store.SetMainChainTip(block.Hash(), int32(i+1)); err != nil {
+			t.Fatalf("设置主链末端失败: %v", err)
+		}
+	}
+
+	for i, block := range blocks {
+		got, err := store.GetBlockByHeight(int32(i + 1))
+		if err != nil {
+			t.Fatalf("读取高度%d失败: %v", i+1, err)
+		}
+		if got.Hash() != block.Hash() {
+			t.Errorf("高度%d的区块不匹配", i+1)
+		}
+
+		_, height, err := store.GetHeader(block.Hash())
+		if err != nil || height != int32(i+1) {
+			t.Errorf("区块头高度错误: %d, %v", height, err)
+		}
+	}
+
+	// 回退主链末端后，更高的高度索引应被删除
+	if err := store.SetMainChainTip(blocks[1].Hash(), 2); err != nil {
+		t.Fatalf("回退主链末端失败: %v", err)
+	}
+	if _, err := store.GetHashByHeight(3); !errors.Is(err, storage.ErrHeightNotFound) {
+		t.Errorf("回退后高度3应不存在, 实际: %v", err)
+	}
+
+	// 区块数据本身仍然保留
+	if ok, _ := store.HasBlock(blocks[4].Hash()); !ok {
+		t.Error("回退主链不应删除区块数据")
+	}
+
+	// 重新打开后状态保持
+	store.Close()
+	store, err = storage.Open(cfg, blockchain.MainNetParams)
+	if err != nil {
+		t.Fatalf("重新打开存储失败: %v", err)
+	}
+	defer store.Close()
+
+	hash, height, _ := store.Tip()
+	if hash != blocks[1].Hash() || height != 2 {
+		t.Errorf("重新打开后主链末端错误: 高度%d", height)
+	}
+}
+
+// TestPutBlocksRejectsGap 测试批量写入不衔接的区块
+func TestPutBlocksRejectsGap(t *testing.T) {
+	store, err := storage.Open(testConfig(t, 10), blockchain.MainNetParams)
+	if err != nil {
+		t.Fatalf("打开存储失败: %v", err)
+	}
+	defer store.Close()
+
+	blocks := buildChain(3)
+	if err := store.PutBlocks([]*blockchain.Block{blocks[0], blocks[2]}, 1); err == nil {
+		t.Error("不衔接的区块应写入失败")
+	}
+}
+
+// TestPutHeaderOnly 测试仅保存区块头
+func TestPutHeaderOnly(t *testing.T) {
+	store, err := storage.Open(testConfig(t, 10), blockchain.MainNetParams)
+	if err != nil {
+		t.Fatalf("打开存储失败: %v", err)
+	}
+	defer store.Close()
+
+	block := buildChain(1)[0]
+	if err := store.PutHeader(block.Header, 1); err != nil {
+		t.Fatalf("保存区块头失败: %v", err)
+	}
+
+	header, height, err := store.GetHeader(block.Hash())
+	if err != nil || height != 1 || header.Hash() != block.Hash() {
+		t.Errorf("读取区块头失败: %v", err)
+	}
+
+	if ok, _ := store.HasBlock(block.Hash()); ok {
+		t.Error("仅保存区块头时不应存在区块数据")
+	}
+	if _, err := store.GetBlock(block.Hash()); !errors.Is(err, storage.ErrBlockNotFound) {
+		t.Errorf("应返回ErrBlockNotFound, 实际: %v", err)
+	}
+
+	count := 0
+	store.ForEachHeader(func(*blockchain.BlockHeader, int32) error {
+		count++
+		return nil
+	})
+	if count != 2 {
+		t.Errorf("区块头数量错误: 期望2, 实际%d", count)
+	}
+}
@@ -0,0 +1,209 @@
+package utxo_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/utils"
+	"simplied-bitcoin-network-go/pkg/utxo"
+)
+
+var (
+	scriptA = []byte{0x51}
+	scriptB = []byte{0x52}
+)
+
+// openTestSet 在临时目录中创建UTXO集合
+func openTestSet(t *testing.T) *utxo.Set {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "utxo.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	set, err := utxo.NewSet(db)
+	if err != nil {
+		t.Fatalf("创建UTXO集合失败: %v", err)
+	}
+	return set
+}
+
+// newTestBlock 创建以prev为前块的测试区块（不需要满足工作量证明）
+func newTestBlock(prev *blockchain.Block, txs ...*blockchain.Transaction) *blockchain.Block {
+	header := blockchain.NewBlockHeader(1, prev.Hash(), [32]byte{}, prev.Header.Timestamp+600, 0x1d00ffff, 0)
+	block := blockchain.NewBlock(header, txs)
+	header.MerkleRoot = block.GetMerkleRoot()
+	return block
+}
+
+// spend 创建花费指定输出的交易
+func spend(prev *blockchain.Transaction, index uint32, outputs ...*blockchain.TxOut) *blockchain.Transaction {
+	txIn := blockchain.NewTxIn(blockchain.NewOutPoint(prev.Hash(), index), nil)
+	return blockchain.NewTransaction(blockchain.TxVersion, []*blockchain.TxIn{txIn}, outputs, 0)
+}
+
+// TestConnectDisconnectBlock 测试区块连接与断开
+func TestConnectDisconnectBlock(t *testing.T) {
+	set := openTestSet(t)
+	genesis := blockchain.GetGenesisBlock()
+	genesisCoinbase := genesis.Transactions[0]
+
+	if err := set.ConnectBlock(genesis, 0); err != nil {
+		t.Fatalf("连接创世区块失败: %v", err)
+	}
+
+	// 区块1：Coinbase + 花费创世输出的交易 + 花费同区块内输出的交易
+	coinbase1 := blockchain.NewCoinbaseTransaction([]byte{0x01}, 5000000000, scriptA)
+	tx1 := spend(genesisCoinbase, 0, blockchain.NewTxOut(3000000000, scriptB), blockchain.NewTxOut(1999990000, scriptA))
+	tx2 := spend(tx1, 0, blockchain.NewTxOut(2999990000, scriptB))
+	block1 := newTestBlock(genesis, coinbase1, tx1, tx2)
+
+	if err := set.ConnectBlock(block1, 1); err != nil {
+		t.Fatalf("连接区块1失败: %v", err)
+	}
+
+	hash, height, err := set.BestBlock()
+	if err != nil || hash != block1.Hash() || height != 1 {
+		t.Fatalf("最新区块错误: height=%d err=%v", height, err)
+	}
+
+	// 创世输出与tx1的第0个输出均已被花费
+	genesisOut := blockchain.OutPoint{Hash: genesisCoinbase.Hash(), Index: 0}
+	if entry, _ := set.FetchEntry(genesisOut); entry != nil {
+		t.Error("创世输出应已被花费")
+	}
+	if entry, _ := set.FetchEntry(blockchain.OutPoint{Hash: tx1.Hash(), Index: 0}); entry != nil {
+		t.Error("tx1的输出0应已被花费")
+	}
+
+	balanceA, _ := set.Balance(scriptA)
+	balanceB, _ := set.Balance(scriptB)
+	if balanceA != 5000000000+1999990000 {
+		t.Errorf("脚本A余额错误: %d", balanceA)
+	}
+	if balanceB != 2999990000 {
+		t.Errorf("脚本B余额错误: %d", balanceB)
+	}
+
+	count, _ := set.Count()
+	if count != 3 {
+		t.Errorf("UTXO数量错误: 期望3, 实际%d", count)
+	}
+
+	// 断开区块1后应恢复到仅有创世输出的状态
+	if err := set.DisconnectBlock(block1); err != nil {
+		t.Fatalf("断开区块1失败: %v", err)
+	}
+
+	entry, err := set.FetchEntry(genesisOut)
+	if err != nil || entry == nil {
+		t.Fatalf("断开后创世输出应被恢复: %v", err)
+	}
+	if entry.Value != 5000000000 || !entry.IsCoinbase || entry.Height != 0 {
+		t.Errorf("恢复的创世输出内容错误: %+v", entry)
+	}
+
+	count, _ = set.Count()
+	if count != 1 {
+		t.Errorf("断开后UTXO数量错误: 期望1, 实际%d", count)
+	}
+
+	_, height, _ = set.BestBlock()
+	if height != 0 {
+		t.Errorf("断开后高度错误: 期望0, 实际%d", height)
+	}
+
+	// 重新连接同一区块应当成功
+	if err := set.ConnectBlock(block1, 1); err != nil {
+		t.Fatalf("重新连接区块1失败: %v", err)
+	}
+}
+
+// TestConnectBlockDoubleSpend 测试双花区块被拒绝且状态保持不变
+func TestConnectBlockDoubleSpend(t *testing.T) {
+	set := openTestSet(t)
+	genesis := blockchain.GetGenesisBlock()
+	genesisCoinbase := genesis.Transactions[0]
+
+	if err := set.ConnectBlock(genesis, 0); err != nil {
+		t.Fatalf("连接创世区块失败: %v", err)
+	}
+
+	coinbase := blockchain.NewCoinbaseTransaction([]byte{0x01}, 5000000000, scriptA)
+	tx1 := spend(genesisCoinbase, 0, blockchain.NewTxOut(100, scriptA))
+	tx2 := spend(genesisCoinbase, 0, blockchain.NewTxOut(200, scriptB))
+	block := newTestBlock(genesis, coinbase, tx1, tx2)
+
+	err := set.ConnectBlock(block, 1)
+	if !errors.Is(err, utxo.ErrMissingInput) {
+		t.Fatalf("双花区块应返回ErrMissingInput, 实际: %v", err)
+	}
+
+	// 失败的连接不应留下任何修改
+	count, _ := set.Count()
+	if count != 1 {
+		t.Errorf("失败连接后UTXO数量错误: 期望1, 实际%d", count)
+	}
+	_, height, _ := set.BestBlock()
+	if height != 0 {
+		t.Errorf("失败连接后高度错误: %d", height)
+	}
+}
+
+// TestConnectBlockOrdering 测试区块必须与当前状态衔接
+func TestConnectBlockOrdering(t *testing.T) {
+	set := openTestSet(t)
+	genesis := blockchain.GetGenesisBlock()
+
+	if err := set.ConnectBlock(genesis, 0); err != nil {
+		t.Fatalf("连接创世区块失败: %v", err)
+	}
+
+	block1 := newTestBlock(genesis, blockchain.NewCoinbaseTransaction([]byte{0x01}, 1, scriptA))
+	block2 := newTestBlock(block1, blockchain.NewCoinbaseTransaction([]byte{0x02}, 1, scriptA))
+
+	if err := set.ConnectBlock(block2, 2); !errors.Is(err, utxo.ErrNotBestBlock) {
+		t.Errorf("跳过区块连接应返回ErrNotBestBlock, 实际: %v", err)
+	}
+
+	if err := set.ConnectBlock(block1, 1); err != nil {
+		t.Fatalf("连接区块1失败: %v", err)
+	}
+
+	if err := set.DisconnectBlock(genesis); !errors.Is(err, utxo.ErrNotBestBlock) {
+		t.Errorf("断开非最新区块应返回ErrNotBestBlock, 实际: %v", err)
+	}
+}
+
+// TestIsSpendableMaturity 测试Coinbase输出成熟度
+func TestIsSpendableMaturity(t *testing.T) {
+	set := openTestSet(t)
+	genesis := blockchain.GetGenesisBlock()
+
+	if err := set.ConnectBlock(genesis, 0); err != nil {
+		t.Fatalf("连接创世区块失败: %v", err)
+	}
+
+	op := blockchain.OutPoint{Hash: genesis.Transactions[0].Hash(), Index: 0}
+
+	spendable, err := set.IsSpendable(op, utils.CoinbaseMaturity-1, blockchain.MainNetParams)
+	if err != nil || spendable {
+		t.Error("未成熟的Coinbase输出不应可花费")
+	}
+
+	spendable, err = set.IsSpendable(op, utils.CoinbaseMaturity, blockchain.MainNetParams)
+	if err != nil || !spendable {
+		t.Error("已成熟的Coinbase输出应可花费")
+	}
+
+	spendable, _ = set.IsSpendable(blockchain.OutPoint{Index: 5}, 1000, blockchain.MainNetParams)
+	if spendable {
+		t.Error("不存在的输出不应可花费")
+	}
+}
@@ -0,0 +1,211 @@
+package wallet_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/wallet"
+)
+
+// TestMnemonicVectors 测试BIP39官方测试向量
+func TestMnemonicVectors(t *testing.T) {
+	vectors := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			entropy:  "00000000000000000000000000000000",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			mnemonic: "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal will",
+			seed:     "f2b94508732bcbacbcc020faefecfc89feafa6649a5491b8c952cede496c214a0c7b3c392d168748f2d4a612bada0753b52a1c7ac53c1e93abd5c6320b9e95dd",
+		},
+		{
+			entropy:  "8080808080808080808080808080808080808080808080808080808080808080",
+			mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic bless",
+			seed:     "c0c519bd0e91a2ed54357d9d1ebef6f5af218a153624cf4f2da911a0ed8f7a09e2ef61af0aca007096df430022f7a2b6fb91661a9589097069720d015e4e982f",
+		},
+	}
+
+	for _, v := range vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+		mnemonic, err := wallet.NewMnemonic(entropy)
+		if err != nil {
+			t.Fatalf("编码助记词失败: %v", err)
+		}
+		if mnemonic != v.mnemonic {
+			t.Errorf("熵%s的助记词错误: %s", v.entropy, mnemonic)
+		}
+
+		decoded, err := wallet.MnemonicToEntropy(v.mnemonic)
+		if err != nil {
+			t.Fatalf("解码助记词失败: %v", err)
+		}
+		if hex.EncodeToString(decoded) != v.entropy {
+			t.Errorf("解码的熵错误: %x", decoded)
+		}
+
+		seed, err := wallet.NewSeed(v.mnemonic, "TREZOR")
+		if err != nil {
+			t.Fatalf("生成种子失败: %v", err)
+		}
+		if hex.EncodeToString(seed) != v.seed {
+			t.Errorf("助记词%q的种子错误: %x", v.mnemonic, seed)
+		}
+	}
+}
+
+// TestInvalidMnemonic 测试无效助记词的检测
+func TestInvalidMnemonic(t *testing.T) {
+	tests := []struct {
+		mnemonic string
+		err      error
+	}{
+		{"abandon abandon abandon", wallet.ErrInvalidMnemonic},
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon bitcoin", wallet.ErrInvalidMnemonic},
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", wallet.ErrMnemonicChecksum},
+		{"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo", wallet.ErrMnemonicChecksum},
+	}
+
+	for _, tt := range tests {
+		if err := wallet.ValidateMnemonic(tt.mnemonic); !errors.Is(err, tt.err) {
+			t.Errorf("助记词%q: 期望%v, 实际: %v", tt.mnemonic, tt.err, err)
+		}
+	}
+
+	if _, err := wallet.NewEntropy(160 + 8); !errors.Is(err, wallet.ErrInvalidEntropy) {
+		t.Errorf("期望ErrInvalidEntropy, 实际: %v", err)
+	}
+}
+
+// TestExtendedKeyVectors 测试BIP32官方测试向量1
+func TestExtendedKeyVectors(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := wallet.NewMaster(seed, blockchain.MainNetParams)
+	if err != nil {
+		t.Fatalf("生成主密钥失败: %v", err)
+	}
+
+	vectors := []struct {
+		path string
+		priv string
+		pub  string
+	}{
+		{
+			path: "m",
+			priv: "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+			pub:  "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+		},
+		{
+			path: "m/0'",
+			priv: "xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+			pub:  "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+		},
+		{
+			path: "m/0'/1",
+			priv: "xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs",
+			pub:  "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+		},
+		{
+			path: "m/0h/1/2h",
+			priv: "xprv9z4pot5VBttmtdRTWfWQmoH1taj2axGVzFqSb8C9xaxKymcFzXBDptWmT7FwuEzG3ryjH4ktypQSAewRiNMjANTtpgP4mLTj34bhnZX7UiM",
+			pub:  "xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5",
+		},
+		{
+			path: "m/0'/1/2'/2/1000000000",
+			priv: "xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76",
+			pub:  "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+		},
+	}
+
+	for _, v := range vectors {
+		key, err := master.DerivePath(v.path)
+		if err != nil {
+			t.Fatalf("推导%s失败: %v", v.path, err)
+		}
+		if got := key.String(); got != v.priv {
+			t.Errorf("%s的扩展私钥错误: %s", v.path, got)
+		}
+		if got := key.Neuter().String(); got != v.pub {
+			t.Errorf("%s的扩展公钥错误: %s", v.path, got)
+		}
+
+		parsed, err := wallet.ParseExtendedKey(v.priv, blockchain.MainNetParams)
+		if err != nil {
+			t.Fatalf("解析%s失败: %v", v.priv, err)
+		}
+		if parsed.String() != v.priv || !parsed.IsPrivate() {
+			t.Errorf("%s的扩展私钥往返编码不一致", v.path)
+		}
+	}
+}
+
+// TestPublicDerivation 测试扩展公钥推导与扩展私钥推导结果一致
+func TestPublicDerivation(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := wallet.NewMaster(seed, blockchain.RegTestParams)
+	if err != nil {
+		t.Fatalf("生成主密钥失败: %v", err)
+	}
+	account, err := master.DerivePath("m/44'/1'/0'")
+	if err != nil {
+		t.Fatalf("推导账户密钥失败: %v", err)
+	}
+
+	fromPriv, err := account.DerivePath("m/0/7")
+	if err != nil {
+		t.Fatalf("推导子私钥失败: %v", err)
+	}
+	fromPub, err := account.Neuter().DerivePath("m/0/7")
+	if err != nil {
+		t.Fatalf("推导子公钥失败: %v", err)
+	}
+	if fromPriv.Neuter().String() != fromPub.String() {
+		t.Errorf("扩展公钥推导结果不一致: %s != %s", fromPub, fromPriv.Neuter())
+	}
+
+	if _, err := account.Neuter().Derive(wallet.HardenedKeyStart); !errors.Is(err, wallet.ErrDeriveHardenedFromPublic) {
+		t.Errorf("期望ErrDeriveHardenedFromPublic, 实际: %v", err)
+	}
+	if _, err := wallet.ParseExtendedKey(account.String(), blockchain.MainNetParams); !errors.Is(err, wallet.ErrWrongNetwork) {
+		t.Errorf("期望ErrWrongNetwork, 实际: %v", err)
+	}
+	if _, err := master.DerivePath("m/0/x"); !errors.Is(err, wallet.ErrInvalidPath) {
+		t.Errorf("期望ErrInvalidPath, 实际: %v", err)
+	}
+}
+
+// TestWIF 测试WIF私钥编码
+func TestWIF(t *testing.T) {
+	priv, _ := hex.DecodeString("0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d")
+	kp, err := wallet.NewKeyPair(priv)
+	if err != nil {
+		t.Fatalf("创建密钥对失败: %v", err)
+	}
+
+	wif := kp.WIF(blockchain.MainNetParams)
+	if wif != "KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617" {
+		t.Errorf("WIF编码错误: %s", wif)
+	}
+
+	decoded, err := wallet.DecodeWIF(wif, blockchain.MainNetParams)
+	if err != nil {
+		t.Fatalf("解码WIF失败: %v", err)
+	}
+	if hex.EncodeToString(decoded.SerializePrivateKey()) != hex.EncodeToString(priv) {
+		t.Errorf("解码的私钥错误: %x", decoded.SerializePrivateKey())
+	}
+	if _, err := wallet.DecodeWIF(wif, blockchain.TestNetParams); !errors.Is(err, wallet.ErrInvalidWIF) {
+		t.Errorf("期望ErrInvalidWIF, 实际: %v", err)
+	}
+
+	if _, err := wallet.NewKeyPair(make([]byte, 32)); !errors.Is(err, wallet.ErrInvalidPrivateKey) {
+		t.Errorf("期望ErrInvalidPrivateKey, 实际: %v", err)
+	}
+}
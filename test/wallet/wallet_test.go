@@ -0,0 +1,195 @@
+package wallet_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/boltdb/bolt"
+
+	"simplied-bitcoin-network-go/pkg/blockchain"
+	"simplied-bitcoin-network-go/pkg/wallet"
+)
+
+// testMnemonic 测试钱包使用的助记词
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// openDB 在临时目录中创建数据库
+func openDB(t *testing.T) *bolt.DB {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "wallets.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newManager 创建钱包管理器
+func newManager(t *testing.T, db *bolt.DB, params *blockchain.ChainParams) *wallet.Manager {
+	t.Helper()
+
+	m, err := wallet.New(db, params)
+	if err != nil {
+		t.Fatalf("创建钱包管理器失败: %v", err)
+	}
+	return m
+}
+
+// TestCreateAndOpen 测试创建、打开、列出和删除钱包
+func TestCreateAndOpen(t *testing.T) {
+	db := openDB(t)
+	m := newManager(t, db, blockchain.RegTestParams)
+
+	w, mnemonic, err := m.Create("alice", "secret")
+	if err != nil {
+		t.Fatalf("创建钱包失败: %v", err)
+	}
+	if err := wallet.ValidateMnemonic(mnemonic); err != nil {
+		t.Fatalf("生成的助记词无效: %v", err)
+	}
+	if _, _, err := m.Create("alice", "secret"); !errors.Is(err, wallet.ErrWalletExists) {
+		t.Errorf("期望ErrWalletExists, 实际: %v", err)
+	}
+	if _, _, err := m.Create("bob", ""); !errors.Is(err, wallet.ErrEmptyPassphrase) {
+		t.Errorf("期望ErrEmptyPassphrase, 实际: %v", err)
+	}
+	if _, _, err := m.Create("a b", "secret"); !errors.Is(err, wallet.ErrInvalidName) {
+		t.Errorf("期望ErrInvalidName, 实际: %v", err)
+	}
+	if _, err := m.Restore("bob", testMnemonic, "other"); err != nil {
+		t.Fatalf("恢复钱包失败: %v", err)
+	}
+
+	first, index, err := w.NextKey(wallet.ExternalBranch)
+	if err != nil || index != 0 {
+		t.Fatalf("获取接收密钥失败: 索引%d %v", index, err)
+	}
+	if _, index, _ := w.NextKey(wallet.ExternalBranch); index != 1 {
+		t.Errorf("第二个接收密钥索引错误: %d", index)
+	}
+
+	// 重新打开数据库，钱包和密钥索引都应保留
+	m = newManager(t, db, blockchain.RegTestParams)
+	if _, err := m.Open("alice", "wrong"); !errors.Is(err, wallet.ErrWrongPassphrase) {
+		t.Errorf("期望ErrWrongPassphrase, 实际: %v", err)
+	}
+	if _, err := m.Open("carol", "secret"); !errors.Is(err, wallet.ErrWalletNotFound) {
+		t.Errorf("期望ErrWalletNotFound, 实际: %v", err)
+	}
+
+	opened, err := m.Open("alice", "secret")
+	if err != nil {
+		t.Fatalf("打开钱包失败: %v", err)
+	}
+	if opened.Mnemonic() != mnemonic || !opened.CreatedAt().Equal(w.CreatedAt()) {
+		t.Error("打开的钱包与创建的钱包不一致")
+	}
+	if opened.NextIndex(wallet.ExternalBranch) != 2 || opened.NextIndex(wallet.InternalBranch) != 0 {
+		t.Errorf("密钥索引未保存: 接收%d 找零%d",
+			opened.NextIndex(wallet.ExternalBranch), opened.NextIndex(wallet.InternalBranch))
+	}
+	key, err := opened.DeriveKey(wallet.ExternalBranch, 0)
+	if err != nil {
+		t.Fatalf("推导密钥失败: %v", err)
+	}
+	if !bytes.Equal(key.SerializePubKey(), first.SerializePubKey()) {
+		t.Error("重新打开后推导的密钥不一致")
+	}
+
+	names, err := m.List()
+	if err != nil {
+		t.Fatalf("列出钱包失败: %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"alice", "bob"}) {
+		t.Errorf("钱包列表错误: %v", names)
+	}
+
+	if err := m.Delete("bob"); err != nil {
+		t.Fatalf("删除钱包失败: %v", err)
+	}
+	if err := m.Delete("bob"); !errors.Is(err, wallet.ErrWalletNotFound) {
+		t.Errorf("期望ErrWalletNotFound, 实际: %v", err)
+	}
+	if names, _ := m.List(); !reflect.DeepEqual(names, []string{"alice"}) {
+		t.Errorf("删除后钱包列表错误: %v", names)
+	}
+}
+
+// TestNextKeyMultipleHandles 测试同一钱包的多个句柄不会分配相同的密钥索引
+func TestNextKeyMultipleHandles(t *testing.T) {
+	m := newManager(t, openDB(t), blockchain.RegTestParams)
+	if _, err := m.Restore("alice", testMnemonic, "secret"); err != nil {
+		t.Fatalf("恢复钱包失败: %v", err)
+	}
+	first, err := m.Open("alice", "secret")
+	if err != nil {
+		t.Fatalf("打开钱包失败: %v", err)
+	}
+	second, err := m.Open("alice", "secret")
+	if err != nil {
+		t.Fatalf("打开钱包失败: %v", err)
+	}
+
+	seen := make(map[uint32]bool)
+	for i, w := range []*wallet.Wallet{first, second, second, first} {
+		_, index, err := w.NextKey(wallet.ExternalBranch)
+		if err != nil {
+			t.Fatalf("获取接收密钥失败: %v", err)
+		}
+		if seen[index] || index != uint32(i) {
+			t.Fatalf("第%d次分配的索引错误: %d", i, index)
+		}
+		seen[index] = true
+	}
+
+	// 较早分配的句柄不会使保存的索引回退
+	reopened, err := m.Open("alice", "secret")
+	if err != nil {
+		t.Fatalf("打开钱包失败: %v", err)
+	}
+	if next := reopened.NextIndex(wallet.ExternalBranch); next != 4 {
+		t.Errorf("保存的索引应为4, 实际%d", next)
+	}
+}
+
+// TestDeterministicRestore 测试由同一助记词恢复的钱包推导出相同的密钥
+func TestDeterministicRestore(t *testing.T) {
+	first, err := newManager(t, openDB(t), blockchain.MainNetParams).Restore("test", testMnemonic, "one")
+	if err != nil {
+		t.Fatalf("恢复钱包失败: %v", err)
+	}
+	second, err := newManager(t, openDB(t), blockchain.MainNetParams).Restore("copy", testMnemonic, "two")
+	if err != nil {
+		t.Fatalf("恢复钱包失败: %v", err)
+	}
+
+	// BIP44测试向量：该助记词的 m/44'/0'/0' 账户扩展公钥
+	const accountXpub = "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj"
+	if got := first.AccountKey().Neuter().String(); got != accountXpub {
+		t.Errorf("账户扩展公钥错误: %s", got)
+	}
+
+	for _, branch := range []uint32{wallet.ExternalBranch, wallet.InternalBranch} {
+		for index := uint32(0); index < 3; index++ {
+			a, err := first.DeriveKey(branch, index)
+			if err != nil {
+				t.Fatalf("推导密钥失败: %v", err)
+			}
+			b, err := second.DeriveKey(branch, index)
+			if err != nil {
+				t.Fatalf("推导密钥失败: %v", err)
+			}
+			if !bytes.Equal(a.SerializePrivateKey(), b.SerializePrivateKey()) {
+				t.Errorf("链%d索引%d的密钥不一致", branch, index)
+			}
+		}
+	}
+
+	if _, err := first.DeriveKey(2, 0); !errors.Is(err, wallet.ErrInvalidBranch) {
+		t.Errorf("期望ErrInvalidBranch, 实际: %v", err)
+	}
+}